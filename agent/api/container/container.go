@@ -70,9 +70,19 @@ const (
 	// SecretProviderASM is to show secret provider being ASM
 	SecretProviderASM = "asm"
 
+	// SecretProviderLocal is to show secret provider being the encrypted secrets directory of the host
+	SecretProviderLocal = "local"
+
+	// SecretProviderVault is to show secret provider being a Vault compatible KV store
+	SecretProviderVault = "vault"
+
 	// SecretTypeEnv is to show secret type being ENVIRONMENT_VARIABLE
 	SecretTypeEnv = "ENVIRONMENT_VARIABLE"
 
+	// SecretTypeMountPoint is to show secret type being MOUNT_POINT, such secrets are delivered
+	// to the container as files on a tmpfs instead of environment variables
+	SecretTypeMountPoint = "MOUNT_POINT"
+
 	// TargetLogDriver is to show secret target being "LOG_DRIVER", the default will be "CONTAINER"
 	SecretTargetLogDriver = "LOG_DRIVER"
 
@@ -365,11 +375,22 @@ type Secret struct {
 }
 
 // GetSecretResourceCacheKey returns the key required to access the secret
-// from the ssmsecret resource
+// from the ssmsecret, asmsecret and providersecret resources
 func (s *Secret) GetSecretResourceCacheKey() string {
+	if s.UsesHostSecretProvider() {
+		// Secrets of these providers have no region, the provider tells apart
+		// secrets with the same valueFrom
+		return s.Provider + ":" + s.ValueFrom
+	}
 	return s.ValueFrom + "_" + s.Region
 }
 
+// UsesHostSecretProvider returns true if the secret is retrieved by a provider
+// configured on the host rather than from an AWS service
+func (s *Secret) UsesHostSecretProvider() bool {
+	return s.Provider == SecretProviderLocal || s.Provider == SecretProviderVault
+}
+
 // String returns a human readable string representation of DockerContainer
 func (dc *DockerContainer) String() string {
 	if dc == nil {
@@ -953,6 +974,22 @@ func (c *Container) ShouldCreateWithSSMSecret() bool {
 	return false
 }
 
+// ShouldCreateWithSecretFiles returns true if this container has secrets that are
+// delivered as files
+func (c *Container) ShouldCreateWithSecretFiles() bool {
+	return c.HasSecret(func(s Secret) bool {
+		return s.Type == SecretTypeMountPoint
+	})
+}
+
+// ShouldCreateWithHostProviderSecret returns true if this container needs to get
+// secret values from a provider configured on the host
+func (c *Container) ShouldCreateWithHostProviderSecret() bool {
+	return c.HasSecret(func(s Secret) bool {
+		return s.UsesHostSecretProvider()
+	})
+}
+
 // ShouldCreateWithASMSecret returns true if this container needs to get secret
 // value from AWS Secrets Manager
 func (c *Container) ShouldCreateWithASMSecret() bool {
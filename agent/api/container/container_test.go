@@ -426,6 +426,57 @@ func TestShouldCreateWithASMSecret(t *testing.T) {
 	}
 }
 
+func TestShouldCreateWithHostProviderSecret(t *testing.T) {
+	cases := []struct {
+		in  Container
+		out bool
+	}{
+		{Container{
+			Name:  "myName",
+			Image: "image:tag",
+			Secrets: []Secret{
+				Secret{
+					Provider:  "local",
+					Name:      "secret",
+					ValueFrom: "db/password",
+				}},
+		}, true},
+		{Container{
+			Name:  "myName",
+			Image: "image:tag",
+			Secrets: []Secret{
+				Secret{
+					Provider:  "vault",
+					Name:      "secret",
+					ValueFrom: "secret/db#password",
+				}},
+		}, true},
+		{Container{
+			Name:  "myName",
+			Image: "image:tag",
+			Secrets: []Secret{
+				Secret{
+					Provider:  "ssm",
+					Name:      "secret",
+					ValueFrom: "/test/secretName",
+				}},
+		}, false},
+	}
+
+	for _, test := range cases {
+		container := test.in
+		assert.Equal(t, test.out, container.ShouldCreateWithHostProviderSecret())
+	}
+}
+
+func TestGetSecretResourceCacheKey(t *testing.T) {
+	ssmSecret := Secret{Provider: SecretProviderSSM, ValueFrom: "/test/secretName", Region: "us-west-2"}
+	assert.Equal(t, "/test/secretName_us-west-2", ssmSecret.GetSecretResourceCacheKey())
+
+	vaultSecret := Secret{Provider: SecretProviderVault, ValueFrom: "secret/db#password"}
+	assert.Equal(t, "vault:secret/db#password", vaultSecret.GetSecretResourceCacheKey())
+}
+
 func TestHasSecret(t *testing.T) {
 	isEnvOrLogDriverSecret := func(s Secret) bool {
 		return s.Type == SecretTypeEnv || s.Target == SecretTargetLogDriver
@@ -301,6 +301,13 @@ func (client *APIECSClient) getResources() ([]*ecs.Resource, error) {
 	return []*ecs.Resource{&cpuResource, &memResource, &portResource, &udpPortResource}, nil
 }
 
+// RegisteredCPUAndMemory returns the CPU units and the memory, in MiB, that the container instance
+// registers with: all the CPUs of the host and its memory less the reserved memory.
+func RegisteredCPUAndMemory(cfg *config.Config) (int64, int64) {
+	cpu, mem := getCpuAndMemory()
+	return cpu, mem - int64(cfg.ReservedMemory)
+}
+
 func getCpuAndMemory() (int64, int64) {
 	memInfo, err := system.ReadMemInfo()
 	mem := int64(0)
@@ -101,6 +101,22 @@ type AttachmentStateChange struct {
 	Attachment *apieni.ENIAttachment
 }
 
+// SecretsRefreshedStateChange represents the refresh of secrets of a running container whose
+// value changed. It's only published to the local subscribers of state changes, ECS isn't
+// notified of it.
+type SecretsRefreshedStateChange struct {
+	// TaskArn is the unique identifier for the task
+	TaskArn string
+	// RuntimeID is the dockerID of the container
+	RuntimeID string
+	// ContainerName is the name of the container
+	ContainerName string
+	// Secrets are the names of the secrets of the container whose value changed
+	Secrets []string
+	// FilesUpdated is set when the secret files of the container were updated with the new values
+	FilesUpdated bool
+}
+
 // NewTaskStateChangeEvent creates a new task state change event
 // returns error if the state change doesn't need to be sent to the ECS backend.
 func NewTaskStateChangeEvent(task *apitask.Task, reason string) (TaskStateChange, error) {
@@ -321,3 +337,8 @@ func (ts TaskStateChange) GetEventType() statechange.EventType {
 func (AttachmentStateChange) GetEventType() statechange.EventType {
 	return statechange.AttachmentEvent
 }
+
+// GetEventType returns an enum identifying the event type
+func (SecretsRefreshedStateChange) GetEventType() statechange.EventType {
+	return statechange.SecretsRefreshedEvent
+}
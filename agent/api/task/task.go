@@ -48,6 +48,8 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/providersecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
@@ -384,6 +386,13 @@ func (task *Task) PostUnmarshalTask(cfg *config.Config,
 		seelog.Errorf("Task [%s]: could not initialize environment files resource: %v", task.Arn, err)
 		return apierrors.NewResourceInitError(task.Arn, err)
 	}
+
+	if task.requiresSecretFilesResource() {
+		if err := task.initializeSecretFilesResource(cfg); err != nil {
+			seelog.Errorf("Task [%s]: could not initialize secret files resource: %v", task.Arn, err)
+			return apierrors.NewResourceInitError(task.Arn, err)
+		}
+	}
 	task.populateTaskARN()
 
 	// fsxWindowsFileserver is the product type -- it is technically "agnostic" ie it should apply to both Windows and Linux tasks
@@ -423,6 +432,10 @@ func (task *Task) initSecretResources(credentialsManager credentials.Manager,
 	if task.requiresASMSecret() {
 		task.initializeASMSecretResource(credentialsManager, resourceFields)
 	}
+
+	if task.requiresHostProviderSecret() {
+		task.initializeProviderSecretResource(resourceFields)
+	}
 }
 
 func (task *Task) applyFirelensSetup(cfg *config.Config, resourceFields *taskresource.ResourceFields,
@@ -943,6 +956,57 @@ func (task *Task) getAllASMSecretRequirements() map[string]apicontainer.Secret {
 	return reqs
 }
 
+// requiresHostProviderSecret returns true if at least one container in the task
+// needs to retrieve secret from a provider configured on the host
+func (task *Task) requiresHostProviderSecret() bool {
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithHostProviderSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// initializeProviderSecretResource builds the resource dependency map for the providersecret resource
+func (task *Task) initializeProviderSecretResource(resourceFields *taskresource.ResourceFields) {
+	providerSecretResource := providersecret.NewProviderSecretResource(task.Arn,
+		task.getAllHostProviderSecretRequirements(), resourceFields.SecretProviders)
+	task.AddResource(providersecret.ResourceName, providerSecretResource)
+
+	// for every container that needs provider secret vending as envvar, it needs to wait all secrets got retrieved
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithHostProviderSecret() {
+			container.BuildResourceDependency(providerSecretResource.GetName(),
+				resourcestatus.ResourceStatus(providersecret.ProviderSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+
+		// Firelens container needs to depends on secret if other containers use secret log options.
+		if container.GetFirelensConfig() != nil &&
+			(task.firelensDependsOnSecretResource(apicontainer.SecretProviderLocal) ||
+				task.firelensDependsOnSecretResource(apicontainer.SecretProviderVault)) {
+			container.BuildResourceDependency(providerSecretResource.GetName(),
+				resourcestatus.ResourceStatus(providersecret.ProviderSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+	}
+}
+
+// getAllHostProviderSecretRequirements stores all secrets of providers configured on the host in
+// a map whose key is the provider and value is all secrets of that provider
+func (task *Task) getAllHostProviderSecretRequirements() map[string][]apicontainer.Secret {
+	reqs := make(map[string][]apicontainer.Secret)
+
+	for _, container := range task.Containers {
+		for _, secret := range container.Secrets {
+			if secret.UsesHostSecretProvider() {
+				reqs[secret.Provider] = append(reqs[secret.Provider], secret)
+			}
+		}
+	}
+	return reqs
+}
+
 // GetFirelensContainer returns the firelens container in the task, if there is one.
 func (task *Task) GetFirelensContainer() *apicontainer.Container {
 	for _, container := range task.Containers {
@@ -2345,6 +2409,7 @@ func (task *Task) getSSMSecretsResource() ([]taskresource.TaskResource, bool) {
 func (task *Task) PopulateSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container) *apierrors.DockerClientConfigError {
 	var ssmRes *ssmsecret.SSMSecretResource
 	var asmRes *asmsecret.ASMSecretResource
+	var providerRes *providersecret.ProviderSecretResource
 
 	if container.ShouldCreateWithSSMSecret() {
 		resource, ok := task.getSSMSecretsResource()
@@ -2362,12 +2427,21 @@ func (task *Task) PopulateSecrets(hostConfig *dockercontainer.HostConfig, contai
 		asmRes = resource[0].(*asmsecret.ASMSecretResource)
 	}
 
-	populateContainerSecrets(hostConfig, container, ssmRes, asmRes)
+	if container.ShouldCreateWithHostProviderSecret() {
+		resource, ok := task.getProviderSecretsResource()
+		if !ok {
+			return &apierrors.DockerClientConfigError{Msg: "task secret data: unable to fetch provider Secrets resource"}
+		}
+		providerRes = resource[0].(*providersecret.ProviderSecretResource)
+	}
+
+	populateContainerSecrets(hostConfig, container, ssmRes, asmRes, providerRes)
 	return nil
 }
 
 func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container,
-	ssmRes *ssmsecret.SSMSecretResource, asmRes *asmsecret.ASMSecretResource) {
+	ssmRes *ssmsecret.SSMSecretResource, asmRes *asmsecret.ASMSecretResource,
+	providerRes *providersecret.ProviderSecretResource) {
 	envVars := make(map[string]string)
 
 	logDriverTokenName := ""
@@ -2390,6 +2464,13 @@ func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container
 			}
 		}
 
+		if secret.UsesHostSecretProvider() {
+			k := secret.GetSecretResourceCacheKey()
+			if secretValue, ok := providerRes.GetCachedSecretValue(k); ok {
+				secretVal = secretValue
+			}
+		}
+
 		if secret.Type == apicontainer.SecretTypeEnv {
 			envVars[secret.Name] = secretVal
 			continue
@@ -2427,6 +2508,7 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 
 	var ssmRes *ssmsecret.SSMSecretResource
 	var asmRes *asmsecret.ASMSecretResource
+	var providerRes *providersecret.ProviderSecretResource
 
 	resource, ok := task.getSSMSecretsResource()
 	if ok {
@@ -2438,12 +2520,17 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 		asmRes = resource[0].(*asmsecret.ASMSecretResource)
 	}
 
+	resource, ok = task.getProviderSecretsResource()
+	if ok {
+		providerRes = resource[0].(*providersecret.ProviderSecretResource)
+	}
+
 	for _, container := range task.Containers {
 		if container.GetLogDriver() != firelensDriverName {
 			continue
 		}
 
-		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, ssmRes, asmRes)
+		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, ssmRes, asmRes, providerRes)
 		if err != nil {
 			return &apierrors.DockerClientConfigError{
 				Msg: fmt.Sprintf("unable to generate config to create firelens container: %v", err),
@@ -2468,7 +2555,7 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 
 // collectLogDriverSecretData collects all the secret values for log driver secrets.
 func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret.SSMSecretResource,
-	asmRes *asmsecret.ASMSecretResource) (map[string]string, error) {
+	asmRes *asmsecret.ASMSecretResource, providerRes *providersecret.ProviderSecretResource) (map[string]string, error) {
 	secretData := make(map[string]string)
 	for _, secret := range secrets {
 		if secret.Target != apicontainer.SecretTargetLogDriver {
@@ -2493,6 +2580,14 @@ func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret
 			if secretValue, ok := asmRes.GetCachedSecretValue(cacheKey); ok {
 				secretVal = secretValue
 			}
+		} else if secret.UsesHostSecretProvider() {
+			if providerRes == nil {
+				return nil, errors.Errorf("missing secret value for secret %s", secret.Name)
+			}
+
+			if secretValue, ok := providerRes.GetCachedSecretValue(cacheKey); ok {
+				secretVal = secretValue
+			}
 		}
 
 		secretData[secret.Name] = secretVal
@@ -2501,6 +2596,15 @@ func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret
 	return secretData, nil
 }
 
+// getProviderSecretsResource retrieves providersecret resource from resource map
+func (task *Task) getProviderSecretsResource() ([]taskresource.TaskResource, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	res, ok := task.ResourcesMapUnsafe[providersecret.ResourceName]
+	return res, ok
+}
+
 // getASMSecretsResource retrieves asmsecret resource from resource map
 func (task *Task) getASMSecretsResource() ([]taskresource.TaskResource, bool) {
 	task.lock.RLock()
@@ -2597,7 +2701,7 @@ func (task *Task) initializeEnvfilesResource(config *config.Config, credentialsM
 	for _, container := range task.Containers {
 		if container.ShouldCreateWithEnvFiles() {
 			envfileResource, err := envFiles.NewEnvironmentFileResource(config.Cluster, task.Arn, config.AWSRegion, config.DataDir,
-				container.Name, container.EnvironmentFiles, credentialsManager, task.ExecutionCredentialsID)
+				config.EnvironmentFilesHostDir, container.Name, container.EnvironmentFiles, credentialsManager, task.ExecutionCredentialsID)
 			if err != nil {
 				return errors.Wrapf(err, "unable to initialize envfiles resource for container %s", container.Name)
 			}
@@ -2654,6 +2758,225 @@ func (task *Task) MergeEnvVarsFromEnvfiles(container *apicontainer.Container) *a
 	return nil
 }
 
+// requiresSecretFilesResource returns true if any container of the task has secrets
+// that are delivered as files
+func (task *Task) requiresSecretFilesResource() bool {
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithSecretFiles() {
+			return true
+		}
+	}
+	return false
+}
+
+// initializeSecretFilesResource adds the resource holding the tmpfs directory of the task,
+// which every container with file based secrets depends on
+func (task *Task) initializeSecretFilesResource(config *config.Config) error {
+	if config.SecretFilesContainerPath == "" {
+		return errors.New("secrets delivered as files are not supported on this instance")
+	}
+
+	secretFilesResource := secretfiles.NewSecretFilesResource(task.Arn, config.DataDir)
+	task.AddResource(secretfiles.ResourceName, secretFilesResource)
+	for _, container := range task.Containers {
+		if !container.ShouldCreateWithSecretFiles() {
+			continue
+		}
+		for _, secret := range container.Secrets {
+			if secret.Type != apicontainer.SecretTypeMountPoint {
+				continue
+			}
+			if err := validateSecretFileName(secret.Name); err != nil {
+				return errors.Wrapf(err, "invalid secret for container %s", container.Name)
+			}
+		}
+		container.BuildResourceDependency(secretFilesResource.GetName(), resourcestatus.ResourceCreated, apicontainerstatus.ContainerCreated)
+	}
+	return nil
+}
+
+// validateSecretFileName makes sure that the name of a secret delivered as a file can't
+// escape the secrets directory of the container
+func validateSecretFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return errors.Errorf("secret name %q can't be used as a file name", name)
+	}
+	return nil
+}
+
+// GetSecretFilesResource returns the secret files resource of the task
+func (task *Task) GetSecretFilesResource() (*secretfiles.SecretFilesResource, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	resources, ok := task.ResourcesMapUnsafe[secretfiles.ResourceName]
+	if !ok || len(resources) == 0 {
+		return nil, false
+	}
+	return resources[0].(*secretfiles.SecretFilesResource), true
+}
+
+// PopulateSecretFiles should be called when creating a container, it writes the container's
+// secrets of type MOUNT_POINT into the tmpfs directory of the task and bind mounts them read only
+// into the container. user is the user the container runs as, files are owned by it when it's numeric.
+func (task *Task) PopulateSecretFiles(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container,
+	user string, config *config.Config) *apierrors.DockerClientConfigError {
+	secretFilesResource, ok := task.GetSecretFilesResource()
+	if !ok {
+		return &apierrors.DockerClientConfigError{Msg: "task secret files: unable to fetch secret files resource"}
+	}
+
+	secretValues, err := task.getSecretFileValues(container)
+	if err != nil {
+		return &apierrors.DockerClientConfigError{Msg: "task secret files: " + err.Error()}
+	}
+
+	uid, gid := secretFilesOwner(user)
+	if err := secretFilesResource.WriteContainerSecrets(container.Name, secretValues, uid, gid); err != nil {
+		return &apierrors.DockerClientConfigError{Msg: "task secret files: " + err.Error()}
+	}
+
+	bind := fmt.Sprintf("%s:%s:ro", secretfiles.ContainerDir(config.DataDirOnHost, task.Arn, container.Name),
+		config.SecretFilesContainerPath)
+	hostConfig.Binds = append(hostConfig.Binds, bind)
+	return nil
+}
+
+// UpdateSecretFiles replaces the files of a running container's secrets of type MOUNT_POINT
+// with the currently cached secret values
+func (task *Task) UpdateSecretFiles(container *apicontainer.Container) error {
+	secretFilesResource, ok := task.GetSecretFilesResource()
+	if !ok {
+		return errors.New("task secret files: unable to fetch secret files resource")
+	}
+
+	secretValues, err := task.getSecretFileValues(container)
+	if err != nil {
+		return errors.Wrap(err, "task secret files")
+	}
+	return secretFilesResource.UpdateContainerSecrets(container.Name, secretValues)
+}
+
+// RefreshSecrets fetches the values of the task's secrets again, using the task execution role
+// for SSM and ASM secrets. It returns the cache keys, as returned by Secret.GetSecretResourceCacheKey,
+// of the secrets whose value changed.
+func (task *Task) RefreshSecrets() (map[string]bool, error) {
+	changed := make(map[string]bool)
+	var refreshErrors []string
+
+	if resources, ok := task.getSSMSecretsResource(); ok && len(resources) > 0 {
+		keys, err := resources[0].(*ssmsecret.SSMSecretResource).Refresh()
+		if err != nil {
+			refreshErrors = append(refreshErrors, err.Error())
+		}
+		for _, key := range keys {
+			changed[key] = true
+		}
+	}
+
+	if resources, ok := task.getASMSecretsResource(); ok && len(resources) > 0 {
+		keys, err := resources[0].(*asmsecret.ASMSecretResource).Refresh()
+		if err != nil {
+			refreshErrors = append(refreshErrors, err.Error())
+		}
+		for _, key := range keys {
+			changed[key] = true
+		}
+	}
+
+	if resources, ok := task.getProviderSecretsResource(); ok && len(resources) > 0 {
+		keys, err := resources[0].(*providersecret.ProviderSecretResource).Refresh()
+		if err != nil {
+			refreshErrors = append(refreshErrors, err.Error())
+		}
+		for _, key := range keys {
+			changed[key] = true
+		}
+	}
+
+	if len(refreshErrors) > 0 {
+		return changed, errors.New(strings.Join(refreshErrors, ";"))
+	}
+	return changed, nil
+}
+
+// getSecretFileValues returns the values of the container's secrets of type MOUNT_POINT, keyed
+// by the secret name
+func (task *Task) getSecretFileValues(container *apicontainer.Container) (map[string]string, error) {
+	var ssmRes *ssmsecret.SSMSecretResource
+	var asmRes *asmsecret.ASMSecretResource
+	var providerRes *providersecret.ProviderSecretResource
+
+	if container.ShouldCreateWithSSMSecret() {
+		resource, ok := task.getSSMSecretsResource()
+		if !ok {
+			return nil, errors.New("unable to fetch SSM Secrets resource")
+		}
+		ssmRes = resource[0].(*ssmsecret.SSMSecretResource)
+	}
+
+	if container.ShouldCreateWithASMSecret() {
+		resource, ok := task.getASMSecretsResource()
+		if !ok {
+			return nil, errors.New("unable to fetch ASM Secrets resource")
+		}
+		asmRes = resource[0].(*asmsecret.ASMSecretResource)
+	}
+
+	if container.ShouldCreateWithHostProviderSecret() {
+		resource, ok := task.getProviderSecretsResource()
+		if !ok {
+			return nil, errors.New("unable to fetch provider Secrets resource")
+		}
+		providerRes = resource[0].(*providersecret.ProviderSecretResource)
+	}
+
+	values := make(map[string]string)
+	for _, secret := range container.Secrets {
+		if secret.Type != apicontainer.SecretTypeMountPoint {
+			continue
+		}
+
+		var value string
+		var found bool
+		switch secret.Provider {
+		case apicontainer.SecretProviderSSM:
+			value, found = ssmRes.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
+		case apicontainer.SecretProviderASM:
+			value, found = asmRes.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
+		case apicontainer.SecretProviderLocal, apicontainer.SecretProviderVault:
+			value, found = providerRes.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
+		}
+		if !found {
+			return nil, errors.Errorf("unable to find value of secret %s", secret.Name)
+		}
+		values[secret.Name] = value
+	}
+	return values, nil
+}
+
+// secretFilesOwner returns the uid and gid that should own the secret files of a container
+// running as user. A negative uid means the user can only be resolved inside the container image.
+func secretFilesOwner(user string) (int, int) {
+	if user == "" {
+		return 0, 0
+	}
+
+	userAndGroup := strings.SplitN(user, ":", 2)
+	uid, err := strconv.Atoi(userAndGroup[0])
+	if err != nil || uid < 0 {
+		return -1, -1
+	}
+	if len(userAndGroup) == 1 {
+		return uid, -1
+	}
+	gid, err := strconv.Atoi(userAndGroup[1])
+	if err != nil || gid < 0 {
+		return uid, -1
+	}
+	return uid, gid
+}
+
 // GetLocalIPAddress returns the local IP address of the task.
 func (task *Task) GetLocalIPAddress() string {
 	task.lock.RLock()
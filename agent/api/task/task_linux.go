@@ -18,6 +18,7 @@ package task
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
@@ -47,12 +48,19 @@ const (
 )
 
 // PlatformFields consists of fields specific to Linux for a task
-type PlatformFields struct{}
+type PlatformFields struct {
+	// CgroupSlice determines whether the task cgroup is a systemd slice, which is the case when
+	// docker manages cgroups with systemd
+	CgroupSlice bool `json:"cgroupSlice,omitempty"`
+}
 
 func (task *Task) adjustForPlatform(cfg *config.Config) {
 	task.lock.Lock()
 	defer task.lock.Unlock()
 	task.MemoryCPULimitsEnabled = cfg.TaskCPUMemLimit.Enabled()
+	task.PlatformFields = PlatformFields{
+		CgroupSlice: cfg.CgroupDriver == config.CgroupDriverSystemd,
+	}
 }
 
 func (task *Task) initializeCgroupResourceSpec(cgroupPath string, cGroupCPUPeriod time.Duration, resourceFields *taskresource.ResourceFields) error {
@@ -76,16 +84,38 @@ func (task *Task) initializeCgroupResourceSpec(cgroupPath string, cGroupCPUPerio
 }
 
 // BuildCgroupRoot helps build the task cgroup prefix
-// Example: /ecs/task-id
+// Example: /ecs/task-id, or /ecstasks.slice/ecstasks-taskid.slice when the task cgroup is a systemd slice
 func (task *Task) BuildCgroupRoot() (string, error) {
 	taskID, err := task.GetID()
 	if err != nil {
 		return "", errors.Wrapf(err, "task build cgroup root: unable to get task-id from task ARN: %s", task.Arn)
 	}
 
+	if task.PlatformFields.CgroupSlice {
+		return filepath.Join("/", config.DefaultTaskCgroupSlicePrefix+".slice", cgroupSliceName(taskID)), nil
+	}
 	return filepath.Join(config.DefaultTaskCgroupPrefix, taskID), nil
 }
 
+// BuildCgroupParent returns the cgroup parent of the containers of the task: the task cgroup root,
+// or the name of the slice when the task cgroup is a systemd slice.
+func (task *Task) BuildCgroupParent() (string, error) {
+	cgroupRoot, err := task.BuildCgroupRoot()
+	if err != nil {
+		return "", err
+	}
+	if task.PlatformFields.CgroupSlice {
+		return filepath.Base(cgroupRoot), nil
+	}
+	return cgroupRoot, nil
+}
+
+// cgroupSliceName returns the name of the slice of the task. Dashes separate the parent slices in
+// slice names, so they're removed from the task id of the ARNs of the old format.
+func cgroupSliceName(taskID string) string {
+	return config.DefaultTaskCgroupSlicePrefix + "-" + strings.Replace(taskID, "-", "", -1) + ".slice"
+}
+
 // BuildLinuxResourceSpec returns a linuxResources object for the task cgroup
 func (task *Task) BuildLinuxResourceSpec(cGroupCPUPeriod time.Duration) (specs.LinuxResources, error) {
 	linuxResourceSpec := specs.LinuxResources{}
@@ -197,11 +227,11 @@ func (task *Task) overrideCgroupParent(hostConfig *dockercontainer.HostConfig) e
 	task.lock.RLock()
 	defer task.lock.RUnlock()
 	if task.MemoryCPULimitsEnabled {
-		cgroupRoot, err := task.BuildCgroupRoot()
+		cgroupParent, err := task.BuildCgroupParent()
 		if err != nil {
 			return errors.Wrapf(err, "task cgroup override: unable to obtain cgroup root for task: %s", task.Arn)
 		}
-		hostConfig.CgroupParent = cgroupRoot
+		hostConfig.CgroupParent = cgroupParent
 	}
 	return nil
 }
@@ -33,6 +33,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control/mock_control"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/providersecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	mock_ioutilwrapper "github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper/mocks"
 	"github.com/golang/mock/gomock"
@@ -381,6 +382,28 @@ func TestOverrideCgroupParentHappyPath(t *testing.T) {
 	assert.Equal(t, expectedCgroupRoot, hostConfig.CgroupParent)
 }
 
+// TestOverrideCgroupParentSlice validates that the containers of tasks whose cgroup is a
+// systemd slice are created in the slice
+func TestOverrideCgroupParentSlice(t *testing.T) {
+	task := &Task{
+		Arn:                    validTaskArn,
+		MemoryCPULimitsEnabled: true,
+	}
+	task.adjustForPlatform(&config.Config{
+		TaskCPUMemLimit: config.BooleanDefaultTrue{Value: config.ExplicitlyEnabled},
+		CgroupDriver:    config.CgroupDriverSystemd,
+	})
+
+	cgroupRoot, err := task.BuildCgroupRoot()
+	require.NoError(t, err)
+	// dashes separate the parent slices, they're removed from the task id
+	assert.Equal(t, "/ecstasks.slice/ecstasks-taskid.slice", cgroupRoot)
+
+	hostConfig := &dockercontainer.HostConfig{}
+	assert.NoError(t, task.overrideCgroupParent(hostConfig))
+	assert.Equal(t, "ecstasks-taskid.slice", hostConfig.CgroupParent)
+}
+
 // TestOverrideCgroupParentErrorPath validates the error path for
 // cgroup parent update
 func TestOverrideCgroupParentErrorPath(t *testing.T) {
@@ -1118,6 +1141,9 @@ func TestCollectLogDriverSecretData(t *testing.T) {
 	asmRes := &asmsecret.ASMSecretResource{}
 	asmRes.SetCachedSecretValue("secret-value-from-asm_us-west-2", "secret-val-asm")
 
+	providerRes := &providersecret.ProviderSecretResource{}
+	providerRes.SetCachedSecretValue("vault:secret/logs#token", "secret-val-vault")
+
 	secrets := []apicontainer.Secret{
 		{
 			Name:      "secret-name",
@@ -1133,13 +1159,23 @@ func TestCollectLogDriverSecretData(t *testing.T) {
 			ValueFrom: "secret-value-from-asm",
 			Region:    "us-west-2",
 		},
+		{
+			Name:      "secret-name-vault",
+			Provider:  apicontainer.SecretProviderVault,
+			Target:    apicontainer.SecretTargetLogDriver,
+			ValueFrom: "secret/logs#token",
+		},
 	}
 
-	secretData, err := collectLogDriverSecretData(secrets, ssmRes, asmRes)
+	secretData, err := collectLogDriverSecretData(secrets, ssmRes, asmRes, providerRes)
 	assert.NoError(t, err)
-	assert.Len(t, secretData, 2)
+	assert.Len(t, secretData, 3)
 	assert.Equal(t, "secret-val", secretData["secret-name"])
 	assert.Equal(t, "secret-val-asm", secretData["secret-name-asm"])
+	assert.Equal(t, "secret-val-vault", secretData["secret-name-vault"])
+
+	_, err = collectLogDriverSecretData(secrets, ssmRes, asmRes, nil)
+	assert.Error(t, err)
 }
 
 // getFirelensTask returns a sample firelens task.
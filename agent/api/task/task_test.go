@@ -18,6 +18,8 @@ package task
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -47,6 +49,8 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/providersecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
@@ -2803,6 +2807,100 @@ func TestPopulateSecrets(t *testing.T) {
 	assert.Equal(t, "option", hostConfig.LogConfig.Config["splunk-option"])
 }
 
+func TestInitializeAndGetProviderSecretResource(t *testing.T) {
+	localSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderLocal,
+		Name:      "DB_PASSWORD",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "db/password",
+	}
+	vaultSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderVault,
+		Name:      "splunk-token",
+		Target:    apicontainer.SecretTargetLogDriver,
+		ValueFrom: "secret/logs#token",
+	}
+
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Image:                     "image:tag",
+		Secrets:                   []apicontainer.Secret{localSecret, vaultSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	container1 := &apicontainer.Container{
+		Name:                      "sidecar",
+		Image:                     "image:tag",
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+
+	task := &Task{
+		Arn:                "test",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container, container1},
+	}
+	assert.True(t, task.requiresHostProviderSecret())
+
+	task.initializeProviderSecretResource(&taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{},
+	})
+
+	resourceDep := apicontainer.ResourceDependency{
+		Name:           providersecret.ResourceName,
+		RequiredStatus: resourcestatus.ResourceStatus(providersecret.ProviderSecretCreated),
+	}
+	assert.Equal(t, resourceDep, task.Containers[0].TransitionDependenciesMap[apicontainerstatus.ContainerCreated].ResourceDependencies[0])
+	assert.Equal(t, 0, len(task.Containers[1].TransitionDependenciesMap))
+
+	_, ok := task.getProviderSecretsResource()
+	assert.True(t, ok)
+	assert.Equal(t, map[string][]apicontainer.Secret{
+		apicontainer.SecretProviderLocal: {localSecret},
+		apicontainer.SecretProviderVault: {vaultSecret},
+	}, task.getAllHostProviderSecretRequirements())
+}
+
+func TestPopulateSecretsHostProvider(t *testing.T) {
+	localSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderLocal,
+		Name:      "DB_PASSWORD",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "db/password",
+	}
+	vaultSecret := apicontainer.Secret{
+		Provider:  apicontainer.SecretProviderVault,
+		Name:      "splunk-token",
+		Target:    apicontainer.SecretTargetLogDriver,
+		ValueFrom: "secret/logs#token",
+	}
+
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Image:                     "image:tag",
+		Secrets:                   []apicontainer.Secret{localSecret, vaultSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+
+	task := &Task{
+		Arn:                "test",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container},
+	}
+
+	hostConfig := &dockercontainer.HostConfig{}
+	hostConfig.LogConfig.Type = "splunk"
+
+	assert.NotNil(t, task.PopulateSecrets(hostConfig, container), "missing provider secret resource should be an error")
+
+	providerRes := &providersecret.ProviderSecretResource{}
+	providerRes.SetCachedSecretValue("local:db/password", "hunter2")
+	providerRes.SetCachedSecretValue("vault:secret/logs#token", "token")
+	task.AddResource(providersecret.ResourceName, providerRes)
+
+	assert.Nil(t, task.PopulateSecrets(hostConfig, container))
+	assert.Equal(t, "hunter2", container.Environment["DB_PASSWORD"])
+	assert.Equal(t, "token", hostConfig.LogConfig.Config["splunk-token"])
+}
+
 func TestPopulateSecretsNoConfigInHostConfig(t *testing.T) {
 	secret1 := apicontainer.Secret{
 		Provider:  "ssm",
@@ -3412,6 +3510,199 @@ func TestInitializeAndGetEnvfilesResource(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestInitializeSecretFilesResource(t *testing.T) {
+	fileSecret := apicontainer.Secret{
+		Provider:  "ssm",
+		Name:      "db-password",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeMountPoint,
+		ValueFrom: "/test/secretName",
+	}
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Secrets:                   []apicontainer.Secret{fileSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	otherContainer := &apicontainer.Container{
+		Name:                      "sidecar",
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	task := &Task{
+		Arn:                "arn:aws:ecs:us-west-2:1234567890:task/cluster/taskID",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container, otherContainer},
+	}
+	cfg := &config.Config{
+		DataDir:                  "/ecs/data",
+		SecretFilesContainerPath: "/run/secrets",
+	}
+
+	assert.True(t, task.requiresSecretFilesResource())
+	require.NoError(t, task.initializeSecretFilesResource(cfg))
+
+	resource, ok := task.GetSecretFilesResource()
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join("/ecs/data", "secretfiles", "taskID"), resource.GetResourceDir())
+	assert.Equal(t, apicontainer.ResourceDependency{
+		Name:           secretfiles.ResourceName,
+		RequiredStatus: resourcestatus.ResourceStatus(secretfiles.SecretFilesCreated),
+	}, container.TransitionDependenciesMap[apicontainerstatus.ContainerCreated].ResourceDependencies[0])
+	assert.Empty(t, otherContainer.TransitionDependenciesMap)
+}
+
+func TestInitializeSecretFilesResourceErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		secretName string
+		path       string
+	}{
+		{
+			name:       "not supported",
+			secretName: "db-password",
+			path:       "",
+		},
+		{
+			name:       "secret name with path separator",
+			secretName: "../db-password",
+			path:       "/run/secrets",
+		},
+		{
+			name:       "secret name is dot dot",
+			secretName: "..",
+			path:       "/run/secrets",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			container := &apicontainer.Container{
+				Name: "app",
+				Secrets: []apicontainer.Secret{{
+					Provider:  "ssm",
+					Name:      tc.secretName,
+					Type:      apicontainer.SecretTypeMountPoint,
+					ValueFrom: "/test/secretName",
+				}},
+				TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+			}
+			task := &Task{
+				Arn:                "arn:aws:ecs:us-west-2:1234567890:task/cluster/taskID",
+				ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+				Containers:         []*apicontainer.Container{container},
+			}
+			cfg := &config.Config{
+				DataDir:                  "/ecs/data",
+				SecretFilesContainerPath: tc.path,
+			}
+			assert.Error(t, task.initializeSecretFilesResource(cfg))
+		})
+	}
+}
+
+func TestPopulateSecretFiles(t *testing.T) {
+	envSecret := apicontainer.Secret{
+		Provider:  "ssm",
+		Name:      "secret1",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "/test/secretName",
+	}
+	fileSecret := apicontainer.Secret{
+		Provider:  "asm",
+		Name:      "secret2",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeMountPoint,
+		ValueFrom: "arn:aws:secretsmanager:us-west-2:11111:secret:/test/secretName",
+	}
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Secrets:                   []apicontainer.Secret{envSecret, fileSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	task := &Task{
+		Arn:                "arn:aws:ecs:us-west-2:1234567890:task/cluster/taskID",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container},
+	}
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		DataDir:                  dataDir,
+		DataDirOnHost:            "/var/lib/ecs",
+		SecretFilesContainerPath: "/run/secrets",
+	}
+	require.NoError(t, task.initializeSecretFilesResource(cfg))
+
+	ssmRes := &ssmsecret.SSMSecretResource{}
+	ssmRes.SetCachedSecretValue(secretKeyWest1, "secretValue1")
+	asmRes := &asmsecret.ASMSecretResource{}
+	asmRes.SetCachedSecretValue(asmSecretKeyWest1, "secretValue2")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+	task.AddResource(asmsecret.ResourceName, asmRes)
+
+	hostConfig := &dockercontainer.HostConfig{}
+	require.Nil(t, task.PopulateSecretFiles(hostConfig, container, fmt.Sprint(os.Getuid()), cfg))
+
+	assert.Equal(t, []string{"/var/lib/ecs/secretfiles/taskID/app:/run/secrets:ro"}, hostConfig.Binds)
+	value, err := os.ReadFile(filepath.Join(dataDir, "secretfiles", "taskID", "app", "secret2"))
+	require.NoError(t, err)
+	assert.Equal(t, "secretValue2", string(value))
+	_, err = os.Stat(filepath.Join(dataDir, "secretfiles", "taskID", "app", "secret1"))
+	assert.True(t, os.IsNotExist(err), "environment variable secrets should not be written to files")
+}
+
+func TestPopulateSecretFilesMissingValue(t *testing.T) {
+	fileSecret := apicontainer.Secret{
+		Provider:  "ssm",
+		Name:      "secret1",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeMountPoint,
+		ValueFrom: "/test/secretName",
+	}
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Secrets:                   []apicontainer.Secret{fileSecret},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	task := &Task{
+		Arn:                "arn:aws:ecs:us-west-2:1234567890:task/cluster/taskID",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container},
+	}
+	cfg := &config.Config{
+		DataDir:                  t.TempDir(),
+		SecretFilesContainerPath: "/run/secrets",
+	}
+	require.NoError(t, task.initializeSecretFilesResource(cfg))
+	task.AddResource(ssmsecret.ResourceName, &ssmsecret.SSMSecretResource{})
+
+	hostConfig := &dockercontainer.HostConfig{}
+	assert.NotNil(t, task.PopulateSecretFiles(hostConfig, container, "", cfg))
+	assert.Empty(t, hostConfig.Binds)
+}
+
+func TestSecretFilesOwner(t *testing.T) {
+	testCases := []struct {
+		user string
+		uid  int
+		gid  int
+	}{
+		{user: "", uid: 0, gid: 0},
+		{user: "1000", uid: 1000, gid: -1},
+		{user: "1000:1001", uid: 1000, gid: 1001},
+		{user: "1000:staff", uid: 1000, gid: -1},
+		{user: "nginx", uid: -1, gid: -1},
+		{user: "nginx:1001", uid: -1, gid: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.user, func(t *testing.T) {
+			uid, gid := secretFilesOwner(tc.user)
+			assert.Equal(t, tc.uid, uid)
+			assert.Equal(t, tc.gid, gid)
+		})
+	}
+}
+
 func TestRequiresEnvfiles(t *testing.T) {
 	envfile := apicontainer.EnvironmentFile{
 		Value: "s3://bucket/envfile",
@@ -30,6 +30,7 @@ import (
 
 	acshandler "github.com/aws/amazon-ecs-agent/agent/acs/handler"
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	"github.com/aws/amazon-ecs-agent/agent/api/ecsclient"
 	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
 	"github.com/aws/amazon-ecs-agent/agent/app/factory"
@@ -45,16 +46,21 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/eni/pause"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretprovider"
 	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/mobypkgwrapper"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
@@ -83,6 +89,9 @@ const (
 	instanceIdBackoffJitter   = 0.2
 	instanceIdBackoffMultiple = 1.3
 	instanceIdMaxRetryCount   = 3
+
+	// vaultRequestTimeout is the timeout of the requests made to the Vault compatible server
+	vaultRequestTimeout = 30 * time.Second
 )
 
 var (
@@ -133,6 +142,8 @@ type ecsAgent struct {
 	resourceFields              *taskresource.ResourceFields
 	availabilityZone            string
 	latestSeqNumberTaskManifest *int64
+	// platformDevices are the devices, such as GPUs, that the container instance registered with
+	platformDevices []*ecs.PlatformDevice
 }
 
 // newAgent returns a new ecsAgent object, but does not start anything
@@ -246,7 +257,7 @@ func (agent *ecsAgent) start() int {
 	sighandlers.StartDebugHandler()
 
 	containerChangeEventStream := eventstream.NewEventStream(containerChangeEventStreamName, agent.ctx)
-	credentialsManager := credentials.NewManager()
+	credentialsManager := agent.newCredentialsManager()
 	state := dockerstate.NewTaskEngineState()
 	imageManager := engine.NewImageManager(agent.cfg, agent.dockerClient, state)
 	client := ecsclient.NewECSClient(agent.credentialProvider, agent.cfg, agent.ec2MetadataClient)
@@ -291,7 +302,16 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		seelog.Criticalf("Unable to initialize new task engine: %v", err)
 		return exitcodes.ExitTerminal
 	}
+	if agent.cfg.ImagePolicyFile != "" {
+		imagePolicy, err := image.LoadPolicy(agent.cfg.ImagePolicyFile)
+		if err != nil {
+			seelog.Criticalf("Unable to load the image policy: %v", err)
+			return exitcodes.ExitTerminal
+		}
+		taskEngine.SetImagePolicy(imagePolicy)
+	}
 	agent.initMetricsEngine()
+	tracing.Init(agent.ctx, agent.cfg)
 
 	loadPauseErr := agent.loadPauseContainer()
 	if loadPauseErr != nil {
@@ -527,6 +547,75 @@ func (agent *ecsAgent) getoutpostARN() string {
 	return ""
 }
 
+// getSecretProviders creates the secret providers configured on the host. A provider that can't be
+// created is left out, so that tasks using it fail with an error naming the missing provider.
+func (agent *ecsAgent) getSecretProviders() secretprovider.Providers {
+	providers := make(secretprovider.Providers)
+	if agent.cfg.LocalSecretsDir != "" {
+		provider, err := secretprovider.NewLocalProvider(agent.cfg.LocalSecretsDir, agent.cfg.LocalSecretsKeyFile)
+		if err != nil {
+			seelog.Errorf("Unable to initialize the local secret provider: %v", err)
+		} else {
+			providers[apicontainer.SecretProviderLocal] = provider
+		}
+	}
+	if agent.cfg.VaultAddress != "" {
+		provider, err := secretprovider.NewVaultProvider(agent.cfg.VaultAddress, agent.cfg.VaultTokenFile,
+			agent.cfg.VaultKVVersion, agent.cfg.VaultKVMounts, httpclient.New(vaultRequestTimeout, agent.cfg.AcceptInsecureCert))
+		if err != nil {
+			seelog.Errorf("Unable to initialize the vault secret provider: %v", err)
+		} else {
+			providers[apicontainer.SecretProviderVault] = provider
+		}
+	}
+	return providers
+}
+
+// newCredentialsManager creates the credentials manager. When checkpointing and the persistence of
+// task credentials are enabled, credentials are persisted in the data store, and the ones persisted
+// by the previous agent are loaded, so that tasks keep getting credentials while the agent restarts.
+// The agent falls back to keeping them in memory only when they can't be persisted.
+func (agent *ecsAgent) newCredentialsManager() credentials.Manager {
+	if !agent.cfg.Checkpoint.Enabled() {
+		return credentials.NewManager()
+	}
+	if !agent.cfg.PersistTaskCredentials.Enabled() {
+		agent.deletePersistedCredentials()
+		return credentials.NewManager()
+	}
+
+	key, err := credentials.LoadHostKey(agent.cfg.DataDir)
+	if err == credentials.ErrNoMachineID {
+		seelog.Warnf("Task credentials will only be kept in memory although ECS_PERSIST_TASK_CREDENTIALS is enabled: %v", err)
+		return credentials.NewManager()
+	}
+	if err != nil {
+		seelog.Errorf("Unable to load the key of persisted credentials, credentials will not be persisted: %v", err)
+		return credentials.NewManager()
+	}
+	credentialsManager, err := credentials.NewPersistentManager(agent.dataClient, key)
+	if err != nil {
+		seelog.Errorf("Unable to load persisted credentials, credentials will not be persisted: %v", err)
+		return credentials.NewManager()
+	}
+	return credentialsManager
+}
+
+// deletePersistedCredentials deletes the credentials persisted while ECS_PERSIST_TASK_CREDENTIALS
+// was enabled
+func (agent *ecsAgent) deletePersistedCredentials() {
+	persisted, err := agent.dataClient.GetCredentials()
+	if err != nil {
+		seelog.Warnf("Unable to get persisted credentials: %v", err)
+		return
+	}
+	for id := range persisted {
+		if err := agent.dataClient.DeleteCredentials(id); err != nil {
+			seelog.Warnf("Unable to delete persisted credentials %s: %v", id, err)
+		}
+	}
+}
+
 // newStateManager creates a new state manager object for the task engine.
 // Rest of the parameters are pointers and it's expected that all of these
 // will be backfilled when state manager's Load() method is invoked
@@ -597,6 +686,7 @@ func (agent *ecsAgent) registerContainerInstance(
 	}
 
 	platformDevices := agent.getPlatformDevices()
+	agent.platformDevices = platformDevices
 
 	outpostARN := agent.getoutpostARN()
 
@@ -689,11 +779,30 @@ func (agent *ecsAgent) startAsyncRoutines(
 
 	go agent.terminationHandler(state, agent.dataClient, taskEngine, agent.cancel)
 
-	// Agent introspection api
-	go handlers.ServeIntrospectionHTTPEndpoint(agent.ctx, &agent.containerInstanceARN, taskEngine, agent.cfg)
+	// Pull the images of the image pre-warm manifest now that the instance is registered
+	var imagePrewarmer *engine.ImagePrewarmer
+	if agent.cfg.ImagePrewarmManifest != "" {
+		imagePrewarmer = engine.NewImagePrewarmer(agent.cfg, agent.dockerClient, imageManager)
+		go imagePrewarmer.Start(agent.ctx)
+	}
 
 	statsEngine := stats.NewDockerStatsEngine(agent.cfg, agent.dockerClient, containerChangeEventStream)
 
+	// Agent introspection api
+	stateChangeBroadcaster := statechange.NewBroadcaster()
+	go handlers.ServeIntrospectionHTTPEndpoint(agent.ctx, &agent.containerInstanceARN, taskEngine, stateChangeBroadcaster,
+		imagePrewarmer, statsEngine, agent.registeredGPUIDs(), agent.cfg)
+	// Endpoints that add and stop tasks, served on the loopback interface only
+	go handlers.ServeTaskLifecycleHTTPEndpoint(agent.ctx, taskEngine, agent.cfg)
+
+	if err := metrics.RegisterCollector(stats.NewPrometheusCollector(statsEngine)); err != nil {
+		seelog.Warnf("Unable to expose container stats as Prometheus metrics: %v", err)
+	}
+	// Publish container stats to the StatsD server and the stats file, alongside the backend
+	go statsEngine.StartSinks(agent.ctx, stats.NewSinksFromConfig(agent.cfg), agent.cfg.StatsSinkPublishInterval)
+	// Account the ephemeral storage used by tasks and stop the ones exceeding the limit
+	go statsEngine.StartStorageCollection(agent.ctx, agent.cfg.TaskStorageCollectionInterval)
+
 	// Start serving the endpoint to fetch IAM Role credentials and other task metadata
 	if agent.cfg.TaskMetadataAZDisabled {
 		// send empty availability zone
@@ -703,7 +812,7 @@ func (agent *ecsAgent) startAsyncRoutines(
 	}
 
 	// Start sending events to the backend
-	go eventhandler.HandleEngineEvents(agent.ctx, taskEngine, client, taskHandler, attachmentEventHandler)
+	go eventhandler.HandleEngineEvents(agent.ctx, taskEngine, client, taskHandler, attachmentEventHandler, stateChangeBroadcaster)
 
 	telemetrySessionParams := tcshandler.TelemetrySessionParams{
 		Ctx:                           agent.ctx,
@@ -721,6 +830,17 @@ func (agent *ecsAgent) startAsyncRoutines(
 	go tcshandler.StartMetricsSession(&telemetrySessionParams)
 }
 
+// registeredGPUIDs returns the ids of the GPUs that the container instance registers with.
+func (agent *ecsAgent) registeredGPUIDs() []string {
+	var gpuIDs []string
+	for _, device := range agent.platformDevices {
+		if aws.StringValue(device.Type) == ecs.PlatformDeviceTypeGpu {
+			gpuIDs = append(gpuIDs, aws.StringValue(device.Id))
+		}
+	}
+	return gpuIDs
+}
+
 func (agent *ecsAgent) startSpotInstanceDrainingPoller(ctx context.Context, client api.ECSClient) {
 	for !agent.spotInstanceDrainingPoller(client) {
 		select {
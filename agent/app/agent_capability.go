@@ -47,6 +47,10 @@ const (
 	capabilitySecretEnvASM                                 = "secrets.asm.environment-variables"
 	capabilitySecretLogDriverSSM                           = "secrets.ssm.bootstrap.log-driver"
 	capabilitySecretLogDriverASM                           = "secrets.asm.bootstrap.log-driver"
+	capabilitySecretEnvLocal                               = "secrets.local.environment-variables"
+	capabilitySecretLogDriverLocal                         = "secrets.local.bootstrap.log-driver"
+	capabilitySecretEnvVault                               = "secrets.vault.environment-variables"
+	capabilitySecretLogDriverVault                         = "secrets.vault.bootstrap.log-driver"
 	capabiltyPIDAndIPCNamespaceSharing                     = "pid-ipc-namespace-sharing"
 	capabilityNvidiaDriverVersionInfix                     = "nvidia-driver-version."
 	capabilityECREndpoint                                  = "ecr-endpoint"
@@ -66,6 +70,9 @@ const (
 	capabilityEFS                                          = "efs"
 	capabilityEFSAuth                                      = "efsAuth"
 	capabilityEnvFilesS3                                   = "env-files.s3"
+	capabilityEnvFilesSSM                                  = "env-files.ssm"
+	capabilityEnvFilesHost                                 = "env-files.host"
+	capabilityEnvFilesHTTPS                                = "env-files.https"
 	capabilityFSxWindowsFileServer                         = "fsxWindowsFileServer"
 	capabilityExec                                         = "execute-command"
 	capabilityExecBinRelativePath                          = "bin"
@@ -95,6 +102,9 @@ var (
 		capabilityFullTaskSync,
 		// ecs agent version 1.39.0 supports bulk loading env vars through environmentFiles in S3
 		capabilityEnvFilesS3,
+		// support bulk loading env vars through environmentFiles in SSM and from https URLs
+		capabilityEnvFilesSSM,
+		capabilityEnvFilesHTTPS,
 	}
 	// use empty struct as value type to simulate set
 	capabilityExecInvalidSsmVersions = map[string]struct{}{}
@@ -157,6 +167,10 @@ var (
 //    ecs.capability.ecr-endpoint
 //    ecs.capability.secrets.asm.environment-variables
 //    ecs.capability.secrets.asm.bootstrap.log-driver
+//    ecs.capability.secrets.local.environment-variables
+//    ecs.capability.secrets.local.bootstrap.log-driver
+//    ecs.capability.secrets.vault.environment-variables
+//    ecs.capability.secrets.vault.bootstrap.log-driver
 //    ecs.capability.aws-appmesh
 //    ecs.capability.task-eia
 //    ecs.capability.task-eni-trunking
@@ -172,6 +186,9 @@ var (
 //    ecs.capability.gmsa
 //    ecs.capability.efsAuth
 //    ecs.capability.env-files.s3
+//    ecs.capability.env-files.ssm
+//    ecs.capability.env-files.https
+//    ecs.capability.env-files.host
 //    ecs.capability.fsxWindowsFileServer
 //    ecs.capability.execute-command
 //    ecs.capability.external
@@ -211,6 +228,8 @@ func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 	}
 
 	capabilities = agent.appendTaskENICapabilities(capabilities)
+	capabilities = agent.appendSecretProviderCapabilities(capabilities)
+	capabilities = agent.appendEnvFilesCapabilities(capabilities)
 	capabilities = agent.appendENITrunkingCapabilities(capabilities)
 	capabilities = agent.appendDockerDependentCapabilities(capabilities, supportedVersions)
 
@@ -379,6 +398,27 @@ func (agent *ecsAgent) appendTaskENICapabilities(capabilities []*ecs.Attribute)
 	return capabilities
 }
 
+// appendSecretProviderCapabilities advertises the secret providers configured on the host
+func (agent *ecsAgent) appendSecretProviderCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if agent.cfg.LocalSecretsDir != "" {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecretEnvLocal)
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecretLogDriverLocal)
+	}
+	if agent.cfg.VaultAddress != "" {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecretEnvVault)
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilitySecretLogDriverVault)
+	}
+	return capabilities
+}
+
+// appendEnvFilesCapabilities advertises environment files on the host when a directory is allowed for them
+func (agent *ecsAgent) appendEnvFilesCapabilities(capabilities []*ecs.Attribute) []*ecs.Attribute {
+	if agent.cfg.EnvironmentFilesHostDir != "" {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityEnvFilesHost)
+	}
+	return capabilities
+}
+
 func (agent *ecsAgent) appendExecCapabilities(capabilities []*ecs.Attribute) ([]*ecs.Attribute, error) {
 
 	// Only Windows 2019 and above are supported, all Linux supported
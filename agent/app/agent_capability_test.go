@@ -129,6 +129,8 @@ func TestCapabilities(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 		attributePrefix + taskENIBlockInstanceMetadataAttributeSuffix,
 		attributePrefix + capabilityExec,
 	}
@@ -193,6 +195,38 @@ func TestCapabilitiesExternal(t *testing.T) {
 	}
 }
 
+func TestCapabilitiesSecretProviders(t *testing.T) {
+	cfg := getCapabilitiesTestConfig()
+	secretProviderCapabilities := []string{
+		attributePrefix + capabilitySecretEnvLocal,
+		attributePrefix + capabilitySecretLogDriverLocal,
+		attributePrefix + capabilitySecretEnvVault,
+		attributePrefix + capabilitySecretLogDriverVault,
+	}
+
+	capabilities := getCapabilitiesWithConfig(cfg, t)
+	for _, cap := range secretProviderCapabilities {
+		assert.NotContains(t, capabilities, &ecs.Attribute{Name: aws.String(cap)})
+	}
+
+	cfg.LocalSecretsDir = "/etc/ecs/secrets"
+	cfg.VaultAddress = "https://vault.local:8200"
+	capabilities = getCapabilitiesWithConfig(cfg, t)
+	for _, cap := range secretProviderCapabilities {
+		assert.Contains(t, capabilities, &ecs.Attribute{Name: aws.String(cap)})
+	}
+}
+
+func TestCapabilitiesEnvFilesHost(t *testing.T) {
+	cfg := getCapabilitiesTestConfig()
+	envFilesHostCapability := &ecs.Attribute{Name: aws.String(attributePrefix + capabilityEnvFilesHost)}
+
+	assert.NotContains(t, getCapabilitiesWithConfig(cfg, t), envFilesHostCapability)
+
+	cfg.EnvironmentFilesHostDir = "/etc/ecs/envfiles"
+	assert.Contains(t, getCapabilitiesWithConfig(cfg, t), envFilesHostCapability)
+}
+
 func getCapabilitiesTestConfig() *config.Config {
 	return &config.Config{
 		AvailableLoggingDrivers: []dockerclient.LoggingDriver{
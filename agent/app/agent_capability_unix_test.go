@@ -473,6 +473,8 @@ func TestPIDAndIPCNamespaceSharingCapabilitiesUnix(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 		attributePrefix + capabiltyPIDAndIPCNamespaceSharing,
 	}
 
@@ -540,6 +542,8 @@ func TestPIDAndIPCNamespaceSharingCapabilitiesNoPauseContainer(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 	}
 
 	var expectedCapabilities []*ecs.Attribute
@@ -606,6 +610,8 @@ func TestAppMeshCapabilitiesUnix(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 		attributePrefix + capabiltyPIDAndIPCNamespaceSharing,
 		attributePrefix + appMeshAttributeSuffix,
 	}
@@ -781,6 +787,8 @@ func TestCapabilitiesUnix(t *testing.T) {
 		capabilityPrefix + capabilityFirelensLoggingDriver,
 		attributePrefix + capabilityFirelensLoggingDriver + capabilityFireLensLoggingDriverConfigBufferLimitSuffix,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 	}
 
 	var expectedCapabilities []*ecs.Attribute
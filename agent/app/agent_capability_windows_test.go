@@ -183,6 +183,8 @@ func TestSupportedCapabilitiesWindows(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesSSM,
+		attributePrefix + capabilityEnvFilesHTTPS,
 		attributePrefix + taskENIBlockInstanceMetadataAttributeSuffix}
 
 	var expectedCapabilities []*ecs.Attribute
@@ -24,6 +24,7 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
@@ -31,6 +32,7 @@ import (
 	app_mocks "github.com/aws/amazon-ecs-agent/agent/app/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	mock_containermetadata "github.com/aws/amazon-ecs-agent/agent/containermetadata/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
@@ -1455,6 +1457,46 @@ func TestSaveMetadata(t *testing.T) {
 	assert.Equal(t, availabilityZone, az)
 }
 
+func TestNewCredentialsManagerPersistsCredentials(t *testing.T) {
+	if _, err := credentials.LoadHostKey(t.TempDir()); err != nil {
+		t.Skipf("Credentials can't be persisted on this host: %v", err)
+	}
+	dataClient, cleanup := newTestDataClient(t)
+	defer cleanup()
+
+	cfg := getTestConfig()
+	cfg.Checkpoint = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	cfg.PersistTaskCredentials = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+	cfg.DataDir = t.TempDir()
+	agent := &ecsAgent{
+		cfg:        &cfg,
+		dataClient: dataClient,
+	}
+
+	credentialsManager := agent.newCredentialsManager()
+	require.NoError(t, credentialsManager.SetTaskCredentials(&credentials.TaskIAMRoleCredentials{
+		ARN: "arn:aws:ecs:us-west-2:123456789012:task/cluster/id",
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			CredentialsID: "credentials-id",
+			AccessKeyID:   "access-key-id",
+			Expiration:    time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		},
+	}))
+
+	// The agent restarts
+	taskCredentials, ok := agent.newCredentialsManager().GetTaskCredentials("credentials-id")
+	require.True(t, ok, "Expected persisted credentials to be loaded")
+	assert.Equal(t, "access-key-id", taskCredentials.GetIAMRoleCredentials().AccessKeyID)
+
+	// The agent restarts with persistence disabled
+	cfg.PersistTaskCredentials = config.BooleanDefaultFalse{Value: config.ExplicitlyDisabled}
+	_, ok = agent.newCredentialsManager().GetTaskCredentials("credentials-id")
+	assert.False(t, ok)
+	persisted, err := dataClient.GetCredentials()
+	require.NoError(t, err)
+	assert.Len(t, persisted, 0, "Expected persisted credentials to be deleted")
+}
+
 func getTestConfig() config.Config {
 	cfg := config.DefaultConfig()
 	cfg.TaskCPUMemLimit.Value = config.ExplicitlyDisabled
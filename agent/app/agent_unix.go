@@ -21,6 +21,7 @@ import (
 	asmfactory "github.com/aws/amazon-ecs-agent/agent/asm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
@@ -150,14 +151,16 @@ func (agent *ecsAgent) startENIWatcher(state dockerstate.TaskEngineState, stateC
 // initializeResourceFields exists mainly for testing doStart() to use mock Control
 // object
 func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.Manager) {
+	agent.cfg.CgroupDriver = agent.dockerCgroupDriver()
 	agent.resourceFields = &taskresource.ResourceFields{
-		Control: cgroup.New(),
+		Control: cgroup.New(agent.cfg.CgroupPath, agent.cfg.CgroupDriver),
 		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
 			IOUtil:             ioutilwrapper.NewIOUtil(),
 			ASMClientCreator:   asmfactory.NewClientCreator(),
 			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
 			CredentialsManager: credentialsManager,
 			EC2InstanceID:      agent.getEC2InstanceID(),
+			SecretProviders:    agent.getSecretProviders(),
 		},
 		Ctx:              agent.ctx,
 		DockerClient:     agent.dockerClient,
@@ -165,6 +168,18 @@ func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.M
 	}
 }
 
+// dockerCgroupDriver returns the cgroup driver of docker, which determines whether task cgroups
+// are systemd slices.
+func (agent *ecsAgent) dockerCgroupDriver() string {
+	info, err := agent.dockerClient.Info(agent.ctx, dockerclient.InfoTimeout)
+	if err != nil {
+		seelog.Warnf("Unable to get the cgroup driver of docker, task cgroups won't be systemd slices: %v", err)
+		return ""
+	}
+	seelog.Infof("Docker uses the %s cgroup driver", info.CgroupDriver)
+	return info.CgroupDriver
+}
+
 func (agent *ecsAgent) cgroupInit() error {
 	err := agent.resourceFields.Control.Init()
 	// When task CPU and memory limits are enabled, all tasks are placed
@@ -307,6 +307,7 @@ func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.M
 			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
 			FSxClientCreator:   fsxfactory.NewFSxClientCreator(),
 			CredentialsManager: credentialsManager,
+			SecretProviders:    agent.getSecretProviders(),
 		},
 		Ctx:             agent.ctx,
 		DockerClient:    agent.dockerClient,
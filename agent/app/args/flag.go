@@ -28,6 +28,7 @@ const (
 	blacholeEC2MetadataUsage = "Blackhole the EC2 Metadata requests. Setting this option can cause the ECS Agent to fail to work properly.  We do not recommend setting this option"
 	windowsServiceUsage      = "Run the ECS agent as a Windows Service"
 	healthcheckServiceUsage  = "Run the agent healthcheck"
+	stateUsage               = "Inspect the agent's state persisted in ECS_DATADIR and exit: [<dump>|<verify>|<prune>]. dump lists all the records, verify validates the references between them and loads them the way the agent does on startup, prune deletes orphaned records. dump and verify open the data store read-only, prune requires the agent to be stopped"
 
 	versionFlagName              = "version"
 	logLevelFlagName             = "loglevel"
@@ -39,6 +40,7 @@ const (
 	blackholeEC2MetadataFlagName = "blackhole-ec2-metadata"
 	windowsServiceFlagName       = "windows-service"
 	healthCheckFlagName          = "healthcheck"
+	stateFlagName                = "state"
 )
 
 // Args wraps various ECS Agent arguments
@@ -65,6 +67,8 @@ type Args struct {
 	WindowsService *bool
 	// Healthcheck indicates that agent should run healthcheck
 	Healthcheck *bool
+	// State is the state inspection command to run
+	State *string
 }
 
 // New creates a new Args object from the argument list
@@ -82,6 +86,7 @@ func New(arguments []string) (*Args, error) {
 		ECSAttributes:        flagset.Bool(ecsAttributesFlagName, false, ecsAttributesUsage),
 		WindowsService:       flagset.Bool(windowsServiceFlagName, false, windowsServiceUsage),
 		Healthcheck:          flagset.Bool(healthCheckFlagName, false, healthcheckServiceUsage),
+		State:                flagset.String(stateFlagName, "", stateUsage),
 	}
 
 	err := flagset.Parse(arguments)
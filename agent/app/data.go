@@ -72,7 +72,13 @@ func (agent *ecsAgent) loadData(containerChangeEventStream *eventstream.EventStr
 	}
 	s.taskEngine.SetDataClient(agent.dataClient)
 
-	err := agent.loadDataFromBoltDB(s)
+	// Upgrade the records saved by older agents before anything reads them
+	err := data.MigrateSchema(agent.dataClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to migrate the schema of previous data")
+	}
+
+	err = agent.loadDataFromBoltDB(s)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to load previous data from BoltDB")
 	}
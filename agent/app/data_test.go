@@ -148,6 +148,36 @@ func TestLoadDataLoadFromBoltDB(t *testing.T) {
 	checkLoadedData(state, s, t)
 }
 
+func TestLoadDataNewerSchemaVersion(t *testing.T) {
+	ctrl, credentialsManager, _, imageManager, _,
+		_, stateManagerFactory, _, execCmdMgr := setup(t)
+	defer ctrl.Finish()
+
+	_, dataClient, cleanup := newTestClient(t)
+	defer cleanup()
+	populateBoltDB(dataClient, t)
+	// Data saved by a newer agent that this agent can't read
+	require.NoError(t, dataClient.SaveMetadata(data.SchemaVersionKey, strconv.Itoa(data.CurrentSchemaVersion+1)))
+
+	cfg := getTestConfig()
+	cfg.Checkpoint = config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                   ctx,
+		cfg:                   &cfg,
+		dataClient:            dataClient,
+		stateManagerFactory:   stateManagerFactory,
+		saveableOptionFactory: factory.NewSaveableOption(),
+	}
+
+	_, err := agent.loadData(eventstream.NewEventStream("events", ctx),
+		credentialsManager, dockerstate.NewTaskEngineState(), imageManager, execCmdMgr)
+	assert.Error(t, err)
+}
+
 func TestLoadDataLoadFromStateFile(t *testing.T) {
 	ctrl, credentialsManager, _, imageManager, _,
 		_, stateManagerFactory, _, execCmdMgr := setup(t)
@@ -65,6 +65,10 @@ func Run(arguments []string) int {
 		logger.SetLevel(*parsedArgs.DriverLogLevel, *parsedArgs.InstanceLogLevel)
 	}
 
+	if *parsedArgs.State != "" {
+		return runStateCommand(*parsedArgs.State, aws.BoolValue(parsedArgs.BlackholeEC2Metadata))
+	}
+
 	// Create an Agent object
 	agent, err := newAgent(aws.BoolValue(parsedArgs.BlackholeEC2Metadata), parsedArgs.AcceptInsecureCert)
 	if err != nil {
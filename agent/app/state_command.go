@@ -0,0 +1,161 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/cihub/seelog"
+)
+
+const (
+	stateCommandDump   = "dump"
+	stateCommandVerify = "verify"
+	stateCommandPrune  = "prune"
+
+	// stateLockTimeout is how long the state commands wait for a running agent to release the
+	// data store before giving up.
+	stateLockTimeout = 5 * time.Second
+)
+
+// runStateCommand inspects the state persisted by the agent without starting it. The dump and verify
+// commands open the data store read-only, the prune command deletes the orphaned records found by
+// verify and can't run while the agent is running.
+func runStateCommand(command string, blackholeEC2Metadata bool) int {
+	ec2MetadataClient := ec2.NewEC2MetadataClient(nil)
+	if blackholeEC2Metadata {
+		ec2MetadataClient = ec2.NewBlackholeEC2MetadataClient()
+	}
+	cfg, err := config.NewConfig(ec2MetadataClient)
+	if err != nil {
+		seelog.Criticalf("Error loading config: %v", err)
+		return exitcodes.ExitError
+	}
+	return runStateCommandWithConfig(cfg, command, os.Stdout)
+}
+
+func runStateCommandWithConfig(cfg *config.Config, command string, out io.Writer) int {
+	switch command {
+	case stateCommandDump, stateCommandVerify, stateCommandPrune:
+	default:
+		seelog.Criticalf("Unknown state command %q, valid commands are %s, %s and %s", command,
+			stateCommandDump, stateCommandVerify, stateCommandPrune)
+		return exitcodes.ExitTerminal
+	}
+
+	client, err := data.OpenWithOptions(cfg.DataDir, data.Options{
+		ReadOnly:    command != stateCommandPrune,
+		LockTimeout: stateLockTimeout,
+	})
+	if err != nil {
+		seelog.Criticalf("Error opening the data store in %s (is the agent running?): %v", cfg.DataDir, err)
+		return exitcodes.ExitTerminal
+	}
+	defer client.Close()
+
+	inventory, err := data.LoadInventory(client)
+	if err != nil {
+		seelog.Criticalf("Error loading the agent's state: %v", err)
+		return exitcodes.ExitError
+	}
+
+	if command == stateCommandDump {
+		dump, err := json.MarshalIndent(inventory, "", "  ")
+		if err != nil {
+			seelog.Criticalf("Error marshaling the agent's state: %v", err)
+			return exitcodes.ExitError
+		}
+		fmt.Fprintln(out, string(dump))
+		return exitcodes.ExitSuccess
+	}
+
+	problems := inventory.Verify()
+	orphaned := 0
+	for _, problem := range problems {
+		fmt.Fprintln(out, problem.String())
+		if problem.Orphaned {
+			orphaned++
+		}
+	}
+	fmt.Fprintf(out, "%d tasks, %d containers, %d image states, %d eni attachments, %d metadata keys: "+
+		"%d problems found, %d orphaned records\n", len(inventory.Tasks), len(inventory.Containers),
+		len(inventory.ImageStates), len(inventory.ENIAttachments), len(inventory.Metadata), len(problems), orphaned)
+
+	if command == stateCommandPrune {
+		pruned, err := data.Prune(client, problems)
+		fmt.Fprintf(out, "Pruned %d orphaned records\n", pruned)
+		if err != nil {
+			seelog.Criticalf("Error pruning orphaned records: %v", err)
+			return exitcodes.ExitError
+		}
+		orphaned = 0
+	}
+	if !verifyAgentLoad(cfg, client, out) || orphaned > 0 {
+		return exitcodes.ExitError
+	}
+	return exitcodes.ExitSuccess
+}
+
+// verifyAgentLoad loads the records of the client the way the agent does on startup: through the
+// task engine, which binds containers to their tasks, followed by the initialization of the
+// resources of the restored tasks. It reports whether the agent would load them. Records of an
+// older schema version are only checked by Verify, as the agent migrates them before loading them.
+func verifyAgentLoad(cfg *config.Config, client data.Client, out io.Writer) bool {
+	version, err := data.GetSchemaVersion(client)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return false
+	}
+	if version < data.CurrentSchemaVersion {
+		fmt.Fprintf(out, "warning: the data store has schema version %d, the agent migrates it to %d before loading it\n",
+			version, data.CurrentSchemaVersion)
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	credentialsManager := credentials.NewManager()
+	resourceFields := &taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			CredentialsManager: credentialsManager,
+		},
+		Ctx: ctx,
+	}
+	state := dockerstate.NewTaskEngineState()
+	taskEngine := engine.NewTaskEngine(cfg, nil, credentialsManager, eventstream.NewEventStream("state", ctx),
+		nil, state, nil, resourceFields, nil)
+	taskEngine.SetDataClient(client)
+	if err := taskEngine.LoadState(); err != nil {
+		fmt.Fprintf(out, "error: the agent would fail to load its state: %v\n", err)
+		return false
+	}
+	for _, task := range state.AllTasks() {
+		task.InitializeResources(resourceFields)
+	}
+	return true
+}
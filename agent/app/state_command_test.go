@@ -0,0 +1,117 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testStateTaskArn       = "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/abc"
+	testStateOrphanTaskArn = "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/orphan"
+)
+
+func setupStateCommandTest(t *testing.T) (*config.Config, func()) {
+	testDir, err := ioutil.TempDir("", "agent_app_unit_test")
+	require.NoError(t, err)
+
+	testClient, err := data.OpenWithOptions(testDir, data.Options{})
+	require.NoError(t, err)
+	require.NoError(t, testClient.SaveMetadata(data.SchemaVersionKey, strconv.Itoa(data.CurrentSchemaVersion)))
+	require.NoError(t, testClient.SaveTask(&apitask.Task{
+		Arn:        testStateTaskArn,
+		Containers: []*apicontainer.Container{{Name: "c1"}},
+	}))
+	require.NoError(t, testClient.SaveContainer(&apicontainer.Container{Name: "c1", TaskARNUnsafe: testStateTaskArn}))
+	require.NoError(t, testClient.SaveContainer(&apicontainer.Container{Name: "c1", TaskARNUnsafe: testStateOrphanTaskArn}))
+	require.NoError(t, testClient.Close())
+
+	cfg := &config.Config{DataDir: testDir}
+	return cfg, func() {
+		os.RemoveAll(testDir)
+	}
+}
+
+func TestStateCommandDump(t *testing.T) {
+	cfg, cleanup := setupStateCommandTest(t)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	require.Equal(t, exitcodes.ExitSuccess, runStateCommandWithConfig(cfg, stateCommandDump, out))
+	dump := &struct {
+		Tasks      []json.RawMessage
+		Containers []json.RawMessage
+	}{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), dump))
+	assert.Len(t, dump.Tasks, 1)
+	assert.Len(t, dump.Containers, 2)
+}
+
+func TestStateCommandVerifyAndPrune(t *testing.T) {
+	cfg, cleanup := setupStateCommandTest(t)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, exitcodes.ExitError, runStateCommandWithConfig(cfg, stateCommandVerify, out))
+	assert.Contains(t, out.String(), "orphaned container orphan-c1")
+	assert.Contains(t, out.String(), "the agent would fail to load its state: did not find the task of container c1")
+
+	out.Reset()
+	assert.Equal(t, exitcodes.ExitSuccess, runStateCommandWithConfig(cfg, stateCommandPrune, out))
+	assert.Contains(t, out.String(), "Pruned 1 orphaned records")
+
+	out.Reset()
+	assert.Equal(t, exitcodes.ExitSuccess, runStateCommandWithConfig(cfg, stateCommandVerify, out))
+	assert.Contains(t, out.String(), "0 problems found")
+	assert.NotContains(t, out.String(), "the agent would fail to load its state")
+}
+
+func TestStateCommandVerifyOlderSchema(t *testing.T) {
+	cfg, cleanup := setupStateCommandTest(t)
+	defer cleanup()
+	testClient, err := data.OpenWithOptions(cfg.DataDir, data.Options{})
+	require.NoError(t, err)
+	require.NoError(t, testClient.SaveMetadata(data.SchemaVersionKey, "0"))
+	require.NoError(t, testClient.Close())
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, exitcodes.ExitError, runStateCommandWithConfig(cfg, stateCommandVerify, out))
+	assert.Contains(t, out.String(), "the agent migrates it to")
+	assert.NotContains(t, out.String(), "the agent would fail to load its state")
+}
+
+func TestStateCommandInvalid(t *testing.T) {
+	cfg, cleanup := setupStateCommandTest(t)
+	defer cleanup()
+
+	assert.Equal(t, exitcodes.ExitTerminal, runStateCommandWithConfig(cfg, "unknown", &bytes.Buffer{}))
+	cfg.DataDir = cfg.DataDir + "/missing"
+	assert.Equal(t, exitcodes.ExitTerminal, runStateCommandWithConfig(cfg, stateCommandVerify, &bytes.Buffer{}))
+}
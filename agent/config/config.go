@@ -18,7 +18,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
@@ -50,6 +54,9 @@ const (
 	// AgentPrometheusExpositionPort is used to expose Prometheus metrics that can be scraped by a Prometheus server
 	AgentPrometheusExpositionPort = 51680
 
+	// AgentTaskLifecyclePort is used to serve the endpoints that add and stop tasks, on the loopback interface only.
+	AgentTaskLifecyclePort = 51681
+
 	// defaultConfigFileName is the default (json-formatted) config file
 	defaultConfigFileName = "/etc/ecs_container_agent/config.json"
 
@@ -64,6 +71,18 @@ const (
 	// This is only used when PollMetrics is set to true
 	DefaultPollingMetricsWaitDuration = DefaultContainerMetricsPublishInterval / 2
 
+	// StatsCollectorDocker collects container stats from Docker stats streams.
+	StatsCollectorDocker = "docker"
+
+	// StatsCollectorCgroupfs collects container stats from the container cgroups in cgroupfs.
+	StatsCollectorCgroupfs = "cgroupfs"
+
+	// StatsDFormatStatsD is the plain StatsD format, with dimensions in the metric name.
+	StatsDFormatStatsD = "statsd"
+
+	// StatsDFormatDogStatsD is the DogStatsD format, with dimensions as tags.
+	StatsDFormatDogStatsD = "dogstatsd"
+
 	// defaultDockerStopTimeout specifies the value for container stop timeout duration
 	defaultDockerStopTimeout = 30 * time.Second
 
@@ -75,6 +94,25 @@ const (
 	// image cleanup.
 	DefaultNumImagesToDeletePerCycle = 5
 
+	// DefaultImageCleanupDiskCheckInterval specifies the default value for the time to wait between checks
+	// of the disk usage when disk pressure driven image cleanup is enabled.
+	DefaultImageCleanupDiskCheckInterval = 1 * time.Minute
+
+	// DefaultStatsFileMaxSizeMB is the default size, in MB, that the stats file grows to before
+	// it's rotated.
+	DefaultStatsFileMaxSizeMB = 10
+
+	// DefaultStatsFileMaxRollCount is the default number of rotated stats files that are kept.
+	DefaultStatsFileMaxRollCount = 5
+
+	// DefaultTaskStorageCollectionInterval specifies the default interval at which the ephemeral
+	// storage used by tasks is measured.
+	DefaultTaskStorageCollectionInterval = 1 * time.Minute
+
+	// DefaultVaultKVVersion specifies the default version of the KV secrets engine that Vault
+	// secrets are read from.
+	DefaultVaultKVVersion = 2
+
 	// DefaultNumNonECSContainersToDeletePerCycle specifies the default number of nonecs containers to delete when agent performs
 	// nonecs containers cleanup.
 	DefaultNumNonECSContainersToDeletePerCycle = 5
@@ -87,6 +125,10 @@ const (
 	// has been created before it can be deleted
 	DefaultNonECSImageDeletionAge = 1 * time.Hour
 
+	// DefaultImagePrewarmProtectionPeriod specifies the default value for the amount of time after an image
+	// has been pre-warmed during which it can't be deleted.
+	DefaultImagePrewarmProtectionPeriod = 24 * time.Hour
+
 	//DefaultImagePullTimeout specifies the timeout for PullImage API.
 	DefaultImagePullTimeout = 2 * time.Hour
 
@@ -106,6 +148,18 @@ const (
 	// from docker. This is only used when PollMetrics is set to true
 	maximumPollingMetricsWaitDuration = DefaultContainerMetricsPublishInterval
 
+	// minimumStatsSinkPublishInterval specifies the minimum interval at which container
+	// utilization metrics are published to the StatsD server and the stats file
+	minimumStatsSinkPublishInterval = time.Second
+
+	// minimumTaskStorageCollectionInterval specifies the minimum interval at which the ephemeral
+	// storage used by tasks is measured, since Docker walks the writable layers to size them.
+	minimumTaskStorageCollectionInterval = 10 * time.Second
+
+	// minimumSecretRefreshInterval specifies the minimum interval at which the secrets of running
+	// tasks are fetched again, to stay well within the SSM and Secrets Manager API rate limits.
+	minimumSecretRefreshInterval = 1 * time.Minute
+
 	// minimumDockerStopTimeout specifies the minimum value for docker StopContainer API
 	minimumDockerStopTimeout = 1 * time.Second
 
@@ -113,6 +167,14 @@ const (
 	// image cleanup.
 	minimumImageCleanupInterval = 10 * time.Minute
 
+	// minimumImageCleanupDiskCheckInterval specifies the minimum time for agent to wait between checks of
+	// the disk usage when disk pressure driven image cleanup is enabled.
+	minimumImageCleanupDiskCheckInterval = 10 * time.Second
+
+	// defaultImageCleanupDiskWatermarkGap specifies the gap between the high and the low disk usage
+	// watermarks of image cleanup when the low watermark isn't set.
+	defaultImageCleanupDiskWatermarkGap = 10
+
 	// minimumNumImagesToDeletePerCycle specifies the minimum number of images that to be deleted when
 	// performing image cleanup.
 	minimumNumImagesToDeletePerCycle = 1
@@ -354,6 +416,23 @@ func (cfg *Config) validateAndOverrideBounds() error {
 		cfg.NumImagesToDeletePerCycle = DefaultNumImagesToDeletePerCycle
 	}
 
+	if cfg.ImagePullConcurrencyPerRegistry < 0 {
+		seelog.Warnf("Invalid value for ECS_IMAGE_PULL_CONCURRENCY_PER_REGISTRY, image pulls will not be limited. Parsed value: %d.", cfg.ImagePullConcurrencyPerRegistry)
+		cfg.ImagePullConcurrencyPerRegistry = 0
+	}
+
+	cfg.imageCleanupDiskWatermarkOverrides()
+
+	if cfg.OTLPTracesEndpoint != "" {
+		endpoint, err := url.Parse(cfg.OTLPTracesEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			seelog.Warnf("Invalid value for ECS_OTLP_TRACES_ENDPOINT, tracing will be disabled. Parsed value: %s, expected an http or https URL.", cfg.OTLPTracesEndpoint)
+			cfg.OTLPTracesEndpoint = ""
+		}
+	}
+
+	cfg.statsSinkOverrides()
+
 	if cfg.TaskMetadataSteadyStateRate <= 0 || cfg.TaskMetadataBurstRate <= 0 {
 		seelog.Warnf("Invalid values for rate limits, will be overridden with default values: %d,%d.", DefaultTaskMetadataSteadyStateRate, DefaultTaskMetadataBurstRate)
 		cfg.TaskMetadataSteadyStateRate = DefaultTaskMetadataSteadyStateRate
@@ -363,6 +442,25 @@ func (cfg *Config) validateAndOverrideBounds() error {
 	// check the PollMetrics specific configurations
 	cfg.pollMetricsOverrides()
 
+	switch cfg.StatsCollector {
+	case StatsCollectorDocker, StatsCollectorCgroupfs:
+	default:
+		seelog.Warnf("Invalid value for ECS_STATS_COLLECTOR, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.",
+			StatsCollectorDocker, cfg.StatsCollector, StatsCollectorDocker, StatsCollectorCgroupfs)
+		cfg.StatsCollector = StatsCollectorDocker
+	}
+
+	cfg.taskStorageOverrides()
+
+	cfg.secretsOverrides()
+
+	cfg.envFilesOverrides()
+
+	// Tasks can't bind the port of the task lifecycle endpoints on the host
+	if cfg.EnableIntrospectionTaskAPI.Enabled() {
+		cfg.ReservedPorts = append(cfg.ReservedPorts, AgentTaskLifecyclePort)
+	}
+
 	cfg.platformOverrides()
 
 	return nil
@@ -384,6 +482,123 @@ func (cfg *Config) pollMetricsOverrides() {
 	}
 }
 
+func (cfg *Config) statsSinkOverrides() {
+	if cfg.StatsDAddress != "" {
+		if _, _, err := net.SplitHostPort(cfg.StatsDAddress); err != nil {
+			seelog.Warnf("Invalid value for ECS_STATSD_ADDRESS, metrics will not be sent to StatsD. Parsed value: %s, expected host:port.", cfg.StatsDAddress)
+			cfg.StatsDAddress = ""
+		}
+	}
+
+	switch cfg.StatsDFormat {
+	case StatsDFormatStatsD, StatsDFormatDogStatsD:
+	default:
+		seelog.Warnf("Invalid value for ECS_STATSD_FORMAT, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.",
+			StatsDFormatStatsD, cfg.StatsDFormat, StatsDFormatStatsD, StatsDFormatDogStatsD)
+		cfg.StatsDFormat = StatsDFormatStatsD
+	}
+
+	if cfg.StatsSinkPublishInterval < minimumStatsSinkPublishInterval {
+		seelog.Warnf("ECS_STATS_SINK_PUBLISH_INTERVAL parsed value (%s) is less than the minimum of %s. Setting publish interval to minimum.",
+			cfg.StatsSinkPublishInterval, minimumStatsSinkPublishInterval)
+		cfg.StatsSinkPublishInterval = minimumStatsSinkPublishInterval
+	}
+
+	if cfg.StatsFileMaxSizeMB <= 0 {
+		seelog.Warnf("Invalid value for ECS_STATS_FILE_MAX_SIZE_MB, will be overridden with the default value: %d. Parsed value: %d, expected a positive number of MB.",
+			DefaultStatsFileMaxSizeMB, cfg.StatsFileMaxSizeMB)
+		cfg.StatsFileMaxSizeMB = DefaultStatsFileMaxSizeMB
+	}
+
+	if cfg.StatsFileMaxRollCount <= 0 {
+		seelog.Warnf("Invalid value for ECS_STATS_FILE_MAX_ROLL_COUNT, will be overridden with the default value: %d. Parsed value: %d, expected a positive number.",
+			DefaultStatsFileMaxRollCount, cfg.StatsFileMaxRollCount)
+		cfg.StatsFileMaxRollCount = DefaultStatsFileMaxRollCount
+	}
+}
+
+func (cfg *Config) taskStorageOverrides() {
+	if cfg.TaskStorageCollectionInterval < minimumTaskStorageCollectionInterval {
+		seelog.Warnf("ECS_TASK_STORAGE_COLLECTION_INTERVAL parsed value (%s) is less than the minimum of %s. Setting collection interval to minimum.",
+			cfg.TaskStorageCollectionInterval, minimumTaskStorageCollectionInterval)
+		cfg.TaskStorageCollectionInterval = minimumTaskStorageCollectionInterval
+	}
+
+	if cfg.TaskEphemeralStorageLimit < 0 {
+		seelog.Warnf("Invalid value for ECS_TASK_EPHEMERAL_STORAGE_LIMIT, the ephemeral storage of tasks will not be limited. Parsed value: %d, expected a number of MiB.", cfg.TaskEphemeralStorageLimit)
+		cfg.TaskEphemeralStorageLimit = 0
+	}
+}
+
+func (cfg *Config) secretsOverrides() {
+	if cfg.SecretFilesContainerPath != "" && !path.IsAbs(cfg.SecretFilesContainerPath) {
+		seelog.Warnf("Invalid value for ECS_SECRET_FILES_CONTAINER_PATH, will be overridden with the default value: %s. Parsed value: %s, expected an absolute path.",
+			defaultSecretFilesContainerPath, cfg.SecretFilesContainerPath)
+		cfg.SecretFilesContainerPath = defaultSecretFilesContainerPath
+	}
+
+	if cfg.SecretRefreshInterval < 0 {
+		seelog.Warnf("Invalid value for ECS_SECRET_REFRESH_INTERVAL, secrets will not be refreshed. Parsed value: %s.", cfg.SecretRefreshInterval)
+		cfg.SecretRefreshInterval = 0
+	}
+	if cfg.SecretRefreshInterval > 0 && cfg.SecretRefreshInterval < minimumSecretRefreshInterval {
+		seelog.Warnf("ECS_SECRET_REFRESH_INTERVAL parsed value (%s) is less than the minimum of %s. Setting refresh interval to minimum.",
+			cfg.SecretRefreshInterval, minimumSecretRefreshInterval)
+		cfg.SecretRefreshInterval = minimumSecretRefreshInterval
+	}
+
+	if (cfg.LocalSecretsDir == "") != (cfg.LocalSecretsKeyFile == "") {
+		seelog.Warn("ECS_LOCAL_SECRETS_DIR and ECS_LOCAL_SECRETS_KEY_FILE must be set together, local secrets will not be available.")
+		cfg.LocalSecretsDir = ""
+		cfg.LocalSecretsKeyFile = ""
+	}
+
+	if cfg.VaultAddress != "" && cfg.VaultTokenFile == "" {
+		seelog.Warn("ECS_VAULT_TOKEN_FILE must be set along with ECS_VAULT_ADDRESS, vault secrets will not be available.")
+		cfg.VaultAddress = ""
+	}
+	if cfg.VaultAddress != "" && len(cfg.VaultKVMounts) == 0 {
+		seelog.Warn("ECS_VAULT_KV_MOUNTS must be set along with ECS_VAULT_ADDRESS, vault secrets will not be available.")
+		cfg.VaultAddress = ""
+	}
+	if cfg.VaultKVVersion != 1 && cfg.VaultKVVersion != 2 {
+		seelog.Warnf("Invalid value for ECS_VAULT_KV_VERSION, will be overridden with the default value: %d. Parsed value: %d, expected 1 or 2.",
+			DefaultVaultKVVersion, cfg.VaultKVVersion)
+		cfg.VaultKVVersion = DefaultVaultKVVersion
+	}
+}
+
+func (cfg *Config) envFilesOverrides() {
+	if cfg.EnvironmentFilesHostDir != "" && !filepath.IsAbs(cfg.EnvironmentFilesHostDir) {
+		seelog.Warnf("Invalid value for ECS_ENV_FILES_HOST_DIR, host environment files will not be available. Parsed value: %s, expected an absolute path.",
+			cfg.EnvironmentFilesHostDir)
+		cfg.EnvironmentFilesHostDir = ""
+	}
+}
+
+func (cfg *Config) imageCleanupDiskWatermarkOverrides() {
+	if cfg.ImageCleanupDiskHighWatermark == 0 {
+		return
+	}
+	if cfg.ImageCleanupDiskHighWatermark < 0 || cfg.ImageCleanupDiskHighWatermark > 100 {
+		seelog.Warnf("Invalid value for ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK, disk pressure driven image cleanup will be disabled. Parsed value: %d, expected a percentage.", cfg.ImageCleanupDiskHighWatermark)
+		cfg.ImageCleanupDiskHighWatermark = 0
+		return
+	}
+	if cfg.ImageCleanupDiskLowWatermark == 0 {
+		cfg.ImageCleanupDiskLowWatermark = cfg.ImageCleanupDiskHighWatermark - defaultImageCleanupDiskWatermarkGap
+	}
+	if cfg.ImageCleanupDiskLowWatermark <= 0 || cfg.ImageCleanupDiskLowWatermark >= cfg.ImageCleanupDiskHighWatermark {
+		seelog.Warnf("Invalid value for ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK, disk pressure driven image cleanup will be disabled. Parsed value: %d, expected a percentage lower than the high watermark: %d.", cfg.ImageCleanupDiskLowWatermark, cfg.ImageCleanupDiskHighWatermark)
+		cfg.ImageCleanupDiskHighWatermark = 0
+		return
+	}
+	if cfg.ImageCleanupDiskCheckInterval < minimumImageCleanupDiskCheckInterval {
+		seelog.Warnf("Invalid value for ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultImageCleanupDiskCheckInterval.String(), cfg.ImageCleanupDiskCheckInterval, minimumImageCleanupDiskCheckInterval)
+		cfg.ImageCleanupDiskCheckInterval = DefaultImageCleanupDiskCheckInterval
+	}
+}
+
 // checkMissingAndDeprecated checks all zero-valued fields for tags of the form
 // missing:STRING and acts based on that string. Current options are: fatal,
 // warn. Fatal will result in an error being returned, warn will result in a
@@ -559,10 +774,18 @@ func environmentConfig() (Config, error) {
 		ImageCleanupDisabled:                parseBooleanDefaultFalseConfig("ECS_DISABLE_IMAGE_CLEANUP"),
 		MinimumImageDeletionAge:             parseEnvVariableDuration("ECS_IMAGE_MINIMUM_CLEANUP_AGE"),
 		NonECSMinimumImageDeletionAge:       parseEnvVariableDuration("NON_ECS_IMAGE_MINIMUM_CLEANUP_AGE"),
+		ImagePrewarmManifest:                os.Getenv("ECS_IMAGE_PREWARM_MANIFEST"),
+		ImagePrewarmProtectionPeriod:        parseEnvVariableDuration("ECS_IMAGE_PREWARM_PROTECTION_PERIOD"),
 		ImageCleanupInterval:                parseEnvVariableDuration("ECS_IMAGE_CLEANUP_INTERVAL"),
 		NumImagesToDeletePerCycle:           parseNumImagesToDeletePerCycle(),
+		ImageCleanupDiskHighWatermark:       parseEnvVariableInt("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK"),
+		ImageCleanupDiskLowWatermark:        parseEnvVariableInt("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK"),
+		ImageCleanupDiskCheckInterval:       parseEnvVariableDuration("ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL"),
+		ImageCleanupDiskPath:                os.Getenv("ECS_IMAGE_CLEANUP_DISK_PATH"),
 		NumNonECSContainersToDeletePerCycle: parseNumNonECSContainersToDeletePerCycle(),
 		ImagePullBehavior:                   parseImagePullBehavior(),
+		ImagePullConcurrencyPerRegistry:     parseEnvVariableInt("ECS_IMAGE_PULL_CONCURRENCY_PER_REGISTRY"),
+		ImagePolicyFile:                     os.Getenv("ECS_IMAGE_POLICY_FILE"),
 		ImageCleanupExclusionList:           parseImageCleanupExclusionList("ECS_EXCLUDE_UNTRACKED_IMAGE"),
 		InstanceAttributes:                  instanceAttributes,
 		CNIPluginsPath:                      os.Getenv("ECS_CNI_PLUGINS_PATH"),
@@ -579,6 +802,27 @@ func environmentConfig() (Config, error) {
 		ContainerInstancePropagateTagsFrom:  parseContainerInstancePropagateTagsFrom(),
 		PollMetrics:                         parseBooleanDefaultFalseConfig("ECS_POLL_METRICS"),
 		PollingMetricsWaitDuration:          parseEnvVariableDuration("ECS_POLLING_METRICS_WAIT_DURATION"),
+		StatsCollector:                      os.Getenv("ECS_STATS_COLLECTOR"),
+		OTLPTracesEndpoint:                  os.Getenv("ECS_OTLP_TRACES_ENDPOINT"),
+		StatsDAddress:                       os.Getenv("ECS_STATSD_ADDRESS"),
+		StatsDFormat:                        os.Getenv("ECS_STATSD_FORMAT"),
+		StatsFilePath:                       os.Getenv("ECS_STATS_FILE_PATH"),
+		StatsFileMaxSizeMB:                  parseEnvVariableInt("ECS_STATS_FILE_MAX_SIZE_MB"),
+		StatsFileMaxRollCount:               parseEnvVariableInt("ECS_STATS_FILE_MAX_ROLL_COUNT"),
+		StatsSinkPublishInterval:            parseEnvVariableDuration("ECS_STATS_SINK_PUBLISH_INTERVAL"),
+		TaskStorageCollectionInterval:       parseEnvVariableDuration("ECS_TASK_STORAGE_COLLECTION_INTERVAL"),
+		TaskEphemeralStorageLimit:           parseEnvVariableInt("ECS_TASK_EPHEMERAL_STORAGE_LIMIT"),
+		SecretFilesContainerPath:            os.Getenv("ECS_SECRET_FILES_CONTAINER_PATH"),
+		SecretRefreshInterval:               parseEnvVariableDuration("ECS_SECRET_REFRESH_INTERVAL"),
+		SecretRefreshSignal:                 os.Getenv("ECS_SECRET_REFRESH_SIGNAL"),
+		LocalSecretsDir:                     os.Getenv("ECS_LOCAL_SECRETS_DIR"),
+		LocalSecretsKeyFile:                 os.Getenv("ECS_LOCAL_SECRETS_KEY_FILE"),
+		VaultAddress:                        os.Getenv("ECS_VAULT_ADDRESS"),
+		VaultTokenFile:                      os.Getenv("ECS_VAULT_TOKEN_FILE"),
+		VaultKVVersion:                      parseEnvVariableInt("ECS_VAULT_KV_VERSION"),
+		VaultKVMounts:                       parseVaultKVMounts(),
+		EnvironmentFilesHostDir:             os.Getenv("ECS_ENV_FILES_HOST_DIR"),
+		PersistTaskCredentials:              parseBooleanDefaultFalseConfig("ECS_PERSIST_TASK_CREDENTIALS"),
 		DisableDockerHealthCheck:            parseBooleanDefaultFalseConfig("ECS_DISABLE_DOCKER_HEALTH_CHECK"),
 		GPUSupportEnabled:                   utils.ParseBool(os.Getenv("ECS_ENABLE_GPU_SUPPORT"), false),
 		InferentiaSupportEnabled:            utils.ParseBool(os.Getenv("ECS_ENABLE_INF_SUPPORT"), false),
@@ -591,6 +835,7 @@ func environmentConfig() (Config, error) {
 		FSxWindowsFileServerCapable:         parseFSxWindowsFileServerCapability(),
 		External:                            parseBooleanDefaultFalseConfig("ECS_EXTERNAL"),
 		EnableRuntimeStats:                  parseBooleanDefaultFalseConfig("ECS_ENABLE_RUNTIME_STATS"),
+		EnableIntrospectionTaskAPI:          parseBooleanDefaultFalseConfig("ECS_ENABLE_INTROSPECTION_TASK_API"),
 		ShouldExcludeIPv6PortBinding:        parseBooleanDefaultTrueConfig("ECS_EXCLUDE_IPV6_PORTBINDING"),
 	}, err
 }
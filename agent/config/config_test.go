@@ -609,6 +609,317 @@ func TestEnableRuntimeStatsConfigEnabled(t *testing.T) {
 	assert.True(t, cfg.EnableRuntimeStats.Enabled(), "Wrong value for EnableRuntimeStats")
 }
 
+func TestEnableIntrospectionTaskAPIConfigEnabled(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENABLE_INTROSPECTION_TASK_API", "true")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.EnableIntrospectionTaskAPI.Enabled(), "Wrong value for EnableIntrospectionTaskAPI")
+	assert.Contains(t, cfg.ReservedPorts, uint16(AgentTaskLifecyclePort), "The task lifecycle port should be reserved")
+}
+
+func TestImagePrewarmConfig(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PREWARM_MANIFEST", " /etc/ecs/prewarm.json ")()
+	defer setTestEnv("ECS_IMAGE_PREWARM_PROTECTION_PERIOD", "6h")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/prewarm.json", cfg.ImagePrewarmManifest, "Wrong value for ImagePrewarmManifest")
+	assert.Equal(t, 6*time.Hour, cfg.ImagePrewarmProtectionPeriod, "Wrong value for ImagePrewarmProtectionPeriod")
+}
+
+func TestImageCleanupDiskWatermarkConfig(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", "85")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", "60")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL", "30s")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_PATH", " /host/var/lib/docker ")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 85, cfg.ImageCleanupDiskHighWatermark, "Wrong value for ImageCleanupDiskHighWatermark")
+	assert.Equal(t, 60, cfg.ImageCleanupDiskLowWatermark, "Wrong value for ImageCleanupDiskLowWatermark")
+	assert.Equal(t, 30*time.Second, cfg.ImageCleanupDiskCheckInterval, "Wrong value for ImageCleanupDiskCheckInterval")
+	assert.Equal(t, "/host/var/lib/docker", cfg.ImageCleanupDiskPath, "Wrong value for ImageCleanupDiskPath")
+}
+
+func TestImageCleanupDiskWatermarkOverrides(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		highWatermark         string
+		lowWatermark          string
+		checkInterval         string
+		expectedHighWatermark int
+		expectedLowWatermark  int
+		expectedCheckInterval time.Duration
+	}{
+		{
+			name:                  "default low watermark",
+			highWatermark:         "90",
+			expectedHighWatermark: 90,
+			expectedLowWatermark:  80,
+			expectedCheckInterval: DefaultImageCleanupDiskCheckInterval,
+		},
+		{
+			name:                  "high watermark above 100",
+			highWatermark:         "101",
+			lowWatermark:          "80",
+			expectedHighWatermark: 0,
+			expectedLowWatermark:  80,
+			expectedCheckInterval: DefaultImageCleanupDiskCheckInterval,
+		},
+		{
+			name:                  "low watermark above high watermark",
+			highWatermark:         "80",
+			lowWatermark:          "90",
+			expectedHighWatermark: 0,
+			expectedLowWatermark:  90,
+			expectedCheckInterval: DefaultImageCleanupDiskCheckInterval,
+		},
+		{
+			name:                  "check interval below minimum",
+			highWatermark:         "80",
+			lowWatermark:          "70",
+			checkInterval:         "1s",
+			expectedHighWatermark: 80,
+			expectedLowWatermark:  70,
+			expectedCheckInterval: DefaultImageCleanupDiskCheckInterval,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer setTestRegion()()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_HIGH_WATERMARK", tc.highWatermark)()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_LOW_WATERMARK", tc.lowWatermark)()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_DISK_CHECK_INTERVAL", tc.checkInterval)()
+			cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHighWatermark, cfg.ImageCleanupDiskHighWatermark)
+			assert.Equal(t, tc.expectedLowWatermark, cfg.ImageCleanupDiskLowWatermark)
+			assert.Equal(t, tc.expectedCheckInterval, cfg.ImageCleanupDiskCheckInterval)
+		})
+	}
+}
+
+func TestImagePullConcurrencyPerRegistry(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_CONCURRENCY_PER_REGISTRY", "3")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.ImagePullConcurrencyPerRegistry, "Wrong value for ImagePullConcurrencyPerRegistry")
+}
+
+func TestImagePolicyFile(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_POLICY_FILE", " /etc/ecs/image-policy.json ")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/image-policy.json", cfg.ImagePolicyFile, "Wrong value for ImagePolicyFile")
+}
+
+func TestOTLPTracesEndpoint(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_OTLP_TRACES_ENDPOINT", "http://localhost:4318/v1/traces")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:4318/v1/traces", cfg.OTLPTracesEndpoint, "Wrong value for OTLPTracesEndpoint")
+}
+
+func TestInvalidOTLPTracesEndpoint(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_OTLP_TRACES_ENDPOINT", "localhost:4318")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.OTLPTracesEndpoint, "Invalid OTLPTracesEndpoint should disable tracing")
+}
+
+func TestStatsCollector(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATS_COLLECTOR", "cgroupfs")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, StatsCollectorCgroupfs, cfg.StatsCollector, "Wrong value for StatsCollector")
+}
+
+func TestInvalidStatsCollector(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATS_COLLECTOR", "procfs")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, StatsCollectorDocker, cfg.StatsCollector, "Invalid StatsCollector should be overridden with the default")
+}
+
+func TestStatsSinks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATSD_ADDRESS", "localhost:8125")()
+	defer setTestEnv("ECS_STATSD_FORMAT", "dogstatsd")()
+	defer setTestEnv("ECS_STATS_FILE_PATH", "/var/log/ecs/stats.json")()
+	defer setTestEnv("ECS_STATS_FILE_MAX_SIZE_MB", "50")()
+	defer setTestEnv("ECS_STATS_FILE_MAX_ROLL_COUNT", "2")()
+	defer setTestEnv("ECS_STATS_SINK_PUBLISH_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:8125", cfg.StatsDAddress, "Wrong value for StatsDAddress")
+	assert.Equal(t, StatsDFormatDogStatsD, cfg.StatsDFormat, "Wrong value for StatsDFormat")
+	assert.Equal(t, "/var/log/ecs/stats.json", cfg.StatsFilePath, "Wrong value for StatsFilePath")
+	assert.Equal(t, 50, cfg.StatsFileMaxSizeMB, "Wrong value for StatsFileMaxSizeMB")
+	assert.Equal(t, 2, cfg.StatsFileMaxRollCount, "Wrong value for StatsFileMaxRollCount")
+	assert.Equal(t, 10*time.Second, cfg.StatsSinkPublishInterval, "Wrong value for StatsSinkPublishInterval")
+}
+
+func TestInvalidStatsSinks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATSD_ADDRESS", "localhost")()
+	defer setTestEnv("ECS_STATSD_FORMAT", "graphite")()
+	defer setTestEnv("ECS_STATS_SINK_PUBLISH_INTERVAL", "1ms")()
+	defer setTestEnv("ECS_STATS_FILE_MAX_SIZE_MB", "-1")()
+	defer setTestEnv("ECS_STATS_FILE_MAX_ROLL_COUNT", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.StatsDAddress, "Invalid StatsDAddress should disable the StatsD sink")
+	assert.Equal(t, StatsDFormatStatsD, cfg.StatsDFormat, "Invalid StatsDFormat should be overridden with the default")
+	assert.Equal(t, minimumStatsSinkPublishInterval, cfg.StatsSinkPublishInterval, "Wrong value for StatsSinkPublishInterval")
+	assert.Equal(t, DefaultStatsFileMaxSizeMB, cfg.StatsFileMaxSizeMB, "Wrong value for StatsFileMaxSizeMB")
+	assert.Equal(t, DefaultStatsFileMaxRollCount, cfg.StatsFileMaxRollCount, "Wrong value for StatsFileMaxRollCount")
+}
+
+func TestTaskStorage(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_STORAGE_COLLECTION_INTERVAL", "5m")()
+	defer setTestEnv("ECS_TASK_EPHEMERAL_STORAGE_LIMIT", "10240")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.TaskStorageCollectionInterval, "Wrong value for TaskStorageCollectionInterval")
+	assert.Equal(t, 10240, cfg.TaskEphemeralStorageLimit, "Wrong value for TaskEphemeralStorageLimit")
+}
+
+func TestInvalidTaskStorage(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_STORAGE_COLLECTION_INTERVAL", "1s")()
+	defer setTestEnv("ECS_TASK_EPHEMERAL_STORAGE_LIMIT", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumTaskStorageCollectionInterval, cfg.TaskStorageCollectionInterval, "Wrong value for TaskStorageCollectionInterval")
+	assert.Zero(t, cfg.TaskEphemeralStorageLimit, "Invalid TaskEphemeralStorageLimit should disable the limit")
+}
+
+func TestSecretFilesContainerPath(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_FILES_CONTAINER_PATH", "/etc/secrets")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/secrets", cfg.SecretFilesContainerPath, "Wrong value for SecretFilesContainerPath")
+}
+
+func TestInvalidSecretFilesContainerPath(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_FILES_CONTAINER_PATH", "secrets")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultSecretFilesContainerPath, cfg.SecretFilesContainerPath, "Relative SecretFilesContainerPath should be reset to the default")
+}
+
+func TestSecretRefresh(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_REFRESH_INTERVAL", "15m")()
+	defer setTestEnv("ECS_SECRET_REFRESH_SIGNAL", "SIGHUP")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, cfg.SecretRefreshInterval, "Wrong value for SecretRefreshInterval")
+	assert.Equal(t, "SIGHUP", cfg.SecretRefreshSignal, "Wrong value for SecretRefreshSignal")
+}
+
+func TestSecretRefreshIntervalBelowMinimum(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_REFRESH_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumSecretRefreshInterval, cfg.SecretRefreshInterval, "Wrong value for SecretRefreshInterval")
+}
+
+func TestSecretRefreshDisabledByDefault(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.SecretRefreshInterval, "Secrets should not be refreshed by default")
+}
+
+func TestSecretProviders(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_LOCAL_SECRETS_DIR", "/etc/ecs/secrets")()
+	defer setTestEnv("ECS_LOCAL_SECRETS_KEY_FILE", "/etc/ecs/secrets.key")()
+	defer setTestEnv("ECS_VAULT_ADDRESS", "https://vault.local:8200")()
+	defer setTestEnv("ECS_VAULT_TOKEN_FILE", "/etc/ecs/vault-token")()
+	defer setTestEnv("ECS_VAULT_KV_VERSION", "1")()
+	defer setTestEnv("ECS_VAULT_KV_MOUNTS", "secret, team/kv/")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/secrets", cfg.LocalSecretsDir, "Wrong value for LocalSecretsDir")
+	assert.Equal(t, "/etc/ecs/secrets.key", cfg.LocalSecretsKeyFile, "Wrong value for LocalSecretsKeyFile")
+	assert.Equal(t, "https://vault.local:8200", cfg.VaultAddress, "Wrong value for VaultAddress")
+	assert.Equal(t, "/etc/ecs/vault-token", cfg.VaultTokenFile, "Wrong value for VaultTokenFile")
+	assert.Equal(t, 1, cfg.VaultKVVersion, "Wrong value for VaultKVVersion")
+	assert.Equal(t, []string{"secret", "team/kv"}, cfg.VaultKVMounts, "Wrong value for VaultKVMounts")
+}
+
+func TestVaultWithoutKVMounts(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_VAULT_ADDRESS", "https://vault.local:8200")()
+	defer setTestEnv("ECS_VAULT_TOKEN_FILE", "/etc/ecs/vault-token")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.VaultAddress, "VaultAddress without KV mounts should be ignored")
+}
+
+func TestSecretProvidersIncomplete(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_LOCAL_SECRETS_DIR", "/etc/ecs/secrets")()
+	defer setTestEnv("ECS_VAULT_ADDRESS", "https://vault.local:8200")()
+	defer setTestEnv("ECS_VAULT_KV_VERSION", "3")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.LocalSecretsDir, "LocalSecretsDir without a key file should be ignored")
+	assert.Empty(t, cfg.VaultAddress, "VaultAddress without a token file should be ignored")
+	assert.Equal(t, DefaultVaultKVVersion, cfg.VaultKVVersion, "Invalid VaultKVVersion should be reset to the default")
+}
+
+func TestEnvironmentFilesHostDir(t *testing.T) {
+	hostDir := t.TempDir()
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENV_FILES_HOST_DIR", hostDir)()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, hostDir, cfg.EnvironmentFilesHostDir, "Wrong value for EnvironmentFilesHostDir")
+}
+
+func TestEnvironmentFilesHostDirNotAbsolute(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENV_FILES_HOST_DIR", "envfiles")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.EnvironmentFilesHostDir, "A relative EnvironmentFilesHostDir should be ignored")
+}
+
+func TestPersistTaskCredentials(t *testing.T) {
+	defer setTestRegion()()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.False(t, cfg.PersistTaskCredentials.Enabled(), "Task credentials should not be persisted by default")
+
+	defer setTestEnv("ECS_PERSIST_TASK_CREDENTIALS", "true")()
+	cfg, err = NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.PersistTaskCredentials.Enabled(), "Wrong value for PersistTaskCredentials")
+}
+
+func TestInvalidImagePullConcurrencyPerRegistry(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_CONCURRENCY_PER_REGISTRY", "-1")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.ImagePullConcurrencyPerRegistry, "Wrong value for ImagePullConcurrencyPerRegistry")
+}
+
 func TestParseImagePullBehavior(t *testing.T) {
 	testcases := []struct {
 		name                      string
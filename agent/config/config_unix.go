@@ -36,6 +36,14 @@ const (
 	// DefaultTaskCgroupPrefix is default cgroup prefix for ECS tasks
 	DefaultTaskCgroupPrefix = "/ecs"
 
+	// DefaultTaskCgroupSlicePrefix is the prefix of the systemd slices of ECS tasks, used instead of
+	// cgroups under DefaultTaskCgroupPrefix when docker manages cgroups with systemd. The slice of a
+	// task is <prefix>-<task-id>.slice, in <prefix>.slice
+	DefaultTaskCgroupSlicePrefix = "ecstasks"
+
+	// CgroupDriverSystemd is the cgroup driver of docker that manages cgroups through systemd
+	CgroupDriverSystemd = "systemd"
+
 	// Default cgroup memory system root path, this is the default used if the
 	// path has not been configured through ECS_CGROUP_PATH
 	defaultCgroupPath = "/sys/fs/cgroup"
@@ -49,6 +57,8 @@ const (
 	minimumContainerCreateTimeout = 1 * time.Minute
 	// default docker inactivity time is extra time needed on container extraction
 	defaultImagePullInactivityTimeout = 1 * time.Minute
+	// defaultSecretFilesContainerPath is where secrets delivered as files are mounted in containers
+	defaultSecretFilesContainerPath = "/run/secrets"
 )
 
 // DefaultConfig returns the default configuration for Linux
@@ -72,10 +82,12 @@ func DefaultConfig() Config {
 		ImageCleanupDisabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		MinimumImageDeletionAge:             DefaultImageDeletionAge,
 		NonECSMinimumImageDeletionAge:       DefaultNonECSImageDeletionAge,
+		ImagePrewarmProtectionPeriod:        DefaultImagePrewarmProtectionPeriod,
 		ImageCleanupInterval:                DefaultImageCleanupTimeInterval,
 		ImagePullInactivityTimeout:          defaultImagePullInactivityTimeout,
 		ImagePullTimeout:                    DefaultImagePullTimeout,
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
+		ImageCleanupDiskCheckInterval:       DefaultImageCleanupDiskCheckInterval,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
 		CNIPluginsPath:                      defaultCNIPluginsPath,
 		PauseContainerTarballPath:           pauseContainerTarballPath,
@@ -92,13 +104,23 @@ func DefaultConfig() Config {
 		PrometheusMetricsEnabled:            false,
 		PollMetrics:                         BooleanDefaultFalse{Value: NotSet},
 		PollingMetricsWaitDuration:          DefaultPollingMetricsWaitDuration,
+		StatsCollector:                      StatsCollectorDocker,
+		StatsDFormat:                        StatsDFormatStatsD,
+		StatsSinkPublishInterval:            DefaultContainerMetricsPublishInterval,
+		StatsFileMaxSizeMB:                  DefaultStatsFileMaxSizeMB,
+		StatsFileMaxRollCount:               DefaultStatsFileMaxRollCount,
+		TaskStorageCollectionInterval:       DefaultTaskStorageCollectionInterval,
+		VaultKVVersion:                      DefaultVaultKVVersion,
+		SecretFilesContainerPath:            defaultSecretFilesContainerPath,
 		NvidiaRuntime:                       DefaultNvidiaRuntime,
 		CgroupCPUPeriod:                     defaultCgroupCPUPeriod,
 		GMSACapable:                         false,
 		FSxWindowsFileServerCapable:         false,
 		RuntimeStatsLogFile:                 defaultRuntimeStatsLogFile,
 		EnableRuntimeStats:                  BooleanDefaultFalse{Value: NotSet},
+		EnableIntrospectionTaskAPI:          BooleanDefaultFalse{Value: NotSet},
 		ShouldExcludeIPv6PortBinding:        BooleanDefaultTrue{Value: ExplicitlyEnabled},
+		PersistTaskCredentials:              BooleanDefaultFalse{Value: ExplicitlyDisabled},
 	}
 }
 
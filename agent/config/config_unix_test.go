@@ -72,6 +72,13 @@ func TestConfigDefault(t *testing.T) {
 	assert.False(t, cfg.DependentContainersPullUpfront.Enabled(), "Default DependentContainersPullUpfront set incorrectly")
 	assert.False(t, cfg.PollMetrics.Enabled(), "ECS_POLL_METRICS default should be false")
 	assert.False(t, cfg.EnableRuntimeStats.Enabled(), "Default EnableRuntimeStats set incorrectly")
+	assert.False(t, cfg.EnableIntrospectionTaskAPI.Enabled(), "Default EnableIntrospectionTaskAPI set incorrectly")
+	assert.Equal(t, "", cfg.ImagePrewarmManifest, "Default ImagePrewarmManifest set incorrectly")
+	assert.Equal(t, DefaultImagePrewarmProtectionPeriod, cfg.ImagePrewarmProtectionPeriod,
+		"Default ImagePrewarmProtectionPeriod set incorrectly")
+	assert.Equal(t, 0, cfg.ImageCleanupDiskHighWatermark, "Default ImageCleanupDiskHighWatermark set incorrectly")
+	assert.Equal(t, DefaultImageCleanupDiskCheckInterval, cfg.ImageCleanupDiskCheckInterval,
+		"Default ImageCleanupDiskCheckInterval set incorrectly")
 	assert.True(t, cfg.ShouldExcludeIPv6PortBinding.Enabled(), "Default ShouldExcludeIPv6PortBinding set incorrectly")
 }
 
@@ -36,6 +36,9 @@ const (
 	// defaultAuditLogFile specifies the default audit log filename
 	defaultCredentialsAuditLogFile = `log\audit.log`
 
+	// defaultSecretFilesContainerPath is empty as secrets can't be delivered as files on Windows
+	defaultSecretFilesContainerPath = ""
+
 	// defaultRuntimeStatsLogFile stores the path where the golang runtime stats are periodically logged
 	defaultRuntimeStatsLogFile = `log\agent-runtime-stats.log`
 
@@ -130,8 +133,10 @@ func DefaultConfig() Config {
 		ImageCleanupDisabled:                BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		MinimumImageDeletionAge:             DefaultImageDeletionAge,
 		NonECSMinimumImageDeletionAge:       DefaultNonECSImageDeletionAge,
+		ImagePrewarmProtectionPeriod:        DefaultImagePrewarmProtectionPeriod,
 		ImageCleanupInterval:                DefaultImageCleanupTimeInterval,
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
+		ImageCleanupDiskCheckInterval:       DefaultImageCleanupDiskCheckInterval,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
 		ContainerMetadataEnabled:            BooleanDefaultFalse{Value: ExplicitlyDisabled},
 		TaskCPUMemLimit:                     BooleanDefaultTrue{Value: ExplicitlyDisabled},
@@ -141,6 +146,13 @@ func DefaultConfig() Config {
 		SharedVolumeMatchFullConfig:         BooleanDefaultFalse{Value: ExplicitlyDisabled}, //only requiring shared volumes to match on name, which is default docker behavior
 		PollMetrics:                         BooleanDefaultFalse{Value: NotSet},
 		PollingMetricsWaitDuration:          DefaultPollingMetricsWaitDuration,
+		StatsCollector:                      StatsCollectorDocker,
+		StatsDFormat:                        StatsDFormatStatsD,
+		StatsSinkPublishInterval:            DefaultContainerMetricsPublishInterval,
+		StatsFileMaxSizeMB:                  DefaultStatsFileMaxSizeMB,
+		StatsFileMaxRollCount:               DefaultStatsFileMaxRollCount,
+		TaskStorageCollectionInterval:       DefaultTaskStorageCollectionInterval,
+		VaultKVVersion:                      DefaultVaultKVVersion,
 		GMSACapable:                         true,
 		FSxWindowsFileServerCapable:         true,
 		PauseContainerImageName:             DefaultPauseContainerImageName,
@@ -148,7 +160,9 @@ func DefaultConfig() Config {
 		CNIPluginsPath:                      filepath.Join(ecsBinaryDir, defaultCNIPluginDirName),
 		RuntimeStatsLogFile:                 filepath.Join(ecsRoot, defaultRuntimeStatsLogFile),
 		EnableRuntimeStats:                  BooleanDefaultFalse{Value: NotSet},
+		EnableIntrospectionTaskAPI:          BooleanDefaultFalse{Value: NotSet},
 		ShouldExcludeIPv6PortBinding:        BooleanDefaultTrue{Value: ExplicitlyEnabled},
+		PersistTaskCredentials:              BooleanDefaultFalse{Value: ExplicitlyDisabled},
 	}
 }
 
@@ -165,6 +179,13 @@ func (cfg *Config) platformOverrides() {
 	// ensure TaskResourceLimit is disabled
 	cfg.TaskCPUMemLimit.Value = ExplicitlyDisabled
 
+	// cgroups don't exist on Windows
+	if cfg.StatsCollector == StatsCollectorCgroupfs {
+		seelog.Warnf("ECS_STATS_COLLECTOR %s is not supported on Windows, container stats will be collected from %s.",
+			StatsCollectorCgroupfs, StatsCollectorDocker)
+		cfg.StatsCollector = StatsCollectorDocker
+	}
+
 	cpuUnbounded := parseBooleanDefaultFalseConfig("ECS_ENABLE_CPU_UNBOUNDED_WINDOWS_WORKAROUND")
 	memoryUnbounded := parseBooleanDefaultFalseConfig("ECS_ENABLE_MEMORY_UNBOUNDED_WINDOWS_WORKAROUND")
 
@@ -325,6 +325,19 @@ func parseEnvVariableUint16(envVar string) uint16 {
 	return var16
 }
 
+func parseEnvVariableInt(envVar string) int {
+	envVal := os.Getenv(envVar)
+	var val int
+	if envVal != "" {
+		var err error
+		val, err = strconv.Atoi(envVal)
+		if err != nil {
+			seelog.Warnf("Invalid format for \""+envVar+"\" environment variable; expected integer. err %v", err)
+		}
+	}
+	return val
+}
+
 func parseEnvVariableDuration(envVar string) time.Duration {
 	var duration time.Duration
 	envVal := os.Getenv(envVar)
@@ -353,6 +366,17 @@ func parseImageCleanupExclusionList(envVar string) []string {
 	return imageCleanupExclusionList
 }
 
+// parseVaultKVMounts parses the comma separated list of Vault KV mounts in ECS_VAULT_KV_MOUNTS
+func parseVaultKVMounts() []string {
+	var mounts []string
+	for _, mount := range strings.Split(os.Getenv("ECS_VAULT_KV_MOUNTS"), ",") {
+		if mount = strings.Trim(strings.TrimSpace(mount), "/"); mount != "" {
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
 func parseCgroupCPUPeriod() time.Duration {
 	duration := parseEnvVariableDuration("ECS_CGROUP_CPU_PERIOD")
 
@@ -97,6 +97,11 @@ type Config struct {
 	// again when PollMetrics is set to true
 	PollingMetricsWaitDuration time.Duration
 
+	// StatsCollector configures how container stats are collected, either from a Docker stats
+	// stream per container ("docker") or by reading the accounting of the container cgroups
+	// directly from cgroupfs ("cgroupfs"), which is cheaper on hosts with many containers.
+	StatsCollector string `trim:"true"`
+
 	// DisableDockerHealthCheck configures whether container health feature was enabled
 	// on the instance
 	DisableDockerHealthCheck BooleanDefaultFalse
@@ -190,6 +195,14 @@ type Config struct {
 	// NonECSMinimumImageDeletionAge specifies the minimum time since non ecs images created before it can be deleted
 	NonECSMinimumImageDeletionAge time.Duration
 
+	// ImagePrewarmManifest is the path to a manifest of images that the Agent pulls in the background
+	// once the instance is registered, so that the first tasks placed on the instance don't wait for them
+	ImagePrewarmManifest string `trim:"true"`
+
+	// ImagePrewarmProtectionPeriod specifies the time since they were pre-warmed during which the images
+	// of the image pre-warm manifest are not deleted by automated image cleanup
+	ImagePrewarmProtectionPeriod time.Duration
+
 	// ImageCleanupInterval specifies the time to wait before performing the image
 	// cleanup since last time it was executed
 	ImageCleanupInterval time.Duration
@@ -198,6 +211,24 @@ type Config struct {
 	// when Agent performs cleanup
 	NumImagesToDeletePerCycle int
 
+	// ImageCleanupDiskHighWatermark is the usage of the disk of the Docker data root, in percent, above
+	// which the Agent cleans up images without waiting for the next image cleanup cycle. Disk pressure
+	// driven image cleanup is disabled when it's 0
+	ImageCleanupDiskHighWatermark int
+
+	// ImageCleanupDiskLowWatermark is the usage of the disk of the Docker data root, in percent, that
+	// the Agent brings the disk back to when it cleans up images under disk pressure
+	ImageCleanupDiskLowWatermark int
+
+	// ImageCleanupDiskCheckInterval specifies the time to wait between checks of the usage of the disk
+	// of the Docker data root
+	ImageCleanupDiskCheckInterval time.Duration
+
+	// ImageCleanupDiskPath is the path, in the Agent container, where the Docker data root is mounted to
+	// check the usage of its disk. The data directory of the Agent is checked when it's not set, which
+	// is only accurate when it's on the same filesystem as the Docker data root
+	ImageCleanupDiskPath string `trim:"true"`
+
 	// NumNonECSContainersToDeletePerCycle specifies the num of NonECS containers to delete every time
 	// when Agent performs cleanup
 	NumNonECSContainersToDeletePerCycle int
@@ -206,6 +237,14 @@ type Config struct {
 	// local Docker image cache
 	ImagePullBehavior ImagePullBehaviorType
 
+	// ImagePolicyFile is the path to a policy that restricts the images containers can be created from
+	// to allowed digests or to digests signed by trusted keys
+	ImagePolicyFile string `trim:"true"`
+
+	// ImagePullConcurrencyPerRegistry specifies the maximum number of images pulled from the same
+	// registry at the same time. Pulls are not limited when it's 0
+	ImagePullConcurrencyPerRegistry int
+
 	// InstanceAttributes contains key/value pairs representing
 	// attributes to be associated with this instance within the
 	// ECS service and used to influence behavior such as launch
@@ -236,6 +275,88 @@ type Config struct {
 	// default.
 	PrometheusMetricsEnabled bool
 
+	// OTLPTracesEndpoint is the URL of the OTLP/HTTP traces endpoint of an OpenTelemetry
+	// collector, such as http://localhost:4318/v1/traces. Tracing is disabled when it's not set.
+	OTLPTracesEndpoint string `trim:"true"`
+
+	// StatsDAddress is the host:port of a StatsD server that container utilization metrics are
+	// sent to over UDP, in addition to the backend. Nothing is sent when it's not set.
+	StatsDAddress string `trim:"true"`
+
+	// StatsDFormat is the flavor of StatsD spoken by the server at StatsDAddress, either "statsd"
+	// or "dogstatsd". DogStatsD metrics carry the task and container as tags, plain StatsD ones
+	// have them in the metric name.
+	StatsDFormat string `trim:"true"`
+
+	// StatsFilePath is the path of a file that container utilization metrics are appended to as
+	// newline-delimited JSON. Nothing is written when it's not set.
+	StatsFilePath string `trim:"true"`
+
+	// StatsFileMaxSizeMB is the size, in MB, that the stats file grows to before it's rotated.
+	StatsFileMaxSizeMB int
+
+	// StatsFileMaxRollCount is the number of rotated stats files that are kept. Older ones are
+	// deleted.
+	StatsFileMaxRollCount int
+
+	// StatsSinkPublishInterval is the interval at which container utilization metrics are
+	// published to the StatsD server and the stats file.
+	StatsSinkPublishInterval time.Duration
+
+	// TaskStorageCollectionInterval is the interval at which the ephemeral storage used by tasks,
+	// the writable layers of their containers and their task scoped volumes, is measured.
+	TaskStorageCollectionInterval time.Duration
+
+	// TaskEphemeralStorageLimit is the ephemeral storage, in MiB, that a task can use before it's
+	// stopped. Tasks aren't limited when it's 0.
+	TaskEphemeralStorageLimit int
+
+	// SecretFilesContainerPath is the path in containers where secrets of type MOUNT_POINT are
+	// mounted, one file per secret. Secrets can't be delivered as files when it's empty.
+	SecretFilesContainerPath string
+
+	// SecretRefreshInterval is the interval at which the secrets of running tasks are fetched
+	// again, so that rotated values reach secrets delivered as files. Secrets aren't refreshed
+	// when it's 0.
+	SecretRefreshInterval time.Duration
+
+	// SecretRefreshSignal is the signal, such as SIGHUP, sent to a container after the files of
+	// its secrets were updated with rotated values. No signal is sent when it's empty.
+	SecretRefreshSignal string
+
+	// LocalSecretsDir is the directory on the host that secrets of the "local" provider are read
+	// from. Each secret is a file encrypted with the key in LocalSecretsKeyFile.
+	LocalSecretsDir string
+
+	// LocalSecretsKeyFile is the file holding the base64 encoded AES-256 key that secrets in
+	// LocalSecretsDir are encrypted with.
+	LocalSecretsKeyFile string
+
+	// VaultAddress is the address of the Vault compatible server that secrets of the "vault"
+	// provider are read from. Vault secrets aren't available when it's empty.
+	VaultAddress string
+
+	// VaultTokenFile is the file holding the token used to authenticate with VaultAddress. It's
+	// read on every request, so that the token can be renewed on the host.
+	VaultTokenFile string
+
+	// VaultKVVersion is the version, 1 or 2, of the KV secrets engine that Vault secrets are
+	// read from.
+	VaultKVVersion int
+
+	// VaultKVMounts are the mounts of the KV secrets engines that Vault secrets can be read from.
+	// The token in VaultTokenFile is never sent for paths outside of them.
+	VaultKVMounts []string
+
+	// EnvironmentFilesHostDir is the directory on the host that environment files of type "host"
+	// are allowed to be read from. Host environment files aren't available when it's empty.
+	EnvironmentFilesHostDir string
+
+	// PersistTaskCredentials specifies whether the credentials of tasks are persisted in the data
+	// store, encrypted with a key bound to the host, so that they're available to tasks as soon as
+	// the agent restarts. They're only persisted when Checkpoint is enabled. It defaults to false.
+	PersistTaskCredentials BooleanDefaultFalse
+
 	// AWSVPCBlockInstanceMetdata specifies if InstanceMetadata endpoint should be blocked
 	// for tasks that are launched with network mode "awsvpc" when ECS_AWSVPC_BLOCK_IMDS=true
 	AWSVPCBlockInstanceMetdata BooleanDefaultFalse
@@ -264,6 +385,10 @@ type Config struct {
 	// '/sys/fs/cgroup'
 	CgroupPath string
 
+	// CgroupDriver is the cgroup driver of docker, either cgroupfs or systemd. It's detected
+	// when the agent starts, and task cgroups are systemd slices when it's systemd.
+	CgroupDriver string
+
 	// PlatformVariables consists of configuration variables specific to linux/windows
 	PlatformVariables PlatformVariables
 
@@ -350,6 +475,12 @@ type Config struct {
 	// is set to false and can be overridden by means of the ECS_ENABLE_RUNTIME_STATS environment variable.
 	EnableRuntimeStats BooleanDefaultFalse
 
+	// EnableIntrospectionTaskAPI specifies if the endpoints that add and stop tasks should be served. These endpoints
+	// are served on their own port of the loopback interface, not on the introspection port, and allow the task engine
+	// to be driven without ECS. By default, this configuration is set to false and can be overridden by means of the
+	// ECS_ENABLE_INTROSPECTION_TASK_API environment variable.
+	EnableIntrospectionTaskAPI BooleanDefaultFalse
+
 	// ShouldExcludeIPv6PortBinding specifies whether agent should exclude IPv6 port bindings reported from docker. This configuration
 	// is set to true by default, and can be overridden by the ECS_EXCLUDE_IPV6_PORTBINDING environment variable. This is a workaround
 	// for docker's bug as detailed in https://github.com/aws/amazon-ecs-agent/issues/2870.
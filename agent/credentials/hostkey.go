@@ -0,0 +1,87 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// hostKeyFileName is the file in the data directory holding the random secret that the key of
+	// persisted credentials is derived from
+	hostKeyFileName = "credentials.key"
+	hostKeySize     = 32
+	hostKeyFileMode = 0600
+)
+
+// machineID is the function used to read the machine id of the host, it's overridden in tests
+var machineID = getMachineID
+
+// LoadHostKey returns the 32 byte key that persisted credentials are encrypted with. It's derived
+// from a random secret that is generated in dataDir the first time, and from the machine id of the
+// host, so that the credentials can't be decrypted from a copy of the data directory. As the
+// secret alone doesn't bind the key to the host, no key is returned when the machine id can't be
+// read, e.g. when the agent runs in a container that doesn't have it.
+func LoadHostKey(dataDir string) ([]byte, error) {
+	id := machineID()
+	if id == "" {
+		return nil, ErrNoMachineID
+	}
+	path := filepath.Join(dataDir, hostKeyFileName)
+	secret, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		secret, err = createHostKeyFile(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load credentials key from %s", path)
+	}
+	if len(secret) != hostKeySize {
+		return nil, errors.Errorf("invalid credentials key in %s, expected %d bytes", path, hostKeySize)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return mac.Sum(nil), nil
+}
+
+func createHostKeyFile(path string) ([]byte, error) {
+	secret := make([]byte, hostKeySize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+	// Never overwrite an existing key, that would make the credentials encrypted with it unreadable
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, hostKeyFileMode)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Write(secret); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,86 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadHostKey tests if the host key is generated the first time it's loaded,
+// and is the same afterwards
+func TestLoadHostKey(t *testing.T) {
+	defer func() { machineID = getMachineID }()
+	machineID = func() string { return "host-1" }
+	dataDir := t.TempDir()
+	key, err := LoadHostKey(dataDir)
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	info, err := os.Stat(filepath.Join(dataDir, hostKeyFileName))
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(hostKeyFileMode), info.Mode().Perm())
+	}
+
+	reloaded, err := LoadHostKey(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, key, reloaded)
+
+	other, err := LoadHostKey(t.TempDir())
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "Expected a new key to be generated in another data directory")
+}
+
+// TestLoadHostKeyBoundToMachineID tests if the host key depends on the machine
+// id, and isn't created when the machine id can't be read
+func TestLoadHostKeyBoundToMachineID(t *testing.T) {
+	defer func() { machineID = getMachineID }()
+	dataDir := t.TempDir()
+
+	machineID = func() string { return "host-1" }
+	key, err := LoadHostKey(dataDir)
+	require.NoError(t, err)
+
+	machineID = func() string { return "host-2" }
+	other, err := LoadHostKey(dataDir)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "Expected the key to be bound to the machine id")
+
+	machineID = func() string { return "" }
+	emptyDataDir := t.TempDir()
+	_, err = LoadHostKey(emptyDataDir)
+	assert.Equal(t, ErrNoMachineID, err)
+	_, err = os.Stat(filepath.Join(emptyDataDir, hostKeyFileName))
+	assert.True(t, os.IsNotExist(err), "Expected no key to be created without a machine id")
+}
+
+// TestLoadHostKeyInvalidFile tests if loading the host key fails when its file
+// doesn't hold a key
+func TestLoadHostKeyInvalidFile(t *testing.T) {
+	defer func() { machineID = getMachineID }()
+	machineID = func() string { return "host-1" }
+	dataDir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, hostKeyFileName), []byte("short"), hostKeyFileMode))
+	_, err := LoadHostKey(dataDir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// machineIDFiles are the files that hold the machine id of the host, in order of preference
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// ErrNoMachineID is returned by LoadHostKey when the machine id of the host can't be read
+var ErrNoMachineID = errors.New("unable to read the machine id of the host, /etc/machine-id is not mounted into the agent container")
+
+// getMachineID returns the machine id of the host, or an empty string when it can't be read, e.g.
+// when the agent runs in a container that doesn't have it
+func getMachineID() string {
+	for _, file := range machineIDFiles {
+		if id, err := ioutil.ReadFile(file); err == nil {
+			return strings.TrimSpace(string(id))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/registry"
+)
+
+const machineGUIDRegistryPath = `SOFTWARE\Microsoft\Cryptography`
+
+// ErrNoMachineID is returned by LoadHostKey when the machine id of the host can't be read
+var ErrNoMachineID = errors.New(`unable to read the machine id of the host from HKLM\SOFTWARE\Microsoft\Cryptography\MachineGuid`)
+
+// getMachineID returns the machine GUID of the host, or an empty string when it can't be read
+func getMachineID() string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, machineGUIDRegistryPath, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+
+	id, _, err := key.GetStringValue("MachineGuid")
+	if err != nil {
+		return ""
+	}
+	return id
+}
@@ -14,8 +14,10 @@
 package credentials
 
 import (
+	"crypto/cipher"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/aws-sdk-go/aws"
@@ -92,6 +94,10 @@ type credentialsManager struct {
 	// idToTaskCredentials maps credentials id to its corresponding TaskIAMRoleCredentials object
 	idToTaskCredentials map[string]TaskIAMRoleCredentials
 	taskCredentialsLock sync.RWMutex
+	// store persists the credentials encrypted with aead, when it's set
+	store       Store
+	aead        cipher.AEAD
+	persistLock sync.Mutex
 }
 
 // IAMRoleCredentialsFromACS translates ecsacs.IAMRoleCredentials object to
@@ -117,9 +123,6 @@ func NewManager() Manager {
 
 // SetTaskCredentials adds or updates credentials in the credentials manager
 func (manager *credentialsManager) SetTaskCredentials(taskCredentials *TaskIAMRoleCredentials) error {
-	manager.taskCredentialsLock.Lock()
-	defer manager.taskCredentialsLock.Unlock()
-
 	credentials := taskCredentials.IAMRoleCredentials
 	// Validate that credentials id is not empty
 	if credentials.CredentialsID == "" {
@@ -131,34 +134,63 @@ func (manager *credentialsManager) SetTaskCredentials(taskCredentials *TaskIAMRo
 		return fmt.Errorf("task ARN is empty")
 	}
 
+	manager.taskCredentialsLock.Lock()
 	manager.idToTaskCredentials[credentials.CredentialsID] = TaskIAMRoleCredentials{
 		ARN:                taskCredentials.ARN,
 		IAMRoleCredentials: taskCredentials.GetIAMRoleCredentials(),
 	}
+	manager.taskCredentialsLock.Unlock()
 
+	if manager.store != nil {
+		manager.persist(credentials.CredentialsID)
+	}
 	return nil
 }
 
-// GetTaskCredentials retrieves credentials for a given credentials id
+// GetTaskCredentials retrieves credentials for a given credentials id. Persisted credentials that
+// expired are removed, rather than returned, since they may have been loaded from the store long
+// after ECS stopped refreshing them.
 func (manager *credentialsManager) GetTaskCredentials(id string) (TaskIAMRoleCredentials, bool) {
+	taskARN, credentials, ok := manager.getTaskCredentials(id)
+	if !ok {
+		return TaskIAMRoleCredentials{}, false
+	}
+	if manager.store != nil && credentialsExpired(credentials, time.Now()) {
+		manager.removeExpiredCredentials(id)
+		return TaskIAMRoleCredentials{}, false
+	}
+	return TaskIAMRoleCredentials{
+		ARN:                taskARN,
+		IAMRoleCredentials: credentials,
+	}, true
+}
+
+// getTaskCredentials returns the task ARN and the credentials held for id.
+func (manager *credentialsManager) getTaskCredentials(id string) (string, IAMRoleCredentials, bool) {
 	manager.taskCredentialsLock.RLock()
 	defer manager.taskCredentialsLock.RUnlock()
 
+	return manager.heldCredentials(id)
+}
+
+// heldCredentials returns the task ARN and the credentials held for id, the caller must hold
+// taskCredentialsLock.
+func (manager *credentialsManager) heldCredentials(id string) (string, IAMRoleCredentials, bool) {
 	taskCredentials, ok := manager.idToTaskCredentials[id]
 
 	if !ok {
-		return TaskIAMRoleCredentials{}, ok
+		return "", IAMRoleCredentials{}, ok
 	}
-	return TaskIAMRoleCredentials{
-		ARN:                taskCredentials.ARN,
-		IAMRoleCredentials: taskCredentials.GetIAMRoleCredentials(),
-	}, ok
+	return taskCredentials.ARN, taskCredentials.GetIAMRoleCredentials(), ok
 }
 
 // RemoveCredentials removes credentials from the credentials manager
 func (manager *credentialsManager) RemoveCredentials(id string) {
 	manager.taskCredentialsLock.Lock()
-	defer manager.taskCredentialsLock.Unlock()
-
 	delete(manager.idToTaskCredentials, id)
+	manager.taskCredentialsLock.Unlock()
+
+	if manager.store != nil {
+		manager.deletePersisted(id)
+	}
 }
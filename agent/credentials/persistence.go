@@ -0,0 +1,195 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// Store persists the credentials of the credentials manager, so that they're available to tasks
+// as soon as the agent restarts. The data is encrypted by the credentials manager before it's saved.
+type Store interface {
+	// SaveCredentials saves the encrypted data of credentials, keyed by credentials id
+	SaveCredentials(string, []byte) error
+	// DeleteCredentials deletes the data of credentials
+	DeleteCredentials(string) error
+	// GetCredentials gets the encrypted data of all the credentials, keyed by credentials id
+	GetCredentials() (map[string][]byte, error)
+}
+
+// persistedCredentials is the JSON encrypted in the store for every credentials. The credentials
+// id is the key of the record, it's used as additional data so that records can't be swapped.
+type persistedCredentials struct {
+	TaskARN     string             `json:"taskARN"`
+	RoleType    string             `json:"roleType"`
+	Credentials IAMRoleCredentials `json:"credentials"`
+}
+
+// NewPersistentManager creates a new credentials manager object that saves credentials in store,
+// encrypted with key, a 32 byte AES-256 key. The credentials saved by a previous agent are loaded,
+// except the ones that expired or can't be decrypted with key, which are deleted.
+func NewPersistentManager(store Store, key []byte) (Manager, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid credentials encryption key")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid credentials encryption key")
+	}
+
+	manager := &credentialsManager{
+		idToTaskCredentials: make(map[string]TaskIAMRoleCredentials),
+		store:               store,
+		aead:                aead,
+	}
+	if err := manager.load(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// load adds the credentials of the store that haven't expired to the credentials manager
+func (manager *credentialsManager) load() error {
+	records, err := manager.store.GetCredentials()
+	if err != nil {
+		return errors.Wrap(err, "unable to get persisted credentials")
+	}
+
+	now := time.Now()
+	for id, data := range records {
+		taskARN, credentials, err := manager.open(id, data)
+		if err != nil {
+			seelog.Warnf("Discarding persisted credentials %s: %v", id, err)
+			manager.deletePersisted(id)
+			continue
+		}
+		if credentialsExpired(credentials, now) {
+			seelog.Infof("Discarding expired persisted credentials %s of task %s", id, taskARN)
+			manager.deletePersisted(id)
+			continue
+		}
+		manager.idToTaskCredentials[id] = TaskIAMRoleCredentials{
+			ARN:                taskARN,
+			IAMRoleCredentials: credentials,
+		}
+	}
+	seelog.Infof("Loaded %d persisted task credentials", len(manager.idToTaskCredentials))
+	return nil
+}
+
+// credentialsExpired returns whether credentials expired at now. Credentials whose expiration can't
+// be parsed are considered expired.
+func credentialsExpired(credentials IAMRoleCredentials, now time.Time) bool {
+	expiration, err := time.Parse(time.RFC3339, credentials.Expiration)
+	return err != nil || !expiration.After(now)
+}
+
+// removeExpiredCredentials removes the credentials of id from the credentials manager and the
+// store, unless they were refreshed since they were found to be expired.
+func (manager *credentialsManager) removeExpiredCredentials(id string) {
+	manager.taskCredentialsLock.Lock()
+	taskARN, credentials, ok := manager.heldCredentials(id)
+	expired := ok && credentialsExpired(credentials, time.Now())
+	if expired {
+		delete(manager.idToTaskCredentials, id)
+	}
+	manager.taskCredentialsLock.Unlock()
+
+	if expired {
+		seelog.Infof("Removing expired credentials %s of task %s", id, taskARN)
+		manager.deletePersisted(id)
+	}
+}
+
+// persist saves the credentials currently held for id in the store. Failing to do so only means that
+// the credentials won't be available right after the agent restarts, so errors are logged rather
+// than returned. The store is written outside of taskCredentialsLock, so that a slow disk doesn't
+// hold up the credentials endpoint; persistLock keeps concurrent writes for the same id in order.
+func (manager *credentialsManager) persist(id string) {
+	manager.persistLock.Lock()
+	defer manager.persistLock.Unlock()
+
+	taskARN, credentials, ok := manager.getTaskCredentials(id)
+	if !ok {
+		// The credentials were removed before they could be persisted
+		return
+	}
+	data, err := manager.seal(taskARN, credentials)
+	if err == nil {
+		err = manager.store.SaveCredentials(id, data)
+	}
+	if err != nil {
+		seelog.Warnf("Unable to persist credentials %s of task %s: %v", id, taskARN, err)
+	}
+}
+
+// deletePersisted deletes the credentials of id from the store, unless they were set again since.
+func (manager *credentialsManager) deletePersisted(id string) {
+	manager.persistLock.Lock()
+	defer manager.persistLock.Unlock()
+
+	if _, _, ok := manager.getTaskCredentials(id); ok {
+		return
+	}
+	if err := manager.store.DeleteCredentials(id); err != nil {
+		seelog.Warnf("Unable to delete persisted credentials %s: %v", id, err)
+	}
+}
+
+// seal returns the encrypted JSON of credentials, prefixed with the nonce it was encrypted with
+func (manager *credentialsManager) seal(taskARN string, credentials IAMRoleCredentials) ([]byte, error) {
+	plaintext, err := json.Marshal(persistedCredentials{
+		TaskARN:     taskARN,
+		RoleType:    credentials.RoleType,
+		Credentials: credentials,
+	})
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, manager.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "unable to generate nonce")
+	}
+	return manager.aead.Seal(nonce, nonce, plaintext, []byte(credentials.CredentialsID)), nil
+}
+
+// open decrypts the credentials sealed with seal, and returns them along with the arn of their task
+func (manager *credentialsManager) open(id string, data []byte) (string, IAMRoleCredentials, error) {
+	nonceSize := manager.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", IAMRoleCredentials{}, errors.New("data is too short")
+	}
+	plaintext, err := manager.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(id))
+	if err != nil {
+		return "", IAMRoleCredentials{}, errors.Wrap(err, "unable to decrypt data, it was encrypted with another key or corrupted")
+	}
+
+	var persisted persistedCredentials
+	if err := json.Unmarshal(plaintext, &persisted); err != nil {
+		return "", IAMRoleCredentials{}, errors.Wrap(err, "unable to unmarshal credentials")
+	}
+	credentials := persisted.Credentials
+	credentials.CredentialsID = id
+	credentials.RoleType = persisted.RoleType
+	return persisted.TaskARN, credentials, nil
+}
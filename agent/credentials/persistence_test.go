@@ -0,0 +1,263 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	persistedTaskARN       = "arn:aws:ecs:us-west-2:123456789012:task/cluster/id"
+	persistedCredentialsID = "credentials-id"
+)
+
+// memoryStore is a Store keeping credentials in memory
+type memoryStore map[string][]byte
+
+func (store memoryStore) SaveCredentials(id string, data []byte) error {
+	store[id] = data
+	return nil
+}
+
+func (store memoryStore) DeleteCredentials(id string) error {
+	delete(store, id)
+	return nil
+}
+
+func (store memoryStore) GetCredentials() (map[string][]byte, error) {
+	return store, nil
+}
+
+// failingStore is a Store whose operations always fail
+type failingStore struct{}
+
+func (failingStore) SaveCredentials(string, []byte) error {
+	return errors.New("save failed")
+}
+
+func (failingStore) DeleteCredentials(string) error {
+	return errors.New("delete failed")
+}
+
+func (failingStore) GetCredentials() (map[string][]byte, error) {
+	return nil, errors.New("get failed")
+}
+
+// blockingStore is a memoryStore whose saves wait for release to be closed
+type blockingStore struct {
+	memoryStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (store blockingStore) SaveCredentials(id string, data []byte) error {
+	close(store.saving)
+	<-store.release
+	return store.memoryStore.SaveCredentials(id, data)
+}
+
+func newTestKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func newPersistedCredentials(expiration time.Time) *TaskIAMRoleCredentials {
+	return &TaskIAMRoleCredentials{
+		ARN: persistedTaskARN,
+		IAMRoleCredentials: IAMRoleCredentials{
+			CredentialsID:   persistedCredentialsID,
+			RoleArn:         "roleArn",
+			AccessKeyID:     "keyId",
+			SecretAccessKey: "OhhSecret",
+			SessionToken:    "sessionToken",
+			Expiration:      expiration.UTC().Format(time.RFC3339),
+			RoleType:        ApplicationRoleType,
+		},
+	}
+}
+
+// TestPersistentManagerReloadsCredentials tests if credentials set in a persistent
+// credentials manager are available in a new one created with the same store and key
+func TestPersistentManagerReloadsCredentials(t *testing.T) {
+	store := make(memoryStore)
+	key := newTestKey(t)
+	manager, err := NewPersistentManager(store, key)
+	require.NoError(t, err)
+
+	taskCredentials := newPersistedCredentials(time.Now().Add(time.Hour))
+	require.NoError(t, manager.SetTaskCredentials(taskCredentials))
+	require.Len(t, store, 1)
+	assert.False(t, bytes.Contains(store[persistedCredentialsID], []byte("OhhSecret")),
+		"Expected persisted credentials to be encrypted")
+
+	reloaded, err := NewPersistentManager(store, key)
+	require.NoError(t, err)
+	reloadedCredentials, ok := reloaded.GetTaskCredentials(persistedCredentialsID)
+	require.True(t, ok, "Expected persisted credentials to be loaded")
+	assert.Equal(t, persistedTaskARN, reloadedCredentials.ARN)
+	assert.Equal(t, taskCredentials.GetIAMRoleCredentials(), reloadedCredentials.GetIAMRoleCredentials())
+}
+
+// TestPersistentManagerRemoveCredentials tests if removing credentials from a persistent
+// credentials manager deletes them from the store
+func TestPersistentManagerRemoveCredentials(t *testing.T) {
+	store := make(memoryStore)
+	manager, err := NewPersistentManager(store, newTestKey(t))
+	require.NoError(t, err)
+
+	require.NoError(t, manager.SetTaskCredentials(newPersistedCredentials(time.Now().Add(time.Hour))))
+	manager.RemoveCredentials(persistedCredentialsID)
+	assert.Len(t, store, 0)
+}
+
+// TestPersistentManagerDiscardsExpiredCredentials tests if persisted credentials that
+// expired, or whose expiration can't be parsed, are not loaded and are deleted
+func TestPersistentManagerDiscardsExpiredCredentials(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expiration string
+	}{
+		{
+			name:       "expired",
+			expiration: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+		},
+		{
+			name:       "invalid expiration",
+			expiration: "soon",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := make(memoryStore)
+			key := newTestKey(t)
+			manager, err := NewPersistentManager(store, key)
+			require.NoError(t, err)
+
+			taskCredentials := newPersistedCredentials(time.Now())
+			taskCredentials.IAMRoleCredentials.Expiration = tc.expiration
+			require.NoError(t, manager.SetTaskCredentials(taskCredentials))
+
+			reloaded, err := NewPersistentManager(store, key)
+			require.NoError(t, err)
+			_, ok := reloaded.GetTaskCredentials(persistedCredentialsID)
+			assert.False(t, ok)
+			assert.Len(t, store, 0)
+		})
+	}
+}
+
+// TestPersistentManagerRemovesCredentialsOnExpiration tests if persisted credentials
+// that expired are removed, along with their record, when they're retrieved
+func TestPersistentManagerRemovesCredentialsOnExpiration(t *testing.T) {
+	store := make(memoryStore)
+	manager, err := NewPersistentManager(store, newTestKey(t))
+	require.NoError(t, err)
+
+	require.NoError(t, manager.SetTaskCredentials(newPersistedCredentials(time.Now().Add(-time.Minute))))
+	require.Len(t, store, 1)
+
+	_, ok := manager.GetTaskCredentials(persistedCredentialsID)
+	assert.False(t, ok, "Expected expired credentials not to be returned")
+	assert.NotContains(t, manager.(*credentialsManager).idToTaskCredentials, persistedCredentialsID)
+	assert.Len(t, store, 0)
+}
+
+// TestPersistentManagerDiscardsUndecryptableCredentials tests if persisted credentials
+// that were encrypted with another key, or tampered with, are not loaded and are deleted
+func TestPersistentManagerDiscardsUndecryptableCredentials(t *testing.T) {
+	store := make(memoryStore)
+	key := newTestKey(t)
+	manager, err := NewPersistentManager(store, key)
+	require.NoError(t, err)
+	require.NoError(t, manager.SetTaskCredentials(newPersistedCredentials(time.Now().Add(time.Hour))))
+	// Records can't be moved to another credentials id
+	store["other-credentials-id"] = store[persistedCredentialsID]
+	store["short"] = []byte("short")
+
+	reloaded, err := NewPersistentManager(store, key)
+	require.NoError(t, err)
+	_, ok := reloaded.GetTaskCredentials("other-credentials-id")
+	assert.False(t, ok)
+	_, ok = reloaded.GetTaskCredentials(persistedCredentialsID)
+	assert.True(t, ok)
+	assert.Len(t, store, 1)
+
+	reloaded, err = NewPersistentManager(store, newTestKey(t))
+	require.NoError(t, err)
+	_, ok = reloaded.GetTaskCredentials(persistedCredentialsID)
+	assert.False(t, ok)
+	assert.Len(t, store, 0)
+}
+
+// TestPersistentManagerSlowStore tests if credentials can be retrieved while they're
+// being persisted
+func TestPersistentManagerSlowStore(t *testing.T) {
+	manager, err := NewPersistentManager(make(memoryStore), newTestKey(t))
+	require.NoError(t, err)
+	store := blockingStore{
+		memoryStore: make(memoryStore),
+		saving:      make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	manager.(*credentialsManager).store = store
+
+	done := make(chan error)
+	go func() {
+		done <- manager.SetTaskCredentials(newPersistedCredentials(time.Now().Add(time.Hour)))
+	}()
+	<-store.saving
+	_, ok := manager.GetTaskCredentials(persistedCredentialsID)
+	assert.True(t, ok, "Expected credentials to be available while they're persisted")
+
+	close(store.release)
+	require.NoError(t, <-done)
+	assert.Len(t, store.memoryStore, 1)
+}
+
+// TestPersistentManagerStoreErrors tests if a persistent credentials manager keeps
+// credentials in memory when they can't be persisted, and fails to be created when
+// persisted credentials can't be loaded
+func TestPersistentManagerStoreErrors(t *testing.T) {
+	_, err := NewPersistentManager(failingStore{}, newTestKey(t))
+	assert.Error(t, err)
+
+	manager, err := NewPersistentManager(make(memoryStore), newTestKey(t))
+	require.NoError(t, err)
+	manager.(*credentialsManager).store = failingStore{}
+	require.NoError(t, manager.SetTaskCredentials(newPersistedCredentials(time.Now().Add(time.Hour))))
+	_, ok := manager.GetTaskCredentials(persistedCredentialsID)
+	assert.True(t, ok)
+	manager.RemoveCredentials(persistedCredentialsID)
+	_, ok = manager.GetTaskCredentials(persistedCredentialsID)
+	assert.False(t, ok)
+}
+
+// TestNewPersistentManagerInvalidKey tests if creating a persistent credentials manager
+// with a key that isn't a valid AES key fails
+func TestNewPersistentManagerInvalidKey(t *testing.T) {
+	_, err := NewPersistentManager(make(memoryStore), []byte("short"))
+	assert.Error(t, err)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/api/task"
 	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 
+	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -34,6 +35,7 @@ const (
 	imagesBucketName         = "images"
 	eniAttachmentsBucketName = "eniattachments"
 	metadataBucketName       = "metadata"
+	credentialsBucketName    = "credentials"
 )
 
 var (
@@ -46,6 +48,15 @@ var (
 		tasksBucketName,
 		eniAttachmentsBucketName,
 		metadataBucketName,
+		credentialsBucketName,
+	}
+
+	// recordTypeBuckets maps the record types to the buckets they are saved in.
+	recordTypeBuckets = map[string]string{
+		RecordTypeTask:          tasksBucketName,
+		RecordTypeContainer:     containersBucketName,
+		RecordTypeImageState:    imagesBucketName,
+		RecordTypeENIAttachment: eniAttachmentsBucketName,
 	}
 )
 
@@ -89,6 +100,13 @@ type Client interface {
 	// GetMetadata gets the value of a certain kind of metadata.
 	GetMetadata(string) (string, error)
 
+	// SaveCredentials saves the encrypted data of task credentials, keyed by credentials id.
+	SaveCredentials(string, []byte) error
+	// DeleteCredentials deletes the data of task credentials.
+	DeleteCredentials(string) error
+	// GetCredentials gets the encrypted data of all the task credentials, keyed by credentials id.
+	GetCredentials() (map[string][]byte, error)
+
 	// Close closes the connection to database.
 	Close() error
 }
@@ -141,3 +159,36 @@ func setup(dataDir string) (*client, error) {
 func (c *client) Close() error {
 	return c.db.Close()
 }
+
+// migrateRecords replaces the records of the record type with the ones returned by migrate, in a
+// single transaction.
+func (c *client) migrateRecords(recordType string, migrate recordMigrationFunc) error {
+	bucketName, ok := recordTypeBuckets[recordType]
+	if !ok {
+		return errors.Errorf("unknown record type %s", recordType)
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		updated := make(map[string][]byte)
+		err := walk(bucket, func(id string, data []byte) error {
+			migrated, err := migrate(id, data)
+			if err != nil {
+				return errors.Wrapf(err, "failed to migrate %s %s", recordType, id)
+			}
+			if migrated != nil {
+				updated[id] = migrated
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Keys are not put while the bucket is walked, as that would invalidate the cursor
+		for id, data := range updated {
+			if err := bucket.Put([]byte(id), data); err != nil {
+				return errors.Wrapf(err, "failed to update %s %s", recordType, id)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+func (c *client) SaveCredentials(id string, data []byte) error {
+	return c.db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(credentialsBucketName))
+		return b.Put([]byte(id), data)
+	})
+}
+
+func (c *client) DeleteCredentials(id string) error {
+	return c.db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(credentialsBucketName))
+		return b.Delete([]byte(id))
+	})
+}
+
+func (c *client) GetCredentials() (map[string][]byte, error) {
+	credentials := make(map[string][]byte)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(credentialsBucketName))
+		if bucket == nil {
+			// databases of older agents opened read-only
+			return nil
+		}
+		return walk(bucket, func(id string, data []byte) error {
+			// data is only valid for the life of the transaction
+			credentials[id] = append([]byte(nil), data...)
+			return nil
+		})
+	})
+	return credentials, err
+}
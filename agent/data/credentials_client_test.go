@@ -0,0 +1,49 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCredentialsID  = "credentials-id"
+	testCredentialsID2 = "credentials-id2"
+)
+
+func TestManageCredentials(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	require.NoError(t, testClient.SaveCredentials(testCredentialsID, []byte("encrypted")))
+	require.NoError(t, testClient.SaveCredentials(testCredentialsID, []byte("encrypted-refreshed")))
+	require.NoError(t, testClient.SaveCredentials(testCredentialsID2, []byte("encrypted2")))
+	res, err := testClient.GetCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		testCredentialsID:  []byte("encrypted-refreshed"),
+		testCredentialsID2: []byte("encrypted2"),
+	}, res)
+
+	require.NoError(t, testClient.DeleteCredentials(testCredentialsID))
+	require.NoError(t, testClient.DeleteCredentials(testCredentialsID2))
+	res, err = testClient.GetCredentials()
+	require.NoError(t, err)
+	assert.Len(t, res, 0)
+}
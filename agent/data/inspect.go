@@ -0,0 +1,224 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"fmt"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RecordTypeTask identifies records of the tasks bucket.
+	RecordTypeTask = "task"
+	// RecordTypeContainer identifies records of the containers bucket.
+	RecordTypeContainer = "container"
+	// RecordTypeImageState identifies records of the images bucket.
+	RecordTypeImageState = "image"
+	// RecordTypeENIAttachment identifies records of the eni attachments bucket.
+	RecordTypeENIAttachment = "eniattachment"
+)
+
+// Inventory holds everything persisted in a data store, as it's loaded by the agent on startup.
+type Inventory struct {
+	Tasks          []*apitask.Task                 `json:"tasks"`
+	Containers     []*apicontainer.DockerContainer `json:"containers"`
+	ImageStates    []*image.ImageState             `json:"imageStates"`
+	ENIAttachments []*apieni.ENIAttachment         `json:"eniAttachments"`
+	Metadata       map[string]string               `json:"metadata"`
+}
+
+// Problem describes a record that is inconsistent with the rest of the data store.
+type Problem struct {
+	// RecordType is the type of the record, one of the RecordType constants.
+	RecordType string
+	// ID is the key of the record in the data store.
+	ID string
+	// Description explains what is wrong with the record.
+	Description string
+	// Orphaned is set when the record refers to data that no longer exists. Orphaned records
+	// can't be used by the agent and can be pruned.
+	Orphaned bool
+}
+
+func (problem Problem) String() string {
+	kind := "warning"
+	if problem.Orphaned {
+		kind = "orphaned"
+	}
+	return fmt.Sprintf("%s %s %s: %s", kind, problem.RecordType, problem.ID, problem.Description)
+}
+
+// LoadInventory reads everything persisted in the data store of the client.
+func LoadInventory(client Client) (*Inventory, error) {
+	var err error
+	inventory := &Inventory{
+		Metadata: make(map[string]string),
+	}
+	if inventory.Tasks, err = client.GetTasks(); err != nil {
+		return nil, errors.Wrap(err, "failed to get tasks")
+	}
+	if inventory.Containers, err = client.GetContainers(); err != nil {
+		return nil, errors.Wrap(err, "failed to get containers")
+	}
+	if inventory.ImageStates, err = client.GetImageStates(); err != nil {
+		return nil, errors.Wrap(err, "failed to get image states")
+	}
+	if inventory.ENIAttachments, err = client.GetENIAttachments(); err != nil {
+		return nil, errors.Wrap(err, "failed to get eni attachments")
+	}
+	for _, key := range metadataKeys {
+		// Metadata that was never saved is simply not found
+		if val, err := client.GetMetadata(key); err == nil {
+			inventory.Metadata[key] = val
+		}
+	}
+	return inventory, nil
+}
+
+// Verify validates the references between the records of the inventory. It follows the same rules
+// as the task engine when it loads its state: every container must belong to a persisted task that
+// has a container of the same name, and every task eni attachment must belong to a persisted task.
+// Image states that are not used by any of these containers are reported, but not orphaned: idle
+// and pre-warmed images are tracked by the image manager until it cleans them up on its own.
+func (inventory *Inventory) Verify() []Problem {
+	var problems []Problem
+	tasks := make(map[string]*apitask.Task)
+	for _, task := range inventory.Tasks {
+		if _, err := utils.GetTaskID(task.Arn); err != nil {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeTask,
+				ID:          task.Arn,
+				Description: "invalid task arn",
+			})
+			continue
+		}
+		tasks[task.Arn] = task
+	}
+
+	usedImages := make(map[string]struct{})
+	for _, dockerContainer := range inventory.Containers {
+		container := dockerContainer.Container
+		if container == nil {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeContainer,
+				ID:          dockerContainer.DockerID,
+				Description: "container record without a container",
+			})
+			continue
+		}
+		id, err := GetContainerID(container)
+		if err != nil {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeContainer,
+				ID:          container.Name,
+				Description: fmt.Sprintf("invalid task arn %q", container.GetTaskARN()),
+			})
+			continue
+		}
+		task, ok := tasks[container.GetTaskARN()]
+		if !ok {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeContainer,
+				ID:          id,
+				Description: fmt.Sprintf("task %s not found", container.GetTaskARN()),
+				Orphaned:    true,
+			})
+			continue
+		}
+		if _, ok := task.ContainerByName(container.Name); !ok {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeContainer,
+				ID:          id,
+				Description: fmt.Sprintf("task %s has no container named %s", task.Arn, container.Name),
+				Orphaned:    true,
+			})
+			continue
+		}
+		usedImages[container.ImageID] = struct{}{}
+	}
+	// Containers that were never saved on their own are only persisted as part of their task
+	for _, task := range tasks {
+		for _, container := range task.Containers {
+			usedImages[container.ImageID] = struct{}{}
+		}
+	}
+
+	for _, imageState := range inventory.ImageStates {
+		if imageState.Image == nil {
+			continue
+		}
+		if _, ok := usedImages[imageState.Image.ImageID]; !ok {
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeImageState,
+				ID:          imageState.Image.ImageID,
+				Description: fmt.Sprintf("image %v is not used by any persisted container", imageState.Image.Names),
+			})
+		}
+	}
+
+	for _, eniAttachment := range inventory.ENIAttachments {
+		if eniAttachment.AttachmentType == apieni.ENIAttachmentTypeInstanceENI || eniAttachment.TaskARN == "" {
+			continue
+		}
+		if _, ok := tasks[eniAttachment.TaskARN]; !ok {
+			id, err := utils.GetENIAttachmentId(eniAttachment.AttachmentARN)
+			if err != nil {
+				id = eniAttachment.AttachmentARN
+			}
+			problems = append(problems, Problem{
+				RecordType:  RecordTypeENIAttachment,
+				ID:          id,
+				Description: fmt.Sprintf("task %s not found", eniAttachment.TaskARN),
+				Orphaned:    true,
+			})
+		}
+	}
+	return problems
+}
+
+// Prune deletes the orphaned records among the problems from the data store of the client. It
+// returns the number of records deleted.
+func Prune(client Client, problems []Problem) (int, error) {
+	pruned := 0
+	for _, problem := range problems {
+		if !problem.Orphaned {
+			continue
+		}
+		var err error
+		switch problem.RecordType {
+		case RecordTypeTask:
+			err = client.DeleteTask(problem.ID)
+		case RecordTypeContainer:
+			err = client.DeleteContainer(problem.ID)
+		case RecordTypeImageState:
+			err = client.DeleteImageState(problem.ID)
+		case RecordTypeENIAttachment:
+			err = client.DeleteENIAttachment(problem.ID)
+		default:
+			err = errors.Errorf("unknown record type %s", problem.RecordType)
+		}
+		if err != nil {
+			return pruned, errors.Wrapf(err, "failed to prune %s %s", problem.RecordType, problem.ID)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
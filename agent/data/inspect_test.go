@@ -0,0 +1,126 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOrphanTaskArn = "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/orphan"
+	testUsedImageId   = "used-image-id"
+	testOrphanImageId = "orphan-image-id"
+)
+
+// saveInconsistentTestData saves a consistent task, container and eni attachment along with records
+// that refer to data that doesn't exist.
+func saveInconsistentTestData(t *testing.T, testClient Client) {
+	require.NoError(t, testClient.SaveTask(&apitask.Task{
+		Arn: testTaskArn,
+		Containers: []*apicontainer.Container{
+			{Name: testContainerName, ImageID: testUsedImageId},
+		},
+	}))
+	for _, container := range []*apicontainer.Container{
+		{Name: testContainerName, TaskARNUnsafe: testTaskArn, ImageID: testUsedImageId},
+		{Name: testContainerName2, TaskARNUnsafe: testTaskArn},
+		{Name: testContainerName, TaskARNUnsafe: testOrphanTaskArn, ImageID: testOrphanImageId},
+	} {
+		require.NoError(t, testClient.SaveDockerContainer(&apicontainer.DockerContainer{Container: container}))
+	}
+	require.NoError(t, testClient.SaveImageState(&image.ImageState{Image: &image.Image{ImageID: testUsedImageId}}))
+	require.NoError(t, testClient.SaveImageState(&image.ImageState{Image: &image.Image{ImageID: testImageId}}))
+	require.NoError(t, testClient.SaveImageState(&image.ImageState{Image: &image.Image{ImageID: testOrphanImageId}}))
+	require.NoError(t, testClient.SaveENIAttachment(&eni.ENIAttachment{
+		AttachmentARN: testAttachmentArn,
+		TaskARN:       testTaskArn,
+	}))
+	require.NoError(t, testClient.SaveENIAttachment(&eni.ENIAttachment{
+		AttachmentARN: testAttachmentArn2,
+		TaskARN:       testOrphanTaskArn,
+	}))
+}
+
+func TestVerifyAndPrune(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+	saveInconsistentTestData(t, testClient)
+	require.NoError(t, testClient.SaveMetadata(ClusterNameKey, "cluster"))
+
+	inventory, err := LoadInventory(testClient)
+	require.NoError(t, err)
+	assert.Len(t, inventory.Tasks, 1)
+	assert.Len(t, inventory.Containers, 3)
+	assert.Len(t, inventory.ImageStates, 3)
+	assert.Len(t, inventory.ENIAttachments, 2)
+	assert.Equal(t, map[string]string{ClusterNameKey: "cluster"}, inventory.Metadata)
+
+	problems := inventory.Verify()
+	assert.ElementsMatch(t, []Problem{
+		{
+			RecordType:  RecordTypeContainer,
+			ID:          "abc-" + testContainerName2,
+			Description: "task " + testTaskArn + " has no container named " + testContainerName2,
+			Orphaned:    true,
+		},
+		{
+			RecordType:  RecordTypeContainer,
+			ID:          "orphan-" + testContainerName,
+			Description: "task " + testOrphanTaskArn + " not found",
+			Orphaned:    true,
+		},
+		{
+			RecordType:  RecordTypeImageState,
+			ID:          testImageId,
+			Description: "image [] is not used by any persisted container",
+		},
+		{
+			// Only used by an orphaned container
+			RecordType:  RecordTypeImageState,
+			ID:          testOrphanImageId,
+			Description: "image [] is not used by any persisted container",
+		},
+		{
+			RecordType:  RecordTypeENIAttachment,
+			ID:          "test-arn2",
+			Description: "task " + testOrphanTaskArn + " not found",
+			Orphaned:    true,
+		},
+	}, problems)
+
+	pruned, err := Prune(testClient, problems)
+	require.NoError(t, err)
+	assert.Equal(t, 3, pruned)
+
+	inventory, err = LoadInventory(testClient)
+	require.NoError(t, err)
+	assert.Len(t, inventory.Containers, 1)
+	// Unused image states are left to the image manager
+	assert.Len(t, inventory.ImageStates, 3)
+	assert.Len(t, inventory.ENIAttachments, 1)
+	for _, problem := range inventory.Verify() {
+		assert.Equal(t, RecordTypeImageState, problem.RecordType)
+		assert.False(t, problem.Orphaned)
+	}
+}
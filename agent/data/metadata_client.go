@@ -23,9 +23,21 @@ const (
 	ClusterNameKey          = "cluster-name"
 	ContainerInstanceARNKey = "container-instance-arn"
 	EC2InstanceIDKey        = "ec2-instance-id"
+	SchemaVersionKey        = "schema-version"
 	TaskManifestSeqNumKey   = "task-manifest-seq-num"
 )
 
+// metadataKeys lists every metadata key persisted by the agent.
+var metadataKeys = []string{
+	AgentVersionKey,
+	AvailabilityZoneKey,
+	ClusterNameKey,
+	ContainerInstanceARNKey,
+	EC2InstanceIDKey,
+	SchemaVersionKey,
+	TaskManifestSeqNumKey,
+}
+
 func (c *client) SaveMetadata(key, val string) error {
 	return c.db.Batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(metadataBucketName))
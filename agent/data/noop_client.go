@@ -88,6 +88,18 @@ func (c *noopClient) GetMetadata(string) (string, error) {
 	return "", nil
 }
 
+func (c *noopClient) SaveCredentials(string, []byte) error {
+	return nil
+}
+
+func (c *noopClient) DeleteCredentials(string) error {
+	return nil
+}
+
+func (c *noopClient) GetCredentials() (map[string][]byte, error) {
+	return nil, nil
+}
+
 func (c *noopClient) Close() error {
 	return nil
 }
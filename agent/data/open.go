@@ -0,0 +1,90 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Options configure how the boltdb data store is opened.
+type Options struct {
+	// ReadOnly opens an existing data store without modifying it. Saving or deleting data with
+	// the returned client fails.
+	ReadOnly bool
+	// LockTimeout is how long to wait for another process, i.e. a running agent, to release the
+	// data store. The default of 0 waits indefinitely.
+	LockTimeout time.Duration
+}
+
+// OpenWithOptions returns a data client that implements the Client interface with boltdb, opened
+// with the given options. Unlike New, it opens a new client on every call.
+func OpenWithOptions(dataDir string, options Options) (Client, error) {
+	c, err := setupWithOptions(dataDir, options)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// setupWithOptions initiates the boltdb client with the given options. The buckets we use are
+// created unless the database is opened read-only, in which case they must already exist.
+func setupWithOptions(dataDir string, options Options) (*client, error) {
+	var boltOptions *bolt.Options
+	if options.ReadOnly || options.LockTimeout != 0 {
+		boltOptions = &bolt.Options{
+			ReadOnly: options.ReadOnly,
+			Timeout:  options.LockTimeout,
+		}
+	}
+	path := filepath.Join(dataDir, dbName)
+	db, err := bolt.Open(path, dbMode, boltOptions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	if options.ReadOnly {
+		err = db.View(func(tx *bolt.Tx) error {
+			for _, b := range buckets {
+				// The credentials bucket was added after the others, databases of older agents
+				// don't have it
+				if b == credentialsBucketName {
+					continue
+				}
+				if tx.Bucket([]byte(b)) == nil {
+					return errors.Errorf("bucket %s not found in %s", b, path)
+				}
+			}
+			return nil
+		})
+	} else {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, b := range buckets {
+				if _, err := tx.CreateBucketIfNotExists([]byte(b)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &client{
+		db: db,
+	}, nil
+}
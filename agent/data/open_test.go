@@ -0,0 +1,121 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveTestData saves one object of every kind with the client.
+func saveTestData(t *testing.T, testClient Client) {
+	testTask := &apitask.Task{
+		Arn:     testTaskArn,
+		Family:  "family",
+		Version: "1",
+	}
+	testTask.SetKnownStatus(apitaskstatus.TaskRunning)
+	require.NoError(t, testClient.SaveTask(testTask))
+	testContainer := &apicontainer.DockerContainer{
+		DockerID:   testDockerID,
+		DockerName: testDockerName,
+		Container: &apicontainer.Container{
+			Name:          testContainerName,
+			TaskARNUnsafe: testTaskArn,
+		},
+	}
+	testContainer.Container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	require.NoError(t, testClient.SaveDockerContainer(testContainer))
+	require.NoError(t, testClient.SaveImageState(&image.ImageState{
+		Image:         &image.Image{ImageID: testImageId, Names: []string{testImageName}},
+		PullSucceeded: true,
+	}))
+	require.NoError(t, testClient.SaveENIAttachment(&eni.ENIAttachment{
+		AttachmentARN: testAttachmentArn,
+		TaskARN:       testTaskArn,
+	}))
+	require.NoError(t, testClient.SaveCredentials(testCredentialsID, []byte("encrypted")))
+	require.NoError(t, testClient.SaveMetadata(AgentVersionKey, "1.0.0"))
+}
+
+// assertTestData asserts that the client returns the data saved by saveTestData.
+func assertTestData(t *testing.T, testClient Client) {
+	tasks, err := testClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, testTaskArn, tasks[0].Arn)
+	assert.Equal(t, apitaskstatus.TaskRunning, tasks[0].GetKnownStatus())
+
+	containers, err := testClient.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	assert.Equal(t, testDockerID, containers[0].DockerID)
+	assert.Equal(t, apicontainerstatus.ContainerRunning, containers[0].Container.GetKnownStatus())
+
+	imageStates, err := testClient.GetImageStates()
+	require.NoError(t, err)
+	require.Len(t, imageStates, 1)
+	assert.Equal(t, testImageId, imageStates[0].GetImageID())
+	assert.True(t, imageStates[0].GetPullSucceeded())
+
+	eniAttachments, err := testClient.GetENIAttachments()
+	require.NoError(t, err)
+	require.Len(t, eniAttachments, 1)
+	assert.Equal(t, testAttachmentArn, eniAttachments[0].AttachmentARN)
+
+	credentials, err := testClient.GetCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{testCredentialsID: []byte("encrypted")}, credentials)
+
+	version, err := testClient.GetMetadata(AgentVersionKey)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", version)
+	_, err = testClient.GetMetadata(ClusterNameKey)
+	assert.Error(t, err, "expected metadata that was never saved to not be found")
+}
+
+func TestOpenWithOptionsReadOnly(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "agent_data_unit_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	_, err = OpenWithOptions(testDir, Options{ReadOnly: true})
+	assert.Error(t, err, "expected opening a missing data store read-only to fail")
+
+	testClient, err := OpenWithOptions(testDir, Options{})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(testDir, dbName))
+	saveTestData(t, testClient)
+	require.NoError(t, testClient.Close())
+
+	testClient, err = OpenWithOptions(testDir, Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer testClient.Close()
+	assertTestData(t, testClient)
+	assert.Error(t, testClient.SaveMetadata(ClusterNameKey, "cluster"))
+}
@@ -0,0 +1,137 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CurrentSchemaVersion is the version of the schema of the records written by this agent. It's the
+	// number of schema migrations known to the agent.
+	CurrentSchemaVersion = 2
+
+	objNotFoundErrMsg = "not found"
+)
+
+// schemaMigrations lists the schema migrations in order. The migration at index i upgrades the records
+// from schema version i to schema version i+1, so new migrations are only ever appended, and
+// CurrentSchemaVersion is bumped along with them.
+//
+// The schema version is saved after each migration, separately from the records it upgraded, so a
+// migration may run again on records it already upgraded if the agent is stopped in between. Migrations
+// must leave records that are already in the new shape untouched.
+var schemaMigrations = []schemaMigration{
+	{
+		description: "store the enis of tasks as a list",
+		migrate:     migrateTaskENIsToList,
+	},
+	{
+		description: "store the transition dependencies of containers as a map keyed by dependent status",
+		migrate:     migrateTransitionDependenciesToMap,
+	},
+}
+
+// schemaMigration upgrades the records of a data store by one schema version.
+type schemaMigration struct {
+	description string
+	migrate     func(store recordMigrator) error
+}
+
+// recordMigrationFunc returns the upgraded JSON of a record, given its key and current JSON. Returning
+// nil leaves the record unchanged.
+type recordMigrationFunc func(id string, data []byte) ([]byte, error)
+
+// recordMigrator is implemented by the clients of data stores that can be migrated. It gives access to
+// the raw JSON of the records, so that migrations don't depend on the current shape of the structs
+// the records are decoded into.
+type recordMigrator interface {
+	// migrateRecords calls migrate with every record of the record type, and replaces the records
+	// whose JSON it changed, within a single transaction.
+	migrateRecords(recordType string, migrate recordMigrationFunc) error
+}
+
+// GetSchemaVersion returns the schema version of the records persisted in the data store of the client.
+// Data stores written by agents that didn't know about schema versions are at version 0.
+func GetSchemaVersion(client Client) (int, error) {
+	val, err := client.GetMetadata(SchemaVersionKey)
+	if err != nil {
+		if strings.Contains(err.Error(), objNotFoundErrMsg) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "failed to get schema version")
+	}
+	if val == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(val)
+	if err != nil || version < 0 {
+		return 0, errors.Errorf("invalid schema version %q", val)
+	}
+	return version, nil
+}
+
+// checkSchemaVersion returns the schema version of the data store of the client, or an error if the
+// records were written by a newer agent and this agent doesn't know how to read them.
+func checkSchemaVersion(client Client) (int, error) {
+	version, err := GetSchemaVersion(client)
+	if err != nil {
+		return 0, err
+	}
+	if version > CurrentSchemaVersion {
+		return 0, errors.Errorf(
+			"data store has schema version %d, which is newer than the schema version %d supported "+
+				"by this agent; refusing to downgrade the persisted state", version, CurrentSchemaVersion)
+	}
+	return version, nil
+}
+
+// MigrateSchema upgrades the records persisted in the data store of the client to the current schema
+// version, one schema migration at a time. It refuses to run on records that were written with a newer
+// schema version, as an older agent can't tell how to read them. Data stores that can't be migrated,
+// e.g. the no-op data store, are left as they are.
+func MigrateSchema(client Client) error {
+	store, ok := client.(recordMigrator)
+	if !ok {
+		return nil
+	}
+	version, err := checkSchemaVersion(client)
+	if err != nil {
+		return err
+	}
+	return migrateSchema(client, store, version, schemaMigrations)
+}
+
+func migrateSchema(client Client, store recordMigrator, version int, migrations []schemaMigration) error {
+	if version == len(migrations) {
+		seelog.Debugf("Data store is at schema version %d", version)
+		return nil
+	}
+	for ; version < len(migrations); version++ {
+		migration := migrations[version]
+		seelog.Infof("Migrating data store from schema version %d to %d: %s",
+			version, version+1, migration.description)
+		if err := migration.migrate(store); err != nil {
+			return errors.Wrapf(err, "failed to migrate data store to schema version %d", version+1)
+		}
+		if err := client.SaveMetadata(SchemaVersionKey, strconv.Itoa(version+1)); err != nil {
+			return errors.Wrapf(err, "failed to save schema version %d", version+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// JSON fields of the records touched by the schema migrations. Migrations work on these names
+	// rather than on the structs, since the structs keep changing after the migrations are written.
+	taskENIsField                   = "ENI"
+	taskContainersField             = "Containers"
+	dockerContainerContainerField   = "Container"
+	containerTransitionDependencies = "TransitionDependencySet"
+)
+
+// migrateTaskENIsToList upgrades tasks saved by agents that stored the eni of a task as a single
+// object rather than as a list of enis.
+func migrateTaskENIsToList(store recordMigrator) error {
+	return store.migrateRecords(RecordTypeTask, func(id string, data []byte) ([]byte, error) {
+		return updateJSONField(data, taskENIsField, func(value json.RawMessage) (json.RawMessage, error) {
+			value = bytes.TrimSpace(value)
+			if len(value) == 0 || value[0] != '{' {
+				// Already a list, or no eni at all
+				return nil, nil
+			}
+			return append(append([]byte{'['}, value...), ']'), nil
+		})
+	})
+}
+
+// migrateTransitionDependenciesToMap upgrades containers saved by agents that stored the transition
+// dependencies of a container as a single set, with the dependent status in every dependency, rather
+// than as a map of dependency sets keyed by the dependent status. Containers are saved both on their
+// own and as part of their task, and both are upgraded.
+func migrateTransitionDependenciesToMap(store recordMigrator) error {
+	err := store.migrateRecords(RecordTypeContainer, func(id string, data []byte) ([]byte, error) {
+		return updateJSONField(data, dockerContainerContainerField, migrateContainerTransitionDependencies)
+	})
+	if err != nil {
+		return err
+	}
+	return store.migrateRecords(RecordTypeTask, func(id string, data []byte) ([]byte, error) {
+		return updateJSONField(data, taskContainersField, func(value json.RawMessage) (json.RawMessage, error) {
+			var containers []json.RawMessage
+			if err := json.Unmarshal(value, &containers); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal task containers")
+			}
+			updated := false
+			for i, container := range containers {
+				migrated, err := migrateContainerTransitionDependencies(container)
+				if err != nil {
+					return nil, err
+				}
+				if migrated != nil {
+					containers[i] = migrated
+					updated = true
+				}
+			}
+			if !updated {
+				return nil, nil
+			}
+			return json.Marshal(containers)
+		})
+	})
+}
+
+func migrateContainerTransitionDependencies(container json.RawMessage) (json.RawMessage, error) {
+	return updateJSONField(container, containerTransitionDependencies,
+		func(value json.RawMessage) (json.RawMessage, error) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(value, &fields); err != nil || fields == nil {
+				// Not an object, there is nothing we know how to upgrade
+				return nil, nil
+			}
+			_, hasContainerDependencies := fields["ContainerDependencies"]
+			_, hasResourceDependencies := fields["ResourceDependencies"]
+			if !hasContainerDependencies && !hasResourceDependencies {
+				// Already a map keyed by dependent status
+				return nil, nil
+			}
+			// The map still knows how to decode the old set, which it converts as it's decoded
+			var dependencies apicontainer.TransitionDependenciesMap
+			if err := json.Unmarshal(value, &dependencies); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal transition dependencies")
+			}
+			return json.Marshal(dependencies)
+		})
+}
+
+// updateJSONField replaces the value of a field of a JSON object with the value returned by update.
+// It returns nil if the object doesn't have the field or if update returned nil, so that records that
+// don't need to be upgraded are not rewritten.
+func updateJSONField(data []byte, field string,
+	update func(value json.RawMessage) (json.RawMessage, error)) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal record")
+	}
+	value, ok := fields[field]
+	if !ok {
+		return nil, nil
+	}
+	updated, err := update(value)
+	if err != nil || updated == nil {
+		return nil, err
+	}
+	fields[field] = updated
+	return json.Marshal(fields)
+}
@@ -0,0 +1,136 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLegacyTransitionDependencies = `{"ContainerDependencies":[` +
+		`{"ContainerName":"dependency","SatisfiedStatus":"RUNNING","DependentStatus":"CREATED"}]}`
+	testTransitionDependencies = `{"2":{"ContainerDependencies":[` +
+		`{"ContainerName":"dependency","SatisfiedStatus":"RUNNING"}],"ResourceDependencies":null}}`
+)
+
+// assertTestRecordField asserts that the JSON value of a field of a raw record is the same as the
+// expected one.
+func assertTestRecordField(t *testing.T, data, field, expected string) {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(data), &fields))
+	assert.JSONEq(t, expected, string(fields[field]))
+}
+
+func TestMigrateTaskENIsToList(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putTestRecord(t, testClient, RecordTypeTask, "legacy",
+		`{"Arn":"arn:aws:ecs:us-west-2:1234567890:task/test-cluster/legacy","ENI":{"ec2Id":"eni-1"}}`)
+	putTestRecord(t, testClient, RecordTypeTask, "current",
+		`{"Arn":"arn:aws:ecs:us-west-2:1234567890:task/test-cluster/current","ENI":[{"ec2Id":"eni-2"}]}`)
+	putTestRecord(t, testClient, RecordTypeTask, "none",
+		`{"Arn":"arn:aws:ecs:us-west-2:1234567890:task/test-cluster/none","ENI":null}`)
+	current := getTestRecord(t, testClient, RecordTypeTask, "current")
+	none := getTestRecord(t, testClient, RecordTypeTask, "none")
+
+	require.NoError(t, migrateTaskENIsToList(testClient.(recordMigrator)))
+	assertTestRecordField(t, getTestRecord(t, testClient, RecordTypeTask, "legacy"), taskENIsField,
+		`[{"ec2Id":"eni-1"}]`)
+	assert.Equal(t, current, getTestRecord(t, testClient, RecordTypeTask, "current"))
+	assert.Equal(t, none, getTestRecord(t, testClient, RecordTypeTask, "none"))
+
+	// Running the migration again doesn't change anything
+	legacy := getTestRecord(t, testClient, RecordTypeTask, "legacy")
+	require.NoError(t, migrateTaskENIsToList(testClient.(recordMigrator)))
+	assert.Equal(t, legacy, getTestRecord(t, testClient, RecordTypeTask, "legacy"))
+
+	tasks, err := testClient.GetTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	for _, task := range tasks {
+		if task.Arn == "arn:aws:ecs:us-west-2:1234567890:task/test-cluster/legacy" {
+			require.Len(t, task.ENIs, 1)
+			assert.Equal(t, "eni-1", task.ENIs[0].ID)
+		}
+	}
+}
+
+func TestMigrateTransitionDependenciesToMap(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putTestRecord(t, testClient, RecordTypeContainer, "abc-legacy",
+		`{"DockerId":"id1","Container":{"Name":"legacy","taskARN":"`+testTaskArn+`",`+
+			`"TransitionDependencySet":`+testLegacyTransitionDependencies+`}}`)
+	putTestRecord(t, testClient, RecordTypeContainer, "abc-current",
+		`{"DockerId":"id2","Container":{"Name":"current","taskARN":"`+testTaskArn+`",`+
+			`"TransitionDependencySet":`+testTransitionDependencies+`}}`)
+	putTestRecord(t, testClient, RecordTypeTask, "abc",
+		`{"Arn":"`+testTaskArn+`","Containers":[`+
+			`{"Name":"legacy","TransitionDependencySet":`+testLegacyTransitionDependencies+`},`+
+			`{"Name":"current","TransitionDependencySet":`+testTransitionDependencies+`}]}`)
+	current := getTestRecord(t, testClient, RecordTypeContainer, "abc-current")
+
+	require.NoError(t, migrateTransitionDependenciesToMap(testClient.(recordMigrator)))
+	var dockerContainer map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(getTestRecord(t, testClient, RecordTypeContainer, "abc-legacy")),
+		&dockerContainer))
+	assertTestRecordField(t, string(dockerContainer[dockerContainerContainerField]),
+		containerTransitionDependencies, testTransitionDependencies)
+	assert.Equal(t, current, getTestRecord(t, testClient, RecordTypeContainer, "abc-current"))
+	var task struct{ Containers []json.RawMessage }
+	require.NoError(t, json.Unmarshal([]byte(getTestRecord(t, testClient, RecordTypeTask, "abc")), &task))
+	require.Len(t, task.Containers, 2)
+	for _, container := range task.Containers {
+		assertTestRecordField(t, string(container), containerTransitionDependencies, testTransitionDependencies)
+	}
+
+	// Running the migration again doesn't change anything
+	legacy := getTestRecord(t, testClient, RecordTypeContainer, "abc-legacy")
+	require.NoError(t, migrateTransitionDependenciesToMap(testClient.(recordMigrator)))
+	assert.Equal(t, legacy, getTestRecord(t, testClient, RecordTypeContainer, "abc-legacy"))
+
+	containers, err := testClient.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, containers, 2)
+	expected := apicontainer.TransitionDependenciesMap{
+		apicontainerstatus.ContainerCreated: apicontainer.TransitionDependencySet{
+			ContainerDependencies: []apicontainer.ContainerDependency{{
+				ContainerName:   "dependency",
+				SatisfiedStatus: apicontainerstatus.ContainerRunning,
+			}},
+		},
+	}
+	for _, container := range containers {
+		assert.Equal(t, expected, container.Container.TransitionDependenciesMap)
+	}
+}
+
+func TestMigrateTransitionDependenciesToMapInvalidRecord(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putTestRecord(t, testClient, RecordTypeContainer, "abc-invalid", `not json`)
+	assert.Error(t, migrateTransitionDependenciesToMap(testClient.(recordMigrator)))
+}
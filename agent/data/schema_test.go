@@ -0,0 +1,193 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package data
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// putTestRecord saves the raw JSON of a record in a boltdb data store.
+func putTestRecord(t *testing.T, testClient Client, recordType, id, data string) {
+	require.NoError(t, testClient.(*client).db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(recordTypeBuckets[recordType])).Put([]byte(id), []byte(data))
+	}))
+}
+
+// getTestRecord returns the raw JSON of a record in a boltdb data store.
+func getTestRecord(t *testing.T, testClient Client, recordType, id string) string {
+	var data string
+	require.NoError(t, testClient.(*client).db.View(func(tx *bolt.Tx) error {
+		data = string(tx.Bucket([]byte(recordTypeBuckets[recordType])).Get([]byte(id)))
+		return nil
+	}))
+	return data
+}
+
+func assertSchemaVersion(t *testing.T, testClient Client, expected int) {
+	version, err := GetSchemaVersion(testClient)
+	require.NoError(t, err)
+	assert.Equal(t, expected, version)
+}
+
+func TestSchemaMigrationsMatchCurrentSchemaVersion(t *testing.T) {
+	assert.Len(t, schemaMigrations, CurrentSchemaVersion)
+}
+
+func TestGetSchemaVersionNotSaved(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	assertSchemaVersion(t, testClient, 0)
+}
+
+func TestGetSchemaVersionInvalid(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	for _, version := range []string{"one", "-1"} {
+		require.NoError(t, testClient.SaveMetadata(SchemaVersionKey, version))
+		_, err := GetSchemaVersion(testClient)
+		assert.Error(t, err, version)
+	}
+}
+
+func TestMigrateSchemaNewDataStore(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	require.NoError(t, MigrateSchema(testClient))
+	assertSchemaVersion(t, testClient, CurrentSchemaVersion)
+
+	// Nothing left to do on the next start
+	require.NoError(t, MigrateSchema(testClient))
+	assertSchemaVersion(t, testClient, CurrentSchemaVersion)
+}
+
+func TestMigrateSchemaRefusesToDowngrade(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	require.NoError(t, testClient.SaveMetadata(SchemaVersionKey, strconv.Itoa(CurrentSchemaVersion+1)))
+	err := MigrateSchema(testClient)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to downgrade")
+	assertSchemaVersion(t, testClient, CurrentSchemaVersion+1)
+}
+
+func TestMigrateSchemaNoopClient(t *testing.T) {
+	assert.NoError(t, MigrateSchema(NewNoopClient()))
+}
+
+func TestMigrateSchemaFromSavedVersion(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	var migrated []int
+	migration := func(version int) schemaMigration {
+		return schemaMigration{
+			description: "test migration",
+			migrate: func(store recordMigrator) error {
+				migrated = append(migrated, version)
+				return nil
+			},
+		}
+	}
+	migrations := []schemaMigration{migration(1), migration(2), migration(3)}
+
+	require.NoError(t, migrateSchema(testClient, testClient.(recordMigrator), 1, migrations))
+	assert.Equal(t, []int{2, 3}, migrated)
+	assertSchemaVersion(t, testClient, 3)
+}
+
+func TestMigrateSchemaStopsAtFailedMigration(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	migrations := []schemaMigration{
+		{
+			description: "test migration",
+			migrate:     func(store recordMigrator) error { return nil },
+		},
+		{
+			description: "failing test migration",
+			migrate:     func(store recordMigrator) error { return errors.New("test error") },
+		},
+		{
+			description: "test migration",
+			migrate: func(store recordMigrator) error {
+				t.Error("migration run after a failed migration")
+				return nil
+			},
+		},
+	}
+
+	assert.Error(t, migrateSchema(testClient, testClient.(recordMigrator), 0, migrations))
+	// The migration that succeeded isn't run again on the next start
+	assertSchemaVersion(t, testClient, 1)
+}
+
+func TestMigrateRecords(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putTestRecord(t, testClient, RecordTypeImageState, "image1", `{"old":true}`)
+	putTestRecord(t, testClient, RecordTypeImageState, "image2", `{"new":true}`)
+
+	var seen []string
+	require.NoError(t, testClient.(recordMigrator).migrateRecords(RecordTypeImageState,
+		func(id string, data []byte) ([]byte, error) {
+			seen = append(seen, id)
+			if string(data) == `{"old":true}` {
+				return []byte(`{"new":true}`), nil
+			}
+			return nil, nil
+		}))
+	assert.Equal(t, []string{"image1", "image2"}, seen)
+	assert.Equal(t, `{"new":true}`, getTestRecord(t, testClient, RecordTypeImageState, "image1"))
+	assert.Equal(t, `{"new":true}`, getTestRecord(t, testClient, RecordTypeImageState, "image2"))
+}
+
+func TestMigrateRecordsFailureLeavesRecordsUnchanged(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putTestRecord(t, testClient, RecordTypeImageState, "image1", `{"old":true}`)
+	putTestRecord(t, testClient, RecordTypeImageState, "image2", `{"old":true}`)
+
+	err := testClient.(recordMigrator).migrateRecords(RecordTypeImageState,
+		func(id string, data []byte) ([]byte, error) {
+			if id == "image2" {
+				return nil, errors.New("test error")
+			}
+			return []byte(`{"new":true}`), nil
+		})
+	assert.Error(t, err)
+	assert.Equal(t, `{"old":true}`, getTestRecord(t, testClient, RecordTypeImageState, "image1"))
+}
+
+func TestMigrateRecordsUnknownRecordType(t *testing.T) {
+	testClient, cleanup := newTestClient(t)
+	defer cleanup()
+
+	assert.Error(t, testClient.(recordMigrator).migrateRecords("unknown",
+		func(id string, data []byte) ([]byte, error) { return nil, nil }))
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/sdkclientfactory"
 	"github.com/aws/amazon-ecs-agent/agent/ecr"
 	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
@@ -121,6 +122,11 @@ type DockerClient interface {
 	// PullImage pulls an image. authData should contain authentication data provided by the ECS backend.
 	PullImage(context.Context, string, *apicontainer.RegistryAuthenticationData, time.Duration) DockerContainerMetadata
 
+	// PullImageForPlatform pulls an image for the given platform, in the os[/arch[/variant]] format. It otherwise
+	// behaves the same as PullImage.
+	PullImageForPlatform(context.Context, string, string, *apicontainer.RegistryAuthenticationData,
+		time.Duration) DockerContainerMetadata
+
 	// CreateContainer creates a container with the provided Config, HostConfig, and name. A timeout value
 	// and a context should be provided for the request.
 	CreateContainer(context.Context, *dockercontainer.Config, *dockercontainer.HostConfig, string, time.Duration) DockerContainerMetadata
@@ -133,6 +139,10 @@ type DockerClient interface {
 	// for the request.
 	StopContainer(context.Context, string, time.Duration) DockerContainerMetadata
 
+	// SignalContainer sends a signal, such as SIGHUP, to the main process of the container identified by
+	// the name provided. A timeout value and a context should be provided for the request.
+	SignalContainer(context.Context, string, string, time.Duration) error
+
 	// DescribeContainer returns status information about the specified container. A context should be provided
 	// for the request
 	DescribeContainer(context.Context, string) (apicontainerstatus.ContainerStatus, DockerContainerMetadata)
@@ -145,6 +155,11 @@ type DockerClient interface {
 	// provided for the request.
 	InspectContainer(context.Context, string, time.Duration) (*types.ContainerJSON, error)
 
+	// InspectContainerWithSize returns information about the specified container, along with the size
+	// of its writable layer, which Docker computes by walking the layer. A timeout value and a context
+	// should be provided for the request.
+	InspectContainerWithSize(context.Context, string, time.Duration) (*types.ContainerJSON, error)
+
 	// CreateContainerExec creates a new exec configuration to run an exec process with the provided Config. A timeout value
 	// and a context should be provided for the request.
 	CreateContainerExec(ctx context.Context, containerID string, execConfig types.ExecConfig, timeout time.Duration) (*types.IDResponse, error)
@@ -323,15 +338,22 @@ func (dg *dockerGoClient) time() ttime.Time {
 }
 
 func (dg *dockerGoClient) PullImage(ctx context.Context, image string,
+	authData *apicontainer.RegistryAuthenticationData, timeout time.Duration) DockerContainerMetadata {
+	return dg.PullImageForPlatform(ctx, image, "", authData, timeout)
+}
+
+func (dg *dockerGoClient) PullImageForPlatform(ctx context.Context, image string, platform string,
 	authData *apicontainer.RegistryAuthenticationData, timeout time.Duration) DockerContainerMetadata {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("PULL_IMAGE")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.PullImage", tracing.SpanKindClient, tracing.String(tracing.AttributeImage, image))
+	defer span.End()
 	response := make(chan DockerContainerMetadata, 1)
 	go func() {
 		err := retry.RetryNWithBackoffCtx(ctx, dg.imagePullBackoff, maximumPullRetries,
 			func() error {
-				err := dg.pullImage(ctx, image, authData)
+				err := dg.pullImage(ctx, image, platform, authData)
 				if err != nil {
 					seelog.Errorf("DockerGoClient: failed to pull image %s: [%s] %s", image, err.ErrorName(), err.Error())
 				}
@@ -368,7 +390,7 @@ func wrapPullErrorAsNamedError(err error) apierrors.NamedError {
 	return retErr
 }
 
-func (dg *dockerGoClient) pullImage(ctx context.Context, image string,
+func (dg *dockerGoClient) pullImage(ctx context.Context, image string, platform string,
 	authData *apicontainer.RegistryAuthenticationData) apierrors.NamedError {
 	seelog.Debugf("DockerGoClient: pulling image: %s", image)
 	client, err := dg.sdkDockerClient()
@@ -389,6 +411,7 @@ func (dg *dockerGoClient) pullImage(ctx context.Context, image string,
 	imagePullOpts := types.ImagePullOptions{
 		All:          false,
 		RegistryAuth: base64.URLEncoding.EncodeToString(buf.Bytes()),
+		Platform:     platform,
 	}
 
 	repository := getRepository(image)
@@ -538,6 +561,8 @@ func (dg *dockerGoClient) CreateContainer(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("CREATE_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.CreateContainer", tracing.SpanKindClient)
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
@@ -583,6 +608,8 @@ func (dg *dockerGoClient) StartContainer(ctx context.Context, id string, timeout
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("START_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.StartContainer", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, id))
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
@@ -644,18 +671,31 @@ func (dg *dockerGoClient) DescribeContainer(ctx context.Context, dockerID string
 }
 
 func (dg *dockerGoClient) InspectContainer(ctx context.Context, dockerID string, timeout time.Duration) (*types.ContainerJSON, error) {
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("INSPECT_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.InspectContainer", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, dockerID))
+	defer span.End()
+	return dg.inspectContainerWithTimeout(ctx, dockerID, false, timeout)
+}
+
+func (dg *dockerGoClient) InspectContainerWithSize(ctx context.Context, dockerID string, timeout time.Duration) (*types.ContainerJSON, error) {
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("INSPECT_CONTAINER_WITH_SIZE")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.InspectContainerWithSize", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, dockerID))
+	defer span.End()
+	return dg.inspectContainerWithTimeout(ctx, dockerID, true, timeout)
+}
+
+func (dg *dockerGoClient) inspectContainerWithTimeout(ctx context.Context, dockerID string, withSize bool, timeout time.Duration) (*types.ContainerJSON, error) {
 	type inspectResponse struct {
 		container *types.ContainerJSON
 		err       error
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	defer metrics.MetricsEngineGlobal.RecordDockerMetric("INSPECT_CONTAINER")()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan inspectResponse, 1)
 	go func() {
-		container, err := dg.inspectContainer(ctx, dockerID)
+		container, err := dg.inspectContainer(ctx, dockerID, withSize)
 		response <- inspectResponse{container, err}
 	}()
 
@@ -673,11 +713,15 @@ func (dg *dockerGoClient) InspectContainer(ctx context.Context, dockerID string,
 	}
 }
 
-func (dg *dockerGoClient) inspectContainer(ctx context.Context, dockerID string) (*types.ContainerJSON, error) {
+func (dg *dockerGoClient) inspectContainer(ctx context.Context, dockerID string, withSize bool) (*types.ContainerJSON, error) {
 	client, err := dg.sdkDockerClient()
 	if err != nil {
 		return nil, err
 	}
+	if withSize {
+		containerData, _, err := client.ContainerInspectWithRaw(ctx, dockerID, true)
+		return &containerData, err
+	}
 	containerData, err := client.ContainerInspect(ctx, dockerID)
 	return &containerData, err
 }
@@ -687,6 +731,8 @@ func (dg *dockerGoClient) StopContainer(ctx context.Context, dockerID string, ti
 	ctx, cancel := context.WithTimeout(ctx, ctxTimeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("STOP_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.StopContainer", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, dockerID))
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
@@ -705,6 +751,43 @@ func (dg *dockerGoClient) StopContainer(ctx context.Context, dockerID string, ti
 	}
 }
 
+func (dg *dockerGoClient) SignalContainer(ctx context.Context, dockerID string, signal string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("SIGNAL_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.SignalContainer", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, dockerID))
+	defer span.End()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() { response <- dg.signalContainer(ctx, dockerID, signal) }()
+
+	// Wait until we get a response or for the 'done' context channel
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return &DockerTimeoutError{timeout, "signaling container"}
+		}
+		return &CannotSignalContainerError{err}
+	}
+}
+
+func (dg *dockerGoClient) signalContainer(ctx context.Context, dockerID string, signal string) error {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return &CannotGetDockerClientError{version: dg.version, err: err}
+	}
+
+	err = client.ContainerKill(ctx, dockerID, signal)
+	if err != nil {
+		return &CannotSignalContainerError{err}
+	}
+	return nil
+}
+
 func (dg *dockerGoClient) stopContainer(ctx context.Context, dockerID string, timeout time.Duration) DockerContainerMetadata {
 	client, err := dg.sdkDockerClient()
 	if err != nil {
@@ -732,6 +815,8 @@ func (dg *dockerGoClient) RemoveContainer(ctx context.Context, dockerID string,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("REMOVE_CONTAINER")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.RemoveContainer", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, dockerID))
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan error, 1)
@@ -1196,6 +1281,8 @@ func (dg *dockerGoClient) CreateVolume(ctx context.Context, name string,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("CREATE_VOLUME")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.CreateVolume", tracing.SpanKindClient)
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan SDKVolumeResponse, 1)
@@ -1246,6 +1333,8 @@ func (dg *dockerGoClient) InspectVolume(ctx context.Context, name string, timeou
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("INSPECT_VOLUME")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.InspectVolume", tracing.SpanKindClient)
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan SDKVolumeResponse, 1)
@@ -1288,6 +1377,8 @@ func (dg *dockerGoClient) RemoveVolume(ctx context.Context, name string, timeout
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("REMOVE_VOLUME")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.RemoveVolume", tracing.SpanKindClient)
+	defer span.End()
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan error, 1)
@@ -1558,6 +1649,8 @@ func (dg *dockerGoClient) LoadImage(ctx context.Context, inputStream io.Reader,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("LOAD_IMAGE")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.LoadImage", tracing.SpanKindClient)
+	defer span.End()
 	response := make(chan error, 1)
 	go func() {
 		response <- dg.loadImage(ctx, inputStream)
@@ -1597,6 +1690,8 @@ func (dg *dockerGoClient) CreateContainerExec(ctx context.Context, containerID s
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("CREATE_CONTAINER_EXEC")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.CreateContainerExec", tracing.SpanKindClient, tracing.String(tracing.AttributeDockerID, containerID))
+	defer span.End()
 	response := make(chan createContainerExecResponse, 1)
 	go func() {
 		execIDresponse, err := dg.createContainerExec(ctx, containerID, execConfig)
@@ -1633,6 +1728,8 @@ func (dg *dockerGoClient) StartContainerExec(ctx context.Context, execID string,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("START_CONTAINER_EXEC")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.StartContainerExec", tracing.SpanKindClient)
+	defer span.End()
 	response := make(chan error, 1)
 	go func() {
 		err := dg.startContainerExec(ctx, execID, execStartCheck)
@@ -1673,6 +1770,8 @@ func (dg *dockerGoClient) InspectContainerExec(ctx context.Context, execID strin
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer metrics.MetricsEngineGlobal.RecordDockerMetric("INSPECT_CONTAINER_EXEC")()
+	ctx, span := tracing.StartChildSpan(ctx, "docker.InspectContainerExec", tracing.SpanKindClient)
+	defer span.End()
 	response := make(chan inspectContainerExecResponse, 1)
 	go func() {
 		execInspectResponse, err := dg.inspectContainerExec(ctx, execID)
@@ -360,6 +360,25 @@ func TestPullImageError(t *testing.T) {
 	assert.Equal(t, "CannotPullContainerError", metadata.Error.(apierrors.NamedError).ErrorName(), "Wrong error type")
 }
 
+func TestPullImageForPlatform(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "image:latest", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, image string, options types.ImagePullOptions) (io.ReadCloser, error) {
+			assert.Equal(t, "linux/arm64", options.Platform)
+			return mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil
+		})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImageForPlatform(ctx, "image", "linux/arm64", nil, defaultTestConfig().ImagePullTimeout)
+	assert.NoError(t, metadata.Error)
+}
+
 type mockReadCloser struct {
 	reader io.Reader
 	delay  time.Duration
@@ -762,6 +781,49 @@ func TestInspectContainer(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(&containerOutput, container))
 }
 
+func TestInspectContainerWithSize(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	sizeRw := int64(1024)
+	containerOutput := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:     "id",
+			SizeRw: &sizeRw,
+			State:  &types.ContainerState{},
+		}}
+	mockDockerSDK.EXPECT().ContainerInspectWithRaw(gomock.Any(), "id", true).Return(containerOutput, nil, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	container, err := client.InspectContainerWithSize(ctx, "id", dockerclient.InspectContainerWithSizeTimeout)
+	assert.NoError(t, err)
+	require.NotNil(t, container.SizeRw)
+	assert.Equal(t, sizeRw, *container.SizeRw)
+}
+
+func TestSignalContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGHUP").Return(nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	err := client.SignalContainer(ctx, "id", "SIGHUP", dockerclient.SignalContainerTimeout)
+	assert.NoError(t, err)
+}
+
+func TestSignalContainerError(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGHUP").Return(errors.New("some docker error"))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	err := client.SignalContainer(ctx, "id", "SIGHUP", dockerclient.SignalContainerTimeout)
+	assert.Error(t, err)
+	assert.Equal(t, "CannotSignalContainerError", err.(apierrors.NamedError).ErrorName())
+}
+
 func TestContainerEvents(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -106,6 +106,20 @@ func (CannotGetDockerClientError) ErrorName() string {
 	return "CannotGetDockerclientError"
 }
 
+// CannotSignalContainerError indicates any error when trying to send a signal to a container
+type CannotSignalContainerError struct {
+	fromError error
+}
+
+func (err CannotSignalContainerError) Error() string {
+	return err.fromError.Error()
+}
+
+// ErrorName returns name of the CannotSignalContainerError.
+func (err CannotSignalContainerError) ErrorName() string {
+	return "CannotSignalContainerError"
+}
+
 // CannotStopContainerError indicates any error when trying to stop a container
 type CannotStopContainerError struct {
 	FromError error
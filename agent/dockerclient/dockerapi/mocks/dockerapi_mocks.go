@@ -205,6 +205,21 @@ func (mr *MockDockerClientMockRecorder) InspectImage(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectImage", reflect.TypeOf((*MockDockerClient)(nil).InspectImage), arg0)
 }
 
+// InspectContainerWithSize mocks base method
+func (m *MockDockerClient) InspectContainerWithSize(arg0 context.Context, arg1 string, arg2 time.Duration) (*types.ContainerJSON, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InspectContainerWithSize", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.ContainerJSON)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectContainerWithSize indicates an expected call of InspectContainerWithSize
+func (mr *MockDockerClientMockRecorder) InspectContainerWithSize(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectContainerWithSize", reflect.TypeOf((*MockDockerClient)(nil).InspectContainerWithSize), arg0, arg1, arg2)
+}
+
 // InspectVolume mocks base method
 func (m *MockDockerClient) InspectVolume(arg0 context.Context, arg1 string, arg2 time.Duration) dockerapi.SDKVolumeResponse {
 	m.ctrl.T.Helper()
@@ -318,6 +333,20 @@ func (mr *MockDockerClientMockRecorder) PullImage(arg0, arg1, arg2, arg3 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImage", reflect.TypeOf((*MockDockerClient)(nil).PullImage), arg0, arg1, arg2, arg3)
 }
 
+// PullImageForPlatform mocks base method
+func (m *MockDockerClient) PullImageForPlatform(arg0 context.Context, arg1, arg2 string, arg3 *container.RegistryAuthenticationData, arg4 time.Duration) dockerapi.DockerContainerMetadata {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PullImageForPlatform", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(dockerapi.DockerContainerMetadata)
+	return ret0
+}
+
+// PullImageForPlatform indicates an expected call of PullImageForPlatform
+func (mr *MockDockerClientMockRecorder) PullImageForPlatform(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImageForPlatform", reflect.TypeOf((*MockDockerClient)(nil).PullImageForPlatform), arg0, arg1, arg2, arg3, arg4)
+}
+
 // RemoveContainer mocks base method
 func (m *MockDockerClient) RemoveContainer(arg0 context.Context, arg1 string, arg2 time.Duration) error {
 	m.ctrl.T.Helper()
@@ -360,6 +389,20 @@ func (mr *MockDockerClientMockRecorder) RemoveVolume(arg0, arg1, arg2 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveVolume", reflect.TypeOf((*MockDockerClient)(nil).RemoveVolume), arg0, arg1, arg2)
 }
 
+// SignalContainer mocks base method
+func (m *MockDockerClient) SignalContainer(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignalContainer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SignalContainer indicates an expected call of SignalContainer
+func (mr *MockDockerClientMockRecorder) SignalContainer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignalContainer", reflect.TypeOf((*MockDockerClient)(nil).SignalContainer), arg0, arg1, arg2, arg3)
+}
+
 // StartContainer mocks base method
 func (m *MockDockerClient) StartContainer(arg0 context.Context, arg1 string, arg2 time.Duration) dockerapi.DockerContainerMetadata {
 	m.ctrl.T.Helper()
@@ -43,6 +43,8 @@ const (
 	ContainerExecInspectTimeout = 1 * time.Minute
 	// StopContainerTimeout is the timeout for the StopContainer API.
 	StopContainerTimeout = 30 * time.Second
+	// SignalContainerTimeout is the timeout for the SignalContainer API.
+	SignalContainerTimeout = 30 * time.Second
 	// RemoveContainerTimeout is the timeout for the RemoveContainer API.
 	RemoveContainerTimeout = 5 * time.Minute
 
@@ -52,6 +54,9 @@ const (
 	InspectVolumeTimeout = 5 * time.Minute
 	// RemoveVolumeTimeout is the timeout for RemoveVolume API.
 	RemoveVolumeTimeout = 5 * time.Minute
+	// InspectContainerWithSizeTimeout is the timeout for InspectContainerWithSize API. Docker
+	// walks the writable layer of the container to size it.
+	InspectContainerWithSizeTimeout = 2 * time.Minute
 
 	// ListPluginsTimeout is the timeout for ListPlugins API.
 	ListPluginsTimeout = 1 * time.Minute
@@ -0,0 +1,34 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// getDiskUsage returns the usage of the filesystem of the path, as reported by statfs.
+func getDiskUsage(path string) (diskUsageStats, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return diskUsageStats{}, errors.Wrapf(err, "unable to stat the filesystem of %s", path)
+	}
+	blockSize := uint64(stat.Bsize)
+	return diskUsageStats{
+		used:      (stat.Blocks - stat.Bfree) * blockSize,
+		available: stat.Bavail * blockSize,
+	}, nil
+}
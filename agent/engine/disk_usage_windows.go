@@ -0,0 +1,45 @@
+//go:build windows
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+var procGetDiskFreeSpaceExW = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// getDiskUsage returns the usage of the volume of the path, as reported by GetDiskFreeSpaceEx.
+func getDiskUsage(path string) (diskUsageStats, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return diskUsageStats{}, errors.Wrapf(err, "invalid path %s", path)
+	}
+	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
+	ret, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)))
+	if ret == 0 {
+		return diskUsageStats{}, errors.Wrapf(err, "unable to get the free space of the volume of %s", path)
+	}
+	return diskUsageStats{
+		used:      totalBytes - totalFreeBytes,
+		available: freeBytesAvailable,
+	}, nil
+}
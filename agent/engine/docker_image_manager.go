@@ -41,6 +41,7 @@ const (
 // adding and removing container references to ImageStates
 type ImageManager interface {
 	RecordContainerReference(container *apicontainer.Container) error
+	RecordPrewarmedImage(imageName string, protectedUntil time.Time) error
 	RemoveContainerReferenceFromImageState(container *apicontainer.Container) error
 	AddAllImageStates(imageStates []*image.ImageState)
 	GetImageStateFromImageName(containerImageName string) (*image.ImageState, bool)
@@ -67,6 +68,27 @@ type dockerImageManager struct {
 	nonECSContainerCleanupWaitDuration time.Duration
 	numNonECSContainersToDelete        int
 	nonECSMinimumAgeBeforeDeletion     time.Duration
+	diskHighWatermark                  int
+	diskLowWatermark                   int
+	diskCheckInterval                  time.Duration
+	diskPath                           string
+	diskUsage                          func(path string) (diskUsageStats, error)
+	diskUsageErrorLogged               bool
+}
+
+// diskUsageStats is the usage of the filesystem of the Docker data root, in bytes.
+type diskUsageStats struct {
+	used      uint64
+	available uint64
+}
+
+// percent returns the usage of the filesystem in percent, as reported by df. Space reserved
+// for the root user is neither used nor available.
+func (stats diskUsageStats) percent() float64 {
+	if stats.used+stats.available == 0 {
+		return 0
+	}
+	return float64(stats.used) * 100 / float64(stats.used+stats.available)
 }
 
 // ImageStatesForDeletion is used for implementing the sort interface
@@ -86,6 +108,11 @@ func NewImageManager(cfg *config.Config, client dockerapi.DockerClient, state do
 		nonECSContainerCleanupWaitDuration: cfg.TaskCleanupWaitDuration,
 		numNonECSContainersToDelete:        cfg.NumNonECSContainersToDeletePerCycle,
 		nonECSMinimumAgeBeforeDeletion:     cfg.NonECSMinimumImageDeletionAge,
+		diskHighWatermark:                  cfg.ImageCleanupDiskHighWatermark,
+		diskLowWatermark:                   cfg.ImageCleanupDiskLowWatermark,
+		diskCheckInterval:                  cfg.ImageCleanupDiskCheckInterval,
+		diskPath:                           getDiskPath(cfg),
+		diskUsage:                          getDiskUsage,
 	}
 }
 
@@ -152,6 +179,41 @@ func (imageManager *dockerImageManager) RecordContainerReference(container *apic
 	return nil
 }
 
+// RecordPrewarmedImage adds an image pulled ahead of the tasks that use it to the corresponding imageState
+// object, and protects the image from cleanup until the given time
+func (imageManager *dockerImageManager) RecordPrewarmedImage(imageName string, protectedUntil time.Time) error {
+	imageInspected, err := imageManager.client.InspectImage(imageName)
+	if err != nil {
+		seelog.Errorf("Error inspecting image %v: %v", imageName, err)
+		return err
+	}
+
+	// this lock is used while creating and adding new image state to image manager
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+	imageManager.removeExistingImageNameOfDifferentID(imageName, imageInspected.ID)
+	imageState, ok := imageManager.getImageState(imageInspected.ID)
+	if !ok {
+		imageState = &image.ImageState{
+			Image: &image.Image{
+				ImageID: imageInspected.ID,
+				Size:    imageInspected.Size,
+			},
+			PulledAt:   time.Now(),
+			LastUsedAt: time.Now(),
+		}
+	}
+	imageState.AddImageName(imageName)
+	imageState.SetPullSucceeded(true)
+	imageState.SetProtectedUntil(protectedUntil)
+	if ok {
+		imageManager.saveImageStateData(imageState)
+	} else {
+		imageManager.addImageState(imageState)
+	}
+	return nil
+}
+
 // check whether image pull from ECR
 func (imageManager *dockerImageManager) isImagePullFromECR(container *apicontainer.Container) bool {
 	return container.RegistryAuthentication != nil && container.RegistryAuthentication.ECRAuthData != nil && container.RegistryAuthentication.Type == apicontainer.AuthTypeECR
@@ -282,6 +344,10 @@ func (imageManager *dockerImageManager) getCandidateImagesForDeletion() []*image
 	}
 	var imagesForDeletion []*image.ImageState
 	for _, imageState := range imageManager.imageStatesConsideredForDeletion {
+		if imageState.IsProtected(time.Now()) {
+			seelog.Debugf("Image protected from deletion: [%s]", imageState.String())
+			continue
+		}
 		if imageManager.isImageOldEnough(imageState) && imageState.HasNoAssociatedContainers() {
 			seelog.Infof("Candidate image for deletion: [%s]", imageState.String())
 			imagesForDeletion = append(imagesForDeletion, imageState)
@@ -314,6 +380,17 @@ func (imageStates ImageStatesForDeletion) Swap(i, j int) {
 	imageStates[i], imageStates[j] = imageStates[j], imageStates[i]
 }
 
+// sortImagesForDiskPressureCleanup sorts the images in the order of last used times, larger
+// images first among the images last used at the same time.
+func sortImagesForDiskPressureCleanup(imageStates []*image.ImageState) {
+	sort.SliceStable(imageStates, func(i, j int) bool {
+		if !imageStates[i].LastUsedAt.Equal(imageStates[j].LastUsedAt) {
+			return imageStates[i].LastUsedAt.Before(imageStates[j].LastUsedAt)
+		}
+		return imageStates[i].Image.Size > imageStates[j].Image.Size
+	})
+}
+
 func (imageManager *dockerImageManager) getLeastRecentlyUsedImage(imagesForDeletion []*image.ImageState) *image.ImageState {
 	var candidateImages ImageStatesForDeletion
 	for _, imageState := range imagesForDeletion {
@@ -349,10 +426,21 @@ func (imageManager *dockerImageManager) StartImageCleanupProcess(ctx context.Con
 
 func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.Context, imageCleanupInterval time.Duration) {
 	imageManager.imageCleanupTicker = time.NewTicker(imageCleanupInterval)
+	// A nil channel blocks forever, which leaves disk pressure driven image cleanup out when it's disabled
+	var diskCheck <-chan time.Time
+	if imageManager.diskHighWatermark > 0 {
+		seelog.Infof("Checking the disk usage every %v, images will be removed when it's above %d%%",
+			imageManager.diskCheckInterval, imageManager.diskHighWatermark)
+		diskCheckTicker := time.NewTicker(imageManager.diskCheckInterval)
+		defer diskCheckTicker.Stop()
+		diskCheck = diskCheckTicker.C
+	}
 	for {
 		select {
 		case <-imageManager.imageCleanupTicker.C:
 			go imageManager.removeUnusedImages(ctx)
+		case <-diskCheck:
+			imageManager.removeImagesUnderDiskPressure(ctx)
 		case <-ctx.Done():
 			imageManager.imageCleanupTicker.Stop()
 			return
@@ -360,6 +448,76 @@ func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.
 	}
 }
 
+// getDiskPath returns the path used to check the disk usage. Docker reports its data root as a path
+// of the host, which isn't mounted in the Agent container, so the disk usage is checked at the path
+// configured with ECS_IMAGE_CLEANUP_DISK_PATH, where the data root is expected to be mounted, or
+// at the data directory of the Agent otherwise. The latter is only accurate when the data directory
+// is on the same filesystem as the Docker data root.
+func getDiskPath(cfg *config.Config) string {
+	if cfg.ImageCleanupDiskPath != "" {
+		return cfg.ImageCleanupDiskPath
+	}
+	if cfg.ImageCleanupDiskHighWatermark > 0 {
+		seelog.Warnf("ECS_IMAGE_CLEANUP_DISK_PATH is not set, the disk usage of the Docker data root will be checked "+
+			"at the data directory of the Agent, %s, instead. Mount the Docker data root in the Agent container and "+
+			"set ECS_IMAGE_CLEANUP_DISK_PATH to it if they are on different filesystems", cfg.DataDir)
+	}
+	return cfg.DataDir
+}
+
+// removeImagesUnderDiskPressure removes the least recently used images, larger images first among
+// the images last used at the same time, when the usage of the disk of the Docker data root is above
+// the high watermark, until it's back below the low watermark. Images that are excluded from cleanup,
+// that are not old enough or that are used by containers are not removed.
+func (imageManager *dockerImageManager) removeImagesUnderDiskPressure(ctx context.Context) {
+	path := imageManager.diskPath
+	usage, err := imageManager.diskUsage(path)
+	if err != nil {
+		// The path doesn't change, so the error is only logged as a warning the first time
+		if !imageManager.diskUsageErrorLogged {
+			seelog.Warnf("Unable to check the disk usage, make sure that the Docker data root is mounted at %s: %v", path, err)
+			imageManager.diskUsageErrorLogged = true
+		} else {
+			seelog.Debugf("Unable to check the disk usage: %v", err)
+		}
+		return
+	}
+	if usage.percent() < float64(imageManager.diskHighWatermark) {
+		seelog.Debugf("Disk usage of %s is %.1f%%", path, usage.percent())
+		return
+	}
+	seelog.Infof("Disk usage of %s is %.1f%%, above the high watermark of %d%%, removing images until it's below %d%%",
+		path, usage.percent(), imageManager.diskHighWatermark, imageManager.diskLowWatermark)
+
+	seelog.Debug("Attempting to obtain ImagePullDeleteLock for removing images under disk pressure")
+	ImagePullDeleteLock.Lock()
+	seelog.Debug("Obtained ImagePullDeleteLock for removing images under disk pressure")
+	defer seelog.Debug("Released ImagePullDeleteLock after removing images under disk pressure")
+	defer ImagePullDeleteLock.Unlock()
+
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+
+	imageManager.imageStatesConsideredForDeletion = imageManager.imagesConsiderForDeletion(imageManager.getAllImageStates())
+	candidateImageStates := imageManager.getCandidateImagesForDeletion()
+	sortImagesForDiskPressureCleanup(candidateImageStates)
+	for _, imageState := range candidateImageStates {
+		imageManager.removeImage(ctx, imageState)
+		usage, err = imageManager.diskUsage(path)
+		if err != nil {
+			seelog.Warnf("Unable to check the disk usage, stopping image cleanup: %v", err)
+			return
+		}
+		if usage.percent() <= float64(imageManager.diskLowWatermark) {
+			seelog.Infof("Disk usage of %s is %.1f%%, below the low watermark of %d%%",
+				path, usage.percent(), imageManager.diskLowWatermark)
+			return
+		}
+	}
+	seelog.Warnf("Disk usage of %s is still %.1f%% after removing %d eligible images",
+		path, usage.percent(), len(candidateImageStates))
+}
+
 func (imageManager *dockerImageManager) removeUnusedImages(ctx context.Context) {
 	seelog.Debug("Attempting to obtain ImagePullDeleteLock for removing images")
 	ImagePullDeleteLock.Lock()
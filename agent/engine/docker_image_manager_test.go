@@ -1973,3 +1973,234 @@ func TestImageCleanupProcessNotStart(t *testing.T) {
 	imageManager.StartImageCleanupProcess(ctx)
 	// Nothing should happen.
 }
+
+func TestRecordPrewarmedImageNewImageState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := NewImageManager(defaultTestConfig(), client, dockerstate.NewTaskEngineState())
+	imageManager.SetDataClient(data.NewNoopClient())
+
+	protectedUntil := time.Now().Add(time.Hour)
+	client.EXPECT().InspectImage("busybox").Return(&types.ImageInspect{ID: "sha256:qwerty", Size: 1024}, nil)
+	require.NoError(t, imageManager.RecordPrewarmedImage("busybox", protectedUntil))
+
+	imageState, ok := imageManager.GetImageStateFromImageName("busybox")
+	require.True(t, ok)
+	assert.Equal(t, "sha256:qwerty", imageState.GetImageID())
+	assert.Equal(t, int64(1024), imageState.Image.Size)
+	assert.True(t, imageState.GetPullSucceeded())
+	assert.True(t, imageState.IsProtected(time.Now()))
+	assert.False(t, imageState.IsProtected(protectedUntil.Add(time.Second)))
+	assert.True(t, imageState.HasNoAssociatedContainers())
+}
+
+func TestRecordPrewarmedImageExistingImageState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := NewImageManager(defaultTestConfig(), client, dockerstate.NewTaskEngineState())
+	imageManager.SetDataClient(data.NewNoopClient())
+	protectedUntil := time.Now().Add(2 * time.Hour)
+	sourceImageState := &image.ImageState{
+		Image:          &image.Image{ImageID: "sha256:qwerty", Names: []string{"busybox:1"}},
+		PulledAt:       time.Now().AddDate(0, -2, 0),
+		ProtectedUntil: protectedUntil,
+	}
+	imageManager.(*dockerImageManager).addImageState(sourceImageState)
+
+	client.EXPECT().InspectImage("busybox:latest").Return(&types.ImageInspect{ID: "sha256:qwerty"}, nil)
+	require.NoError(t, imageManager.RecordPrewarmedImage("busybox:latest", time.Now().Add(time.Hour)))
+
+	assert.Equal(t, 1, imageManager.(*dockerImageManager).GetImageStatesCount())
+	assert.Equal(t, []string{"busybox:1", "busybox:latest"}, sourceImageState.Image.Names)
+	assert.True(t, sourceImageState.GetPullSucceeded())
+	// An image that is already protected for longer keeps its protection
+	assert.Equal(t, protectedUntil, sourceImageState.ProtectedUntil)
+}
+
+func TestRecordPrewarmedImageInspectError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := NewImageManager(defaultTestConfig(), client, dockerstate.NewTaskEngineState())
+	imageManager.SetDataClient(data.NewNoopClient())
+
+	client.EXPECT().InspectImage("busybox").Return(nil, errors.New("inspect error"))
+	assert.Error(t, imageManager.RecordPrewarmedImage("busybox", time.Now().Add(time.Hour)))
+	assert.Equal(t, 0, imageManager.(*dockerImageManager).GetImageStatesCount())
+}
+
+func TestGetCandidateImagesForDeletionImageProtected(t *testing.T) {
+	imageManager := &dockerImageManager{
+		state:                    dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion: config.DefaultImageDeletionAge,
+		numImagesToDelete:        config.DefaultNumImagesToDeletePerCycle,
+		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
+	}
+	imageManager.SetDataClient(data.NewNoopClient())
+
+	protectedImageState := &image.ImageState{
+		Image:          &image.Image{ImageID: "sha256:protected", Names: []string{"protected"}},
+		PulledAt:       time.Now().AddDate(0, -2, 0),
+		ProtectedUntil: time.Now().Add(time.Hour),
+	}
+	expiredImageState := &image.ImageState{
+		Image:          &image.Image{ImageID: "sha256:expired", Names: []string{"expired"}},
+		PulledAt:       time.Now().AddDate(0, -2, 0),
+		ProtectedUntil: time.Now().Add(-time.Hour),
+	}
+	imageManager.addImageState(protectedImageState)
+	imageManager.addImageState(expiredImageState)
+	imageManager.imageStatesConsideredForDeletion = imageManager.imagesConsiderForDeletion(
+		imageManager.getAllImageStates())
+
+	imageStates := imageManager.getCandidateImagesForDeletion()
+	require.Len(t, imageStates, 1)
+	assert.Equal(t, expiredImageState, imageStates[0])
+}
+
+// fakeDiskUsage returns a disk usage func that reports the given percents in turn, and the last
+// one once they're all reported.
+func fakeDiskUsage(t *testing.T, expectedPath string, percents ...uint64) func(string) (diskUsageStats, error) {
+	return func(path string) (diskUsageStats, error) {
+		assert.Equal(t, expectedPath, path)
+		percent := percents[0]
+		if len(percents) > 1 {
+			percents = percents[1:]
+		}
+		return diskUsageStats{used: percent, available: 100 - percent}, nil
+	}
+}
+
+func newDiskPressureTestImageManager(client dockerapi.DockerClient) *dockerImageManager {
+	imageManager := &dockerImageManager{
+		client:                    client,
+		state:                     dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion:  config.DefaultImageDeletionAge,
+		imageCleanupExclusionList: []string{"excluded"},
+		diskHighWatermark:         80,
+		diskLowWatermark:          60,
+		diskPath:                  "/var/lib/docker",
+	}
+	imageManager.SetDataClient(data.NewNoopClient())
+	return imageManager
+}
+
+func TestDiskUsageStatsPercent(t *testing.T) {
+	assert.Equal(t, float64(0), diskUsageStats{}.percent())
+	assert.Equal(t, float64(25), diskUsageStats{used: 1, available: 3}.percent())
+}
+
+func TestSortImagesForDiskPressureCleanup(t *testing.T) {
+	now := time.Now()
+	oldSmall := &image.ImageState{Image: &image.Image{ImageID: "old-small", Size: 1}, LastUsedAt: now.Add(-2 * time.Hour)}
+	oldLarge := &image.ImageState{Image: &image.Image{ImageID: "old-large", Size: 10}, LastUsedAt: now.Add(-2 * time.Hour)}
+	recent := &image.ImageState{Image: &image.Image{ImageID: "recent", Size: 100}, LastUsedAt: now}
+	imageStates := []*image.ImageState{recent, oldSmall, oldLarge}
+
+	sortImagesForDiskPressureCleanup(imageStates)
+	assert.Equal(t, []*image.ImageState{oldLarge, oldSmall, recent}, imageStates)
+}
+
+func TestRemoveImagesUnderDiskPressureBelowHighWatermark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := newDiskPressureTestImageManager(client)
+	imageManager.diskUsage = fakeDiskUsage(t, "/var/lib/docker", 79)
+	imageManager.addImageState(&image.ImageState{
+		Image:    &image.Image{ImageID: "sha256:unused", Names: []string{"unused"}},
+		PulledAt: time.Now().AddDate(0, -2, 0),
+	})
+
+	// No image is expected to be removed
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.Len(t, imageManager.getAllImageStates(), 1)
+}
+
+func TestRemoveImagesUnderDiskPressure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := newDiskPressureTestImageManager(client)
+	// The disk goes from 90% to 70% and then to 55% used as images are removed
+	imageManager.diskUsage = fakeDiskUsage(t, "/var/lib/docker", 90, 70, 55)
+
+	pulledAt := time.Now().AddDate(0, -2, 0)
+	lastUsedAt := time.Now().AddDate(0, -1, 0)
+	smallImageState := &image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:small", Names: []string{"small"}, Size: 1},
+		PulledAt:   pulledAt,
+		LastUsedAt: lastUsedAt,
+	}
+	largeImageState := &image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:large", Names: []string{"large"}, Size: 10},
+		PulledAt:   pulledAt,
+		LastUsedAt: lastUsedAt,
+	}
+	recentImageState := &image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:recent", Names: []string{"recent"}, Size: 100},
+		PulledAt:   pulledAt,
+		LastUsedAt: time.Now(),
+	}
+	excludedImageState := &image.ImageState{
+		Image:    &image.Image{ImageID: "sha256:excluded", Names: []string{"excluded"}, Size: 100},
+		PulledAt: pulledAt,
+	}
+	justPulledImageState := &image.ImageState{
+		Image:    &image.Image{ImageID: "sha256:new", Names: []string{"new"}, Size: 100},
+		PulledAt: time.Now(),
+	}
+	usedImageState := &image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:used", Names: []string{"used"}, Size: 100},
+		Containers: []*apicontainer.Container{{Name: "container"}},
+		PulledAt:   pulledAt,
+	}
+	for _, imageState := range []*image.ImageState{smallImageState, largeImageState, recentImageState,
+		excludedImageState, justPulledImageState, usedImageState} {
+		imageManager.addImageState(imageState)
+	}
+
+	gomock.InOrder(
+		client.EXPECT().RemoveImage(gomock.Any(), "large", dockerclient.RemoveImageTimeout).Return(nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "small", dockerclient.RemoveImageTimeout).Return(nil),
+	)
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+
+	_, ok := imageManager.getImageState("sha256:large")
+	assert.False(t, ok)
+	_, ok = imageManager.getImageState("sha256:small")
+	assert.False(t, ok)
+	assert.Len(t, imageManager.getAllImageStates(), 4)
+}
+
+func TestGetDiskPath(t *testing.T) {
+	cfg := &config.Config{DataDir: "/data/", ImageCleanupDiskHighWatermark: 80}
+	assert.Equal(t, "/data/", getDiskPath(cfg), "the data directory is used when no path is configured")
+	cfg.ImageCleanupDiskPath = "/host/var/lib/docker"
+	assert.Equal(t, "/host/var/lib/docker", getDiskPath(cfg))
+}
+
+func TestRemoveImagesUnderDiskPressureDiskUsageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := newDiskPressureTestImageManager(client)
+	imageManager.diskUsage = func(string) (diskUsageStats, error) {
+		return diskUsageStats{}, errors.New("error")
+	}
+	imageManager.addImageState(&image.ImageState{
+		Image:    &image.Image{ImageID: "sha256:unused", Names: []string{"unused"}},
+		PulledAt: time.Now().AddDate(0, -2, 0),
+	})
+
+	// No image is expected to be removed, and the error is only logged as a warning once
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.True(t, imageManager.diskUsageErrorLogged)
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.Len(t, imageManager.getAllImageStates(), 1)
+}
@@ -44,6 +44,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/execcmd"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
@@ -104,6 +105,10 @@ const (
 
 	defaultMonitorExecAgentsInterval = 15 * time.Minute
 
+	// containerOOMKillsRecordInterval is the interval between reads of the OOM kill counts of
+	// the cgroups of running containers
+	containerOOMKillsRecordInterval = time.Second
+
 	defaultStopContainerBackoffMin = time.Second
 	defaultStopContainerBackoffMax = time.Second * 5
 	stopContainerBackoffJitter     = 0.2
@@ -158,9 +163,15 @@ type DockerTaskEngine struct {
 	_time                               ttime.Time
 	_timeOnce                           sync.Once
 	imageManager                        ImageManager
+	imagePullScheduler                  *imagePullScheduler
+	imagePolicy                         *image.Policy
 	containerStatusToTransitionFunction map[apicontainerstatus.ContainerStatus]transitionApplyFunc
 	metadataManager                     containermetadata.Manager
 
+	// containerTraceContexts maps the containers being transitioned to the contexts that carry
+	// the spans of their transitions
+	containerTraceContexts sync.Map
+
 	// taskSteadyStatePollInterval is the duration that a managed task waits
 	// once the task gets into steady state before polling the state of all of
 	// the task's containers to re-evaluate if the task is still in steady state
@@ -210,6 +221,7 @@ func NewDockerTaskEngine(cfg *config.Config,
 
 		containerChangeEventStream: containerChangeEventStream,
 		imageManager:               imageManager,
+		imagePullScheduler:         newImagePullScheduler(cfg.ImagePullConcurrencyPerRegistry),
 		cniClient:                  ecscni.NewClient(cfg.CNIPluginsPath),
 
 		metadataManager:                   metadataManager,
@@ -275,6 +287,12 @@ func (engine *DockerTaskEngine) Init(ctx context.Context) error {
 	go engine.handleDockerEvents(derivedCtx)
 	engine.initialized = true
 	go engine.startPeriodicExecAgentsMonitoring(derivedCtx)
+	if engine.cfg.TaskCPUMemLimit.Enabled() {
+		go engine.startPeriodicContainerOOMKillsRecording(derivedCtx)
+	}
+	if engine.cfg.SecretRefreshInterval > 0 {
+		go engine.startPeriodicSecretRefresh(derivedCtx)
+	}
 	return nil
 }
 
@@ -291,6 +309,32 @@ func (engine *DockerTaskEngine) startPeriodicExecAgentsMonitoring(ctx context.Co
 	}
 }
 
+// startPeriodicContainerOOMKillsRecording records the OOM kill counts of the cgroups of the
+// running containers of tasks, which may be gone by the time their stop is handled.
+func (engine *DockerTaskEngine) startPeriodicContainerOOMKillsRecording(ctx context.Context) {
+	ticker := time.NewTicker(containerOOMKillsRecordInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.recordContainerOOMKills()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (engine *DockerTaskEngine) recordContainerOOMKills() {
+	engine.tasksLock.RLock()
+	defer engine.tasksLock.RUnlock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.GetKnownStatus() != apitaskstatus.TaskRunning {
+			continue
+		}
+		mTask.recordContainerOOMKills()
+	}
+}
+
 func (engine *DockerTaskEngine) monitorExecAgentProcesses(ctx context.Context) {
 	// TODO: [ecs-exec]add jitter between containers to not overload docker with top calls
 	engine.tasksLock.RLock()
@@ -364,6 +408,12 @@ func (engine *DockerTaskEngine) MustInit(ctx context.Context) {
 	})
 }
 
+// SetImagePolicy sets the policy the images of the containers are verified against before the
+// containers are created.
+func (engine *DockerTaskEngine) SetImagePolicy(policy *image.Policy) {
+	engine.imagePolicy = policy
+}
+
 // SetDataClient sets the saver that is used by the DockerTaskEngine.
 func (engine *DockerTaskEngine) SetDataClient(client data.Client) {
 	engine.dataClient = client
@@ -863,7 +913,6 @@ func (engine *DockerTaskEngine) pullContainer(task *apitask.Task, container *api
 
 	}
 
-	// No pull image is required, the cached image will be used.
 	// Add the container that uses the cached image to the pulled container state.
 	dockerContainer := &apicontainer.DockerContainer{
 		Container: container,
@@ -926,11 +975,13 @@ func (engine *DockerTaskEngine) concurrentPull(task *apitask.Task, container *ap
 	if ok {
 		seelog.Infof("Task engine [%s]: recording timestamp for starting image pulltime: %s",
 			task.Arn, pullStart)
+		engine.recordTimeToPullStart(task, pullStart)
 	}
 	metadata := engine.pullAndUpdateContainerReference(task, container)
 	if metadata.Error == nil {
 		seelog.Infof("Task engine [%s]: finished pulling image %s for container %s in %s",
 			task.Arn, container.Image, container.Name, time.Since(pullStart).String())
+		metrics.MetricsEngineGlobal.RecordTaskLifecyclePhase(metrics.TaskPhasePull, time.Since(pullStart))
 	} else {
 		seelog.Errorf("Task engine [%s]: failed to pull image %s for container %s: %v",
 			task.Arn, container.Image, container.Name, metadata.Error)
@@ -938,6 +989,19 @@ func (engine *DockerTaskEngine) concurrentPull(task *apitask.Task, container *ap
 	return metadata
 }
 
+// recordTimeToPullStart records the time from the task being added to the engine to the start
+// of its first image pull.
+func (engine *DockerTaskEngine) recordTimeToPullStart(task *apitask.Task, pullStart time.Time) {
+	engine.tasksLock.RLock()
+	managedTask, ok := engine.managedTasks[task.Arn]
+	engine.tasksLock.RUnlock()
+	if !ok || managedTask.addedAt.IsZero() {
+		return
+	}
+	metrics.MetricsEngineGlobal.RecordTaskLifecyclePhase(metrics.TaskPhaseAddedToPullStart,
+		pullStart.Sub(managedTask.addedAt))
+}
+
 func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *apitask.Task, container *apicontainer.Container) dockerapi.DockerContainerMetadata {
 	// If a task is blocked here for some time, and before it starts pulling image,
 	// the task's desired status is set to stopped, then don't pull the image
@@ -981,7 +1045,9 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *apitask.Ta
 		defer container.SetASMDockerAuthConfig(types.AuthConfig{})
 	}
 
-	metadata := engine.client.PullImage(engine.ctx, container.Image, container.RegistryAuthentication, engine.cfg.ImagePullTimeout)
+	metadata := engine.imagePullScheduler.pull(engine.ctx, container, func() dockerapi.DockerContainerMetadata {
+		return engine.client.PullImage(engine.containerContext(container), container.Image, container.RegistryAuthentication, engine.cfg.ImagePullTimeout)
+	})
 
 	// Don't add internal images(created by ecs-agent) into imagemanger state
 	if container.IsInternal() {
@@ -1023,6 +1089,26 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *apitask.Ta
 	return metadata
 }
 
+// verifyImagePolicy verifies the image of the container against the image policy and returns the
+// ID of the verified image, which the container must be created from so that the image can't be
+// swapped under its tag after the check.
+func (engine *DockerTaskEngine) verifyImagePolicy(task *apitask.Task, container *apicontainer.Container) (string, apierrors.NamedError) {
+	imageInspected, err := engine.client.InspectImage(container.Image)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to verify image %s against the image policy", container.Image)
+	} else {
+		err = engine.imagePolicy.Verify(container.Image, imageInspected.RepoDigests)
+	}
+	if err != nil {
+		seelog.Errorf("Task engine [%s]: image of container %s violates the image policy: %v",
+			task.Arn, container.Name, err)
+		return "", ImagePolicyViolationError{fromError: err}
+	}
+	seelog.Infof("Task engine [%s]: image %s (%s) of container %s complies with the image policy",
+		task.Arn, container.Image, imageInspected.ID, container.Name)
+	return imageInspected.ID, nil
+}
+
 func (engine *DockerTaskEngine) updateContainerReference(pullSucceeded bool, container *apicontainer.Container, taskArn string) {
 	err := engine.imageManager.RecordContainerReference(container)
 	if err != nil {
@@ -1216,6 +1302,23 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(err)}
 	}
 
+	// Whether the image was pulled, found in the cache or neither, the container is only created
+	// from an image that complies with the image policy
+	if engine.imagePolicy != nil && !container.IsInternal() {
+		imageID, policyErr := engine.verifyImagePolicy(task, container)
+		if policyErr != nil {
+			return dockerapi.DockerContainerMetadata{Error: policyErr}
+		}
+		config.Image = imageID
+	}
+
+	// Write the secrets delivered as files, their owner depends on the user of the container
+	if container.ShouldCreateWithSecretFiles() {
+		if err := task.PopulateSecretFiles(hostConfig, container, config.User, engine.cfg); err != nil {
+			return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(err)}
+		}
+	}
+
 	// Augment labels with some metadata from the agent. Explicitly do this last
 	// such that it will always override duplicates in the provided raw config
 	// data.
@@ -1260,7 +1363,7 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 	}
 
 	createContainerBegin := time.Now()
-	metadata := client.CreateContainer(engine.ctx, config, hostConfig,
+	metadata := client.CreateContainer(engine.containerContext(container), config, hostConfig,
 		dockerContainerName, engine.cfg.ContainerCreateTimeout)
 	if metadata.DockerID != "" {
 		seelog.Infof("Task engine [%s]: created docker container for task: %s -> %s",
@@ -1315,7 +1418,7 @@ func (engine *DockerTaskEngine) startContainer(task *apitask.Task, container *ap
 	}
 
 	startContainerBegin := time.Now()
-	dockerContainerMD := client.StartContainer(engine.ctx, dockerID, engine.cfg.ContainerStartTimeout)
+	dockerContainerMD := client.StartContainer(engine.containerContext(container), dockerID, engine.cfg.ContainerStartTimeout)
 	if dockerContainerMD.Error != nil {
 		return dockerContainerMD
 	}
@@ -1602,18 +1705,19 @@ func (engine *DockerTaskEngine) stopContainer(task *apitask.Task, container *api
 		apiTimeoutStopContainer = engine.cfg.DockerStopTimeout
 	}
 
-	return engine.stopDockerContainer(dockerID, container.Name, apiTimeoutStopContainer)
+	return engine.stopDockerContainer(engine.containerContext(container), dockerID, container.Name, apiTimeoutStopContainer)
 }
 
 // stopDockerContainer attempts to stop the container, retrying only in case of time out errors.
 // If the maximum number of retries is reached, the container is marked as stopped. This is because docker sometimes
 // deadlocks when trying to stop a container but the actual container process is stopped.
 // for more information, see: https://github.com/moby/moby/issues/41587
-func (engine *DockerTaskEngine) stopDockerContainer(dockerID, containerName string, apiTimeoutStopContainer time.Duration) dockerapi.DockerContainerMetadata {
+func (engine *DockerTaskEngine) stopDockerContainer(ctx context.Context, dockerID, containerName string,
+	apiTimeoutStopContainer time.Duration) dockerapi.DockerContainerMetadata {
 	var md dockerapi.DockerContainerMetadata
 	backoff := newExponentialBackoff(engine.stopContainerBackoffMin, engine.stopContainerBackoffMax, stopContainerBackoffJitter, stopContainerBackoffMultiplier)
 	for i := 0; i < stopContainerMaxRetryCount; i++ {
-		md = engine.client.StopContainer(engine.ctx, dockerID, apiTimeoutStopContainer)
+		md = engine.client.StopContainer(ctx, dockerID, apiTimeoutStopContainer)
 		if md.Error == nil {
 			return md
 		}
@@ -1665,8 +1769,9 @@ func (engine *DockerTaskEngine) updateTaskUnsafe(task *apitask.Task, update *api
 	seelog.Debugf("Task engine [%s]: putting update on the acs channel: [%s] with seqnum [%d]",
 		task.Arn, updateDesiredStatus.String(), update.StopSequenceNumber)
 	managedTask.emitACSTransition(acsTransition{
-		desiredStatus: updateDesiredStatus,
-		seqnum:        update.StopSequenceNumber,
+		desiredStatus:  updateDesiredStatus,
+		seqnum:         update.StopSequenceNumber,
+		terminalReason: update.GetTerminalReason(),
 	})
 	seelog.Debugf("Task engine [%s]: update taken off the acs channel: [%s] with seqnum [%d]",
 		task.Arn, updateDesiredStatus.String(), update.StopSequenceNumber)
@@ -1703,13 +1808,21 @@ func (engine *DockerTaskEngine) applyContainerState(task *apitask.Task, containe
 			task.Arn, container.Name, nextState.String())
 		return dockerapi.DockerContainerMetadata{Error: &impossibleTransitionError{nextState}}
 	}
+	transitionStart := ttime.Now()
+	span := engine.startContainerTransitionSpan(task, container, nextState)
 	metadata := transitionFunction(task, container)
+	engine.endContainerTransitionSpan(container, span, metadata)
 	if metadata.Error != nil {
 		seelog.Infof("Task engine [%s]: error transitioning container [%s (Runtime ID: %s)] to [%s]: %v",
 			task.Arn, container.Name, container.GetRuntimeID(), nextState.String(), metadata.Error)
+		metrics.MetricsEngineGlobal.RecordTaskTransitionFailure(nextState.String(), metadata.Error.ErrorName())
 	} else {
 		seelog.Debugf("Task engine [%s]: transitioned container [%s (Runtime ID: %s)] to [%s]",
 			task.Arn, container.Name, container.GetRuntimeID(), nextState.String())
+		// Image pulls are recorded on their own, since most transitions to PULLED don't pull anything
+		if phase, ok := containerTransitionPhases[nextState]; ok {
+			metrics.MetricsEngineGlobal.RecordTaskLifecyclePhase(phase, ttime.Since(transitionStart))
+		}
 	}
 	return metadata
 }
@@ -1721,6 +1834,14 @@ func (engine *DockerTaskEngine) transitionFunctionMap() map[apicontainerstatus.C
 	return engine.containerStatusToTransitionFunction
 }
 
+// containerTransitionPhases maps the container transitions to the task lifecycle phases that
+// they are recorded as.
+var containerTransitionPhases = map[apicontainerstatus.ContainerStatus]string{
+	apicontainerstatus.ContainerCreated: metrics.TaskPhaseCreate,
+	apicontainerstatus.ContainerRunning: metrics.TaskPhaseStart,
+	apicontainerstatus.ContainerStopped: metrics.TaskPhaseStop,
+}
+
 type transitionApplyFunc (func(*apitask.Task, *apicontainer.Container) dockerapi.DockerContainerMetadata)
 
 // State is a function primarily meant for testing usage; it is explicitly not
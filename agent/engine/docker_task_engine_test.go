@@ -54,6 +54,7 @@ import (
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	mock_ssm_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
 	mock_ssmiface "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
@@ -72,6 +73,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -701,6 +703,75 @@ func TestCreateContainerMergesLabels(t *testing.T) {
 	taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
 }
 
+func TestCreateContainerImagePolicy(t *testing.T) {
+	allowedDigest := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	otherDigest := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	imageID := "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	testcases := []struct {
+		name            string
+		imageInspect    *types.ImageInspect
+		imageInspectErr error
+		violation       bool
+	}{
+		{
+			name:         "allowed digest",
+			imageInspect: &types.ImageInspect{ID: imageID, RepoDigests: []string{"busybox@" + allowedDigest}},
+		},
+		{
+			name:         "other digest",
+			imageInspect: &types.ImageInspect{ID: imageID, RepoDigests: []string{"busybox@" + otherDigest}},
+			violation:    true,
+		},
+		{
+			// The pull failed and there's no cached image, there's nothing to verify
+			name:            "no image",
+			imageInspectErr: errors.New("no such image"),
+			violation:       true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			ctrl, client, _, privateTaskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+			defer ctrl.Finish()
+			taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+			taskEngine.SetImagePolicy(&image.Policy{
+				Rules: []*image.PolicyRule{{Repository: "docker.io/library/busybox", AllowedDigests: []string{allowedDigest}}},
+			})
+			imageName := "busybox:latest"
+			testTask := &apitask.Task{
+				Arn: testTaskARN,
+				Containers: []*apicontainer.Container{
+					{
+						Name:  "c1",
+						Type:  apicontainer.ContainerNormal,
+						Image: imageName,
+					},
+				},
+			}
+
+			client.EXPECT().APIVersion().Return(defaultDockerClientAPIVersion, nil).AnyTimes()
+			client.EXPECT().InspectImage(imageName).Return(tc.imageInspect, tc.imageInspectErr)
+			if !tc.violation {
+				// The container is created from the verified image, not from its tag
+				client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+					func(ctx interface{}, config *dockercontainer.Config, hostConfig, name, timeout interface{}) {
+						assert.Equal(t, imageID, config.Image)
+					})
+			}
+			metadata := taskEngine.createContainer(testTask, testTask.Containers[0])
+			if tc.violation {
+				require.Error(t, metadata.Error)
+				assert.Equal(t, ImagePolicyViolationErrorName, metadata.Error.ErrorName())
+			} else {
+				assert.NoError(t, metadata.Error)
+			}
+		})
+	}
+}
+
 // TestCreateContainerAddV3EndpointIDToState tests that in createContainer, when the
 // container's v3 endpoint id is set, we will add mappings to engine state
 func TestCreateContainerAddV3EndpointIDToState(t *testing.T) {
@@ -3577,3 +3648,49 @@ func TestCreateContainerWithExecAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyContainerStateRecordsLifecycleMetrics(t *testing.T) {
+	cfg := defaultConfig
+	cfg.PrometheusMetricsEnabled = true
+	registry := prometheus.NewRegistry()
+	metrics.MustInit(&cfg, registry)
+	defer func() {
+		metrics.MetricsEngineGlobal = &metrics.MetricsEngine{}
+	}()
+
+	taskEngine := &DockerTaskEngine{
+		containerStatusToTransitionFunction: map[apicontainerstatus.ContainerStatus]transitionApplyFunc{
+			apicontainerstatus.ContainerCreated: func(*apitask.Task, *apicontainer.Container) dockerapi.DockerContainerMetadata {
+				return dockerapi.DockerContainerMetadata{}
+			},
+			apicontainerstatus.ContainerRunning: func(*apitask.Task, *apicontainer.Container) dockerapi.DockerContainerMetadata {
+				return dockerapi.DockerContainerMetadata{Error: dockerapi.CannotStartContainerError{}}
+			},
+		},
+	}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c"}
+	taskEngine.applyContainerState(task, container, apicontainerstatus.ContainerCreated)
+	taskEngine.applyContainerState(task, container, apicontainerstatus.ContainerRunning)
+
+	metricFamilies, err := registry.Gather()
+	require.NoError(t, err)
+	phases := make(map[string]uint64)
+	failures := make(map[string]float64)
+	for _, metricFamily := range metricFamilies {
+		for _, metric := range metricFamily.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			switch metricFamily.GetName() {
+			case "AgentMetrics_TaskLifecycle_phase_duration_seconds":
+				phases[labels["Phase"]] = metric.GetHistogram().GetSampleCount()
+			case "AgentMetrics_TaskLifecycle_transition_failure_count":
+				failures[labels["Transition"]+"/"+labels["Reason"]] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{metrics.TaskPhaseCreate: 1}, phases)
+	assert.Equal(t, map[string]float64{"RUNNING/" + dockerapi.CannotStartContainerErrorName: 1}, failures)
+}
@@ -115,7 +115,7 @@ func TestStartStopWithCgroup(t *testing.T) {
 	for _, container := range testTask.Containers {
 		container.TransitionDependenciesMap = make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet)
 	}
-	control := cgroup.New()
+	control := cgroup.New(cfg.CgroupPath, "")
 
 	commonResources := &taskresource.ResourceFieldsCommon{
 		IOUtil: ioutilwrapper.NewIOUtil(),
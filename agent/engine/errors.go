@@ -42,6 +42,15 @@ func (err ContainerVanishedError) Error() string { return "No container matching
 // ErrorName returns the name of the error
 func (err ContainerVanishedError) ErrorName() string { return "ContainerVanishedError" }
 
+// OutOfMemoryKilledByCgroupError is the error of containers killed by the OOM killer of
+// their task cgroup
+type OutOfMemoryKilledByCgroupError struct{}
+
+func (err OutOfMemoryKilledByCgroupError) Error() string { return "killed by cgroup" }
+
+// ErrorName returns the name of the error
+func (err OutOfMemoryKilledByCgroupError) ErrorName() string { return "OutOfMemory" }
+
 // TaskDependencyError is the error for task that dependencies can't
 // be resolved
 type TaskDependencyError struct {
@@ -71,6 +80,23 @@ func (TaskStoppedBeforePullBeginError) ErrorName() string {
 	return "TaskStoppedBeforePullBeginError"
 }
 
+// ImagePolicyViolationErrorName is the name of ImagePolicyViolationError
+const ImagePolicyViolationErrorName = "ImagePolicyViolationError"
+
+// ImagePolicyViolationError is a type for containers whose image isn't allowed by the image policy
+type ImagePolicyViolationError struct {
+	fromError error
+}
+
+func (err ImagePolicyViolationError) Error() string {
+	return err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err ImagePolicyViolationError) ErrorName() string {
+	return ImagePolicyViolationErrorName
+}
+
 // ContainerNetworkingError indicates any error when dealing with the network
 // namespace of container
 type ContainerNetworkingError struct {
@@ -0,0 +1,267 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package image
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PolicyActionAllow lets the images that match no rule of the image policy be used.
+	PolicyActionAllow = "allow"
+	// PolicyActionDeny prevents the images that match no rule of the image policy from being used.
+	PolicyActionDeny = "deny"
+
+	// policyRepositoryWildcard ends the repository patterns that match every nested repository.
+	policyRepositoryWildcard = "/**"
+	// signatureFileExtension is the extension of the detached signatures of the image digests.
+	signatureFileExtension = ".sig"
+)
+
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Policy restricts the images that containers can be created from. Each image is checked against
+// the first rule whose repository pattern matches its repository, and must have been pulled with
+// one of the allowed digests of the rule, or with a digest that has a valid signature from one of
+// the public keys of the rule.
+type Policy struct {
+	// DefaultAction is applied to the images that match no rule, either allow or deny. Images are
+	// allowed when it's not set.
+	DefaultAction string `json:"defaultAction"`
+	// LocalSignaturesDirectory holds the detached signatures of the image digests, supplied on the
+	// instance. The signature of the digest sha256:<hex> is read from the sha256-<hex>.sig file, and
+	// is the base64 encoded signature of the digest string. Signatures attached to the images in the
+	// registry aren't supported.
+	LocalSignaturesDirectory string `json:"localSignaturesDirectory"`
+	// Rules are the rules of the policy, in order of precedence.
+	Rules []*PolicyRule `json:"rules"`
+}
+
+// PolicyRule lists the digests and the signing keys allowed for the repositories that match its
+// pattern.
+type PolicyRule struct {
+	// Repository is the pattern of the fully qualified repositories the rule applies to, such as
+	// docker.io/library/busybox. Patterns follow the syntax of path.Match, and a pattern that ends
+	// with /** matches every nested repository.
+	Repository string `json:"repository"`
+	// AllowedDigests are the digests the images can be pulled with.
+	AllowedDigests []string `json:"allowedDigests"`
+	// PublicKeys are the paths to the PEM encoded public keys that sign the digests of the images.
+	// ECDSA, Ed25519 and RSA keys are supported.
+	PublicKeys []string `json:"publicKeys"`
+
+	publicKeys []crypto.PublicKey
+}
+
+// LoadPolicy reads and validates the image policy at the path, along with the public keys of
+// its rules.
+func LoadPolicy(policyPath string) (*Policy, error) {
+	data, err := ioutil.ReadFile(policyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read image policy")
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal image policy")
+	}
+	switch policy.DefaultAction {
+	case "", PolicyActionAllow, PolicyActionDeny:
+	default:
+		return nil, errors.Errorf("invalid image policy: unknown default action %q", policy.DefaultAction)
+	}
+	for i, rule := range policy.Rules {
+		if rule == nil {
+			return nil, errors.Errorf("invalid image policy: rule %d is empty", i)
+		}
+		if err := rule.load(); err != nil {
+			return nil, errors.Wrapf(err, "invalid image policy: rule %d", i)
+		}
+		if len(rule.publicKeys) > 0 && policy.LocalSignaturesDirectory == "" {
+			return nil, errors.Errorf("invalid image policy: rule %d has public keys but there's no local signatures directory", i)
+		}
+	}
+	return policy, nil
+}
+
+func (rule *PolicyRule) load() error {
+	if rule.Repository == "" {
+		return errors.New("repository pattern is empty")
+	}
+	if _, err := path.Match(rule.Repository, ""); err != nil {
+		return errors.Wrapf(err, "invalid repository pattern %q", rule.Repository)
+	}
+	if len(rule.AllowedDigests) == 0 && len(rule.PublicKeys) == 0 {
+		return errors.New("neither allowed digests nor public keys")
+	}
+	for _, digest := range rule.AllowedDigests {
+		if !digestRegexp.MatchString(digest) {
+			return errors.Errorf("invalid digest %q", digest)
+		}
+	}
+	for _, keyPath := range rule.PublicKeys {
+		publicKey, err := loadPublicKey(keyPath)
+		if err != nil {
+			return err
+		}
+		rule.publicKeys = append(rule.publicKeys, publicKey)
+	}
+	return nil
+}
+
+func loadPublicKey(keyPath string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read public key")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("public key %s is not PEM encoded", keyPath)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse public key %s", keyPath)
+	}
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return publicKey, nil
+	default:
+		return nil, errors.Errorf("public key %s is of an unsupported type %T", keyPath, publicKey)
+	}
+}
+
+// matches returns whether the rule applies to the fully qualified repository.
+func (rule *PolicyRule) matches(repository string) bool {
+	if strings.HasSuffix(rule.Repository, policyRepositoryWildcard) {
+		return strings.HasPrefix(repository, strings.TrimSuffix(rule.Repository, "**"))
+	}
+	matched, _ := path.Match(rule.Repository, repository)
+	return matched
+}
+
+// Verify checks the image, given the repo digests docker reports for it, against the policy. It
+// returns an error describing the violation if the image can't be used.
+func (policy *Policy) Verify(imageName string, repoDigests []string) error {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return errors.Wrapf(err, "invalid image reference %s", imageName)
+	}
+	repository := named.Name()
+	var rule *PolicyRule
+	for _, candidate := range policy.Rules {
+		if candidate.matches(repository) {
+			rule = candidate
+			break
+		}
+	}
+	if rule == nil {
+		if policy.DefaultAction == PolicyActionDeny {
+			return errors.Errorf("repository %s of image %s matches no rule of the image policy", repository, imageName)
+		}
+		return nil
+	}
+
+	digests := repositoryDigests(repository, repoDigests)
+	if len(digests) == 0 {
+		return errors.Errorf("unable to resolve the digest of image %s", imageName)
+	}
+	for _, digest := range digests {
+		if rule.allowsDigest(digest) {
+			return nil
+		}
+		if len(rule.publicKeys) > 0 && policy.verifySignature(rule, digest) == nil {
+			return nil
+		}
+	}
+	if len(rule.publicKeys) == 0 {
+		return errors.Errorf("image %s resolved to %s, which is not an allowed digest of repositories %s",
+			imageName, strings.Join(digests, ", "), rule.Repository)
+	}
+	return errors.Errorf("image %s resolved to %s, which is neither an allowed digest nor signed by a trusted key of repositories %s",
+		imageName, strings.Join(digests, ", "), rule.Repository)
+}
+
+// repositoryDigests returns the digests among the repo digests that belong to the repository.
+func repositoryDigests(repository string, repoDigests []string) []string {
+	var digests []string
+	for _, repoDigest := range repoDigests {
+		named, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		canonical, ok := named.(reference.Canonical)
+		if !ok || canonical.Name() != repository {
+			continue
+		}
+		digests = append(digests, canonical.Digest().String())
+	}
+	return digests
+}
+
+func (rule *PolicyRule) allowsDigest(digest string) bool {
+	for _, allowedDigest := range rule.AllowedDigests {
+		if allowedDigest == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature checks the locally supplied signature of the digest against the public keys of the rule.
+func (policy *Policy) verifySignature(rule *PolicyRule, digest string) error {
+	signaturePath := filepath.Join(policy.LocalSignaturesDirectory, strings.Replace(digest, ":", "-", 1)+signatureFileExtension)
+	data, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read signature")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode signature %s", signaturePath)
+	}
+	for _, publicKey := range rule.publicKeys {
+		if verifyDigestSignature(publicKey, digest, signature) {
+			return nil
+		}
+	}
+	return errors.Errorf("signature %s doesn't match any trusted key", signaturePath)
+}
+
+func verifyDigestSignature(publicKey crypto.PublicKey, digest string, signature []byte) bool {
+	payload := []byte(digest)
+	hash := sha256.Sum256(payload)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package image
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDigest      = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	otherTestDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	testRepository  = "123456789012.dkr.ecr.us-west-2.amazonaws.com/payments/api"
+)
+
+// writePublicKey writes the PEM encoded public key to the directory and returns its path.
+func writePublicKey(t *testing.T, dir, name string, publicKey crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, name+".pem")
+	require.NoError(t, ioutil.WriteFile(keyPath,
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	return keyPath
+}
+
+// writeSignature writes the signature of the digest to the local signatures directory.
+func writeSignature(t *testing.T, dir, digest string, signature []byte) {
+	signaturePath := filepath.Join(dir, strings.Replace(digest, ":", "-", 1)+".sig")
+	require.NoError(t, ioutil.WriteFile(signaturePath,
+		[]byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644))
+}
+
+// writePolicy writes the policy to the directory and loads it.
+func writePolicy(t *testing.T, dir string, policy *Policy) (*Policy, error) {
+	data, err := json.Marshal(policy)
+	require.NoError(t, err)
+	policyPath := filepath.Join(dir, "image-policy.json")
+	require.NoError(t, ioutil.WriteFile(policyPath, data, 0644))
+	return LoadPolicy(policyPath)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "image_policy_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyPath := writePublicKey(t, testDir, "key", ed25519Key.Public())
+	notAKeyPath := filepath.Join(testDir, "not-a-key.pem")
+	require.NoError(t, ioutil.WriteFile(notAKeyPath, []byte("not a key"), 0644))
+
+	testCases := []struct {
+		name   string
+		policy *Policy
+		valid  bool
+	}{
+		{
+			name: "valid policy",
+			policy: &Policy{
+				DefaultAction:            PolicyActionDeny,
+				LocalSignaturesDirectory: testDir,
+				Rules: []*PolicyRule{
+					{Repository: testRepository, AllowedDigests: []string{testDigest}},
+					{Repository: "docker.io/library/*", PublicKeys: []string{keyPath}},
+				},
+			},
+			valid: true,
+		},
+		{
+			name:   "unknown default action",
+			policy: &Policy{DefaultAction: "maybe"},
+		},
+		{
+			name:   "empty repository pattern",
+			policy: &Policy{Rules: []*PolicyRule{{AllowedDigests: []string{testDigest}}}},
+		},
+		{
+			name:   "invalid repository pattern",
+			policy: &Policy{Rules: []*PolicyRule{{Repository: "[", AllowedDigests: []string{testDigest}}}},
+		},
+		{
+			name:   "rule without digests or keys",
+			policy: &Policy{Rules: []*PolicyRule{{Repository: testRepository}}},
+		},
+		{
+			name:   "invalid digest",
+			policy: &Policy{Rules: []*PolicyRule{{Repository: testRepository, AllowedDigests: []string{"sha256:abc"}}}},
+		},
+		{
+			name: "invalid public key",
+			policy: &Policy{
+				LocalSignaturesDirectory: testDir,
+				Rules:                    []*PolicyRule{{Repository: testRepository, PublicKeys: []string{notAKeyPath}}},
+			},
+		},
+		{
+			name:   "public keys without local signatures directory",
+			policy: &Policy{Rules: []*PolicyRule{{Repository: testRepository, PublicKeys: []string{keyPath}}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := writePolicy(t, testDir, tc.policy)
+			if tc.valid {
+				require.NoError(t, err)
+				assert.Len(t, policy.Rules, 2)
+				assert.Len(t, policy.Rules[1].publicKeys, 1)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyMissingFile(t *testing.T) {
+	_, err := LoadPolicy(filepath.Join(os.TempDir(), "image_policy_test_missing.json"))
+	assert.Error(t, err)
+}
+
+func TestPolicyVerifyAllowedDigests(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "image_policy_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	policy, err := writePolicy(t, testDir, &Policy{
+		Rules: []*PolicyRule{
+			{Repository: "123456789012.dkr.ecr.us-west-2.amazonaws.com/payments/**", AllowedDigests: []string{testDigest}},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		image       string
+		repoDigests []string
+		allowed     bool
+	}{
+		{
+			name:        "allowed digest",
+			image:       testRepository + ":latest",
+			repoDigests: []string{testRepository + "@" + testDigest},
+			allowed:     true,
+		},
+		{
+			name:        "other digest",
+			image:       testRepository + ":latest",
+			repoDigests: []string{testRepository + "@" + otherTestDigest},
+		},
+		{
+			name:        "allowed digest of another repository",
+			image:       testRepository + ":latest",
+			repoDigests: []string{"123456789012.dkr.ecr.us-west-2.amazonaws.com/other@" + testDigest},
+		},
+		{
+			name:  "no repo digest",
+			image: testRepository + ":latest",
+		},
+		{
+			name:    "repository without rule",
+			image:   "busybox:latest",
+			allowed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Verify(tc.image, tc.repoDigests)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyVerifyDefaultActionDeny(t *testing.T) {
+	policy := &Policy{
+		DefaultAction: PolicyActionDeny,
+		Rules:         []*PolicyRule{{Repository: "docker.io/library/*", AllowedDigests: []string{testDigest}}},
+	}
+	assert.NoError(t, policy.Verify("busybox", []string{"busybox@" + testDigest}))
+	assert.Error(t, policy.Verify("amazon/amazon-ecs-agent", []string{"amazon/amazon-ecs-agent@" + testDigest}))
+}
+
+func TestPolicyVerifySignatures(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "image_policy_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	signaturesDir := filepath.Join(testDir, "signatures")
+	require.NoError(t, os.Mkdir(signaturesDir, 0755))
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ed25519PublicKey, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	untrustedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	hash := sha256.Sum256([]byte(testDigest))
+	testCases := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		sign      func() ([]byte, error)
+		valid     bool
+	}{
+		{
+			name:      "ecdsa",
+			publicKey: &ecdsaKey.PublicKey,
+			sign:      func() ([]byte, error) { return ecdsa.SignASN1(rand.Reader, ecdsaKey, hash[:]) },
+			valid:     true,
+		},
+		{
+			name:      "ed25519",
+			publicKey: ed25519PublicKey,
+			sign:      func() ([]byte, error) { return ed25519.Sign(ed25519Key, []byte(testDigest)), nil },
+			valid:     true,
+		},
+		{
+			name:      "rsa",
+			publicKey: &rsaKey.PublicKey,
+			sign:      func() ([]byte, error) { return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:]) },
+			valid:     true,
+		},
+		{
+			name:      "untrusted key",
+			publicKey: &ecdsaKey.PublicKey,
+			sign:      func() ([]byte, error) { return ecdsa.SignASN1(rand.Reader, untrustedKey, hash[:]) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyPath := writePublicKey(t, testDir, tc.name, tc.publicKey)
+			policy, err := writePolicy(t, testDir, &Policy{
+				LocalSignaturesDirectory: signaturesDir,
+				Rules:                    []*PolicyRule{{Repository: testRepository, PublicKeys: []string{keyPath}}},
+			})
+			require.NoError(t, err)
+			signature, err := tc.sign()
+			require.NoError(t, err)
+			writeSignature(t, signaturesDir, testDigest, signature)
+
+			err = policy.Verify(testRepository+":latest", []string{testRepository + "@" + testDigest})
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyVerifyMissingSignature(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "image_policy_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyPath := writePublicKey(t, testDir, "key", ed25519Key.Public())
+	policy, err := writePolicy(t, testDir, &Policy{
+		LocalSignaturesDirectory: testDir,
+		Rules: []*PolicyRule{
+			{Repository: testRepository, AllowedDigests: []string{otherTestDigest}, PublicKeys: []string{keyPath}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, policy.Verify(testRepository, []string{testRepository + "@" + testDigest}))
+	// Allowed digests don't need a signature
+	assert.NoError(t, policy.Verify(testRepository, []string{testRepository + "@" + otherTestDigest}))
+}
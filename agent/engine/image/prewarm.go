@@ -0,0 +1,126 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package image
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PrewarmPending is the status of an image of the image pre-warm manifest that isn't pulled yet.
+	PrewarmPending = "PENDING"
+	// PrewarmPulling is the status of an image of the image pre-warm manifest that is being pulled.
+	PrewarmPulling = "PULLING"
+	// PrewarmPulled is the status of an image of the image pre-warm manifest that was pulled.
+	PrewarmPulled = "PULLED"
+	// PrewarmFailed is the status of an image of the image pre-warm manifest that couldn't be pulled.
+	PrewarmFailed = "FAILED"
+)
+
+// PrewarmManifest lists the images that are pulled ahead of the tasks that use them.
+type PrewarmManifest struct {
+	Images []*PrewarmImage `json:"images"`
+}
+
+// PrewarmImage is an image of the image pre-warm manifest.
+type PrewarmImage struct {
+	// Image is the name of the image, as it's referenced by the containers that use it.
+	Image string `json:"image"`
+	// Platform is the platform of the image to pull, in the os[/arch[/variant]] format. The platform of
+	// the instance is pulled when it's empty.
+	Platform string `json:"platform,omitempty"`
+	// RegistryAuthentication is the data used to authenticate with the registry of the image. Only ECR
+	// authentication with the instance credentials is supported, as there is no task to get other
+	// credentials from.
+	RegistryAuthentication *apicontainer.RegistryAuthenticationData `json:"registryAuthentication,omitempty"`
+}
+
+// LoadPrewarmManifest reads and validates the image pre-warm manifest at the given path.
+func LoadPrewarmManifest(path string) (*PrewarmManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read image pre-warm manifest")
+	}
+	manifest := &PrewarmManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal image pre-warm manifest")
+	}
+	for i, prewarmImage := range manifest.Images {
+		if prewarmImage == nil {
+			return nil, errors.Errorf("invalid image pre-warm manifest: image %d is empty", i)
+		}
+		if err := prewarmImage.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid image pre-warm manifest: image %d", i)
+		}
+	}
+	return manifest, nil
+}
+
+func (prewarmImage *PrewarmImage) validate() error {
+	if strings.TrimSpace(prewarmImage.Image) == "" {
+		return errors.New("image name is empty")
+	}
+	if prewarmImage.Platform != "" {
+		parts := strings.Split(prewarmImage.Platform, "/")
+		if len(parts) > 3 {
+			return errors.Errorf("platform %q is not in the os[/arch[/variant]] format", prewarmImage.Platform)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return errors.Errorf("platform %q is not in the os[/arch[/variant]] format", prewarmImage.Platform)
+			}
+		}
+	}
+	auth := prewarmImage.RegistryAuthentication
+	if auth == nil {
+		return nil
+	}
+	if auth.Type != apicontainer.AuthTypeECR || auth.ECRAuthData == nil {
+		return errors.Errorf("registry authentication of type %q is not supported, only ecr is", auth.Type)
+	}
+	if auth.ECRAuthData.UseExecutionRole {
+		return errors.New("ecr authentication can't use an execution role")
+	}
+	return nil
+}
+
+// PrewarmStatus reports the progress of image pre-warming.
+type PrewarmStatus struct {
+	// Manifest is the path to the image pre-warm manifest.
+	Manifest string
+	// Error is set when the manifest couldn't be loaded.
+	Error string `json:",omitempty"`
+	// Done is set once every image of the manifest was either pulled or failed to be pulled.
+	Done bool
+	// Images reports the progress of the pull of every image of the manifest, in the manifest order.
+	Images []PrewarmImageStatus
+}
+
+// PrewarmImageStatus reports the progress of the pull of an image of the image pre-warm manifest.
+type PrewarmImageStatus struct {
+	Image          string
+	Platform       string `json:",omitempty"`
+	Status         string
+	Error          string     `json:",omitempty"`
+	PullStartedAt  *time.Time `json:",omitempty"`
+	PullStoppedAt  *time.Time `json:",omitempty"`
+	ProtectedUntil *time.Time `json:",omitempty"`
+}
@@ -0,0 +1,94 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPrewarmManifest(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "prewarm_manifest_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	path := filepath.Join(testDir, "image-prewarm.json")
+
+	testCases := []struct {
+		name     string
+		manifest string
+		valid    bool
+	}{
+		{"image", `{"images": [{"image": "busybox"}]}`, true},
+		{"platform", `{"images": [{"image": "busybox", "platform": "linux/arm64/v8"}]}`, true},
+		{"ecr auth", `{"images": [{"image": "busybox", "registryAuthentication": ` +
+			`{"type": "ecr", "ecrAuthData": {"region": "us-west-2", "registryId": "123456789012"}}}]}`, true},
+		{"no images", `{}`, true},
+		{"invalid json", `{"images": `, false},
+		{"empty image", `{"images": [null]}`, false},
+		{"no image name", `{"images": [{"platform": "linux"}]}`, false},
+		{"invalid platform", `{"images": [{"image": "busybox", "platform": "linux//"}]}`, false},
+		{"platform too long", `{"images": [{"image": "busybox", "platform": "linux/arm64/v8/x"}]}`, false},
+		{"asm auth", `{"images": [{"image": "busybox", "registryAuthentication": ` +
+			`{"type": "asm", "asmAuthData": {"credentialsParameter": "secret"}}}]}`, false},
+		{"ecr auth without data", `{"images": [{"image": "busybox", "registryAuthentication": {"type": "ecr"}}]}`, false},
+		{"execution role", `{"images": [{"image": "busybox", "registryAuthentication": ` +
+			`{"type": "ecr", "ecrAuthData": {"region": "us-west-2", "useExecutionRole": true}}}]}`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.manifest), 0600))
+			manifest, err := LoadPrewarmManifest(path)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, manifest)
+		})
+	}
+}
+
+func TestLoadPrewarmManifestECRAuth(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "prewarm_manifest_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+	path := filepath.Join(testDir, "image-prewarm.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"images": [{"image": "busybox", "platform": "linux/amd64", `+
+		`"registryAuthentication": {"type": "ecr", "ecrAuthData": {"region": "us-west-2", "registryId": "1234"}}}]}`),
+		0600))
+
+	manifest, err := LoadPrewarmManifest(path)
+	require.NoError(t, err)
+	require.Len(t, manifest.Images, 1)
+	assert.Equal(t, "busybox", manifest.Images[0].Image)
+	assert.Equal(t, "linux/amd64", manifest.Images[0].Platform)
+	require.NotNil(t, manifest.Images[0].RegistryAuthentication)
+	assert.Equal(t, apicontainer.AuthTypeECR, manifest.Images[0].RegistryAuthentication.Type)
+	assert.Equal(t, "1234", manifest.Images[0].RegistryAuthentication.ECRAuthData.RegistryID)
+	assert.Equal(t, "us-west-2", manifest.Images[0].RegistryAuthentication.ECRAuthData.Region)
+}
+
+func TestLoadPrewarmManifestMissingFile(t *testing.T) {
+	_, err := LoadPrewarmManifest(filepath.Join(os.TempDir(), "does-not-exist", "image-prewarm.json"))
+	assert.Error(t, err)
+}
@@ -48,7 +48,10 @@ type ImageState struct {
 	// PullSucceeded defines whether this image has been pulled successfully before,
 	// this should be set to true when one of the pull image call succeeds.
 	PullSucceeded bool
-	lock          sync.RWMutex
+	// ProtectedUntil is the time until which this image is not deleted by automated image
+	// cleanup, even if it's not used by any container.
+	ProtectedUntil time.Time
+	lock           sync.RWMutex
 }
 
 // UpdateContainerReference updates container reference in image state
@@ -151,21 +154,42 @@ func (imageState *ImageState) GetPullSucceeded() bool {
 	return imageState.PullSucceeded
 }
 
+// SetProtectedUntil sets the time until which the image is protected from automated image cleanup,
+// unless it's already protected for longer.
+func (imageState *ImageState) SetProtectedUntil(protectedUntil time.Time) {
+	imageState.lock.Lock()
+	defer imageState.lock.Unlock()
+
+	if protectedUntil.After(imageState.ProtectedUntil) {
+		imageState.ProtectedUntil = protectedUntil
+	}
+}
+
+// IsProtected returns true if the image is protected from automated image cleanup at the given time.
+func (imageState *ImageState) IsProtected(now time.Time) bool {
+	imageState.lock.RLock()
+	defer imageState.lock.RUnlock()
+
+	return now.Before(imageState.ProtectedUntil)
+}
+
 // MarshalJSON marshals image state
 func (imageState *ImageState) MarshalJSON() ([]byte, error) {
 	imageState.lock.Lock()
 	defer imageState.lock.Unlock()
 
 	return json.Marshal(&struct {
-		Image         *Image
-		PulledAt      time.Time
-		LastUsedAt    time.Time
-		PullSucceeded bool
+		Image          *Image
+		PulledAt       time.Time
+		LastUsedAt     time.Time
+		PullSucceeded  bool
+		ProtectedUntil time.Time
 	}{
-		Image:         imageState.Image,
-		PulledAt:      imageState.PulledAt,
-		LastUsedAt:    imageState.LastUsedAt,
-		PullSucceeded: imageState.PullSucceeded,
+		Image:          imageState.Image,
+		PulledAt:       imageState.PulledAt,
+		LastUsedAt:     imageState.LastUsedAt,
+		PullSucceeded:  imageState.PullSucceeded,
+		ProtectedUntil: imageState.ProtectedUntil,
 	})
 }
 
@@ -0,0 +1,151 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/cihub/seelog"
+)
+
+// ImagePrewarmer pulls the images of the image pre-warm manifest in the background, so that the first
+// tasks placed on the instance don't wait for them. The images are recorded in the image manager, which
+// protects them from cleanup for the configured period.
+type ImagePrewarmer struct {
+	manifestPath     string
+	protectionPeriod time.Duration
+	pullTimeout      time.Duration
+	client           dockerapi.DockerClient
+	imageManager     ImageManager
+	lock             sync.RWMutex
+	status           image.PrewarmStatus
+}
+
+// NewImagePrewarmer returns a new ImagePrewarmer for the image pre-warm manifest of the config
+func NewImagePrewarmer(cfg *config.Config, client dockerapi.DockerClient, imageManager ImageManager) *ImagePrewarmer {
+	return &ImagePrewarmer{
+		manifestPath:     cfg.ImagePrewarmManifest,
+		protectionPeriod: cfg.ImagePrewarmProtectionPeriod,
+		pullTimeout:      cfg.ImagePullTimeout,
+		client:           client,
+		imageManager:     imageManager,
+		status: image.PrewarmStatus{
+			Manifest: cfg.ImagePrewarmManifest,
+		},
+	}
+}
+
+// Start loads the image pre-warm manifest and pulls its images one at a time, until they have all been
+// pulled or the context is canceled. Images that fail to be pulled are not retried; they are pulled
+// again when a task uses them.
+func (prewarmer *ImagePrewarmer) Start(ctx context.Context) {
+	manifest, err := image.LoadPrewarmManifest(prewarmer.manifestPath)
+	if err != nil {
+		seelog.Errorf("Image prewarmer: unable to load manifest %s: %v", prewarmer.manifestPath, err)
+		prewarmer.lock.Lock()
+		prewarmer.status.Error = err.Error()
+		prewarmer.status.Done = true
+		prewarmer.lock.Unlock()
+		return
+	}
+
+	prewarmer.lock.Lock()
+	for _, prewarmImage := range manifest.Images {
+		prewarmer.status.Images = append(prewarmer.status.Images, image.PrewarmImageStatus{
+			Image:    prewarmImage.Image,
+			Platform: prewarmImage.Platform,
+			Status:   image.PrewarmPending,
+		})
+	}
+	prewarmer.lock.Unlock()
+
+	seelog.Infof("Image prewarmer: pulling %d images of manifest %s", len(manifest.Images), prewarmer.manifestPath)
+	for i, prewarmImage := range manifest.Images {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		prewarmer.pull(ctx, i, prewarmImage)
+	}
+
+	prewarmer.lock.Lock()
+	prewarmer.status.Done = true
+	prewarmer.lock.Unlock()
+	seelog.Infof("Image prewarmer: done with manifest %s", prewarmer.manifestPath)
+}
+
+func (prewarmer *ImagePrewarmer) pull(ctx context.Context, index int, prewarmImage *image.PrewarmImage) {
+	pullStartedAt := time.Now()
+	prewarmer.updateImageStatus(index, func(status *image.PrewarmImageStatus) {
+		status.Status = image.PrewarmPulling
+		status.PullStartedAt = &pullStartedAt
+	})
+
+	// Pulls must not run while images are being deleted, and the image is recorded before the lock is
+	// released so that it can't be deleted before it's protected
+	ImagePullDeleteLock.RLock()
+	defer ImagePullDeleteLock.RUnlock()
+
+	seelog.Infof("Image prewarmer: pulling image %s", prewarmImage.Image)
+	metadata := prewarmer.client.PullImageForPlatform(ctx, prewarmImage.Image, prewarmImage.Platform,
+		prewarmImage.RegistryAuthentication, prewarmer.pullTimeout)
+	var err error
+	if metadata.Error != nil {
+		err = metadata.Error
+	}
+	var protectedUntil time.Time
+	if err == nil {
+		protectedUntil = time.Now().Add(prewarmer.protectionPeriod)
+		err = prewarmer.imageManager.RecordPrewarmedImage(prewarmImage.Image, protectedUntil)
+	}
+
+	pullStoppedAt := time.Now()
+	prewarmer.updateImageStatus(index, func(status *image.PrewarmImageStatus) {
+		status.PullStoppedAt = &pullStoppedAt
+		if err != nil {
+			status.Status = image.PrewarmFailed
+			status.Error = err.Error()
+			return
+		}
+		status.Status = image.PrewarmPulled
+		status.ProtectedUntil = &protectedUntil
+	})
+	if err != nil {
+		seelog.Errorf("Image prewarmer: failed to pull image %s: %v", prewarmImage.Image, err)
+		return
+	}
+	seelog.Infof("Image prewarmer: finished pulling image %s in %s", prewarmImage.Image,
+		pullStoppedAt.Sub(pullStartedAt).String())
+}
+
+func (prewarmer *ImagePrewarmer) updateImageStatus(index int, update func(status *image.PrewarmImageStatus)) {
+	prewarmer.lock.Lock()
+	defer prewarmer.lock.Unlock()
+	update(&prewarmer.status.Images[index])
+}
+
+// Status returns the progress of image pre-warming.
+func (prewarmer *ImagePrewarmer) Status() image.PrewarmStatus {
+	prewarmer.lock.RLock()
+	defer prewarmer.lock.RUnlock()
+	status := prewarmer.status
+	status.Images = append([]image.PrewarmImageStatus(nil), prewarmer.status.Images...)
+	return status
+}
@@ -0,0 +1,159 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testImagePrewarmManifest = `{"images": [
+	{"image": "busybox"},
+	{"image": "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:latest", "platform": "linux/arm64",
+	 "registryAuthentication": {"type": "ecr", "ecrAuthData": {"region": "us-west-2", "registryId": "123456789012"}}}
+]}`
+
+func writeTestImagePrewarmManifest(t *testing.T, manifest string) (*config.Config, func()) {
+	testDir, err := ioutil.TempDir("", "image_prewarmer_test")
+	require.NoError(t, err)
+	path := filepath.Join(testDir, "image-prewarm.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(manifest), 0600))
+	cfg := &config.Config{
+		ImagePrewarmManifest:         path,
+		ImagePrewarmProtectionPeriod: time.Hour,
+		ImagePullTimeout:             config.DefaultImagePullTimeout,
+	}
+	return cfg, func() {
+		require.NoError(t, os.RemoveAll(testDir))
+	}
+}
+
+func TestImagePrewarmer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := mock_engine.NewMockImageManager(ctrl)
+	cfg, cleanup := writeTestImagePrewarmManifest(t, testImagePrewarmManifest)
+	defer cleanup()
+
+	ecrImage := "123456789012.dkr.ecr.us-west-2.amazonaws.com/app:latest"
+	gomock.InOrder(
+		client.EXPECT().PullImageForPlatform(gomock.Any(), "busybox", "", nil, config.DefaultImagePullTimeout).
+			Return(dockerapi.DockerContainerMetadata{}),
+		imageManager.EXPECT().RecordPrewarmedImage("busybox", gomock.Any()).Do(
+			func(imageName string, protectedUntil time.Time) {
+				assert.WithinDuration(t, time.Now().Add(time.Hour), protectedUntil, time.Minute)
+			}).Return(nil),
+		client.EXPECT().PullImageForPlatform(gomock.Any(), ecrImage, "linux/arm64", gomock.Any(),
+			config.DefaultImagePullTimeout).Do(
+			func(ctx context.Context, image, platform string, authData *apicontainer.RegistryAuthenticationData,
+				timeout time.Duration) {
+				require.NotNil(t, authData)
+				assert.Equal(t, apicontainer.AuthTypeECR, authData.Type)
+				assert.Equal(t, "123456789012", authData.ECRAuthData.RegistryID)
+				assert.Equal(t, "us-west-2", authData.ECRAuthData.Region)
+			}).Return(dockerapi.DockerContainerMetadata{
+			Error: dockerapi.CannotPullContainerError{FromError: errors.New("pull error")},
+		}),
+	)
+
+	prewarmer := NewImagePrewarmer(cfg, client, imageManager)
+	prewarmer.Start(context.TODO())
+
+	status := prewarmer.Status()
+	assert.True(t, status.Done)
+	assert.Empty(t, status.Error)
+	require.Len(t, status.Images, 2)
+	assert.Equal(t, "busybox", status.Images[0].Image)
+	assert.Equal(t, image.PrewarmPulled, status.Images[0].Status)
+	assert.NotNil(t, status.Images[0].PullStartedAt)
+	assert.NotNil(t, status.Images[0].PullStoppedAt)
+	assert.NotNil(t, status.Images[0].ProtectedUntil)
+	assert.Equal(t, ecrImage, status.Images[1].Image)
+	assert.Equal(t, "linux/arm64", status.Images[1].Platform)
+	assert.Equal(t, image.PrewarmFailed, status.Images[1].Status)
+	assert.Contains(t, status.Images[1].Error, "pull error")
+	assert.Nil(t, status.Images[1].ProtectedUntil)
+}
+
+func TestImagePrewarmerRecordFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := mock_engine.NewMockImageManager(ctrl)
+	cfg, cleanup := writeTestImagePrewarmManifest(t, `{"images": [{"image": "busybox"}]}`)
+	defer cleanup()
+
+	client.EXPECT().PullImageForPlatform(gomock.Any(), "busybox", "", nil, gomock.Any()).
+		Return(dockerapi.DockerContainerMetadata{})
+	imageManager.EXPECT().RecordPrewarmedImage("busybox", gomock.Any()).Return(errors.New("inspect error"))
+
+	prewarmer := NewImagePrewarmer(cfg, client, imageManager)
+	prewarmer.Start(context.TODO())
+
+	status := prewarmer.Status()
+	require.Len(t, status.Images, 1)
+	assert.Equal(t, image.PrewarmFailed, status.Images[0].Status)
+	assert.Equal(t, "inspect error", status.Images[0].Error)
+}
+
+func TestImagePrewarmerInvalidManifest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg, cleanup := writeTestImagePrewarmManifest(t, `{"images": [{"platform": "linux"}]}`)
+	defer cleanup()
+
+	prewarmer := NewImagePrewarmer(cfg, mock_dockerapi.NewMockDockerClient(ctrl), mock_engine.NewMockImageManager(ctrl))
+	prewarmer.Start(context.TODO())
+
+	status := prewarmer.Status()
+	assert.True(t, status.Done)
+	assert.Contains(t, status.Error, "image name is empty")
+	assert.Empty(t, status.Images)
+}
+
+func TestImagePrewarmerCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg, cleanup := writeTestImagePrewarmManifest(t, testImagePrewarmManifest)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	prewarmer := NewImagePrewarmer(cfg, mock_dockerapi.NewMockDockerClient(ctrl), mock_engine.NewMockImageManager(ctrl))
+	prewarmer.Start(ctx)
+
+	status := prewarmer.Status()
+	assert.False(t, status.Done)
+	require.Len(t, status.Images, 2)
+	assert.Equal(t, image.PrewarmPending, status.Images[0].Status)
+}
@@ -0,0 +1,219 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+
+	"github.com/cihub/seelog"
+	"github.com/docker/distribution/reference"
+)
+
+// imagePullScheduler schedules the image pulls of the task engine. Concurrent pulls of the same
+// image with the same credentials are run only once, and the number of images pulled from the
+// same registry at the same time can be limited. Pulls waiting for a slot are started in order,
+// images of essential containers first.
+type imagePullScheduler struct {
+	maxConcurrentPullsPerRegistry int
+	lock                          sync.Mutex
+	// pulls holds the scheduled pulls by key
+	pulls map[string]*scheduledImagePull
+	// registries holds the running and waiting pulls by registry host
+	registries map[string]*registryImagePulls
+}
+
+// scheduledImagePull is a pull that is either running or waiting for a slot. Its metadata is set
+// before done is closed.
+type scheduledImagePull struct {
+	key       string
+	registry  string
+	essential bool
+	start     chan struct{}
+	done      chan struct{}
+	metadata  dockerapi.DockerContainerMetadata
+}
+
+type registryImagePulls struct {
+	running int
+	waiting []*scheduledImagePull
+}
+
+func newImagePullScheduler(maxConcurrentPullsPerRegistry int) *imagePullScheduler {
+	return &imagePullScheduler{
+		maxConcurrentPullsPerRegistry: maxConcurrentPullsPerRegistry,
+		pulls:                         make(map[string]*scheduledImagePull),
+		registries:                    make(map[string]*registryImagePulls),
+	}
+}
+
+// pull pulls the image of the container with the pull function once the registry of the image has
+// a slot available. If the same image is already being pulled with the same credentials, it waits
+// for that pull to finish instead and returns its result.
+func (scheduler *imagePullScheduler) pull(ctx context.Context, container *apicontainer.Container,
+	pullImage func() dockerapi.DockerContainerMetadata) dockerapi.DockerContainerMetadata {
+	key := imagePullKey(container)
+	registry := imageRegistryHost(container.Image)
+
+	scheduler.lock.Lock()
+	if pull, ok := scheduler.pulls[key]; ok {
+		// A waiting pull gets the priority of the most important container that needs it
+		if container.IsEssential() {
+			pull.essential = true
+		}
+		scheduler.lock.Unlock()
+		seelog.Infof("Image pull scheduler: image %s is already being pulled, waiting for it", container.Image)
+		return scheduler.wait(ctx, pull)
+	}
+	pull := &scheduledImagePull{
+		key:       key,
+		registry:  registry,
+		essential: container.IsEssential(),
+		start:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	scheduler.pulls[key] = pull
+	pulls, ok := scheduler.registries[registry]
+	if !ok {
+		pulls = &registryImagePulls{}
+		scheduler.registries[registry] = pulls
+	}
+	if scheduler.maxConcurrentPullsPerRegistry <= 0 || pulls.running < scheduler.maxConcurrentPullsPerRegistry {
+		pulls.running++
+		close(pull.start)
+	} else {
+		seelog.Infof("Image pull scheduler: %d images are already being pulled from %s, image %s is waiting",
+			pulls.running, registry, container.Image)
+		pulls.waiting = append(pulls.waiting, pull)
+	}
+	scheduler.lock.Unlock()
+
+	select {
+	case <-pull.start:
+	case <-ctx.Done():
+		if scheduler.cancel(pull, ctx.Err()) {
+			return pull.metadata
+		}
+		// The pull was started while it was being canceled
+		<-pull.start
+	}
+
+	pull.metadata = pullImage()
+	scheduler.finish(pull)
+	return pull.metadata
+}
+
+// wait waits for a pull scheduled by another container to finish.
+func (scheduler *imagePullScheduler) wait(ctx context.Context, pull *scheduledImagePull) dockerapi.DockerContainerMetadata {
+	select {
+	case <-pull.done:
+		return pull.metadata
+	case <-ctx.Done():
+		return dockerapi.DockerContainerMetadata{
+			Error: dockerapi.CannotPullContainerError{FromError: ctx.Err()},
+		}
+	}
+}
+
+// cancel removes a pull that is still waiting for a slot. It returns false if the pull was
+// already started.
+func (scheduler *imagePullScheduler) cancel(pull *scheduledImagePull, err error) bool {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	pulls := scheduler.registries[pull.registry]
+	for i, waiting := range pulls.waiting {
+		if waiting == pull {
+			pulls.waiting = append(pulls.waiting[:i], pulls.waiting[i+1:]...)
+			scheduler.remove(pull)
+			pull.metadata = dockerapi.DockerContainerMetadata{
+				Error: dockerapi.CannotPullContainerError{FromError: err},
+			}
+			close(pull.done)
+			return true
+		}
+	}
+	return false
+}
+
+// finish releases the slot of a pull that ran, and starts the next waiting pull of the registry.
+func (scheduler *imagePullScheduler) finish(pull *scheduledImagePull) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	pulls := scheduler.registries[pull.registry]
+	pulls.running--
+	scheduler.remove(pull)
+	close(pull.done)
+	if next := pulls.next(); next != nil {
+		pulls.running++
+		close(next.start)
+	}
+}
+
+// remove forgets the pull, and the registry when it has no pulls left. The caller must hold the lock.
+func (scheduler *imagePullScheduler) remove(pull *scheduledImagePull) {
+	delete(scheduler.pulls, pull.key)
+	pulls := scheduler.registries[pull.registry]
+	if pulls.running == 0 && len(pulls.waiting) == 0 {
+		delete(scheduler.registries, pull.registry)
+	}
+}
+
+// next removes and returns the first waiting pull of an essential container, or the first waiting
+// pull if there are none.
+func (pulls *registryImagePulls) next() *scheduledImagePull {
+	if len(pulls.waiting) == 0 {
+		return nil
+	}
+	index := 0
+	for i, pull := range pulls.waiting {
+		if pull.essential {
+			index = i
+			break
+		}
+	}
+	next := pulls.waiting[index]
+	pulls.waiting = append(pulls.waiting[:index], pulls.waiting[index+1:]...)
+	return next
+}
+
+// imagePullKey identifies the pulls of the container's image that can be shared: the image
+// reference and the credentials it's pulled with.
+func imagePullKey(container *apicontainer.Container) string {
+	key := []string{container.Image}
+	if auth := container.RegistryAuthentication; auth != nil {
+		key = append(key, auth.Type)
+		if auth.ECRAuthData != nil {
+			key = append(key, auth.ECRAuthData.Region, auth.ECRAuthData.RegistryID,
+				auth.ECRAuthData.EndpointOverride, auth.ECRAuthData.GetPullCredentials().RoleArn)
+		}
+		if auth.ASMAuthData != nil {
+			key = append(key, auth.ASMAuthData.Region, auth.ASMAuthData.CredentialsParameter)
+		}
+	}
+	return strings.Join(key, "|")
+}
+
+// imageRegistryHost returns the host of the registry the image is pulled from.
+func imageRegistryHost(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		// Let docker report invalid references, and group them on their own
+		return image
+	}
+	return reference.Domain(named)
+}
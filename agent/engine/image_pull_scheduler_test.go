@@ -0,0 +1,208 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pullSchedulerTestTimeout = 5 * time.Second
+
+// blockingPull returns a pull function that blocks until release is closed and records the
+// order in which the pulls were started.
+func blockingPull(name string, release <-chan struct{}, started chan<- string,
+	metadata dockerapi.DockerContainerMetadata) func() dockerapi.DockerContainerMetadata {
+	return func() dockerapi.DockerContainerMetadata {
+		started <- name
+		<-release
+		return metadata
+	}
+}
+
+func waitForPullStart(t *testing.T, started <-chan string) string {
+	select {
+	case name := <-started:
+		return name
+	case <-time.After(pullSchedulerTestTimeout):
+		t.Fatal("Timed out waiting for a pull to start")
+	}
+	return ""
+}
+
+// waitForWaitingPulls waits until the registry has the given number of pulls waiting for a slot.
+func waitForWaitingPulls(t *testing.T, scheduler *imagePullScheduler, registry string, count int) {
+	deadline := time.Now().Add(pullSchedulerTestTimeout)
+	for time.Now().Before(deadline) {
+		scheduler.lock.Lock()
+		pulls, ok := scheduler.registries[registry]
+		waiting := 0
+		if ok {
+			waiting = len(pulls.waiting)
+		}
+		scheduler.lock.Unlock()
+		if waiting == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d pulls waiting for %s", count, registry)
+}
+
+func TestImagePullSchedulerDeduplicatesPulls(t *testing.T) {
+	scheduler := newImagePullScheduler(0)
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	pullErr := dockerapi.CannotPullContainerError{FromError: errors.New("error")}
+	var pulls int32
+	pullImage := func() dockerapi.DockerContainerMetadata {
+		atomic.AddInt32(&pulls, 1)
+		return blockingPull("image", release, started, dockerapi.DockerContainerMetadata{Error: pullErr})()
+	}
+
+	var wg sync.WaitGroup
+	results := make([]dockerapi.DockerContainerMetadata, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = scheduler.pull(context.TODO(), &apicontainer.Container{Image: "image"}, pullImage)
+	}()
+	waitForPullStart(t, started)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[1] = scheduler.pull(context.TODO(), &apicontainer.Container{Image: "image"}, pullImage)
+	}()
+	// Let the second pull join the first one before it finishes
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pulls))
+	assert.Equal(t, pullErr, results[0].Error)
+	assert.Equal(t, pullErr, results[1].Error)
+	assert.Empty(t, scheduler.pulls)
+	assert.Empty(t, scheduler.registries)
+}
+
+func TestImagePullSchedulerLimitsPullsPerRegistry(t *testing.T) {
+	scheduler := newImagePullScheduler(1)
+	release := make(chan struct{})
+	started := make(chan string, 4)
+	pull := func(name, image string, essential bool, wg *sync.WaitGroup) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			container := &apicontainer.Container{Name: name, Image: image, Essential: essential}
+			scheduler.pull(context.TODO(), container,
+				blockingPull(name, release, started, dockerapi.DockerContainerMetadata{}))
+		}()
+	}
+
+	var wg sync.WaitGroup
+	pull("first", "registry.example.com/first", true, &wg)
+	require.Equal(t, "first", waitForPullStart(t, started))
+	pull("sidecar", "registry.example.com/sidecar", false, &wg)
+	waitForWaitingPulls(t, scheduler, "registry.example.com", 1)
+	pull("essential", "registry.example.com/essential", true, &wg)
+	waitForWaitingPulls(t, scheduler, "registry.example.com", 2)
+
+	// Other registries are not limited by the pulls of the registry
+	pull("other", "other.example.com/image", false, &wg)
+	require.Equal(t, "other", waitForPullStart(t, started))
+
+	close(release)
+	// The essential container is pulled first, even though it was scheduled last
+	assert.Equal(t, "essential", waitForPullStart(t, started))
+	assert.Equal(t, "sidecar", waitForPullStart(t, started))
+	wg.Wait()
+	assert.Empty(t, scheduler.pulls)
+	assert.Empty(t, scheduler.registries)
+}
+
+func TestImagePullSchedulerCancelWaitingPull(t *testing.T) {
+	scheduler := newImagePullScheduler(1)
+	release := make(chan struct{})
+	started := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		scheduler.pull(context.TODO(), &apicontainer.Container{Image: "first"},
+			blockingPull("first", release, started, dockerapi.DockerContainerMetadata{}))
+		close(done)
+	}()
+	waitForPullStart(t, started)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	metadata := scheduler.pull(ctx, &apicontainer.Container{Image: "second"}, func() dockerapi.DockerContainerMetadata {
+		t.Error("Canceled pull was started")
+		return dockerapi.DockerContainerMetadata{}
+	})
+	assert.IsType(t, dockerapi.CannotPullContainerError{}, metadata.Error)
+
+	close(release)
+	<-done
+	assert.Empty(t, scheduler.pulls)
+	assert.Empty(t, scheduler.registries)
+}
+
+func TestImagePullKey(t *testing.T) {
+	withRole := func(roleARN string) *apicontainer.Container {
+		container := &apicontainer.Container{
+			Image: "123456789012.dkr.ecr.us-west-2.amazonaws.com/image:latest",
+			RegistryAuthentication: &apicontainer.RegistryAuthenticationData{
+				Type:        "ecr",
+				ECRAuthData: &apicontainer.ECRAuthData{Region: "us-west-2", UseExecutionRole: true},
+			},
+		}
+		container.SetRegistryAuthCredentials(credentials.IAMRoleCredentials{RoleArn: roleARN})
+		return container
+	}
+
+	assert.Equal(t, imagePullKey(withRole("role")), imagePullKey(withRole("role")))
+	assert.NotEqual(t, imagePullKey(withRole("role")), imagePullKey(withRole("other-role")))
+	assert.NotEqual(t, imagePullKey(withRole("role")),
+		imagePullKey(&apicontainer.Container{Image: "123456789012.dkr.ecr.us-west-2.amazonaws.com/image:latest"}))
+}
+
+func TestImageRegistryHost(t *testing.T) {
+	testCases := []struct {
+		image    string
+		registry string
+	}{
+		{"busybox", "docker.io"},
+		{"library/busybox:latest", "docker.io"},
+		{"123456789012.dkr.ecr.us-west-2.amazonaws.com/image:latest", "123456789012.dkr.ecr.us-west-2.amazonaws.com"},
+		{"localhost:5000/image@sha256:" + "0000000000000000000000000000000000000000000000000000000000000000", "localhost:5000"},
+		{"INVALID", "INVALID"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			assert.Equal(t, tc.registry, imageRegistryHost(tc.image))
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 )
 
@@ -38,6 +39,9 @@ type TaskEngine interface {
 	StateChangeEvents() chan statechange.Event
 	// SetDataClient sets the data client that is used by the task engine.
 	SetDataClient(data.Client)
+	// SetImagePolicy sets the policy the images of the containers are verified against before the
+	// containers are created.
+	SetImagePolicy(*image.Policy)
 
 	// AddTask adds a new task to the task engine and manages its container's
 	// lifecycle. If it returns an error, the task was not added.
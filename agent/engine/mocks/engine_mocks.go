@@ -21,6 +21,7 @@ package mock_engine
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	container "github.com/aws/amazon-ecs-agent/agent/api/container"
 	task "github.com/aws/amazon-ecs-agent/agent/api/task"
@@ -188,6 +189,18 @@ func (mr *MockTaskEngineMockRecorder) SetDataClient(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDataClient", reflect.TypeOf((*MockTaskEngine)(nil).SetDataClient), arg0)
 }
 
+// SetImagePolicy mocks base method
+func (m *MockTaskEngine) SetImagePolicy(arg0 *image.Policy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetImagePolicy", arg0)
+}
+
+// SetImagePolicy indicates an expected call of SetImagePolicy
+func (mr *MockTaskEngineMockRecorder) SetImagePolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImagePolicy", reflect.TypeOf((*MockTaskEngine)(nil).SetImagePolicy), arg0)
+}
+
 // StateChangeEvents mocks base method
 func (m *MockTaskEngine) StateChangeEvents() chan statechange.Event {
 	m.ctrl.T.Helper()
@@ -295,6 +308,20 @@ func (mr *MockImageManagerMockRecorder) RecordContainerReference(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordContainerReference", reflect.TypeOf((*MockImageManager)(nil).RecordContainerReference), arg0)
 }
 
+// RecordPrewarmedImage mocks base method
+func (m *MockImageManager) RecordPrewarmedImage(arg0 string, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordPrewarmedImage", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordPrewarmedImage indicates an expected call of RecordPrewarmedImage
+func (mr *MockImageManagerMockRecorder) RecordPrewarmedImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordPrewarmedImage", reflect.TypeOf((*MockImageManager)(nil).RecordPrewarmedImage), arg0, arg1)
+}
+
 // RemoveContainerReferenceFromImageState mocks base method
 func (m *MockImageManager) RemoveContainerReferenceFromImageState(arg0 *container.Container) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,153 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+)
+
+// secretsRefreshedEvent is the event logged when the value of secrets of a running container changed
+const secretsRefreshedEvent = "SecretsRefreshed"
+
+// startPeriodicSecretRefresh fetches the secrets of running tasks again every
+// SecretRefreshInterval, so that rotated values reach the containers without replacing the tasks
+func (engine *DockerTaskEngine) startPeriodicSecretRefresh(ctx context.Context) {
+	ticker := time.NewTicker(engine.cfg.SecretRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			engine.refreshSecrets(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshSecrets refreshes the secrets of every task that is running and meant to keep running
+func (engine *DockerTaskEngine) refreshSecrets(ctx context.Context) {
+	var tasks []*apitask.Task
+	engine.tasksLock.RLock()
+	for _, mTask := range engine.managedTasks {
+		if mTask.GetKnownStatus() == apitaskstatus.TaskRunning && mTask.GetDesiredStatus() == apitaskstatus.TaskRunning {
+			tasks = append(tasks, mTask.Task)
+		}
+	}
+	engine.tasksLock.RUnlock()
+
+	for _, task := range tasks {
+		engine.refreshTaskSecrets(ctx, task)
+	}
+}
+
+// refreshTaskSecrets fetches the secrets of a task again. When the value of secrets of a running
+// container changed, the files of its secrets of type MOUNT_POINT are updated in place and the
+// container is sent SecretRefreshSignal. Secrets of other types keep their value until the
+// container is replaced. Either way, a SecretsRefreshedStateChange event is emitted.
+func (engine *DockerTaskEngine) refreshTaskSecrets(ctx context.Context, task *apitask.Task) {
+	changed, err := task.RefreshSecrets()
+	if err != nil {
+		logger.Warn("Unable to refresh the secrets of the task", logger.Fields{
+			field.TaskARN: task.Arn,
+			field.Error:   err,
+		})
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	for _, container := range task.Containers {
+		if !container.IsRunning() {
+			continue
+		}
+		var changedSecrets []string
+		fileSecretsChanged := false
+		for _, secret := range container.Secrets {
+			if !changed[secret.GetSecretResourceCacheKey()] {
+				continue
+			}
+			changedSecrets = append(changedSecrets, secret.Name)
+			if secret.Type == apicontainer.SecretTypeMountPoint {
+				fileSecretsChanged = true
+			}
+		}
+		if len(changedSecrets) == 0 {
+			continue
+		}
+
+		logger.Info("Secret values of the container changed", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: container.GetRuntimeID(),
+			field.Event:     secretsRefreshedEvent,
+			"secrets":       strings.Join(changedSecrets, ","),
+			"filesUpdated":  fileSecretsChanged,
+		})
+		if fileSecretsChanged {
+			if err := task.UpdateSecretFiles(container); err != nil {
+				logger.Error("Unable to update the secret files of the container", logger.Fields{
+					field.TaskARN:   task.Arn,
+					field.Container: container.Name,
+					field.Error:     err,
+				})
+				continue
+			}
+			engine.signalSecretsRefreshed(ctx, task, container)
+		}
+		engine.emitSecretsRefreshedEvent(ctx, api.SecretsRefreshedStateChange{
+			TaskArn:       task.Arn,
+			RuntimeID:     container.GetRuntimeID(),
+			ContainerName: container.Name,
+			Secrets:       changedSecrets,
+			FilesUpdated:  fileSecretsChanged,
+		})
+	}
+}
+
+// emitSecretsRefreshedEvent sends the event of refreshed secrets of a container to the state
+// change events, so that local subscribers can react to the new values
+func (engine *DockerTaskEngine) emitSecretsRefreshedEvent(ctx context.Context, event api.SecretsRefreshedStateChange) {
+	select {
+	case engine.stateChangeEvents <- event:
+	case <-ctx.Done():
+	}
+}
+
+// signalSecretsRefreshed sends SecretRefreshSignal to a container whose secret files were updated,
+// so that it can reload them
+func (engine *DockerTaskEngine) signalSecretsRefreshed(ctx context.Context, task *apitask.Task, container *apicontainer.Container) {
+	if engine.cfg.SecretRefreshSignal == "" {
+		return
+	}
+	err := engine.client.SignalContainer(ctx, container.GetRuntimeID(), engine.cfg.SecretRefreshSignal,
+		dockerclient.SignalContainerTimeout)
+	if err != nil {
+		logger.Warn("Unable to signal the container after its secrets were refreshed", logger.Fields{
+			field.TaskARN:   task.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: container.GetRuntimeID(),
+			field.Error:     err,
+		})
+	}
+}
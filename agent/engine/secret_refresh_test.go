@@ -0,0 +1,194 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	mock_ssm_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
+	mock_ssmiface "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfiles"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	refreshTestTaskARN        = "arn:aws:ecs:us-west-2:1234567890:task/cluster/taskID"
+	refreshTestSecretRegion   = "us-west-2"
+	refreshTestSecretFrom     = "/db/password"
+	refreshTestSecretCacheKey = refreshTestSecretFrom + "_" + refreshTestSecretRegion
+	refreshTestExecCredsID    = "exec-creds-id"
+	refreshTestDockerID       = "dockerID"
+)
+
+// secretRefreshTestTask returns a running task with a container that has a single SSM secret of
+// the given type, whose cached value is "old-value"
+func secretRefreshTestTask(t *testing.T, cfg *config.Config, credentialsManager credentials.Manager,
+	ssmClientCreator *mock_ssm_factory.MockSSMClientCreator, secretType string) (*apitask.Task, *apicontainer.Container) {
+	secret := apicontainer.Secret{
+		Name:      "db-password",
+		ValueFrom: refreshTestSecretFrom,
+		Region:    refreshTestSecretRegion,
+		Provider:  apicontainer.SecretProviderSSM,
+		Type:      secretType,
+	}
+	container := &apicontainer.Container{
+		Name:                      "app",
+		Secrets:                   []apicontainer.Secret{secret},
+		KnownStatusUnsafe:         apicontainerstatus.ContainerRunning,
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	container.SetRuntimeID(refreshTestDockerID)
+	task := &apitask.Task{
+		Arn:                    refreshTestTaskARN,
+		ExecutionCredentialsID: refreshTestExecCredsID,
+		ResourcesMapUnsafe:     make(map[string][]taskresource.TaskResource),
+		Containers:             []*apicontainer.Container{container},
+	}
+
+	ssmRes := ssmsecret.NewSSMSecretResource(task.Arn,
+		map[string][]apicontainer.Secret{refreshTestSecretRegion: {secret}},
+		refreshTestExecCredsID, credentialsManager, ssmClientCreator)
+	ssmRes.SetCachedSecretValue(refreshTestSecretCacheKey, "old-value")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+
+	if secretType == apicontainer.SecretTypeMountPoint {
+		task.AddResource(secretfiles.ResourceName, secretfiles.NewSecretFilesResource(task.Arn, cfg.DataDir))
+		require.Nil(t, task.PopulateSecretFiles(&dockercontainer.HostConfig{}, container, fmt.Sprint(os.Getuid()), cfg))
+	}
+	return task, container
+}
+
+func expectSSMSecretValue(ctrl *gomock.Controller, credentialsManager *mock_credentials.MockManager,
+	ssmClientCreator *mock_ssm_factory.MockSSMClientCreator, value string) {
+	mockSSMClient := mock_ssmiface.NewMockSSMClient(ctrl)
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	credentialsManager.EXPECT().GetTaskCredentials(refreshTestExecCredsID).Return(
+		credentials.TaskIAMRoleCredentials{IAMRoleCredentials: iamRoleCreds}, true)
+	ssmClientCreator.EXPECT().NewSSMClient(refreshTestSecretRegion, iamRoleCreds).Return(mockSSMClient)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(&ssm.GetParametersOutput{
+		Parameters: []*ssm.Parameter{
+			{
+				Name:  aws.String(refreshTestSecretFrom),
+				Value: aws.String(value),
+			},
+		},
+	}, nil)
+}
+
+// expectSecretsRefreshedEvent returns a channel receiving the next state change event of the
+// engine, which must be a SecretsRefreshedStateChange
+func expectSecretsRefreshedEvent(taskEngine TaskEngine) <-chan api.SecretsRefreshedStateChange {
+	events := make(chan api.SecretsRefreshedStateChange, 1)
+	go func() {
+		event := <-taskEngine.StateChangeEvents()
+		refreshed, _ := event.(api.SecretsRefreshedStateChange)
+		events <- refreshed
+	}()
+	return events
+}
+
+func TestRefreshTaskSecretsUpdatesFilesAndSignals(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.DataDir = t.TempDir()
+	cfg.DataDirOnHost = "/var/lib/ecs"
+	cfg.SecretFilesContainerPath = "/run/secrets"
+	cfg.SecretRefreshSignal = "SIGHUP"
+	ctrl, client, _, taskEngine, credentialsManager, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	ssmClientCreator := mock_ssm_factory.NewMockSSMClientCreator(ctrl)
+
+	task, _ := secretRefreshTestTask(t, &cfg, credentialsManager, ssmClientCreator, apicontainer.SecretTypeMountPoint)
+	expectSSMSecretValue(ctrl, credentialsManager, ssmClientCreator, "new-value")
+	client.EXPECT().SignalContainer(gomock.Any(), refreshTestDockerID, "SIGHUP", dockerclient.SignalContainerTimeout).Return(nil)
+
+	events := expectSecretsRefreshedEvent(taskEngine)
+	taskEngine.(*DockerTaskEngine).refreshTaskSecrets(ctx, task)
+	assert.Equal(t, api.SecretsRefreshedStateChange{
+		TaskArn:       refreshTestTaskARN,
+		RuntimeID:     refreshTestDockerID,
+		ContainerName: "app",
+		Secrets:       []string{"db-password"},
+		FilesUpdated:  true,
+	}, <-events)
+
+	value, err := os.ReadFile(filepath.Join(secretfiles.ContainerDir(cfg.DataDir, task.Arn, "app"), "db-password"))
+	require.NoError(t, err)
+	assert.Equal(t, "new-value", string(value))
+}
+
+func TestRefreshTaskSecretsUnchangedValue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.DataDir = t.TempDir()
+	cfg.SecretFilesContainerPath = "/run/secrets"
+	cfg.SecretRefreshSignal = "SIGHUP"
+	ctrl, _, _, taskEngine, credentialsManager, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	ssmClientCreator := mock_ssm_factory.NewMockSSMClientCreator(ctrl)
+
+	task, _ := secretRefreshTestTask(t, &cfg, credentialsManager, ssmClientCreator, apicontainer.SecretTypeMountPoint)
+	// No signal is expected when the value didn't change
+	expectSSMSecretValue(ctrl, credentialsManager, ssmClientCreator, "old-value")
+
+	taskEngine.(*DockerTaskEngine).refreshTaskSecrets(ctx, task)
+}
+
+func TestRefreshTaskSecretsEnvironmentVariableNotSignaled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := defaultConfig
+	cfg.SecretRefreshSignal = "SIGHUP"
+	ctrl, _, _, taskEngine, credentialsManager, _, _ := mocks(t, ctx, &cfg)
+	defer ctrl.Finish()
+	ssmClientCreator := mock_ssm_factory.NewMockSSMClientCreator(ctrl)
+
+	task, _ := secretRefreshTestTask(t, &cfg, credentialsManager, ssmClientCreator, apicontainer.SecretTypeEnv)
+	// The environment of a running container can't be updated, so it isn't signaled
+	expectSSMSecretValue(ctrl, credentialsManager, ssmClientCreator, "new-value")
+
+	events := expectSecretsRefreshedEvent(taskEngine)
+	taskEngine.(*DockerTaskEngine).refreshTaskSecrets(ctx, task)
+	event := <-events
+	assert.Equal(t, []string{"db-password"}, event.Secrets)
+	assert.False(t, event.FilesUpdated)
+
+	ssmRes, ok := task.ResourcesMapUnsafe[ssmsecret.ResourceName]
+	require.True(t, ok)
+	value, _ := ssmRes[0].(*ssmsecret.SSMSecretResource).GetCachedSecretValue(refreshTestSecretCacheKey)
+	assert.Equal(t, "new-value", value)
+}
@@ -38,9 +38,11 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
@@ -80,6 +82,9 @@ type resourceStateChange struct {
 type acsTransition struct {
 	seqnum        int64
 	desiredStatus apitaskstatus.TaskStatus
+	// terminalReason is the reason the task is stopped, when the update comes
+	// from the agent itself rather than from ACS
+	terminalReason string
 }
 
 // containerTransition defines the struct for a container to transition
@@ -154,6 +159,31 @@ type managedTask struct {
 	_time     ttime.Time
 	_timeOnce sync.Once
 
+	// addedAt is the time at which the task started being managed by the engine. It's the
+	// reference for the task lifecycle metrics.
+	addedAt time.Time
+
+	// traceLock protects the spans that the transitions of the containers and resources of the
+	// task are traced under. The launch span is the root span of the trace of the task until it
+	// first reaches RUNNING. Stopping the task afterwards is traced under the stop span, the root
+	// span of another trace that's linked to the launch span.
+	traceLock  sync.Mutex
+	launchCtx  context.Context
+	launchSpan *tracing.Span
+	launched   bool
+	stopCtx    context.Context
+	stopSpan   *tracing.Span
+
+	// oomKills is the OOM kill count of the task cgroup when a container of the task last
+	// stopped. It's used to tell whether the next container to stop was OOM killed.
+	oomKills uint64
+
+	// containerOOMKills are the OOM kill counts of the cgroups of the running containers of the
+	// task, by runtime id, as last recorded on the v1 hierarchy. They stand in for the counts of
+	// the cgroups that are already gone when the stop of their containers is handled.
+	containerOOMKillsLock sync.Mutex
+	containerOOMKills     map[string]uint64
+
 	// steadyStatePollInterval is the duration that a managed task waits
 	// once the task gets into steady state before polling the state of all of
 	// the task's containers to re-evaluate if the task is still in steady state
@@ -169,6 +199,10 @@ type managedTask struct {
 // already held.
 func (engine *DockerTaskEngine) newManagedTask(task *apitask.Task) *managedTask {
 	ctx, cancel := context.WithCancel(engine.ctx)
+	launchCtx, launchSpan := tracing.StartSpan(engine.ctx, "task.launch", tracing.SpanKindInternal,
+		tracing.String(tracing.AttributeTaskARN, task.Arn),
+		tracing.String(tracing.AttributeTaskFamily, task.Family),
+		tracing.String(tracing.AttributeTaskRevision, task.Version))
 	t := &managedTask{
 		ctx:                           ctx,
 		cancel:                        cancel,
@@ -186,6 +220,9 @@ func (engine *DockerTaskEngine) newManagedTask(task *apitask.Task) *managedTask
 		taskStopWG:                    engine.taskStopGroup,
 		steadyStatePollInterval:       engine.taskSteadyStatePollInterval,
 		steadyStatePollIntervalJitter: engine.taskSteadyStatePollIntervalJitter,
+		addedAt:                       ttime.Now(),
+		launchCtx:                     launchCtx,
+		launchSpan:                    launchSpan,
 	}
 	engine.managedTasks[task.Arn] = t
 	return t
@@ -199,6 +236,8 @@ func (mtask *managedTask) overseeTask() {
 	// `desiredstatus`es which are a construct of the engine used only here,
 	// not present on the backend
 	mtask.UpdateStatus()
+	// Tasks restored from the state may already be running
+	mtask.traceKnownStatus()
 	// If this was a 'state restore', send all unsent statuses
 	mtask.emitCurrentStatus()
 
@@ -239,6 +278,7 @@ func (mtask *managedTask) overseeTask() {
 	logger.Info("Managed task has reached stopped; waiting for container cleanup", logger.Fields{
 		field.TaskARN: mtask.Arn,
 	})
+	mtask.endTrace()
 	mtask.engine.checkTearDownPauseContainer(mtask.Task)
 	mtask.cleanupCredentials()
 	if mtask.StopSequenceNumber != 0 {
@@ -372,7 +412,7 @@ func (mtask *managedTask) waitEvent(stopWaiting <-chan struct{}) bool {
 		logger.Info("Managed task got acs event", logger.Fields{
 			field.TaskARN: mtask.Arn,
 		})
-		mtask.handleDesiredStatusChange(acsTransition.desiredStatus, acsTransition.seqnum)
+		mtask.handleACSTransition(acsTransition)
 		return false
 	case dockerChange := <-mtask.dockerMessages:
 		mtask.handleContainerChange(dockerChange)
@@ -391,6 +431,15 @@ func (mtask *managedTask) waitEvent(stopWaiting <-chan struct{}) bool {
 	}
 }
 
+// handleACSTransition records the terminal reason carried by the transition,
+// unless the transition is redundant, and then updates the desired status.
+func (mtask *managedTask) handleACSTransition(transition acsTransition) {
+	if transition.terminalReason != "" && transition.desiredStatus > mtask.GetDesiredStatus() {
+		mtask.SetTerminalReason(transition.terminalReason)
+	}
+	mtask.handleDesiredStatusChange(transition.desiredStatus, transition.seqnum)
+}
+
 // handleDesiredStatusChange updates the desired status on the task. Updates
 // only occur if the new desired status is "compatible" (farther along than the
 // current desired state); "redundant" (less-than or equal desired states) are
@@ -477,6 +526,16 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 	// Container has progressed its status if we reach here. Make sure to save it to database.
 	defer mtask.engine.saveContainerData(container)
 
+	if event.Status == apicontainerstatus.ContainerStopped && mtask.oomKilledByTaskCgroup(event.DockerContainerMetadata) {
+		logger.Warn("Container was killed by the OOM killer of the task cgroup", logger.Fields{
+			field.TaskARN:   mtask.Arn,
+			field.Container: container.Name,
+			field.RuntimeID: runtimeID,
+		})
+		event.Error = OutOfMemoryKilledByCgroupError{}
+		containerChange.event = event
+	}
+
 	// Update the container to be known
 	currentKnownStatus := containerKnownStatus
 	container.SetKnownStatus(event.Status)
@@ -519,12 +578,23 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 		if mtask.GetKnownStatus().Terminal() {
 			taskStateChangeReason = mtask.Task.GetTerminalReason()
 		}
+		mtask.recordTimeToRunning()
+		mtask.traceKnownStatus()
 		mtask.emitTaskEvent(mtask.Task, taskStateChangeReason)
 		// Save the new task status to database.
 		mtask.engine.saveTaskData(mtask.Task)
 	}
 }
 
+// recordTimeToRunning records the time it took the task to become RUNNING since it was added to
+// the engine. It must be called when the known status of the task changed.
+func (mtask *managedTask) recordTimeToRunning() {
+	if mtask.GetKnownStatus() != apitaskstatus.TaskRunning || mtask.addedAt.IsZero() {
+		return
+	}
+	metrics.MetricsEngineGlobal.RecordTaskLifecyclePhase(metrics.TaskPhaseTimeToRunning, ttime.Since(mtask.addedAt))
+}
+
 // handleResourceStateChange attempts to update resource's known status depending on
 // the current status and errors during transition
 func (mtask *managedTask) handleResourceStateChange(resChange resourceStateChange) {
@@ -1050,6 +1120,8 @@ func (mtask *managedTask) progressTask() {
 		if mtask.GetKnownStatus().Terminal() {
 			taskStateChangeReason = mtask.Task.GetTerminalReason()
 		}
+		mtask.recordTimeToRunning()
+		mtask.traceKnownStatus()
 		mtask.emitTaskEvent(mtask.Task, taskStateChangeReason)
 	}
 }
@@ -1191,8 +1263,14 @@ func (mtask *managedTask) applyResourceState(resource taskresource.TaskResource,
 	nextState resourcestatus.ResourceStatus) error {
 	resName := resource.GetName()
 	resStatus := resource.StatusString(nextState)
+	_, span := tracing.StartSpan(mtask.traceContext(), "resource.transition", tracing.SpanKindInternal,
+		tracing.String(tracing.AttributeTaskARN, mtask.Arn),
+		tracing.String(tracing.AttributeResourceName, resName),
+		tracing.String(tracing.AttributeStatus, resStatus))
+	defer span.End()
 	err := resource.ApplyTransition(nextState)
 	if err != nil {
+		span.RecordError(err)
 		logger.Info("Error transitioning resource", logger.Fields{
 			field.TaskARN:      mtask.Arn,
 			field.Resource:     resName,
@@ -1463,8 +1541,13 @@ func (mtask *managedTask) cleanupTask(taskStoppedDuration time.Duration) {
 	// speedy processing of other events for other tasks
 	// discard events while the task is being removed from engine state
 	go mtask.discardEvents()
+	cleanupStart := ttime.Now()
+	_, span := tracing.StartLinkedSpan(mtask.engine.ctx, mtask.traceLinks(), "task.cleanup", tracing.SpanKindInternal,
+		tracing.String(tracing.AttributeTaskARN, mtask.Arn))
 	mtask.engine.sweepTask(mtask.Task)
 	mtask.engine.deleteTask(mtask.Task)
+	span.End()
+	metrics.MetricsEngineGlobal.RecordTaskLifecyclePhase(metrics.TaskPhaseCleanup, ttime.Since(cleanupStart))
 
 	// Remove TaskExecutionCredentials from credentialsManager
 	if taskExecutionCredentialsID != "" {
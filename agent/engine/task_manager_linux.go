@@ -0,0 +1,124 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"path/filepath"
+
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/logger/field"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+)
+
+// sigkillExitCode is the exit code of containers whose main process was killed by a SIGKILL
+const sigkillExitCode = 137
+
+// oomKilledByTaskCgroup returns whether a container that stopped was killed by the OOM killer of
+// the task cgroup. Docker only detects OOM kills of the container cgroup, so containers killed
+// because the task reached its memory limit look like they were sent a SIGKILL. The OOM kill
+// count of the task cgroup is compared to its count when the previous container stopped, so
+// that every kill is attributed to a single container.
+func (mtask *managedTask) oomKilledByTaskCgroup(metadata dockerapi.DockerContainerMetadata) bool {
+	if !mtask.MemoryCPULimitsEnabled {
+		return false
+	}
+	cgroupRoot, err := mtask.BuildCgroupRoot()
+	if err != nil {
+		return false
+	}
+	if !control.IsUnifiedHierarchy(mtask.cfg.CgroupPath) {
+		return mtask.oomKilledInContainerCgroup(cgroupRoot, metadata)
+	}
+	events, err := memevents.Read(mtask.cfg.CgroupPath, cgroupRoot)
+	if err != nil {
+		logger.Debug("Unable to read the memory events of the task cgroup", logger.Fields{
+			field.TaskARN: mtask.Arn,
+			field.Error:   err,
+		})
+		return false
+	}
+	previousOOMKills := mtask.oomKills
+	mtask.oomKills = events.OOMKills
+	if metadata.Error != nil || metadata.ExitCode == nil || *metadata.ExitCode != sigkillExitCode {
+		return false
+	}
+	return events.OOMKills > previousOOMKills
+}
+
+// oomKilledInContainerCgroup returns whether a container that stopped was OOM killed according to
+// its own cgroup. On the v1 hierarchy, OOM kills are only counted by the cgroup of the killed
+// process and not by its ancestors, so the count of the task cgroup doesn't change when the task
+// reaches its memory limit. Docker may remove the container cgroup before the stop is handled, in
+// which case the count last recorded while the container ran is used instead.
+func (mtask *managedTask) oomKilledInContainerCgroup(cgroupRoot string, metadata dockerapi.DockerContainerMetadata) bool {
+	if metadata.DockerID == "" {
+		return false
+	}
+	oomKills, recorded := mtask.popContainerOOMKills(metadata.DockerID)
+	if metadata.Error != nil || metadata.ExitCode == nil || *metadata.ExitCode != sigkillExitCode {
+		return false
+	}
+	events, err := memevents.Read(mtask.cfg.CgroupPath, filepath.Join(cgroupRoot, metadata.DockerID))
+	if err != nil {
+		logger.Debug("Unable to read the memory events of the container cgroup", logger.Fields{
+			field.TaskARN:   mtask.Arn,
+			field.RuntimeID: metadata.DockerID,
+			"recorded":      recorded,
+			field.Error:     err,
+		})
+		return oomKills > 0
+	}
+	return events.OOMKills > 0
+}
+
+// recordContainerOOMKills records the OOM kill counts of the cgroups of the running containers of
+// the task on the v1 hierarchy, for oomKilledInContainerCgroup to use once the cgroups are gone.
+func (mtask *managedTask) recordContainerOOMKills() {
+	if !mtask.MemoryCPULimitsEnabled || control.IsUnifiedHierarchy(mtask.cfg.CgroupPath) {
+		return
+	}
+	cgroupRoot, err := mtask.BuildCgroupRoot()
+	if err != nil {
+		return
+	}
+	for _, container := range mtask.Containers {
+		dockerID := container.GetRuntimeID()
+		if dockerID == "" || !container.IsRunning() {
+			continue
+		}
+		events, err := memevents.Read(mtask.cfg.CgroupPath, filepath.Join(cgroupRoot, dockerID))
+		if err != nil {
+			continue
+		}
+		mtask.containerOOMKillsLock.Lock()
+		if mtask.containerOOMKills == nil {
+			mtask.containerOOMKills = make(map[string]uint64)
+		}
+		mtask.containerOOMKills[dockerID] = events.OOMKills
+		mtask.containerOOMKillsLock.Unlock()
+	}
+}
+
+// popContainerOOMKills returns and forgets the OOM kill count last recorded for the container.
+func (mtask *managedTask) popContainerOOMKills(dockerID string) (uint64, bool) {
+	mtask.containerOOMKillsLock.Lock()
+	defer mtask.containerOOMKillsLock.Unlock()
+	oomKills, ok := mtask.containerOOMKills[dockerID]
+	delete(mtask.containerOOMKills, dockerID)
+	return oomKills, ok
+}
@@ -42,6 +42,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
@@ -879,6 +880,60 @@ func TestWaitForContainerTransitionsForTerminalTask(t *testing.T) {
 	task.waitForTransition(transitions, transitionChange, transitionChangeContainer)
 }
 
+// TestStopUpdateFromAddTask verifies that an update carrying only the arn and
+// the STOPPED desired status, as sent by ACS and the introspection API, stops
+// the task owned by the managed task.
+func TestStopUpdateFromAddTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	task := &apitask.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+		Containers: []*apicontainer.Container{
+			{
+				Name:                "c1",
+				DesiredStatusUnsafe: apicontainerstatus.ContainerRunning,
+				KnownStatusUnsafe:   apicontainerstatus.ContainerRunning,
+			},
+		},
+	}
+	state := dockerstate.NewTaskEngineState()
+	state.AddTask(task)
+	taskEngine := &DockerTaskEngine{
+		cfg:          &config.Config{},
+		state:        state,
+		managedTasks: make(map[string]*managedTask),
+		dataClient:   data.NewNoopClient(),
+	}
+	mtask := &managedTask{
+		Task:        task,
+		engine:      taskEngine,
+		acsMessages: make(chan acsTransition),
+		ctx:         ctx,
+	}
+	taskEngine.managedTasks[task.Arn] = mtask
+
+	update := &apitask.Task{
+		Arn:                 task.Arn,
+		DesiredStatusUnsafe: apitaskstatus.TaskStopped,
+	}
+	update.SetTerminalReason("Stopped by the agent")
+	go taskEngine.AddTask(update)
+	mtask.handleACSTransition(<-mtask.acsMessages)
+
+	assert.Equal(t, apitaskstatus.TaskStopped, task.GetDesiredStatus())
+	assert.Equal(t, apicontainerstatus.ContainerStopped, task.Containers[0].GetDesiredStatus())
+	assert.Equal(t, "Stopped by the agent", task.GetTerminalReason())
+
+	// A redundant update doesn't override the terminal reason
+	mtask.handleACSTransition(acsTransition{
+		desiredStatus:  apitaskstatus.TaskStopped,
+		terminalReason: "stopped again",
+	})
+	assert.Equal(t, "Stopped by the agent", task.GetTerminalReason())
+}
+
 func TestOnContainersUnableToTransitionStateForDesiredStoppedTask(t *testing.T) {
 	stateChangeEvents := make(chan statechange.Event)
 	task := &managedTask{
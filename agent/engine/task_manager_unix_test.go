@@ -19,29 +19,37 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/data"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/golang/mock/gomock"
 
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // These tests use cgroup resource, which is linux specific.
@@ -453,3 +461,176 @@ func TestCleanupExecEnabledTask(t *testing.T) {
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mTask.cleanupTask(taskStoppedDuration)
 }
+
+func TestHandleContainerChangeOOMKilledByTaskCgroup(t *testing.T) {
+	testCases := []struct {
+		name     string
+		oomKills uint64
+		exitCode int
+		// cgroupV1 uses the v1 hierarchy, where the OOM kill is only counted by the container cgroup
+		cgroupV1 bool
+		// containerCgroupRemoved removes the container cgroup before the container stop is handled
+		containerCgroupRemoved bool
+		// recordedOOMKills is the OOM kill count of the container cgroup recorded while it ran
+		recordedOOMKills *uint64
+		expectedReason   string
+	}{
+		{
+			name:           "task cgroup OOM kill",
+			oomKills:       0,
+			exitCode:       sigkillExitCode,
+			expectedReason: "OutOfMemory: killed by cgroup",
+		},
+		{
+			name:     "OOM kill already attributed",
+			oomKills: 1,
+			exitCode: sigkillExitCode,
+		},
+		{
+			name:     "container exited",
+			oomKills: 0,
+			exitCode: 1,
+		},
+		{
+			name:           "task cgroup OOM kill on cgroup v1",
+			exitCode:       sigkillExitCode,
+			cgroupV1:       true,
+			expectedReason: "OutOfMemory: killed by cgroup",
+		},
+		{
+			name:     "container exited on cgroup v1",
+			exitCode: 1,
+			cgroupV1: true,
+		},
+		{
+			name:                   "container cgroup removed on cgroup v1",
+			exitCode:               sigkillExitCode,
+			cgroupV1:               true,
+			containerCgroupRemoved: true,
+			recordedOOMKills:       aws.Uint64(1),
+			expectedReason:         "OutOfMemory: killed by cgroup",
+		},
+		{
+			name:                   "container cgroup removed without an OOM kill on cgroup v1",
+			exitCode:               sigkillExitCode,
+			cgroupV1:               true,
+			containerCgroupRemoved: true,
+			recordedOOMKills:       aws.Uint64(0),
+		},
+		{
+			name:                   "container cgroup removed before any record on cgroup v1",
+			exitCode:               sigkillExitCode,
+			cgroupV1:               true,
+			containerCgroupRemoved: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			containerChangeEventStream := eventstream.NewEventStream("TestHandleContainerChangeOOMKilledByTaskCgroup", ctx)
+			containerChangeEventStream.StartListening()
+			client := mock_dockerapi.NewMockDockerClient(ctrl)
+			client.EXPECT().SystemPing(gomock.Any(), gomock.Any()).Return(dockerapi.PingResponse{}).AnyTimes()
+
+			task := testdata.LoadTask("sleep5TaskCgroup")
+			task.MemoryCPULimitsEnabled = true
+			cgroupRoot, err := task.BuildCgroupRoot()
+			require.NoError(t, err)
+			cgroupPath := t.TempDir()
+			expectedOOMKills := uint64(1)
+			if tc.cgroupV1 {
+				// The kill is counted by the container cgroup but not by the task cgroup
+				expectedOOMKills = tc.oomKills
+				taskCgroup := filepath.Join(cgroupPath, "memory", cgroupRoot)
+				require.NoError(t, os.MkdirAll(filepath.Join(taskCgroup, "dockerID"), 0755))
+				require.NoError(t, ioutil.WriteFile(filepath.Join(taskCgroup, "memory.oom_control"),
+					[]byte("oom_kill_disable 0\nunder_oom 0\noom_kill 0\n"), 0644))
+				if !tc.containerCgroupRemoved {
+					require.NoError(t, ioutil.WriteFile(filepath.Join(taskCgroup, "dockerID", "memory.oom_control"),
+						[]byte("oom_kill_disable 0\nunder_oom 0\noom_kill 1\n"), 0644))
+				}
+			} else {
+				require.NoError(t, os.MkdirAll(filepath.Join(cgroupPath, cgroupRoot), 0755))
+				require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupPath, "cgroup.controllers"), []byte("cpu memory"), 0644))
+				require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupPath, cgroupRoot, "memory.events"),
+					[]byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644))
+			}
+
+			mTask := &managedTask{
+				Task:                       task,
+				cfg:                        &config.Config{CgroupPath: cgroupPath},
+				dockerClient:               client,
+				containerChangeEventStream: containerChangeEventStream,
+				stateChangeEvents:          make(chan statechange.Event),
+				ctx:                        ctx,
+				engine: &DockerTaskEngine{
+					dataClient: data.NewNoopClient(),
+				},
+				oomKills: tc.oomKills,
+			}
+			if tc.recordedOOMKills != nil {
+				mTask.containerOOMKills = map[string]uint64{"dockerID": *tc.recordedOOMKills}
+			}
+			defer discardEvents(mTask.stateChangeEvents)()
+			container := mTask.Containers[0]
+			container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+
+			exitCode := tc.exitCode
+			mTask.handleContainerChange(dockerContainerChange{
+				container: container,
+				event: dockerapi.DockerContainerChangeEvent{
+					Status: apicontainerstatus.ContainerStopped,
+					DockerContainerMetadata: dockerapi.DockerContainerMetadata{
+						DockerID: "dockerID",
+						ExitCode: &exitCode,
+					},
+				},
+			})
+
+			assert.Equal(t, apicontainerstatus.ContainerStopped, container.GetKnownStatus())
+			assert.Equal(t, expectedOOMKills, mTask.oomKills)
+			assert.Empty(t, mTask.containerOOMKills, "the recorded count is forgotten once the container stopped")
+			if tc.expectedReason == "" {
+				assert.Nil(t, container.ApplyingError)
+			} else {
+				require.NotNil(t, container.ApplyingError)
+				assert.Equal(t, tc.expectedReason, container.ApplyingError.Error())
+			}
+		})
+	}
+}
+
+func TestRecordContainerOOMKills(t *testing.T) {
+	task := testdata.LoadTask("sleep5TaskCgroup")
+	task.MemoryCPULimitsEnabled = true
+	cgroupRoot, err := task.BuildCgroupRoot()
+	require.NoError(t, err)
+	cgroupPath := t.TempDir()
+	containerCgroup := filepath.Join(cgroupPath, "memory", cgroupRoot, "dockerID")
+	require.NoError(t, os.MkdirAll(containerCgroup, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(containerCgroup, "memory.oom_control"),
+		[]byte("oom_kill_disable 0\nunder_oom 0\noom_kill 1\n"), 0644))
+
+	mTask := &managedTask{
+		Task: task,
+		cfg:  &config.Config{CgroupPath: cgroupPath},
+	}
+	container := mTask.Containers[0]
+	container.SetRuntimeID("dockerID")
+
+	// Only the cgroups of running containers are read
+	mTask.recordContainerOOMKills()
+	assert.Empty(t, mTask.containerOOMKills)
+
+	container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	mTask.recordContainerOOMKills()
+	assert.Equal(t, map[string]uint64{"dockerID": 1}, mTask.containerOOMKills)
+
+	// The count last recorded is kept once the cgroup is gone
+	require.NoError(t, os.RemoveAll(containerCgroup))
+	mTask.recordContainerOOMKills()
+	assert.Equal(t, map[string]uint64{"dockerID": 1}, mTask.containerOOMKills)
+}
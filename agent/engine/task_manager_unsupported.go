@@ -0,0 +1,26 @@
+//go:build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+
+// oomKilledByTaskCgroup is always false on platforms without task cgroups.
+func (mtask *managedTask) oomKilledByTaskCgroup(metadata dockerapi.DockerContainerMetadata) bool {
+	return false
+}
+
+// recordContainerOOMKills is a no-op on platforms without task cgroups.
+func (mtask *managedTask) recordContainerOOMKills() {}
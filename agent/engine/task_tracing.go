@@ -0,0 +1,146 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+)
+
+// containerTransitionSpanNames maps the container transitions to the names of their spans.
+var containerTransitionSpanNames = map[apicontainerstatus.ContainerStatus]string{
+	apicontainerstatus.ContainerPulled:               "container.pull",
+	apicontainerstatus.ContainerCreated:              "container.create",
+	apicontainerstatus.ContainerRunning:              "container.start",
+	apicontainerstatus.ContainerResourcesProvisioned: "container.provision",
+	apicontainerstatus.ContainerStopped:              "container.stop",
+}
+
+// taskTraceContext returns the context that carries the span that the work on the task is
+// traced under, or the context of the engine if the task isn't managed.
+func (engine *DockerTaskEngine) taskTraceContext(task *apitask.Task) context.Context {
+	engine.tasksLock.RLock()
+	managedTask, ok := engine.managedTasks[task.Arn]
+	engine.tasksLock.RUnlock()
+	if !ok {
+		return engine.ctx
+	}
+	if ctx := managedTask.traceContext(); ctx != nil {
+		return ctx
+	}
+	return engine.ctx
+}
+
+// traceContext returns the context that carries the span that the work on the task is traced
+// under: the launch span until the task first reaches RUNNING, then the stop span once the task
+// is stopping. The stop span is started by the first work done to stop the task. The work done
+// on a running task that isn't stopping, such as restarting a container, is traced as a child of
+// the launch span, which has already been exported by then.
+func (mtask *managedTask) traceContext() context.Context {
+	mtask.traceLock.Lock()
+	defer mtask.traceLock.Unlock()
+	if mtask.launchSpan == nil || !mtask.launched || !mtask.GetDesiredStatus().Terminal() {
+		return mtask.launchCtx
+	}
+	if mtask.stopCtx == nil {
+		mtask.stopCtx, mtask.stopSpan = tracing.StartLinkedSpan(mtask.engine.ctx, []*tracing.Span{mtask.launchSpan},
+			"task.stop", tracing.SpanKindInternal, tracing.String(tracing.AttributeTaskARN, mtask.Arn))
+	}
+	return mtask.stopCtx
+}
+
+// traceKnownStatus ends the launch span once the task first reaches RUNNING, or a terminal status
+// if it never runs, and the stop span once the task is stopped. It must be called when the known
+// status of the task changed.
+func (mtask *managedTask) traceKnownStatus() {
+	knownStatus := mtask.GetKnownStatus()
+	mtask.traceLock.Lock()
+	defer mtask.traceLock.Unlock()
+	if !mtask.launched && (knownStatus == apitaskstatus.TaskRunning || knownStatus.Terminal()) {
+		mtask.launched = true
+		mtask.launchSpan.SetAttributes(tracing.String(tracing.AttributeStatus, knownStatus.String()))
+		mtask.launchSpan.End()
+	}
+	if knownStatus.Terminal() {
+		mtask.stopSpan.End()
+	}
+}
+
+// endTrace ends the spans of the task that are still in progress once it's stopped.
+func (mtask *managedTask) endTrace() {
+	mtask.traceLock.Lock()
+	defer mtask.traceLock.Unlock()
+	mtask.launched = true
+	mtask.launchSpan.End()
+	mtask.stopSpan.End()
+}
+
+// traceLinks returns the spans that the work done on the task after it stopped, such as its
+// cleanup, is linked to.
+func (mtask *managedTask) traceLinks() []*tracing.Span {
+	mtask.traceLock.Lock()
+	defer mtask.traceLock.Unlock()
+	return []*tracing.Span{mtask.launchSpan, mtask.stopSpan}
+}
+
+// startContainerTransitionSpan starts the span of a container transition as a child of the
+// span of its task. The docker calls made for the container until the span ends are recorded
+// as its children.
+func (engine *DockerTaskEngine) startContainerTransitionSpan(task *apitask.Task, container *apicontainer.Container,
+	nextState apicontainerstatus.ContainerStatus) *tracing.Span {
+	name, ok := containerTransitionSpanNames[nextState]
+	if !ok {
+		name = "container.transition"
+	}
+	ctx, span := tracing.StartSpan(engine.taskTraceContext(task), name, tracing.SpanKindInternal,
+		tracing.String(tracing.AttributeTaskARN, task.Arn),
+		tracing.String(tracing.AttributeContainerName, container.Name),
+		tracing.String(tracing.AttributeStatus, nextState.String()))
+	if span != nil {
+		engine.containerTraceContexts.Store(container, ctx)
+	}
+	return span
+}
+
+// endContainerTransitionSpan ends the span of a container transition with the outcome of the
+// transition.
+func (engine *DockerTaskEngine) endContainerTransitionSpan(container *apicontainer.Container, span *tracing.Span,
+	metadata dockerapi.DockerContainerMetadata) {
+	if span == nil {
+		return
+	}
+	engine.containerTraceContexts.Delete(container)
+	if dockerID := container.GetRuntimeID(); dockerID != "" {
+		span.SetAttributes(tracing.String(tracing.AttributeDockerID, dockerID))
+	}
+	if metadata.Error != nil {
+		span.RecordError(metadata.Error)
+	}
+	span.End()
+}
+
+// containerContext returns the context to make the docker calls of a container transition
+// with, so that they're traced as part of the transition.
+func (engine *DockerTaskEngine) containerContext(container *apicontainer.Container) context.Context {
+	if ctx, ok := engine.containerTraceContexts.Load(container); ok {
+		return ctx.(context.Context)
+	}
+	return engine.ctx
+}
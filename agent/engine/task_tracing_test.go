@@ -0,0 +1,196 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportedSpan is the part of an OTLP span that the tests check
+type exportedSpan struct {
+	TraceID      string         `json:"traceId"`
+	SpanID       string         `json:"spanId"`
+	ParentSpanID string         `json:"parentSpanId"`
+	Name         string         `json:"name"`
+	Links        []exportedLink `json:"links"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+}
+
+type exportedLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+func (span exportedSpan) attribute(key string) string {
+	for _, attribute := range span.Attributes {
+		if attribute.Key == key {
+			return attribute.Value.StringValue
+		}
+	}
+	return ""
+}
+
+// startTestCollector enables tracing with a collector that sends the spans it receives on the
+// returned channel. The spans are exported when the returned cancel function is called.
+func startTestCollector(t *testing.T) (<-chan []exportedSpan, func(), func()) {
+	exportedSpans := make(chan []exportedSpan, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		exportedSpans <- request.ResourceSpans[0].ScopeSpans[0].Spans
+	}))
+	tracingCtx, cancelTracing := context.WithCancel(context.Background())
+	tracing.Init(tracingCtx, &config.Config{OTLPTracesEndpoint: server.URL})
+	return exportedSpans, cancelTracing, func() {
+		tracing.Init(context.Background(), &config.Config{})
+		server.Close()
+	}
+}
+
+func receiveSpans(t *testing.T, exportedSpans <-chan []exportedSpan) []exportedSpan {
+	select {
+	case spans := <-exportedSpans:
+		return spans
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for spans to be exported")
+	}
+	return nil
+}
+
+func TestContainerTransitionTracing(t *testing.T) {
+	exportedSpans, cancelTracing, done := startTestCollector(t)
+	defer done()
+
+	taskEngine := &DockerTaskEngine{
+		ctx:          context.Background(),
+		managedTasks: make(map[string]*managedTask),
+	}
+	var dockerCallSpan *tracing.Span
+	taskEngine.containerStatusToTransitionFunction = map[apicontainerstatus.ContainerStatus]transitionApplyFunc{
+		apicontainerstatus.ContainerCreated: func(task *apitask.Task, container *apicontainer.Container) dockerapi.DockerContainerMetadata {
+			// This is the context that docker calls are made with
+			dockerCallSpan = tracing.SpanFromContext(taskEngine.containerContext(container))
+			container.SetRuntimeID("dockerid")
+			return dockerapi.DockerContainerMetadata{DockerID: "dockerid"}
+		},
+	}
+	task := &apitask.Task{Arn: "arn", Family: "family", Version: "1"}
+	container := &apicontainer.Container{Name: "c"}
+	mtask := taskEngine.newManagedTask(task)
+	require.NotNil(t, mtask.launchSpan)
+
+	taskEngine.applyContainerState(task, container, apicontainerstatus.ContainerCreated)
+	require.NotNil(t, dockerCallSpan)
+	assert.Equal(t, mtask.launchSpan.TraceID(), dockerCallSpan.TraceID())
+	assert.Equal(t, taskEngine.ctx, taskEngine.containerContext(container),
+		"Docker calls made after the transition shouldn't be traced as part of it")
+
+	mtask.launchSpan.End()
+	cancelTracing()
+	spans := receiveSpans(t, exportedSpans)
+	require.Len(t, spans, 2)
+	transitionSpan, taskSpan := spans[0], spans[1]
+	assert.Equal(t, "task.launch", taskSpan.Name)
+	assert.Equal(t, "arn", taskSpan.attribute(tracing.AttributeTaskARN))
+	assert.Equal(t, "family", taskSpan.attribute(tracing.AttributeTaskFamily))
+	assert.Equal(t, "container.create", transitionSpan.Name)
+	assert.Equal(t, taskSpan.SpanID, transitionSpan.ParentSpanID)
+	assert.Equal(t, "c", transitionSpan.attribute(tracing.AttributeContainerName))
+	assert.Equal(t, "dockerid", transitionSpan.attribute(tracing.AttributeDockerID))
+}
+
+func TestTaskLaunchAndStopTracing(t *testing.T) {
+	exportedSpans, cancelTracing, done := startTestCollector(t)
+	defer done()
+
+	taskEngine := &DockerTaskEngine{
+		ctx:          context.Background(),
+		managedTasks: make(map[string]*managedTask),
+	}
+	noop := func(task *apitask.Task, container *apicontainer.Container) dockerapi.DockerContainerMetadata {
+		return dockerapi.DockerContainerMetadata{}
+	}
+	taskEngine.containerStatusToTransitionFunction = map[apicontainerstatus.ContainerStatus]transitionApplyFunc{
+		apicontainerstatus.ContainerRunning: noop,
+		apicontainerstatus.ContainerStopped: noop,
+	}
+	task := &apitask.Task{Arn: "arn", DesiredStatusUnsafe: apitaskstatus.TaskRunning}
+	container := &apicontainer.Container{Name: "c"}
+	mtask := taskEngine.newManagedTask(task)
+
+	taskEngine.applyContainerState(task, container, apicontainerstatus.ContainerRunning)
+	// The launch span is exported as soon as the task is running
+	task.SetKnownStatus(apitaskstatus.TaskRunning)
+	mtask.traceKnownStatus()
+
+	task.SetDesiredStatus(apitaskstatus.TaskStopped)
+	taskEngine.applyContainerState(task, container, apicontainerstatus.ContainerStopped)
+	task.SetKnownStatus(apitaskstatus.TaskStopped)
+	mtask.traceKnownStatus()
+	_, cleanupSpan := tracing.StartLinkedSpan(taskEngine.ctx, mtask.traceLinks(), "task.cleanup", tracing.SpanKindInternal)
+	cleanupSpan.End()
+
+	cancelTracing()
+	spans := receiveSpans(t, exportedSpans)
+	require.Len(t, spans, 5)
+	startSpan, launchSpan := spans[0], spans[1]
+	stopTransitionSpan, stopSpan, taskCleanupSpan := spans[2], spans[3], spans[4]
+	assert.Equal(t, "task.launch", launchSpan.Name)
+	assert.Equal(t, "RUNNING", launchSpan.attribute(tracing.AttributeStatus))
+	assert.Equal(t, "container.start", startSpan.Name)
+	assert.Equal(t, launchSpan.SpanID, startSpan.ParentSpanID)
+
+	assert.Equal(t, "task.stop", stopSpan.Name)
+	assert.Empty(t, stopSpan.ParentSpanID)
+	assert.NotEqual(t, launchSpan.TraceID, stopSpan.TraceID)
+	assert.Equal(t, []exportedLink{{TraceID: launchSpan.TraceID, SpanID: launchSpan.SpanID}}, stopSpan.Links)
+	assert.Equal(t, "container.stop", stopTransitionSpan.Name)
+	assert.Equal(t, stopSpan.SpanID, stopTransitionSpan.ParentSpanID)
+	assert.Equal(t, stopSpan.TraceID, stopTransitionSpan.TraceID)
+
+	assert.Equal(t, "task.cleanup", taskCleanupSpan.Name)
+	assert.Empty(t, taskCleanupSpan.ParentSpanID)
+	assert.Equal(t, []exportedLink{
+		{TraceID: launchSpan.TraceID, SpanID: launchSpan.SpanID},
+		{TraceID: stopSpan.TraceID, SpanID: stopSpan.SpanID},
+	}, taskCleanupSpan.Links)
+}
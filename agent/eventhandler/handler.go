@@ -24,9 +24,9 @@ import (
 )
 
 // HandleEngineEvents handles state change events from the state change event channel by sending it to
-// responsible event handler
+// responsible event handler. Every event is also published to the local subscribers of the broadcaster, if any.
 func HandleEngineEvents(ctx context.Context, taskEngine engine.TaskEngine, client api.ECSClient,
-	taskHandler *TaskHandler, attachmentEventHandler *AttachmentEventHandler) {
+	taskHandler *TaskHandler, attachmentEventHandler *AttachmentEventHandler, broadcaster *statechange.Broadcaster) {
 
 	for {
 		stateChangeEvents := taskEngine.StateChangeEvents()
@@ -42,6 +42,9 @@ func HandleEngineEvents(ctx context.Context, taskEngine engine.TaskEngine, clien
 					seelog.Error("Unable to handle state change event. The events channel is closed")
 					break
 				}
+				if broadcaster != nil {
+					broadcaster.Publish(event)
+				}
 				err := handleEngineEvent(event, client, taskHandler, attachmentEventHandler)
 				if err != nil {
 					seelog.Errorf("Handler unable to add state change event %v: %v", event, err)
@@ -58,6 +61,9 @@ func handleEngineEvent(event statechange.Event, client api.ECSClient, taskHandle
 		return taskHandler.AddStateChangeEvent(event, client)
 	case statechange.AttachmentEvent:
 		return attachmentEventHandler.AddStateChangeEvent(event)
+	case statechange.SecretsRefreshedEvent:
+		// Refreshed secrets are only published to local subscribers
+		return nil
 	default:
 		return fmt.Errorf("unrecognized event type: %d", event.GetEventType())
 	}
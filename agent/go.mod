@@ -12,13 +12,14 @@ require (
 	github.com/containerd/continuity v0.0.0-20181023183536-c220ac4f01b8 // indirect
 	github.com/containernetworking/cni v0.7.1
 	github.com/containernetworking/plugins v0.8.6
+	github.com/coreos/go-systemd v0.0.0-20180511133405-39ca1b05acc7
 	github.com/deniswernert/udev v0.0.0-20140626150257-82fe5be8ca5f
 	github.com/didip/tollbooth v3.0.2+incompatible
-	github.com/docker/distribution v0.0.0-20181002220433-1cb4180b1a5b // indirect
+	github.com/docker/distribution v0.0.0-20181002220433-1cb4180b1a5b
 	github.com/docker/docker v0.0.0-20200531234253-77e06fda0c94
 	github.com/docker/go-connections v0.4.0
 	github.com/docker/go-units v0.4.0
-	github.com/godbus/dbus v4.1.0+incompatible // indirect
+	github.com/godbus/dbus v4.1.0+incompatible
 	github.com/golang/mock v1.1.1
 	github.com/google/go-cmp v0.5.6 // indirect
 	github.com/gorilla/mux v1.8.0
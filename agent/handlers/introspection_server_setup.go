@@ -22,10 +22,13 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/api/ecsclient"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	handlersutils "github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
 )
@@ -55,8 +58,15 @@ var (
 	pprofTraceHandler   = pprof.Trace
 )
 
-func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersutils.DockerStateResolver, cfg *config.Config) *http.Server {
-	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.LicensePath}
+func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersutils.DockerStateResolver,
+	broadcaster *statechange.Broadcaster, imagePrewarmer handlersutils.ImagePrewarmStatusReporter,
+	statsEngine stats.Engine, registeredResources v1.RegisteredResources, cfg *config.Config) *http.Server {
+	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.LicensePath, v1.StateChangeStreamPath,
+		v1.ResourcesPath}
+
+	if imagePrewarmer != nil {
+		paths = append(paths, v1.ImagePrewarmPath)
+	}
 
 	if cfg.EnableRuntimeStats.Enabled() {
 		paths = append(paths, pprofBasePath, pprofCMDLinePath, pprofProfilePath, pprofSymbolPath, pprofTracePath)
@@ -77,6 +87,11 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 	serverMux.HandleFunc("/", defaultHandler)
 
 	v1HandlersSetup(serverMux, containerInstanceArn, taskEngine, cfg)
+	serverMux.HandleFunc(v1.StateChangeStreamPath, v1.StateChangeStreamHandler(broadcaster))
+	serverMux.HandleFunc(v1.ResourcesPath, v1.ResourcesHandler(registeredResources, taskEngine, statsEngine))
+	if imagePrewarmer != nil {
+		serverMux.HandleFunc(v1.ImagePrewarmPath, v1.ImagePrewarmHandler(imagePrewarmer))
+	}
 	pprofHandlerSetup(serverMux, cfg)
 
 	// Log all requests and then pass through to serverMux
@@ -121,12 +136,31 @@ func pprofHandlerSetup(serverMux *http.ServeMux, cfg *config.Config) {
 // ServeIntrospectionHTTPEndpoint serves information about this agent/containerInstance and tasks
 // running on it. "V1" here indicates the hostname version of this server instead
 // of the handler versions, i.e. "V1" server can include "V1" and "V2" handlers.
-func ServeIntrospectionHTTPEndpoint(ctx context.Context, containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *config.Config) {
+// State change events published to the broadcaster are streamed to local subscribers. The progress of
+// the image prewarmer is reported when there is one. The resources of the container instance, including
+// the registered GPUs, are compared to the reservations of its tasks and to their usage from the stats engine.
+func ServeIntrospectionHTTPEndpoint(ctx context.Context, containerInstanceArn *string, taskEngine engine.TaskEngine,
+	broadcaster *statechange.Broadcaster, imagePrewarmer *engine.ImagePrewarmer, statsEngine stats.Engine,
+	registeredGPUIDs []string, cfg *config.Config) {
 	// Is this the right level to type assert, assuming we'd abstract multiple taskengines here?
 	// Revisit if we ever add another type..
 	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
 
-	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, cfg)
+	var prewarmStatusReporter handlersutils.ImagePrewarmStatusReporter
+	if imagePrewarmer != nil {
+		prewarmStatusReporter = imagePrewarmer
+	}
+	cpu, memory := ecsclient.RegisteredCPUAndMemory(cfg)
+	registeredResources := v1.RegisteredResources{
+		CPU:               cpu,
+		MemoryMiB:         memory,
+		ReservedMemoryMiB: cfg.ReservedMemory,
+		ReservedPorts:     cfg.ReservedPorts,
+		ReservedPortsUDP:  cfg.ReservedPortsUDP,
+		GPUIDs:            registeredGPUIDs,
+	}
+	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, broadcaster, prewarmStatusReporter,
+		statsEngine, registeredResources, cfg)
 
 	go func() {
 		<-ctx.Done()
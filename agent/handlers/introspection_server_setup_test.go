@@ -16,21 +16,30 @@
 package handlers
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/api"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	mock_utils "github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -298,7 +307,7 @@ func TestPProfHandlerSetup(t *testing.T) {
 					assert.Equal(t, p, recorder.Body.String())
 				} else {
 					assert.Equal(t, http.StatusOK, recorder.Code)
-					assert.Equal(t, `{"AvailableCommands":["/v1/metadata","/v1/tasks","/license"]}`, recorder.Body.String())
+					assert.Equal(t, `{"AvailableCommands":["/v1/metadata","/v1/tasks","/license","/v1/events","/v1/resources"]}`, recorder.Body.String())
 
 				}
 			})
@@ -471,7 +480,7 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockStateResolver := mock_utils.NewMockDockerStateResolver(ctrl)
+	mockStateResolver := mock_utils.NewMockTaskLifecycleManager(ctrl)
 
 	state := dockerstate.NewTaskEngineState()
 	stateSetupHelper(state, testTasks)
@@ -480,7 +489,7 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 		mockStateResolver.EXPECT().State().Return(state)
 	}
 
-	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, &config.Config{
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, statechange.NewBroadcaster(), nil, nil, v1.RegisteredResources{}, &config.Config{
 		Cluster:            testClusterArn,
 		EnableRuntimeStats: runtimeStatsConfigForTest,
 	})
@@ -491,3 +500,253 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 
 	return recorder
 }
+
+func TestIntrospectionServerDoesntServeTaskLifecycleAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The task lifecycle API has its own server, bound to the loopback interface
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockTaskLifecycleManager(ctrl), statechange.NewBroadcaster(), nil, nil, v1.RegisteredResources{},
+		&config.Config{
+			Cluster:                    testClusterArn,
+			EnableIntrospectionTaskAPI: config.BooleanDefaultFalse{Value: config.ExplicitlyEnabled},
+		})
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+
+	var resp rootResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.NotContains(t, resp.AvailableCommands, v1.AddTaskPath)
+	assert.NotContains(t, resp.AvailableCommands, v1.StopTaskPath)
+
+	// The request falls through to the list of the available commands, the task engine isn't called
+	recorder = httptest.NewRecorder()
+	requestHandler.Handler.ServeHTTP(recorder, newTaskLifecycleRequest(v1.StopTaskPath+"?taskarn=task1", "127.0.0.1:4242", ""))
+	var stopResp rootResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stopResp))
+	assert.Equal(t, resp, stopResp)
+}
+
+func TestImagePrewarmHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pullStartedAt := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	prewarmStatus := image.PrewarmStatus{
+		Manifest: "/etc/ecs/image-prewarm.json",
+		Images: []image.PrewarmImageStatus{
+			{Image: "busybox", Status: image.PrewarmPulling, PullStartedAt: &pullStartedAt},
+			{Image: "alpine", Platform: "linux/arm64", Status: image.PrewarmPending},
+		},
+	}
+	imagePrewarmer := mock_utils.NewMockImagePrewarmStatusReporter(ctrl)
+	imagePrewarmer.EXPECT().Status().Return(prewarmStatus)
+
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockTaskLifecycleManager(ctrl), statechange.NewBroadcaster(), imagePrewarmer,
+		nil, v1.RegisteredResources{}, &config.Config{Cluster: testClusterArn})
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	var rootResp rootResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rootResp))
+	assert.Contains(t, rootResp.AvailableCommands, v1.ImagePrewarmPath)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", v1.ImagePrewarmPath, nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp image.PrewarmStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, prewarmStatus, resp)
+}
+
+func TestImagePrewarmHandlerWithoutManifest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockTaskLifecycleManager(ctrl), statechange.NewBroadcaster(), nil,
+		nil, v1.RegisteredResources{}, &config.Config{Cluster: testClusterArn})
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	var resp rootResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.NotContains(t, resp.AvailableCommands, v1.ImagePrewarmPath)
+}
+
+func TestResourcesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	web := &apicontainer.Container{
+		Name:   "web",
+		GPUIDs: []string{"gpu-0"},
+		KnownPortBindingsUnsafe: []apicontainer.PortBinding{
+			{ContainerPort: 80, HostPort: 8080, Protocol: apicontainer.TransportProtocolTCP},
+			{ContainerPort: 53, HostPort: 5353, Protocol: apicontainer.TransportProtocolUDP},
+		},
+	}
+	web.SetRuntimeID("web-id")
+	webTask := &apitask.Task{Arn: "web-task", CPU: 1, Memory: 512, Containers: []*apicontainer.Container{web}}
+	webTask.SetKnownStatus(apitaskstatus.TaskRunning)
+
+	worker := &apicontainer.Container{Name: "worker", CPU: 256, Memory: 128,
+		KnownPortBindingsUnsafe: []apicontainer.PortBinding{
+			{ContainerPort: 80, HostPort: 8081, Protocol: apicontainer.TransportProtocolTCP},
+		},
+	}
+	worker.SetRuntimeID("worker-id")
+	workerTask := &apitask.Task{Arn: "worker-task", Containers: []*apicontainer.Container{worker}}
+	workerTask.SetKnownStatus(apitaskstatus.TaskRunning)
+
+	stoppedTask := &apitask.Task{Arn: "stopped-task", CPU: 4, Memory: 4096,
+		Containers: []*apicontainer.Container{{Name: "stopped"}}}
+	stoppedTask.SetKnownStatus(apitaskstatus.TaskStopped)
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*apitask.Task{webTask, workerTask, stoppedTask})
+	taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+	taskEngine.EXPECT().State().Return(state)
+
+	cpuUsage := 50.0
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	statsEngine.EXPECT().ContainerAverageUsage("web-task", "web-id", time.Minute).Return(
+		&stats.ContainerUsage{Samples: 60, CPUUsagePercent: &cpuUsage, MemoryUsageBytes: 200 * stats.BytesInMiB}, nil)
+	statsEngine.EXPECT().ContainerAverageUsage("worker-task", "worker-id", time.Minute).Return(
+		nil, errors.New("no stats"))
+
+	registered := v1.RegisteredResources{
+		CPU:              4096,
+		MemoryMiB:        7680,
+		ReservedPorts:    []uint16{22},
+		ReservedPortsUDP: []uint16{},
+		GPUIDs:           []string{"gpu-0", "gpu-1"},
+	}
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), taskEngine,
+		statechange.NewBroadcaster(), nil, statsEngine, registered, &config.Config{Cluster: testClusterArn})
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v1.ResourcesPath, nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp v1.ResourcesResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, registered, resp.Registered)
+	assert.Equal(t, v1.AllocatedResources{
+		Tasks:     2,
+		CPU:       1024 + 256,
+		MemoryMiB: 512 + 128,
+		Ports:     []uint16{8080, 8081},
+		PortsUDP:  []uint16{5353},
+		GPUIDs:    []string{"gpu-0"},
+	}, resp.Allocated)
+	assert.Equal(t, v1.UsedResources{CPU: 512, MemoryMiB: 200, Containers: 1}, resp.Used)
+	assert.Equal(t, v1.UnallocatedResources{CPU: 4096 - 1280, MemoryMiB: 7680 - 640, GPUs: 1}, resp.Unallocated)
+}
+
+func TestStateChangeStreamHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	broadcaster := statechange.NewBroadcaster()
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockTaskLifecycleManager(ctrl), broadcaster, nil, nil, v1.RegisteredResources{}, &config.Config{Cluster: testClusterArn})
+	server := httptest.NewServer(requestHandler.Handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + v1.StateChangeStreamPath + "?taskarn=task1&type=container,task")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The subscription is created before the response headers are written
+	broadcaster.Publish(api.ContainerStateChange{TaskArn: "task2", ContainerName: "other",
+		Status: apicontainerstatus.ContainerRunning})
+	broadcaster.Publish(api.ManagedAgentStateChange{TaskArn: "task1", Name: "ExecuteCommandAgent"})
+	broadcaster.Publish(api.ContainerStateChange{TaskArn: "task1", ContainerName: "foobar",
+		RuntimeID: "dockerid", Status: apicontainerstatus.ContainerRunning})
+	broadcaster.Publish(api.TaskStateChange{TaskARN: "task1", Status: apitaskstatus.TaskRunning})
+	// The events are timestamped when published rather than when they're written to the stream
+	publishedBy := time.Now()
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() (string, *v1.StateChangeEventResponse) {
+		eventLine, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		dataLine, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		event := &v1.StateChangeEventResponse{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(dataLine), "data: ")), event))
+		return strings.TrimSpace(eventLine), event
+	}
+
+	eventType, event := readEvent()
+	assert.Equal(t, "event: container", eventType)
+	assert.Equal(t, "task1", event.TaskARN)
+	assert.Equal(t, "foobar", event.ContainerName)
+	assert.Equal(t, "dockerid", event.DockerId)
+	assert.Equal(t, "RUNNING", event.Status)
+	assert.False(t, event.Timestamp.After(publishedBy), "%v is after the event was published", event.Timestamp)
+
+	eventType, event = readEvent()
+	assert.Equal(t, "event: task", eventType)
+	assert.Equal(t, "task1", event.TaskARN)
+	assert.Equal(t, "RUNNING", event.Status)
+	assert.False(t, event.Timestamp.After(publishedBy), "%v is after the event was published", event.Timestamp)
+}
+
+func TestStateChangeStreamHandlerSecretsRefreshed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	broadcaster := statechange.NewBroadcaster()
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockTaskLifecycleManager(ctrl), broadcaster, nil, nil, v1.RegisteredResources{}, &config.Config{Cluster: testClusterArn})
+	server := httptest.NewServer(requestHandler.Handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + v1.StateChangeStreamPath + "?type=secretsrefreshed")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	broadcaster.Publish(api.TaskStateChange{TaskARN: "task1", Status: apitaskstatus.TaskRunning})
+	broadcaster.Publish(api.SecretsRefreshedStateChange{TaskArn: "task1", ContainerName: "foobar",
+		RuntimeID: "dockerid", Secrets: []string{"db-password"}, FilesUpdated: true})
+
+	reader := bufio.NewReader(resp.Body)
+	eventLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: secretsrefreshed", strings.TrimSpace(eventLine))
+	dataLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	event := &v1.StateChangeEventResponse{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(dataLine), "data: ")), event))
+	assert.Equal(t, "task1", event.TaskARN)
+	assert.Equal(t, "foobar", event.ContainerName)
+	assert.Equal(t, "dockerid", event.DockerId)
+	assert.Equal(t, []string{"db-password"}, event.Secrets)
+	require.NotNil(t, event.FilesUpdated)
+	assert.True(t, *event.FilesUpdated)
+}
+
+func TestStateChangeStreamHandlerInvalidRequests(t *testing.T) {
+	handler := v1.StateChangeStreamHandler(statechange.NewBroadcaster())
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v1.StateChangeStreamPath+"?type=task,foo", nil)
+	handler(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", v1.StateChangeStreamPath, nil)
+	handler(recorder, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -13,7 +13,7 @@
 //
 
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/aws/amazon-ecs-agent/agent/handlers/utils (interfaces: DockerStateResolver)
+// Source: github.com/aws/amazon-ecs-agent/agent/handlers/utils (interfaces: DockerStateResolver,TaskLifecycleManager,ImagePrewarmStatusReporter)
 
 // Package mock_utils is a generated GoMock package.
 package mock_utils
@@ -21,7 +21,9 @@ package mock_utils
 import (
 	reflect "reflect"
 
+	task "github.com/aws/amazon-ecs-agent/agent/api/task"
 	dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	image "github.com/aws/amazon-ecs-agent/agent/engine/image"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -61,3 +63,89 @@ func (mr *MockDockerStateResolverMockRecorder) State() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockDockerStateResolver)(nil).State))
 }
+
+// MockTaskLifecycleManager is a mock of TaskLifecycleManager interface
+type MockTaskLifecycleManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockTaskLifecycleManagerMockRecorder
+}
+
+// MockTaskLifecycleManagerMockRecorder is the mock recorder for MockTaskLifecycleManager
+type MockTaskLifecycleManagerMockRecorder struct {
+	mock *MockTaskLifecycleManager
+}
+
+// NewMockTaskLifecycleManager creates a new mock instance
+func NewMockTaskLifecycleManager(ctrl *gomock.Controller) *MockTaskLifecycleManager {
+	mock := &MockTaskLifecycleManager{ctrl: ctrl}
+	mock.recorder = &MockTaskLifecycleManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTaskLifecycleManager) EXPECT() *MockTaskLifecycleManagerMockRecorder {
+	return m.recorder
+}
+
+// AddTask mocks base method
+func (m *MockTaskLifecycleManager) AddTask(arg0 *task.Task) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddTask", arg0)
+}
+
+// AddTask indicates an expected call of AddTask
+func (mr *MockTaskLifecycleManagerMockRecorder) AddTask(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTask", reflect.TypeOf((*MockTaskLifecycleManager)(nil).AddTask), arg0)
+}
+
+// State mocks base method
+func (m *MockTaskLifecycleManager) State() dockerstate.TaskEngineState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(dockerstate.TaskEngineState)
+	return ret0
+}
+
+// State indicates an expected call of State
+func (mr *MockTaskLifecycleManagerMockRecorder) State() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockTaskLifecycleManager)(nil).State))
+}
+
+// MockImagePrewarmStatusReporter is a mock of ImagePrewarmStatusReporter interface
+type MockImagePrewarmStatusReporter struct {
+	ctrl     *gomock.Controller
+	recorder *MockImagePrewarmStatusReporterMockRecorder
+}
+
+// MockImagePrewarmStatusReporterMockRecorder is the mock recorder for MockImagePrewarmStatusReporter
+type MockImagePrewarmStatusReporterMockRecorder struct {
+	mock *MockImagePrewarmStatusReporter
+}
+
+// NewMockImagePrewarmStatusReporter creates a new mock instance
+func NewMockImagePrewarmStatusReporter(ctrl *gomock.Controller) *MockImagePrewarmStatusReporter {
+	mock := &MockImagePrewarmStatusReporter{ctrl: ctrl}
+	mock.recorder = &MockImagePrewarmStatusReporterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockImagePrewarmStatusReporter) EXPECT() *MockImagePrewarmStatusReporterMockRecorder {
+	return m.recorder
+}
+
+// Status mocks base method
+func (m *MockImagePrewarmStatusReporter) Status() image.PrewarmStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(image.PrewarmStatus)
+	return ret0
+}
+
+// Status indicates an expected call of Status
+func (mr *MockImagePrewarmStatusReporterMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockImagePrewarmStatusReporter)(nil).Status))
+}
@@ -0,0 +1,78 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	handlersutils "github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/cihub/seelog"
+)
+
+// taskLifecycleServerAddress is the address of the server of the endpoints that add and stop tasks. Unlike the
+// introspection server, it only listens on the loopback interface.
+var taskLifecycleServerAddress = net.JoinHostPort("127.0.0.1", strconv.Itoa(config.AgentTaskLifecyclePort))
+
+func taskLifecycleServerSetup(taskEngine handlersutils.TaskLifecycleManager) *http.Server {
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc(v1.AddTaskPath, v1.AddTaskHandler(taskEngine))
+	serverMux.HandleFunc(v1.StopTaskPath, v1.StopTaskHandler(taskEngine))
+
+	// Log all requests and then pass through to serverMux
+	loggingServeMux := http.NewServeMux()
+	loggingServeMux.Handle("/", LoggingHandler{serverMux})
+
+	return &http.Server{
+		Addr:         taskLifecycleServerAddress,
+		Handler:      loggingServeMux,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+}
+
+// ServeTaskLifecycleHTTPEndpoint serves the endpoints that add and stop tasks on the loopback interface, when
+// they are enabled.
+func ServeTaskLifecycleHTTPEndpoint(ctx context.Context, taskEngine engine.TaskEngine, cfg *config.Config) {
+	if !cfg.EnableIntrospectionTaskAPI.Enabled() {
+		return
+	}
+	server := taskLifecycleServerSetup(taskEngine.(*engine.DockerTaskEngine))
+
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			// Error from closing listeners, or context timeout:
+			seelog.Infof("HTTP server Shutdown: %v", err)
+		}
+	}()
+
+	for {
+		retry.RetryWithBackoff(retry.NewExponentialBackoff(time.Second, time.Minute, 0.2, 2), func() error {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				seelog.Errorf("Error running task lifecycle endpoint: %v", err)
+				return err
+			}
+			// server was cleanly closed via context
+			return nil
+		})
+	}
+}
@@ -0,0 +1,202 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	mock_utils "github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
+	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskLifecycleServerListensOnLoopback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := taskLifecycleServerSetup(mock_utils.NewMockTaskLifecycleManager(ctrl))
+	assert.Equal(t, "127.0.0.1:51681", server.Addr)
+}
+
+const testAddTaskBody = `{"arn":"task-new","family":"sleep","version":"1","desiredStatus":"RUNNING",` +
+	`"containers":[{"name":"sleep","image":"busybox","command":["sleep","60"]}]}`
+
+func performTaskLifecycleRequest(taskEngine *mock_utils.MockTaskLifecycleManager, path, remoteAddr, body string) *httptest.ResponseRecorder {
+	return serveTaskLifecycleRequest(taskEngine, newTaskLifecycleRequest(path, remoteAddr, body))
+}
+
+func serveTaskLifecycleRequest(taskEngine *mock_utils.MockTaskLifecycleManager, req *http.Request) *httptest.ResponseRecorder {
+	requestHandler := taskLifecycleServerSetup(taskEngine)
+
+	recorder := httptest.NewRecorder()
+	requestHandler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// newTaskLifecycleRequest returns a request to the task lifecycle API that passes its checks, as sent by
+// curl on the host.
+func newTaskLifecycleRequest(path, remoteAddr, body string) *http.Request {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	req.Host = "localhost:51681"
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestAddTaskHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+	taskEngine.EXPECT().State().Return(dockerstate.NewTaskEngineState())
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *apitask.Task) {
+		assert.Equal(t, "task-new", task.Arn)
+		assert.Equal(t, apitaskstatus.TaskRunning, task.GetDesiredStatus())
+		require.Len(t, task.Containers, 1)
+		assert.Equal(t, "busybox", task.Containers[0].Image)
+	})
+
+	recorder := performTaskLifecycleRequest(taskEngine, v1.AddTaskPath, "127.0.0.1:4242", testAddTaskBody)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var taskResponse v1.TaskResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &taskResponse))
+	assert.Equal(t, "task-new", taskResponse.Arn)
+	assert.Equal(t, "RUNNING", taskResponse.DesiredStatus)
+}
+
+func TestAddTaskHandlerInvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name           string
+		remoteAddr     string
+		body           string
+		expectState    bool
+		expectedStatus int
+	}{
+		{"non loopback", "10.0.0.1:4242", testAddTaskBody, false, http.StatusForbidden},
+		{"malformed body", "127.0.0.1:4242", `{"arn":`, false, http.StatusBadRequest},
+		{"missing arn", "[::1]:4242", `{"family":"sleep"}`, false, http.StatusBadRequest},
+		{"stopped task", "127.0.0.1:4242", `{"arn":"task-new","desiredStatus":"STOPPED"}`, false, http.StatusBadRequest},
+		{"existing task", "127.0.0.1:4242", `{"arn":"task1"}`, true, http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+			if tc.expectState {
+				state := dockerstate.NewTaskEngineState()
+				stateSetupHelper(state, testTasks)
+				taskEngine.EXPECT().State().Return(state)
+			}
+
+			recorder := performTaskLifecycleRequest(taskEngine, v1.AddTaskPath, tc.remoteAddr, tc.body)
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestStopTaskHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, testTasks)
+	taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+	taskEngine.EXPECT().State().Return(state).Times(2)
+	stateTask, ok := state.TaskByArn("task1")
+	require.True(t, ok)
+	desiredStatus := stateTask.GetDesiredStatus()
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *apitask.Task) {
+		assert.False(t, stateTask == task, "The update must not be the task owned by the engine")
+		assert.Equal(t, "task1", task.Arn)
+		assert.Equal(t, apitaskstatus.TaskStopped, task.GetDesiredStatus())
+		assert.Empty(t, task.Containers)
+	})
+
+	recorder := performTaskLifecycleRequest(taskEngine, v1.StopTaskPath+"?taskarn=task1", "127.0.0.1:4242", "")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, desiredStatus, stateTask.GetDesiredStatus(), "Only the engine should change the desired status")
+
+	recorder = performTaskLifecycleRequest(taskEngine, v1.StopTaskPath+"?taskarn=unknown", "127.0.0.1:4242", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = performTaskLifecycleRequest(taskEngine, v1.StopTaskPath, "127.0.0.1:4242", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestTaskLifecycleRequestsFromBrowsers(t *testing.T) {
+	testCases := []struct {
+		name           string
+		modify         func(req *http.Request)
+		expectedStatus int
+	}{
+		{"text/plain body", func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") }, http.StatusUnsupportedMediaType},
+		{"form body", func(req *http.Request) { req.Header.Set("Content-Type", "multipart/form-data") }, http.StatusUnsupportedMediaType},
+		{"no content type", func(req *http.Request) { req.Header.Del("Content-Type") }, http.StatusUnsupportedMediaType},
+		{"cross origin", func(req *http.Request) { req.Header.Set("Origin", "http://example.com") }, http.StatusForbidden},
+		{"null origin", func(req *http.Request) { req.Header.Set("Origin", "null") }, http.StatusForbidden},
+		{"rebound host", func(req *http.Request) { req.Host = "attacker.example.com:51681" }, http.StatusForbidden},
+		{"host without port", func(req *http.Request) { req.Host = "attacker.example.com" }, http.StatusForbidden},
+	}
+
+	for _, path := range []string{v1.AddTaskPath, v1.StopTaskPath + "?taskarn=task1"} {
+		for _, tc := range testCases {
+			t.Run(path+" "+tc.name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				// The task engine must not be called
+				taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+				req := newTaskLifecycleRequest(path, "127.0.0.1:4242", testAddTaskBody)
+				tc.modify(req)
+				recorder := serveTaskLifecycleRequest(taskEngine, req)
+				assert.Equal(t, tc.expectedStatus, recorder.Code)
+			})
+		}
+	}
+}
+
+func TestTaskLifecycleRequestHosts(t *testing.T) {
+	for _, host := range []string{"localhost", "localhost:51681", "127.0.0.1", "127.0.0.1:51681"} {
+		t.Run(host, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			state := dockerstate.NewTaskEngineState()
+			stateSetupHelper(state, testTasks)
+			taskEngine := mock_utils.NewMockTaskLifecycleManager(ctrl)
+			taskEngine.EXPECT().State().Return(state)
+			taskEngine.EXPECT().AddTask(gomock.Any())
+			req := newTaskLifecycleRequest(v1.StopTaskPath+"?taskarn=task1", "127.0.0.1:4242", "")
+			req.Host = host
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			recorder := serveTaskLifecycleRequest(taskEngine, req)
+			assert.Equal(t, http.StatusAccepted, recorder.Code)
+		})
+	}
+}
@@ -18,6 +18,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -45,6 +46,7 @@ import (
 	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
 	mock_audit "github.com/aws/amazon-ecs-agent/agent/logger/audit/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
@@ -1357,6 +1359,8 @@ func TestV4ContainerMetadata(t *testing.T) {
 		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByID(containerID).Return(task, true).Times(2),
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		statsEngine.EXPECT().ContainerMemoryEvents(taskARN, containerID).Return(nil, errors.New("not running")),
 	)
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
 		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "us-west-2b", containerInstanceArn)
@@ -1534,6 +1538,8 @@ func TestV4BridgeContainerMetadata(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
 		state.EXPECT().TaskByID(containerID).Return(bridgeTask, true),
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		statsEngine.EXPECT().ContainerMemoryEvents(taskARN, containerID).Return(nil, errors.New("not running")),
 	)
 
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
@@ -1563,6 +1569,18 @@ func TestV4TaskStats(t *testing.T) {
 
 	dockerStats := &types.StatsJSON{}
 	dockerStats.NumProcs = 2
+	memoryEvents := &memevents.MemoryEvents{
+		OOMKills: 1,
+		Pressure: &memevents.Pressure{
+			Some: memevents.PressureStats{Avg10: 1.5, Total: 2000},
+		},
+	}
+	storageStats := &stats.ContainerStorageStats{
+		Timestamp:          time.Now(),
+		WritableLayerBytes: 1024,
+		Volumes:            map[string]int64{"scratch": 2048},
+		TaskTotalBytes:     3072,
+	}
 
 	containerMap := map[string]*apicontainer.DockerContainer{
 		containerName: {
@@ -1574,6 +1592,8 @@ func TestV4TaskStats(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, &stats.NetworkStatsPerSec{}, nil),
+		statsEngine.EXPECT().ContainerMemoryEvents(taskARN, containerID).Return(memoryEvents, nil),
+		statsEngine.EXPECT().ContainerStorageStats(taskARN, containerID).Return(storageStats, nil),
 	)
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
 		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
@@ -1583,12 +1603,17 @@ func TestV4TaskStats(t *testing.T) {
 	res, err := ioutil.ReadAll(recorder.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, recorder.Code)
-	var statsFromResult map[string]*types.StatsJSON
+	var statsFromResult map[string]*v4.StatsResponse
 	err = json.Unmarshal(res, &statsFromResult)
 	assert.NoError(t, err)
 	containerStats, ok := statsFromResult[containerID]
 	assert.True(t, ok)
 	assert.Equal(t, dockerStats.NumProcs, containerStats.NumProcs)
+	assert.Equal(t, memoryEvents, containerStats.Memory_events)
+	require.NotNil(t, containerStats.Storage_stats)
+	assert.Equal(t, storageStats.WritableLayerBytes, containerStats.Storage_stats.WritableLayerBytes)
+	assert.Equal(t, storageStats.Volumes, containerStats.Storage_stats.Volumes)
+	assert.Equal(t, storageStats.TaskTotalBytes, containerStats.Storage_stats.TaskTotalBytes)
 }
 
 func TestV4ContainerStats(t *testing.T) {
@@ -1607,6 +1632,8 @@ func TestV4ContainerStats(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, &stats.NetworkStatsPerSec{}, nil),
+		statsEngine.EXPECT().ContainerMemoryEvents(taskARN, containerID).Return(nil, errors.New("cgroup not found")),
+		statsEngine.EXPECT().ContainerStorageStats(taskARN, containerID).Return(nil, errors.New("not collected yet")),
 	)
 	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
 		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
@@ -1616,10 +1643,94 @@ func TestV4ContainerStats(t *testing.T) {
 	res, err := ioutil.ReadAll(recorder.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, recorder.Code)
-	var statsFromResult *types.StatsJSON
+	var statsFromResult *v4.StatsResponse
 	err = json.Unmarshal(res, &statsFromResult)
 	assert.NoError(t, err)
 	assert.Equal(t, dockerStats.NumProcs, statsFromResult.NumProcs)
+	assert.Nil(t, statsFromResult.Memory_events)
+	assert.Nil(t, statsFromResult.Storage_stats)
+}
+
+func TestV4ContainerStatsHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	history := []stats.StatsHistoryPoint{{Samples: 5, MemoryUsageBytes: 1024}}
+	gomock.InOrder(
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
+		statsEngine.EXPECT().ContainerStatsHistory(taskARN, containerID, 30*time.Second, 5*time.Second).Return(history, nil),
+	)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/stats/history?window=30s&resolution=5s", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var historyFromResult []stats.StatsHistoryPoint
+	err = json.Unmarshal(res, &historyFromResult)
+	assert.NoError(t, err)
+	assert.Equal(t, history, historyFromResult)
+}
+
+func TestV4ContainerStatsHistoryInvalidResolution(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	for _, query := range []string{"?window=abc", "?resolution=-1s", "?window=10s&resolution=1m", "?window=1h"} {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/stats/history"+query, nil)
+		server.Handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+	}
+}
+
+func TestV4TaskStatsHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	containerMap := map[string]*apicontainer.DockerContainer{
+		containerName: {
+			DockerID: containerID,
+		},
+	}
+	history := []stats.StatsHistoryPoint{{Samples: 1, MemoryUsageBytes: 2048}}
+	gomock.InOrder(
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
+		statsEngine.EXPECT().ContainerStatsHistory(taskARN, containerID, time.Minute, time.Second).Return(history, nil),
+	)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task/stats/history", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var historyFromResult map[string][]stats.StatsHistoryPoint
+	err = json.Unmarshal(res, &historyFromResult)
+	assert.NoError(t, err)
+	assert.Equal(t, history, historyFromResult[containerID])
 }
 
 func TestV4ContainerAssociations(t *testing.T) {
@@ -42,6 +42,12 @@ const (
 	// RequestTypeContainerStats specifies the container stats request type of StatsHandler.
 	RequestTypeContainerStats = "container stats"
 
+	// RequestTypeTaskStatsHistory specifies the task stats history request type of TaskStatsHistoryHandler.
+	RequestTypeTaskStatsHistory = "task stats history"
+
+	// RequestTypeContainerStatsHistory specifies the container stats history request type of ContainerStatsHistoryHandler.
+	RequestTypeContainerStatsHistory = "container stats history"
+
 	// RequestTypeAgentMetadata specifies the Agent metadata request type of AgentMetadataHandler.
 	RequestTypeAgentMetadata = "agent metadata"
 
@@ -13,10 +13,27 @@
 
 package utils
 
-import "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+import (
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+)
 
 // DockerStateResolver is a sub-interface for the engine.TaskEngine interface
 // to make it easy to test code in this package
 type DockerStateResolver interface {
 	State() dockerstate.TaskEngineState
 }
+
+// TaskLifecycleManager is a sub-interface for the engine.TaskEngine interface
+// used by the handlers that add and stop tasks
+type TaskLifecycleManager interface {
+	DockerStateResolver
+	AddTask(*apitask.Task)
+}
+
+// ImagePrewarmStatusReporter is a sub-interface for the engine.ImagePrewarmer
+// used by the handler that reports the progress of image pre-warming
+type ImagePrewarmStatusReporter interface {
+	Status() image.PrewarmStatus
+}
@@ -0,0 +1,41 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+)
+
+const (
+	// ImagePrewarmPath is the path of the progress of image pre-warming for v1 handler.
+	ImagePrewarmPath = "/v1/imageprewarm"
+
+	// RequestTypeImagePrewarm specifies the request type of ImagePrewarmHandler.
+	RequestTypeImagePrewarm = "image prewarm"
+)
+
+// ImagePrewarmHandler creates response for 'v1/imageprewarm' API. It reports the status of the pull of
+// every image of the image pre-warm manifest.
+func ImagePrewarmHandler(prewarmer utils.ImagePrewarmStatusReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, err := json.Marshal(prewarmer.Status())
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, RequestTypeImagePrewarm)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// ResourcesPath is the path of the resource accounting of the container instance for v1 handler.
+	ResourcesPath = "/v1/resources"
+
+	// RequestTypeResources specifies the request type of ResourcesHandler.
+	RequestTypeResources = "instance resources"
+
+	// usageWindow is the period over which the usage of containers is averaged.
+	usageWindow = time.Minute
+
+	cpuUnitsPerCore = 1024
+)
+
+// RegisteredResources are the resources that the container instance registers with.
+type RegisteredResources struct {
+	// CPU is in CPU units, 1024 units per core
+	CPU int64 `json:"CPU"`
+	// MemoryMiB is the memory of the host less ReservedMemoryMiB
+	MemoryMiB         int64    `json:"MemoryMiB"`
+	ReservedMemoryMiB uint16   `json:"ReservedMemoryMiB"`
+	ReservedPorts     []uint16 `json:"ReservedPorts"`
+	ReservedPortsUDP  []uint16 `json:"ReservedPortsUDP"`
+	GPUIDs            []string `json:"GPUIDs,omitempty"`
+}
+
+// AllocatedResources are the resources reserved by the tasks that aren't stopped yet.
+type AllocatedResources struct {
+	Tasks     int      `json:"Tasks"`
+	CPU       int64    `json:"CPU"`
+	MemoryMiB int64    `json:"MemoryMiB"`
+	Ports     []uint16 `json:"Ports"`
+	PortsUDP  []uint16 `json:"PortsUDP"`
+	GPUIDs    []string `json:"GPUIDs,omitempty"`
+}
+
+// UsedResources is the usage of the containers of running tasks, averaged over the last minute.
+type UsedResources struct {
+	CPU       int64 `json:"CPU"`
+	MemoryMiB int64 `json:"MemoryMiB"`
+	// Containers is the number of containers with stats, containers without stats aren't counted
+	Containers int `json:"Containers"`
+}
+
+// UnallocatedResources are the registered resources that no task has reserved.
+type UnallocatedResources struct {
+	CPU       int64 `json:"CPU"`
+	MemoryMiB int64 `json:"MemoryMiB"`
+	GPUs      int   `json:"GPUs"`
+}
+
+// ResourcesResponse is the schema for the resource accounting of the container instance.
+type ResourcesResponse struct {
+	Registered  RegisteredResources  `json:"Registered"`
+	Allocated   AllocatedResources   `json:"Allocated"`
+	Used        UsedResources        `json:"Used"`
+	Unallocated UnallocatedResources `json:"Unallocated"`
+}
+
+// ResourcesHandler creates response for 'v1/resources' API. It compares the resources that the
+// container instance registered with to the resources reserved by its tasks and to their usage.
+func ResourcesHandler(registered RegisteredResources, taskEngine utils.DockerStateResolver,
+	statsEngine stats.Engine) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := newResourcesResponse(registered, taskEngine.State().AllTasks(), statsEngine)
+		responseJSON, err := json.Marshal(resp)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, RequestTypeResources)
+	}
+}
+
+func newResourcesResponse(registered RegisteredResources, tasks []*apitask.Task, statsEngine stats.Engine) *ResourcesResponse {
+	resp := &ResourcesResponse{
+		Registered: registered,
+		Allocated: AllocatedResources{
+			Ports:    []uint16{},
+			PortsUDP: []uint16{},
+		},
+	}
+	for _, task := range tasks {
+		if task.GetKnownStatus().Terminal() {
+			continue
+		}
+		resp.Allocated.Tasks++
+		cpu, memory := taskReservations(task)
+		resp.Allocated.CPU += cpu
+		resp.Allocated.MemoryMiB += memory
+		for _, container := range task.Containers {
+			for _, binding := range container.GetKnownPortBindings() {
+				if binding.HostPort == 0 {
+					continue
+				}
+				if binding.Protocol == apicontainer.TransportProtocolUDP {
+					resp.Allocated.PortsUDP = append(resp.Allocated.PortsUDP, binding.HostPort)
+				} else {
+					resp.Allocated.Ports = append(resp.Allocated.Ports, binding.HostPort)
+				}
+			}
+			resp.Allocated.GPUIDs = append(resp.Allocated.GPUIDs, container.GPUIDs...)
+			if dockerID := container.GetRuntimeID(); dockerID != "" {
+				addContainerUsage(&resp.Used, task.Arn, dockerID, statsEngine)
+			}
+		}
+	}
+	resp.Allocated.Ports = sortedUniquePorts(resp.Allocated.Ports)
+	resp.Allocated.PortsUDP = sortedUniquePorts(resp.Allocated.PortsUDP)
+
+	resp.Unallocated = UnallocatedResources{
+		CPU:       registered.CPU - resp.Allocated.CPU,
+		MemoryMiB: registered.MemoryMiB - resp.Allocated.MemoryMiB,
+		GPUs:      len(registered.GPUIDs) - len(resp.Allocated.GPUIDs),
+	}
+	return resp
+}
+
+// taskReservations returns the CPU units and the memory, in MiB, reserved by a task. Tasks without
+// task level limits reserve the sum of the limits of their containers.
+func taskReservations(task *apitask.Task) (int64, int64) {
+	cpu := int64(task.CPU * cpuUnitsPerCore)
+	memory := task.Memory
+	if cpu == 0 {
+		for _, container := range task.Containers {
+			cpu += int64(container.CPU)
+		}
+	}
+	if memory == 0 {
+		for _, container := range task.Containers {
+			memory += int64(container.Memory)
+		}
+	}
+	return cpu, memory
+}
+
+// addContainerUsage adds the average usage of a container over the last minute.
+func addContainerUsage(used *UsedResources, taskARN string, dockerID string, statsEngine stats.Engine) {
+	usage, err := statsEngine.ContainerAverageUsage(taskARN, dockerID, usageWindow)
+	if err != nil {
+		seelog.Debugf("V1 resources: Unable to get the usage of container '%s' of task '%s': %v", dockerID, taskARN, err)
+		return
+	}
+	if usage.Samples == 0 {
+		return
+	}
+	used.Containers++
+	used.MemoryMiB += int64(usage.MemoryUsageBytes / stats.BytesInMiB)
+	if usage.CPUUsagePercent != nil {
+		used.CPU += int64(*usage.CPUUsagePercent * cpuUnitsPerCore / 100)
+	}
+}
+
+func sortedUniquePorts(ports []uint16) []uint16 {
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	unique := ports[:0]
+	for _, port := range ports {
+		if len(unique) > 0 && unique[len(unique)-1] == port {
+			continue
+		}
+		unique = append(unique, port)
+	}
+	return unique
+}
@@ -0,0 +1,278 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// StateChangeStreamPath is the path of the server-sent event stream of task,
+	// container, managed agent and attachment state changes, and of refreshed
+	// secrets. The stream can be filtered with the 'taskarn' query field and
+	// with the 'type' query field, which takes a comma separated list of event
+	// types.
+	StateChangeStreamPath = "/v1/events"
+
+	// RequestTypeStateChangeStream specifies the request type of StateChangeStreamHandler.
+	RequestTypeStateChangeStream = "state change stream"
+
+	eventTypeQueryField = "type"
+
+	// stateChangeStreamBufferSize is the number of events buffered for a
+	// subscriber before it's considered too slow and disconnected.
+	stateChangeStreamBufferSize = 1024
+)
+
+var (
+	// stateChangeStreamKeepAliveInterval is the interval at which a comment is
+	// written to an idle stream, so that closed connections are detected.
+	stateChangeStreamKeepAliveInterval = 30 * time.Second
+
+	stateChangeEventTypes = []statechange.EventType{
+		statechange.TaskEvent,
+		statechange.ContainerEvent,
+		statechange.ManagedAgentEvent,
+		statechange.AttachmentEvent,
+		statechange.SecretsRefreshedEvent,
+	}
+)
+
+// StateChangeEventResponse is the data of an event on the state change stream.
+type StateChangeEventResponse struct {
+	Type             string
+	TaskARN          string    `json:",omitempty"`
+	ContainerName    string    `json:",omitempty"`
+	DockerId         string    `json:",omitempty"`
+	ManagedAgentName string    `json:",omitempty"`
+	AttachmentARN    string    `json:",omitempty"`
+	Status           string    `json:",omitempty"`
+	Reason           string    `json:",omitempty"`
+	ExitCode         *int      `json:",omitempty"`
+	ImageDigest      string    `json:",omitempty"`
+	Secrets          []string  `json:",omitempty"`
+	FilesUpdated     *bool     `json:",omitempty"`
+	Timestamp        time.Time `json:",omitempty"`
+}
+
+// NewStateChangeEventResponse creates a StateChangeEventResponse from a state
+// change event, timestamped with the time it was published at. It returns
+// false for events of an unknown type.
+func NewStateChangeEventResponse(published statechange.PublishedEvent) (*StateChangeEventResponse, bool) {
+	event := published.Event
+	response := &StateChangeEventResponse{
+		Type:      event.GetEventType().String(),
+		Timestamp: published.PublishedAt,
+	}
+	switch change := event.(type) {
+	case api.TaskStateChange:
+		response.TaskARN = change.TaskARN
+		response.Status = change.Status.String()
+		response.Reason = change.Reason
+	case api.ContainerStateChange:
+		response.TaskARN = change.TaskArn
+		response.ContainerName = change.ContainerName
+		response.DockerId = change.RuntimeID
+		response.Status = change.Status.String()
+		response.Reason = change.Reason
+		response.ExitCode = change.ExitCode
+		response.ImageDigest = change.ImageDigest
+	case api.ManagedAgentStateChange:
+		response.TaskARN = change.TaskArn
+		response.ManagedAgentName = change.Name
+		if change.Container != nil {
+			response.ContainerName = change.Container.Name
+		}
+		response.Status = change.Status.String()
+		response.Reason = change.Reason
+	case api.AttachmentStateChange:
+		if change.Attachment == nil {
+			return nil, false
+		}
+		response.TaskARN = change.Attachment.TaskARN
+		response.AttachmentARN = change.Attachment.AttachmentARN
+		response.Status = change.Attachment.Status.String()
+	case api.SecretsRefreshedStateChange:
+		response.TaskARN = change.TaskArn
+		response.ContainerName = change.ContainerName
+		response.DockerId = change.RuntimeID
+		response.Secrets = change.Secrets
+		filesUpdated := change.FilesUpdated
+		response.FilesUpdated = &filesUpdated
+	default:
+		return nil, false
+	}
+	return response, true
+}
+
+// stateChangeFilter selects the events written to a stream.
+type stateChangeFilter struct {
+	taskARN    string
+	eventTypes map[string]struct{}
+}
+
+func (filter *stateChangeFilter) matches(response *StateChangeEventResponse) bool {
+	if filter.taskARN != "" && filter.taskARN != response.TaskARN {
+		return false
+	}
+	if len(filter.eventTypes) == 0 {
+		return true
+	}
+	_, ok := filter.eventTypes[response.Type]
+	return ok
+}
+
+func newStateChangeFilter(r *http.Request) (*stateChangeFilter, error) {
+	filter := &stateChangeFilter{eventTypes: make(map[string]struct{})}
+	filter.taskARN, _ = utils.ValueFromRequest(r, taskARNQueryField)
+	types, ok := utils.ValueFromRequest(r, eventTypeQueryField)
+	if !ok || types == "" {
+		return filter, nil
+	}
+	for _, eventType := range strings.Split(types, ",") {
+		eventType = strings.ToLower(strings.TrimSpace(eventType))
+		if !isStateChangeEventType(eventType) {
+			return nil, fmt.Errorf("unknown event type %q, valid types are %s", eventType,
+				strings.Join(stateChangeEventTypeNames(), ", "))
+		}
+		filter.eventTypes[eventType] = struct{}{}
+	}
+	return filter, nil
+}
+
+func isStateChangeEventType(name string) bool {
+	for _, eventType := range stateChangeEventTypes {
+		if eventType.String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func stateChangeEventTypeNames() []string {
+	names := make([]string, 0, len(stateChangeEventTypes))
+	for _, eventType := range stateChangeEventTypes {
+		names = append(names, eventType.String())
+	}
+	return names
+}
+
+// StateChangeStreamHandler creates the response for the 'v1/events' API. It
+// streams every state change event published to the broadcaster as a
+// server-sent event until the client disconnects. The connection is taken
+// over from the http server so that the stream isn't cut by the server's
+// write timeout.
+func StateChangeStreamHandler(broadcaster *statechange.Broadcaster) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeStateChangeStreamError(w, http.StatusMethodNotAllowed, "only GET is supported")
+			return
+		}
+		filter, err := newStateChangeFilter(r)
+		if err != nil {
+			writeStateChangeStreamError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			writeStateChangeStreamError(w, http.StatusInternalServerError, "streaming is not supported")
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			seelog.Errorf("Unable to take over the connection for the state change stream: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		subscription := broadcaster.Subscribe(stateChangeStreamBufferSize)
+		defer subscription.Unsubscribe()
+		seelog.Infof("Streaming state change events to %s", r.RemoteAddr)
+		if err := streamStateChanges(conn, rw.Writer, subscription, filter); err != nil {
+			seelog.Infof("State change stream to %s ended: %v", r.RemoteAddr, err)
+		}
+	}
+}
+
+func streamStateChanges(conn net.Conn, w *bufio.Writer, subscription *statechange.Subscription,
+	filter *stateChangeFilter) error {
+	// Deadlines set by the http server are not cleared when the connection is hijacked
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+	// The client isn't expected to send anything, reads only detect that it went away
+	clientGone := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(clientGone)
+	}()
+
+	fmt.Fprint(w, "HTTP/1.1 200 OK\r\n"+
+		"Content-Type: text/event-stream\r\n"+
+		"Cache-Control: no-cache\r\n"+
+		"Connection: close\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	keepAlive := time.NewTicker(stateChangeStreamKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-clientGone:
+			return fmt.Errorf("client closed the connection")
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case published, ok := <-subscription.Events():
+			if !ok {
+				return fmt.Errorf("subscriber too slow, events were dropped")
+			}
+			response, ok := NewStateChangeEventResponse(published)
+			if !ok || !filter.matches(response) {
+				continue
+			}
+			data, err := json.Marshal(response)
+			if err != nil {
+				seelog.Warnf("Unable to marshal state change event %v: %v", published.Event, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", response.Type, data)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func writeStateChangeStreamError(w http.ResponseWriter, status int, message string) {
+	responseJSON, _ := json.Marshal(&utils.ErrorMessage{
+		Code:          http.StatusText(status),
+		Message:       message,
+		HTTPErrorCode: status,
+	})
+	utils.WriteJSONToResponse(w, status, responseJSON, RequestTypeStateChangeStream)
+}
@@ -0,0 +1,195 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// AddTaskPath is the path used to add a task to the task engine. It accepts
+	// a POST with a task of the same shape as the tasks sent by ACS.
+	AddTaskPath = "/v1/tasks/add"
+	// StopTaskPath is the path used to set the desired status of the task
+	// identified by the 'taskarn' query field to STOPPED.
+	StopTaskPath = "/v1/tasks/stop"
+
+	// RequestTypeAddTask specifies the request type of AddTaskHandler.
+	RequestTypeAddTask = "add task"
+	// RequestTypeStopTask specifies the request type of StopTaskHandler.
+	RequestTypeStopTask = "stop task"
+
+	maxTaskRequestBodySize = 1024 * 1024
+)
+
+// AddTaskHandler creates the response for the 'v1/tasks/add' API. It converts
+// the task in the request body the same way tasks received from ACS are
+// converted and adds it to the task engine.
+func AddTaskHandler(taskEngine utils.TaskLifecycleManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTaskLifecycleRequest(w, r, RequestTypeAddTask) {
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxTaskRequestBodySize))
+		if err != nil {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "unable to read request body: "+err.Error(),
+				RequestTypeAddTask)
+			return
+		}
+		acsTask := &ecsacs.Task{}
+		if err := jsonutil.UnmarshalJSON(acsTask, bytes.NewReader(body)); err != nil {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "unable to decode task: "+err.Error(),
+				RequestTypeAddTask)
+			return
+		}
+		if acsTask.Arn == nil || *acsTask.Arn == "" {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "task must have an arn", RequestTypeAddTask)
+			return
+		}
+		task, err := apitask.TaskFromACS(acsTask, &ecsacs.PayloadMessage{})
+		if err != nil {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "invalid task: "+err.Error(), RequestTypeAddTask)
+			return
+		}
+		if task.GetDesiredStatus() == apitaskstatus.TaskStatusNone {
+			task.SetDesiredStatus(apitaskstatus.TaskRunning)
+		}
+		if task.GetDesiredStatus() != apitaskstatus.TaskRunning {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "desired status of a new task must be RUNNING",
+				RequestTypeAddTask)
+			return
+		}
+		if _, exists := taskEngine.State().TaskByArn(task.Arn); exists {
+			writeTaskLifecycleError(w, http.StatusConflict, "task already exists: "+task.Arn, RequestTypeAddTask)
+			return
+		}
+
+		seelog.Infof("Adding task %s to the task engine through the introspection API", task.Arn)
+		taskEngine.AddTask(task)
+		writeTaskLifecycleResponse(w, task, RequestTypeAddTask)
+	}
+}
+
+// StopTaskHandler creates the response for the 'v1/tasks/stop' API. Like a
+// stop received from ACS, it sends the task engine an update that only carries
+// the task arn and the STOPPED desired status, and leaves it to the managed
+// task to transition the task it owns.
+func StopTaskHandler(taskEngine utils.TaskLifecycleManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTaskLifecycleRequest(w, r, RequestTypeStopTask) {
+			return
+		}
+		taskArn, ok := utils.ValueFromRequest(r, taskARNQueryField)
+		if !ok || taskArn == "" {
+			writeTaskLifecycleError(w, http.StatusBadRequest, "request must contain "+taskARNQueryField,
+				RequestTypeStopTask)
+			return
+		}
+		task, found := taskEngine.State().TaskByArn(taskArn)
+		if !found {
+			writeTaskLifecycleError(w, http.StatusNotFound, "unable to find task: "+taskArn, RequestTypeStopTask)
+			return
+		}
+
+		seelog.Infof("Stopping task %s through the introspection API", task.Arn)
+		taskEngine.AddTask(&apitask.Task{
+			Arn:                 task.Arn,
+			DesiredStatusUnsafe: apitaskstatus.TaskStopped,
+		})
+		writeTaskLifecycleResponse(w, task, RequestTypeStopTask)
+	}
+}
+
+// validateTaskLifecycleRequest makes sure that the request is a JSON POST
+// coming from the loopback interface and addressed to localhost. It writes the
+// error response and returns false otherwise.
+//
+// The server of these endpoints only listens on the loopback interface, which
+// doesn't keep browsers on the host out: a page can send a cross origin
+// text/plain POST, or rebind its own domain to 127.0.0.1. Browsers only
+// send application/json cross origin after a preflight this server never
+// answers, always set the Origin header of cross origin POSTs, and keep the
+// rebound domain in the Host header.
+func validateTaskLifecycleRequest(w http.ResponseWriter, r *http.Request, requestType string) bool {
+	if r.Method != http.MethodPost {
+		writeTaskLifecycleError(w, http.StatusMethodNotAllowed, "only POST is supported", requestType)
+		return false
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		mediaType != "application/json" {
+		writeTaskLifecycleError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json",
+			requestType)
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		seelog.Warnf("Rejecting %s request with origin %s", requestType, origin)
+		writeTaskLifecycleError(w, http.StatusForbidden, "cross origin requests are not allowed", requestType)
+		return false
+	}
+	if !isLocalhost(r.Host) {
+		seelog.Warnf("Rejecting %s request for host %s", requestType, r.Host)
+		writeTaskLifecycleError(w, http.StatusForbidden, "only requests for localhost or 127.0.0.1 are allowed",
+			requestType)
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		seelog.Warnf("Rejecting %s request from non-loopback address %s", requestType, r.RemoteAddr)
+		writeTaskLifecycleError(w, http.StatusForbidden, "only requests from the loopback interface are allowed",
+			requestType)
+		return false
+	}
+	return true
+}
+
+// isLocalhost returns whether the host, with or without a port, is localhost
+// or 127.0.0.1.
+func isLocalhost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host == "localhost" || host == "127.0.0.1"
+}
+
+func writeTaskLifecycleResponse(w http.ResponseWriter, task *apitask.Task, requestType string) {
+	responseJSON, err := json.Marshal(NewTaskResponse(task, nil))
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, http.StatusAccepted, responseJSON, requestType)
+}
+
+func writeTaskLifecycleError(w http.ResponseWriter, status int, message string, requestType string) {
+	responseJSON, _ := json.Marshal(&utils.ErrorMessage{
+		Code:          http.StatusText(status),
+		Message:       message,
+		HTTPErrorCode: status,
+	})
+	utils.WriteJSONToResponse(w, status, responseJSON, requestType)
+}
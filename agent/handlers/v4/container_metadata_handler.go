@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 )
@@ -30,7 +31,7 @@ import (
 var ContainerMetadataPath = "/v4/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx)
 
 // ContainerMetadataHandler returns the handler method for handling container metadata requests.
-func ContainerMetadataHandler(state dockerstate.TaskEngineState) func(http.ResponseWriter, *http.Request) {
+func ContainerMetadataHandler(state dockerstate.TaskEngineState, statsEngine stats.Engine) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		containerID, err := v3.GetContainerIDByRequest(r, state)
 		if err != nil {
@@ -51,6 +52,9 @@ func ContainerMetadataHandler(state dockerstate.TaskEngineState) func(http.Respo
 			utils.WriteJSONToResponse(w, http.StatusInternalServerError, errResponseJSON, utils.RequestTypeContainerMetadata)
 			return
 		}
+		if taskARN, err := v3.GetTaskARNByRequest(r, state); err == nil {
+			containerResponse.MemoryEvents = getContainerMemoryEvents(taskARN, containerID, statsEngine)
+		}
 		seelog.Infof("V4 container metadata handler: writing response for container '%s'", containerID)
 
 		responseJSON, err := json.Marshal(containerResponse)
@@ -75,6 +75,8 @@ func WriteV4ContainerStatsResponse(w http.ResponseWriter,
 	containerStatsResponse := StatsResponse{
 		StatsJSON:          dockerStats,
 		Network_rate_stats: network_rate_stats,
+		Memory_events:      getContainerMemoryEvents(taskARN, containerID, statsEngine),
+		Storage_stats:      getContainerStorageStats(taskARN, containerID, statsEngine),
 	}
 
 	responseJSON, err := json.Marshal(containerStatsResponse)
@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	"github.com/pkg/errors"
 )
 
@@ -37,6 +38,9 @@ type TaskResponse struct {
 type ContainerResponse struct {
 	*v2.ContainerResponse
 	Networks []Network `json:"Networks,omitempty"`
+	// MemoryEvents are the OOM kill count and the memory pressure of the container cgroup. They're
+	// only set in the container metadata response, for running containers.
+	MemoryEvents *memevents.MemoryEvents `json:"MemoryEvents,omitempty"`
 }
 
 // Network is the v4 Network response. It adds a bunch of information about network
@@ -0,0 +1,173 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// statsHistoryWindowQueryField is the query field of the duration of the history, such as
+	// "30s", that ends now. It can't be longer than stats.MaxStatsHistoryWindow.
+	statsHistoryWindowQueryField = "window"
+	// statsHistoryResolutionQueryField is the query field of the duration of the intervals that
+	// the stats of the history are aggregated over.
+	statsHistoryResolutionQueryField = "resolution"
+
+	defaultStatsHistoryWindow     = time.Minute
+	defaultStatsHistoryResolution = time.Second
+)
+
+var (
+	// ContainerStatsHistoryPath specifies the relative URI path for serving the stats history of a container.
+	ContainerStatsHistoryPath = "/v4/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx) + "/stats/history"
+
+	// TaskStatsHistoryPath specifies the relative URI path for serving the stats history of the containers of a task.
+	TaskStatsHistoryPath = "/v4/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx) + "/task/stats/history"
+)
+
+// ContainerStatsHistoryHandler returns the handler method for handling container stats history
+// requests. The response is the list of stats history points of the container, oldest first.
+func ContainerStatsHistoryHandler(state dockerstate.TaskEngineState, statsEngine stats.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window, resolution, err := parseStatsHistoryRequest(r)
+		if err != nil {
+			writeStatsHistoryError(w, fmt.Sprintf("V4 container stats history handler: %s", err.Error()),
+				utils.RequestTypeContainerStatsHistory)
+			return
+		}
+		taskArn, err := v3.GetTaskARNByRequest(r, state)
+		if err != nil {
+			writeStatsHistoryError(w, fmt.Sprintf("V4 container stats history handler: unable to get task arn from request: %s", err.Error()),
+				utils.RequestTypeContainerStatsHistory)
+			return
+		}
+		containerID, err := v3.GetContainerIDByRequest(r, state)
+		if err != nil {
+			writeStatsHistoryError(w, fmt.Sprintf("V4 container stats history handler: unable to get container ID from request: %s", err.Error()),
+				utils.RequestTypeContainerStatsHistory)
+			return
+		}
+
+		seelog.Infof("V4 container stats history handler: writing response for container '%s'", containerID)
+		history, err := statsEngine.ContainerStatsHistory(taskArn, containerID, window, resolution)
+		if err != nil {
+			writeStatsHistoryError(w, "Unable to get container stats history for: "+containerID,
+				utils.RequestTypeContainerStatsHistory)
+			return
+		}
+		if history == nil {
+			history = []stats.StatsHistoryPoint{}
+		}
+		responseJSON, err := json.Marshal(history)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeContainerStatsHistory)
+	}
+}
+
+// TaskStatsHistoryHandler returns the handler method for handling task stats history requests.
+// The response maps the docker id of every container of the task to its stats history points.
+func TaskStatsHistoryHandler(state dockerstate.TaskEngineState, statsEngine stats.Engine) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window, resolution, err := parseStatsHistoryRequest(r)
+		if err != nil {
+			writeStatsHistoryError(w, fmt.Sprintf("V4 task stats history handler: %s", err.Error()),
+				utils.RequestTypeTaskStatsHistory)
+			return
+		}
+		taskArn, err := v3.GetTaskARNByRequest(r, state)
+		if err != nil {
+			writeStatsHistoryError(w, fmt.Sprintf("V4 task stats history handler: unable to get task arn from request: %s", err.Error()),
+				utils.RequestTypeTaskStatsHistory)
+			return
+		}
+		containerMap, ok := state.ContainerMapByArn(taskArn)
+		if !ok {
+			writeStatsHistoryError(w, "Unable to get task stats history for: "+taskArn,
+				utils.RequestTypeTaskStatsHistory)
+			return
+		}
+
+		seelog.Infof("V4 task stats history handler: writing response for task '%s'", taskArn)
+		resp := make(map[string][]stats.StatsHistoryPoint)
+		for _, dockerContainer := range containerMap {
+			containerID := dockerContainer.DockerID
+			history, err := statsEngine.ContainerStatsHistory(taskArn, containerID, window, resolution)
+			if err != nil {
+				seelog.Warnf("V4 task stats history handler: Unable to get stats history for container '%s' for task '%s': %v",
+					containerID, taskArn, err)
+			}
+			if history == nil {
+				history = []stats.StatsHistoryPoint{}
+			}
+			resp[containerID] = history
+		}
+		responseJSON, err := json.Marshal(resp)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeTaskStatsHistory)
+	}
+}
+
+// parseStatsHistoryRequest returns the window and resolution of a stats history request.
+func parseStatsHistoryRequest(r *http.Request) (time.Duration, time.Duration, error) {
+	window, err := parseStatsHistoryDuration(r, statsHistoryWindowQueryField, defaultStatsHistoryWindow)
+	if err != nil {
+		return 0, 0, err
+	}
+	resolution, err := parseStatsHistoryDuration(r, statsHistoryResolutionQueryField, defaultStatsHistoryResolution)
+	if err != nil {
+		return 0, 0, err
+	}
+	if window > stats.MaxStatsHistoryWindow {
+		return 0, 0, fmt.Errorf("window %s is longer than the maximum window %s, the stats history that's kept",
+			window, stats.MaxStatsHistoryWindow)
+	}
+	if resolution > window {
+		return 0, 0, fmt.Errorf("resolution %s is larger than window %s", resolution, window)
+	}
+	return window, resolution, nil
+}
+
+func parseStatsHistoryDuration(r *http.Request, field string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := utils.ValueFromRequest(r, field)
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a positive duration such as 30s", field, value)
+	}
+	return duration, nil
+}
+
+func writeStatsHistoryError(w http.ResponseWriter, message string, requestType string) {
+	errResponseJSON, err := json.Marshal(message)
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, http.StatusBadRequest, errResponseJSON, requestType)
+}
@@ -16,6 +16,7 @@ package v4
 import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	"github.com/cihub/seelog"
 	"github.com/docker/docker/api/types"
 	"github.com/pkg/errors"
@@ -25,7 +26,9 @@ import (
 // with the docker stats.
 type StatsResponse struct {
 	*types.StatsJSON
-	Network_rate_stats *stats.NetworkStatsPerSec `json:"network_rate_stats,omitempty"`
+	Network_rate_stats *stats.NetworkStatsPerSec    `json:"network_rate_stats,omitempty"`
+	Memory_events      *memevents.MemoryEvents      `json:"memory_events,omitempty"`
+	Storage_stats      *stats.ContainerStorageStats `json:"storage_stats,omitempty"`
 }
 
 // NewV4TaskStatsResponse returns a new v4 task stats response object
@@ -54,6 +57,8 @@ func NewV4TaskStatsResponse(taskARN string,
 		statsResponse := StatsResponse{
 			StatsJSON:          dockerStats,
 			Network_rate_stats: network_rate_stats,
+			Memory_events:      getContainerMemoryEvents(taskARN, containerID, statsEngine),
+			Storage_stats:      getContainerStorageStats(taskARN, containerID, statsEngine),
 		}
 
 		resp[containerID] = statsResponse
@@ -61,3 +66,27 @@ func NewV4TaskStatsResponse(taskARN string,
 
 	return resp, nil
 }
+
+// getContainerMemoryEvents returns the memory events of the container, or nil when they're
+// unavailable, as the rest of the stats are still worth returning.
+func getContainerMemoryEvents(taskARN string, containerID string, statsEngine stats.Engine) *memevents.MemoryEvents {
+	memoryEvents, err := statsEngine.ContainerMemoryEvents(taskARN, containerID)
+	if err != nil {
+		seelog.Debugf("V4 stats response: Unable to get memory events for container '%s' for task '%s': %v",
+			containerID, taskARN, err)
+		return nil
+	}
+	return memoryEvents
+}
+
+// getContainerStorageStats returns the ephemeral storage used by the container and its task, or
+// nil when it hasn't been collected yet.
+func getContainerStorageStats(taskARN string, containerID string, statsEngine stats.Engine) *stats.ContainerStorageStats {
+	storageStats, err := statsEngine.ContainerStorageStats(taskARN, containerID)
+	if err != nil {
+		seelog.Debugf("V4 stats response: Unable to get storage stats for container '%s' for task '%s': %v",
+			containerID, taskARN, err)
+		return nil
+	}
+	return storageStats
+}
@@ -30,6 +30,7 @@ type MetricsEngine struct {
 	cfg            *config.Config
 	Registry       *prometheus.Registry
 	managedMetrics map[APIType]MetricsClient
+	taskLifecycle  *TaskLifecycleMetrics
 }
 
 const (
@@ -60,6 +61,15 @@ func PublishMetrics() {
 	}
 }
 
+// RegisterCollector registers a collector of metrics owned by another component of Agent with the
+// Registry of the Global MetricsEngine. It does nothing when metrics collection is disabled.
+func RegisterCollector(collector prometheus.Collector) error {
+	if !MetricsEngineGlobal.collection {
+		return nil
+	}
+	return MetricsEngineGlobal.Registry.Register(collector)
+}
+
 // Initializes the Global MetricsEngine used throughout Agent
 // Currently, we use the Prometheus Global Default Registerer, which also collecs
 // basic Go application metrics that we use (like memory usage).
@@ -91,6 +101,7 @@ func NewMetricsEngine(cfg *config.Config, registry *prometheus.Registry) *Metric
 		aClient := NewMetricsClient(managedAPI, metricsEngine.Registry)
 		metricsEngine.managedMetrics[managedAPI] = aClient
 	}
+	metricsEngine.taskLifecycle = NewTaskLifecycleMetrics(metricsEngine.Registry)
 	return metricsEngine
 }
 
@@ -114,6 +125,24 @@ func (engine *MetricsEngine) RecordECSClientMetric(callName string) func() {
 	return engine.recordGenericMetric(ECSClient, callName)
 }
 
+// Records the duration of a phase of the task lifecycle. The phase is one of the
+// TaskPhase constants.
+func (engine *MetricsEngine) RecordTaskLifecyclePhase(phase string, duration time.Duration) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.taskLifecycle.ObservePhase(phase, duration)
+}
+
+// Counts a failure to transition a container to the desired status, by the name
+// of the error that caused it.
+func (engine *MetricsEngine) RecordTaskTransitionFailure(transition, reason string) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.taskLifecycle.IncrementTransitionFailure(transition, reason)
+}
+
 // Records a call's start and returns a function to be deferred.
 // Wrapper functions will use this function for GenericMetricsClients.
 // If Metrics collection is enabled from the cfg, we record a metric with callID
@@ -64,6 +64,73 @@ func TestDisablePrometheusMetrics(t *testing.T) {
 	assert.False(t, MetricsEngineGlobal.collection)
 }
 
+// Tests if collectors of other components are only registered when metrics
+// collection is enabled
+func TestRegisterCollector(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge"})
+	assert.NoError(t, RegisterCollector(gauge))
+
+	cfg := getTestConfig()
+	registry := prometheus.NewRegistry()
+	MustInit(&cfg, registry)
+	assert.NoError(t, RegisterCollector(gauge))
+	assert.True(t, registry.Unregister(gauge))
+}
+
+// Tests if task lifecycle phases and transition failures are recorded in the
+// registry of the MetricsEngine
+func TestTaskLifecycleMetrics(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	// Recording with metrics collection disabled is a no-op
+	MetricsEngineGlobal.RecordTaskLifecyclePhase(TaskPhasePull, time.Second)
+	MetricsEngineGlobal.RecordTaskTransitionFailure("PULLED", "CannotPullContainerError")
+
+	cfg := getTestConfig()
+	registry := prometheus.NewRegistry()
+	MustInit(&cfg, registry)
+	MetricsEngineGlobal.RecordTaskLifecyclePhase(TaskPhasePull, time.Second)
+	MetricsEngineGlobal.RecordTaskLifecyclePhase(TaskPhasePull, 3*time.Second)
+	MetricsEngineGlobal.RecordTaskLifecyclePhase(TaskPhaseStart, 200*time.Millisecond)
+	MetricsEngineGlobal.RecordTaskTransitionFailure("PULLED", "CannotPullContainerError")
+
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+	var histogram, counter *dto.MetricFamily
+	for _, metricFamily := range metricFamilies {
+		switch metricFamily.GetName() {
+		case "AgentMetrics_TaskLifecycle_phase_duration_seconds":
+			histogram = metricFamily
+		case "AgentMetrics_TaskLifecycle_transition_failure_count":
+			counter = metricFamily
+		}
+	}
+	if assert.NotNil(t, histogram) && assert.Len(t, histogram.GetMetric(), 2) {
+		for _, metric := range histogram.GetMetric() {
+			switch metric.GetLabel()[0].GetValue() {
+			case TaskPhasePull:
+				assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+				assert.Equal(t, float64(4), metric.GetHistogram().GetSampleSum())
+			case TaskPhaseStart:
+				assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+			default:
+				t.Errorf("Unexpected phase %s", metric.GetLabel()[0].GetValue())
+			}
+		}
+	}
+	if assert.NotNil(t, counter) && assert.Len(t, counter.GetMetric(), 1) {
+		assert.Equal(t, float64(1), counter.GetMetric()[0].GetCounter().GetValue())
+	}
+}
+
 // Mimicks metric collection of Docker API calls through Go routines. The method
 // call to record a metric is the same used by various clients throughout Agent.
 // We sleep the go routine to simulate "work" being done.
@@ -0,0 +1,83 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	TaskLifecycleSubsystem = "TaskLifecycle"
+
+	// TaskPhaseAddedToPullStart is the time from a task being added to the engine to the start
+	// of its first image pull
+	TaskPhaseAddedToPullStart = "added_to_pull_start"
+	// TaskPhasePull is the time to pull the image of a container
+	TaskPhasePull = "pull"
+	// TaskPhaseCreate is the time to create a container
+	TaskPhaseCreate = "create"
+	// TaskPhaseStart is the time to start a container
+	TaskPhaseStart = "start"
+	// TaskPhaseTimeToRunning is the time from a task being added to the engine to the task
+	// being known as RUNNING
+	TaskPhaseTimeToRunning = "time_to_running"
+	// TaskPhaseStop is the time to stop a container
+	TaskPhaseStop = "stop"
+	// TaskPhaseCleanup is the time to remove the containers and the state of a stopped task
+	TaskPhaseCleanup = "cleanup"
+)
+
+// TaskLifecycleMetrics collects the latencies of the phases that a task goes through on the
+// instance, and the failures of its transitions.
+type TaskLifecycleMetrics struct {
+	phaseDurations     *prometheus.HistogramVec
+	transitionFailures *prometheus.CounterVec
+}
+
+func NewTaskLifecycleMetrics(registry *prometheus.Registry) *TaskLifecycleMetrics {
+	phaseDurations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskLifecycleSubsystem,
+		Name:      "phase_duration_seconds",
+		Help:      "Task lifecycle phase duration in seconds",
+		// From 100ms to about 14 minutes
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"Phase"})
+	registry.MustRegister(phaseDurations)
+
+	transitionFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: TaskLifecycleSubsystem,
+		Name:      "transition_failure_count",
+		Help:      "Number of failed container transitions by desired status and error name",
+	}, []string{"Transition", "Reason"})
+	registry.MustRegister(transitionFailures)
+
+	return &TaskLifecycleMetrics{
+		phaseDurations:     phaseDurations,
+		transitionFailures: transitionFailures,
+	}
+}
+
+// ObservePhase records the duration of a phase of the task lifecycle
+func (tm *TaskLifecycleMetrics) ObservePhase(phase string, duration time.Duration) {
+	tm.phaseDurations.WithLabelValues(phase).Observe(duration.Seconds())
+}
+
+// IncrementTransitionFailure counts a failure to transition a container to a status
+func (tm *TaskLifecycleMetrics) IncrementTransitionFailure(transition, reason string) {
+	tm.transitionFailures.WithLabelValues(transition, reason).Inc()
+}
@@ -0,0 +1,178 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"net/http"
+	"time"
+
+	acsclient "github.com/aws/amazon-ecs-agent/agent/acs/client"
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+	"github.com/pborman/uuid"
+)
+
+const taskDesiredStatusStopped = "STOPPED"
+
+// acsHandler upgrades the agent's connection to ACS to a websocket, sends any
+// messages that were queued while the agent was disconnected and then keeps
+// the session alive with heartbeats until the agent disconnects.
+func (server *Server) acsHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := server.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		seelog.Warnf("Simulator unable to upgrade ACS connection: %v", err)
+		return
+	}
+	seelog.Infof("Simulator accepted ACS connection from %s", r.RemoteAddr)
+	session := newWSSession(conn, acsclient.NewACSDecoder())
+
+	server.lock.Lock()
+	if server.acsSession != nil {
+		server.acsSession.close()
+	}
+	server.acsSession = session
+	pending := server.pendingACSMessages
+	server.pendingACSMessages = nil
+	server.lock.Unlock()
+
+	for _, message := range pending {
+		if err := session.send(message); err != nil {
+			seelog.Warnf("Simulator unable to send queued ACS message: %v", err)
+		}
+	}
+
+	go server.heartbeat(session, func() interface{} {
+		return &ecsacs.HeartbeatMessage{
+			Healthy:   aws.Bool(true),
+			MessageId: aws.String(uuid.New()),
+		}
+	})
+
+	err = session.serve(server.handleACSMessage)
+	seelog.Infof("Simulator ACS connection closed: %v", err)
+
+	server.lock.Lock()
+	if server.acsSession == session {
+		server.acsSession = nil
+	}
+	server.lock.Unlock()
+}
+
+// handleACSMessage handles messages sent by the agent to ACS.
+func (server *Server) handleACSMessage(message interface{}, typeStr string) {
+	switch msg := message.(type) {
+	case *ecsacs.AckRequest:
+		server.recorder.recordAck(msg)
+	case *ecsacs.NackRequest:
+		seelog.Warnf("Simulator received nack for message %s: %s",
+			aws.StringValue(msg.MessageId), aws.StringValue(msg.Reason))
+	default:
+		seelog.Debugf("Simulator received ACS message of type %s", typeStr)
+	}
+}
+
+// SendTasks sends the tasks to the agent in a single PayloadMessage. If the
+// agent is not connected yet, the message is sent once it connects.
+func (server *Server) SendTasks(tasks ...*ecsacs.Task) error {
+	server.lock.Lock()
+	for _, task := range tasks {
+		server.tasks[aws.StringValue(task.Arn)] = task
+	}
+	server.seqNum++
+	payload := &ecsacs.PayloadMessage{
+		ClusterArn:           aws.String(server.cfg.Cluster),
+		ContainerInstanceArn: aws.String(server.cfg.ContainerInstanceArn),
+		GeneratedAt:          aws.Int64(time.Now().Unix()),
+		MessageId:            aws.String(uuid.New()),
+		SeqNum:               aws.Int64(server.seqNum),
+		Tasks:                tasks,
+	}
+	server.lock.Unlock()
+
+	return server.sendACSMessage(payload)
+}
+
+// StopTask sends a PayloadMessage setting the desired status of a task that
+// was previously sent to the agent to STOPPED.
+func (server *Server) StopTask(taskArn string) error {
+	server.lock.RLock()
+	task, ok := server.tasks[taskArn]
+	server.lock.RUnlock()
+	if !ok {
+		return errUnknownTask(taskArn)
+	}
+
+	stopped := *task
+	stopped.DesiredStatus = aws.String(taskDesiredStatusStopped)
+	return server.SendTasks(&stopped)
+}
+
+// SendCredentials sends an IAMRoleCredentialsMessage for the task to the agent.
+func (server *Server) SendCredentials(taskArn, roleType string, credentials *ecsacs.IAMRoleCredentials) error {
+	return server.sendACSMessage(&ecsacs.IAMRoleCredentialsMessage{
+		MessageId:       aws.String(uuid.New()),
+		RoleCredentials: credentials,
+		RoleType:        aws.String(roleType),
+		TaskArn:         aws.String(taskArn),
+	})
+}
+
+// SendTaskENIAttachment sends an AttachTaskNetworkInterfacesMessage for the
+// task to the agent.
+func (server *Server) SendTaskENIAttachment(taskArn string, enis []*ecsacs.ElasticNetworkInterface,
+	waitTimeout time.Duration) error {
+	return server.sendACSMessage(&ecsacs.AttachTaskNetworkInterfacesMessage{
+		ClusterArn:               aws.String(server.cfg.Cluster),
+		ContainerInstanceArn:     aws.String(server.cfg.ContainerInstanceArn),
+		ElasticNetworkInterfaces: enis,
+		GeneratedAt:              aws.Int64(time.Now().Unix()),
+		MessageId:                aws.String(uuid.New()),
+		TaskArn:                  aws.String(taskArn),
+		WaitTimeoutMs:            aws.Int64(waitTimeout.Nanoseconds() / int64(time.Millisecond)),
+	})
+}
+
+// sendACSMessage sends the message over the current ACS session or queues it
+// until the agent connects.
+func (server *Server) sendACSMessage(message interface{}) error {
+	server.lock.Lock()
+	session := server.acsSession
+	if session == nil {
+		seelog.Infof("Agent is not connected to the simulator yet; queueing ACS message")
+		server.pendingACSMessages = append(server.pendingACSMessages, message)
+		server.lock.Unlock()
+		return nil
+	}
+	server.lock.Unlock()
+
+	return session.send(message)
+}
+
+// heartbeat sends the message returned by newMessage over the session every
+// heartbeat interval until the session is closed.
+func (server *Server) heartbeat(session *wsSession, newMessage func() interface{}) {
+	ticker := time.NewTicker(server.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := session.send(newMessage()); err != nil {
+				seelog.Warnf("Simulator unable to send heartbeat: %v", err)
+			}
+		case <-session.closed:
+			return
+		}
+	}
+}
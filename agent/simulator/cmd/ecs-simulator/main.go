@@ -0,0 +1,70 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// ecs-simulator runs a local stand-in for the ECS control plane. Point the
+// agent at it by setting ECS_BACKEND_HOST to the simulator's address, e.g.
+//
+//	ecs-simulator -listen 127.0.0.1:8080 -tasks tasks.json
+//	ECS_BACKEND_HOST=http://127.0.0.1:8080 ECS_CLUSTER=local agent
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/simulator"
+	"github.com/cihub/seelog"
+)
+
+func main() {
+	logger.InitSeelog()
+	os.Exit(run())
+}
+
+func run() int {
+	listen := flag.String("listen", "127.0.0.1:8080", "Address to serve the ECS, ACS and TCS endpoints on")
+	cluster := flag.String("cluster", "default", "Name of the simulated cluster")
+	region := flag.String("region", simulator.DefaultRegion, "Region used to build ARNs")
+	tasksFile := flag.String("tasks", "", "Optional file with tasks to send to the agent once it connects")
+	heartbeat := flag.Duration("heartbeat-interval", simulator.DefaultHeartbeatInterval,
+		"Interval between ACS and TCS heartbeats")
+	flag.Parse()
+
+	server := simulator.NewServer(simulator.Config{
+		Cluster:           *cluster,
+		Region:            *region,
+		HeartbeatInterval: *heartbeat,
+	})
+	defer server.Close()
+
+	if *tasksFile != "" {
+		tasks, err := simulator.LoadTasksFile(*tasksFile)
+		if err != nil {
+			seelog.Criticalf("Unable to load tasks: %v", err)
+			return 1
+		}
+		if err := server.SendTasks(tasks...); err != nil {
+			seelog.Criticalf("Unable to send tasks: %v", err)
+			return 1
+		}
+	}
+
+	seelog.Infof("ECS simulator listening on %s, container instance %s", *listen, server.ContainerInstanceArn())
+	if err := http.ListenAndServe(*listen, server.Handler()); err != nil {
+		seelog.Criticalf("ECS simulator exited: %v", err)
+		return 1
+	}
+	return 0
+}
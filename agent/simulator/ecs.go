@@ -0,0 +1,171 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// ecsTargetPrefix is the prefix of the X-Amz-Target header sent by the
+	// ECS sdk client for every operation.
+	ecsTargetPrefix = "AmazonEC2ContainerServiceV20141113."
+	ecsContentType  = "application/x-amz-json-1.1"
+
+	invalidParameterException   = "InvalidParameterException"
+	unsupportedOperationErrType = "UnsupportedFeatureException"
+)
+
+// ecsError is the error body understood by the jsonrpc protocol of the sdk.
+type ecsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// ecsHandler serves the subset of the ECS API used by the agent over the
+// json 1.1 protocol.
+func (server *Server) ecsHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	if r.Method != http.MethodPost || !strings.HasPrefix(target, ecsTargetPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	operation := strings.TrimPrefix(target, ecsTargetPrefix)
+	seelog.Debugf("Simulator received ECS request: %s", operation)
+
+	var (
+		output interface{}
+		err    error
+	)
+	switch operation {
+	case "CreateCluster":
+		input := &ecs.CreateClusterInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			output = server.createCluster(input)
+		}
+	case "RegisterContainerInstance":
+		input := &ecs.RegisterContainerInstanceInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			output = server.registerContainerInstance(input)
+		}
+	case "DiscoverPollEndpoint":
+		input := &ecs.DiscoverPollEndpointInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			output = server.discoverPollEndpoint(r)
+		}
+	case "SubmitTaskStateChange":
+		input := &ecs.SubmitTaskStateChangeInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			server.recorder.recordTaskStateChange(input)
+			output = &ecs.SubmitTaskStateChangeOutput{Acknowledgment: aws.String("ACK")}
+		}
+	case "SubmitContainerStateChange":
+		input := &ecs.SubmitContainerStateChangeInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			server.recorder.recordContainerStateChange(input)
+			output = &ecs.SubmitContainerStateChangeOutput{Acknowledgment: aws.String("ACK")}
+		}
+	case "SubmitAttachmentStateChanges":
+		input := &ecs.SubmitAttachmentStateChangesInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			server.recorder.recordAttachmentStateChange(input)
+			output = &ecs.SubmitAttachmentStateChangesOutput{Acknowledgment: aws.String("ACK")}
+		}
+	case "ListTagsForResource":
+		output = &ecs.ListTagsForResourceOutput{}
+	case "UpdateContainerInstancesState":
+		output = &ecs.UpdateContainerInstancesStateOutput{}
+	default:
+		writeECSError(w, unsupportedOperationErrType, fmt.Sprintf("operation %s is not supported by the simulator", operation))
+		return
+	}
+	if err != nil {
+		writeECSError(w, invalidParameterException, err.Error())
+		return
+	}
+
+	body, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		seelog.Errorf("Simulator unable to marshal %s response: %v", operation, err)
+		writeECSError(w, "ServerException", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", ecsContentType)
+	w.Write(body)
+}
+
+func (server *Server) createCluster(input *ecs.CreateClusterInput) *ecs.CreateClusterOutput {
+	name := aws.StringValue(input.ClusterName)
+	return &ecs.CreateClusterOutput{
+		Cluster: &ecs.Cluster{
+			ClusterName: aws.String(name),
+			ClusterArn:  aws.String(server.clusterArn(name)),
+			Status:      aws.String("ACTIVE"),
+		},
+	}
+}
+
+// registerContainerInstance records the registration and echoes the
+// attributes back, as the agent validates that all of them were registered.
+func (server *Server) registerContainerInstance(input *ecs.RegisterContainerInstanceInput) *ecs.RegisterContainerInstanceOutput {
+	server.recorder.recordRegistration(input)
+
+	server.lock.Lock()
+	if cluster := aws.StringValue(input.Cluster); cluster != "" {
+		server.cfg.Cluster = cluster
+	}
+	server.lock.Unlock()
+
+	attributes := input.Attributes
+	if server.cfg.AvailabilityZone != "" {
+		attributes = append(attributes, &ecs.Attribute{
+			Name:  aws.String(availabilityZoneAttributeName),
+			Value: aws.String(server.cfg.AvailabilityZone),
+		})
+	}
+	return &ecs.RegisterContainerInstanceOutput{
+		ContainerInstance: &ecs.ContainerInstance{
+			ContainerInstanceArn: aws.String(server.cfg.ContainerInstanceArn),
+			Attributes:           attributes,
+			RegisteredResources:  input.TotalResources,
+			RemainingResources:   input.TotalResources,
+			Status:               aws.String("ACTIVE"),
+		},
+	}
+}
+
+// discoverPollEndpoint points both ACS and TCS at this server, using the host
+// the agent used to reach the ECS endpoint.
+func (server *Server) discoverPollEndpoint(r *http.Request) *ecs.DiscoverPollEndpointOutput {
+	base := "http://" + r.Host
+	return &ecs.DiscoverPollEndpointOutput{
+		Endpoint:          aws.String(base + acsBasePath),
+		TelemetryEndpoint: aws.String(base + tcsBasePath),
+	}
+}
+
+func writeECSError(w http.ResponseWriter, errType, message string) {
+	body, _ := json.Marshal(&ecsError{Type: errType, Message: message})
+	w.Header().Set("Content-Type", ecsContentType)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
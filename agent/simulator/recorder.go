@@ -0,0 +1,144 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+// Recorder keeps every request the simulator has received from the agent so
+// that tests can make assertions on them.
+type Recorder struct {
+	registrations          []*ecs.RegisterContainerInstanceInput
+	taskStateChanges       []*ecs.SubmitTaskStateChangeInput
+	containerStateChanges  []*ecs.SubmitContainerStateChangeInput
+	attachmentStateChanges []*ecs.SubmitAttachmentStateChangesInput
+	acks                   []*ecsacs.AckRequest
+	metrics                []*ecstcs.PublishMetricsRequest
+	health                 []*ecstcs.PublishHealthRequest
+	lock                   sync.RWMutex
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) recordRegistration(input *ecs.RegisterContainerInstanceInput) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registrations = append(r.registrations, input)
+}
+
+func (r *Recorder) recordTaskStateChange(input *ecs.SubmitTaskStateChangeInput) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.taskStateChanges = append(r.taskStateChanges, input)
+}
+
+func (r *Recorder) recordContainerStateChange(input *ecs.SubmitContainerStateChangeInput) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.containerStateChanges = append(r.containerStateChanges, input)
+}
+
+func (r *Recorder) recordAttachmentStateChange(input *ecs.SubmitAttachmentStateChangesInput) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.attachmentStateChanges = append(r.attachmentStateChanges, input)
+}
+
+func (r *Recorder) recordAck(ack *ecsacs.AckRequest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.acks = append(r.acks, ack)
+}
+
+func (r *Recorder) recordMetrics(request *ecstcs.PublishMetricsRequest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = append(r.metrics, request)
+}
+
+func (r *Recorder) recordHealth(request *ecstcs.PublishHealthRequest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.health = append(r.health, request)
+}
+
+// Registrations returns every RegisterContainerInstance request received.
+func (r *Recorder) Registrations() []*ecs.RegisterContainerInstanceInput {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecs.RegisterContainerInstanceInput(nil), r.registrations...)
+}
+
+// TaskStateChanges returns every SubmitTaskStateChange request received.
+func (r *Recorder) TaskStateChanges() []*ecs.SubmitTaskStateChangeInput {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecs.SubmitTaskStateChangeInput(nil), r.taskStateChanges...)
+}
+
+// ContainerStateChanges returns every SubmitContainerStateChange request received.
+func (r *Recorder) ContainerStateChanges() []*ecs.SubmitContainerStateChangeInput {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecs.SubmitContainerStateChangeInput(nil), r.containerStateChanges...)
+}
+
+// AttachmentStateChanges returns every SubmitAttachmentStateChanges request received.
+func (r *Recorder) AttachmentStateChanges() []*ecs.SubmitAttachmentStateChangesInput {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecs.SubmitAttachmentStateChangesInput(nil), r.attachmentStateChanges...)
+}
+
+// Acks returns every AckRequest the agent sent over the ACS websocket.
+func (r *Recorder) Acks() []*ecsacs.AckRequest {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecsacs.AckRequest(nil), r.acks...)
+}
+
+// Metrics returns every PublishMetricsRequest the agent sent over the TCS websocket.
+func (r *Recorder) Metrics() []*ecstcs.PublishMetricsRequest {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecstcs.PublishMetricsRequest(nil), r.metrics...)
+}
+
+// Health returns every PublishHealthRequest the agent sent over the TCS websocket.
+func (r *Recorder) Health() []*ecstcs.PublishHealthRequest {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*ecstcs.PublishHealthRequest(nil), r.health...)
+}
+
+// Reset discards everything recorded so far.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registrations = nil
+	r.taskStateChanges = nil
+	r.containerStateChanges = nil
+	r.attachmentStateChanges = nil
+	r.acks = nil
+	r.metrics = nil
+	r.health = nil
+}
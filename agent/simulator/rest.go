@@ -0,0 +1,218 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// TasksPath accepts a POST with a body of the same shape as an ACS
+	// PayloadMessage ({"tasks":[...]}) and sends the tasks to the agent.
+	TasksPath = "/simulator/v1/tasks"
+	// StopTaskPath accepts a POST with a body of {"taskArn":"..."} and sends
+	// the task with a desired status of STOPPED to the agent.
+	StopTaskPath = "/simulator/v1/tasks/stop"
+	// CredentialsPath accepts a POST with a body of the same shape as an ACS
+	// IAMRoleCredentialsMessage and sends it to the agent.
+	CredentialsPath = "/simulator/v1/credentials"
+	// TaskENIAttachmentsPath accepts a POST with a body of the same shape as an
+	// ACS AttachTaskNetworkInterfacesMessage and sends it to the agent.
+	TaskENIAttachmentsPath = "/simulator/v1/enis"
+	// RecordsPath returns everything recorded so far on GET and discards it
+	// on DELETE.
+	RecordsPath = "/simulator/v1/records"
+
+	maxRequestBodySize = 10 * 1024 * 1024
+)
+
+type stopTaskRequest struct {
+	TaskArn string `json:"taskArn"`
+}
+
+// recordsResponse is the response to a GET on RecordsPath. Requests are
+// rendered with the same json field names they have on the wire.
+type recordsResponse struct {
+	Registrations          []json.RawMessage `json:"registrations"`
+	TaskStateChanges       []json.RawMessage `json:"taskStateChanges"`
+	ContainerStateChanges  []json.RawMessage `json:"containerStateChanges"`
+	AttachmentStateChanges []json.RawMessage `json:"attachmentStateChanges"`
+	Acks                   []json.RawMessage `json:"acks"`
+	Metrics                []json.RawMessage `json:"metrics"`
+	Health                 []json.RawMessage `json:"health"`
+}
+
+func (server *Server) restHandlersSetup(mux *http.ServeMux) {
+	mux.HandleFunc(TasksPath, server.tasksHandler)
+	mux.HandleFunc(StopTaskPath, server.stopTaskHandler)
+	mux.HandleFunc(CredentialsPath, server.credentialsHandler)
+	mux.HandleFunc(TaskENIAttachmentsPath, server.taskENIAttachmentsHandler)
+	mux.HandleFunc(RecordsPath, server.recordsHandler)
+}
+
+func (server *Server) tasksHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readPostBody(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := decodeTasks(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := server.SendTasks(tasks...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (server *Server) stopTaskHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readPostBody(w, r)
+	if !ok {
+		return
+	}
+	request := &stopTaskRequest{}
+	if err := json.Unmarshal(body, request); err != nil || request.TaskArn == "" {
+		http.Error(w, "request must be of the form {\"taskArn\":\"...\"}", http.StatusBadRequest)
+		return
+	}
+	if err := server.StopTask(request.TaskArn); err != nil {
+		if _, ok := err.(errUnknownTask); ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (server *Server) credentialsHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readPostBody(w, r)
+	if !ok {
+		return
+	}
+	message := &ecsacs.IAMRoleCredentialsMessage{}
+	if err := jsonutil.UnmarshalJSON(message, bytes.NewReader(body)); err != nil ||
+		message.TaskArn == nil || message.RoleCredentials == nil {
+		http.Error(w, "request must be an IAMRoleCredentialsMessage with a taskArn and roleCredentials",
+			http.StatusBadRequest)
+		return
+	}
+	if err := server.SendCredentials(aws.StringValue(message.TaskArn), aws.StringValue(message.RoleType),
+		message.RoleCredentials); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (server *Server) taskENIAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readPostBody(w, r)
+	if !ok {
+		return
+	}
+	message := &ecsacs.AttachTaskNetworkInterfacesMessage{}
+	if err := jsonutil.UnmarshalJSON(message, bytes.NewReader(body)); err != nil ||
+		message.TaskArn == nil || len(message.ElasticNetworkInterfaces) == 0 {
+		http.Error(w, "request must be an AttachTaskNetworkInterfacesMessage with a taskArn and elasticNetworkInterfaces",
+			http.StatusBadRequest)
+		return
+	}
+	waitTimeout := time.Duration(aws.Int64Value(message.WaitTimeoutMs)) * time.Millisecond
+	if err := server.SendTaskENIAttachment(aws.StringValue(message.TaskArn), message.ElasticNetworkInterfaces,
+		waitTimeout); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (server *Server) recordsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		server.recorder.Reset()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := &recordsResponse{}
+	var err error
+	for _, field := range []struct {
+		dst  *[]json.RawMessage
+		recs interface{}
+	}{
+		{&response.Registrations, server.recorder.Registrations()},
+		{&response.TaskStateChanges, server.recorder.TaskStateChanges()},
+		{&response.ContainerStateChanges, server.recorder.ContainerStateChanges()},
+		{&response.AttachmentStateChanges, server.recorder.AttachmentStateChanges()},
+		{&response.Acks, server.recorder.Acks()},
+		{&response.Metrics, server.recorder.Metrics()},
+		{&response.Health, server.recorder.Health()},
+	} {
+		if *field.dst, err = marshalRecords(field.recs); err != nil {
+			seelog.Errorf("Simulator unable to marshal records: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// marshalRecords marshals each element of a slice of sdk structs.
+func marshalRecords(records interface{}) ([]json.RawMessage, error) {
+	data, err := jsonutil.BuildJSON(records)
+	if err != nil {
+		return nil, err
+	}
+	rendered := []json.RawMessage{}
+	if err := json.Unmarshal(data, &rendered); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+func readPostBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to read request body: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
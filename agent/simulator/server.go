@@ -0,0 +1,173 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package simulator implements a local stand-in for the ECS control plane.
+// It serves the subset of the ECS API used by the agent over http and the ACS
+// and TCS websocket protocols, so that the agent can be exercised end-to-end
+// without a real ECS backend. Every state change and metrics request sent by
+// the agent is recorded and can be inspected through the Recorder or the
+// simulator's REST API.
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/gorilla/websocket"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHeartbeatInterval is the interval at which the simulator sends
+	// heartbeats to the agent over ACS and TCS.
+	DefaultHeartbeatInterval = 20 * time.Second
+	// DefaultRegion is the region used to build ARNs when none is configured.
+	DefaultRegion = "us-west-2"
+
+	defaultAccountID = "000000000000"
+
+	acsBasePath = "/acs/"
+	tcsBasePath = "/tcs/"
+	// The agent appends "ws" to the endpoints returned by DiscoverPollEndpoint.
+	acsWSPath = acsBasePath + "ws"
+	tcsWSPath = tcsBasePath + "ws"
+
+	availabilityZoneAttributeName = "ecs.availability-zone"
+
+	wsBufferSize = 4096
+)
+
+// Config is the configuration of the simulator.
+type Config struct {
+	// Cluster is the cluster name reported to the agent. It is updated with
+	// the cluster the agent registers into.
+	Cluster string
+	// ContainerInstanceArn is returned by RegisterContainerInstance. A random
+	// ARN is generated when empty.
+	ContainerInstanceArn string
+	// Region is used to build ARNs.
+	Region string
+	// AvailabilityZone, if set, is returned as the availability zone attribute
+	// of the registered container instance.
+	AvailabilityZone string
+	// HeartbeatInterval is the interval between heartbeats sent over ACS and TCS.
+	HeartbeatInterval time.Duration
+}
+
+// Server is the simulated ECS control plane.
+type Server struct {
+	cfg                Config
+	recorder           *Recorder
+	upgrader           websocket.Upgrader
+	acsSession         *wsSession
+	pendingACSMessages []interface{}
+	// tasks holds the tasks sent to the agent, keyed by ARN
+	tasks  map[string]*ecsacs.Task
+	seqNum int64
+	lock   sync.RWMutex
+}
+
+// NewServer creates a new simulator with the given configuration.
+func NewServer(cfg Config) *Server {
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+	if cfg.HeartbeatInterval == 0 {
+		cfg.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if cfg.ContainerInstanceArn == "" {
+		cfg.ContainerInstanceArn = fmt.Sprintf("arn:aws:ecs:%s:%s:container-instance/%s",
+			cfg.Region, defaultAccountID, uuid.New())
+	}
+	return &Server{
+		cfg:      cfg,
+		recorder: NewRecorder(),
+		upgrader: websocket.Upgrader{ReadBufferSize: wsBufferSize, WriteBufferSize: wsBufferSize},
+		tasks:    make(map[string]*ecsacs.Task),
+	}
+}
+
+// Recorder returns the recorder holding every request received from the agent.
+func (server *Server) Recorder() *Recorder {
+	return server.recorder
+}
+
+// ContainerInstanceArn returns the ARN handed out to the agent on registration.
+func (server *Server) ContainerInstanceArn() string {
+	return server.cfg.ContainerInstanceArn
+}
+
+// Handler returns the http handler serving the ECS API, the ACS and TCS
+// websockets and the simulator's REST API. The agent's ECS endpoint should be
+// pointed at the address this handler is served on.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", server.ecsHandler)
+	mux.HandleFunc(acsWSPath, server.acsHandler)
+	mux.HandleFunc(tcsWSPath, server.tcsHandler)
+	server.restHandlersSetup(mux)
+	return mux
+}
+
+// Close closes the current ACS session, if any.
+func (server *Server) Close() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.acsSession != nil {
+		server.acsSession.close()
+		server.acsSession = nil
+	}
+}
+
+func (server *Server) clusterArn(name string) string {
+	return fmt.Sprintf("arn:aws:ecs:%s:%s:cluster/%s", server.cfg.Region, defaultAccountID, name)
+}
+
+// LoadTasksFile reads tasks from a file. The file has the same shape as the
+// payload of an ACS PayloadMessage, i.e. {"tasks":[{"arn":...}]}.
+func LoadTasksFile(path string) ([]*ecsacs.Task, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read tasks file %s", path)
+	}
+	return decodeTasks(data)
+}
+
+func decodeTasks(data []byte) ([]*ecsacs.Task, error) {
+	payload := &ecsacs.PayloadMessage{}
+	if err := jsonutil.UnmarshalJSON(payload, bytes.NewReader(data)); err != nil {
+		return nil, errors.Wrap(err, "unable to decode tasks")
+	}
+	if len(payload.Tasks) == 0 {
+		return nil, errors.New("no tasks found")
+	}
+	for _, task := range payload.Tasks {
+		if task == nil || task.Arn == nil {
+			return nil, errors.New("every task must have an arn")
+		}
+	}
+	return payload.Tasks, nil
+}
+
+type errUnknownTask string
+
+func (err errUnknownTask) Error() string {
+	return fmt.Sprintf("task %s was not sent by the simulator", string(err))
+}
@@ -0,0 +1,213 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	acsclient "github.com/aws/amazon-ecs-agent/agent/acs/client"
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	tcsclient "github.com/aws/amazon-ecs-agent/agent/tcs/client"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCluster = "test-cluster"
+	testTaskArn = "arn:aws:ecs:us-west-2:000000000000:task/test-cluster/abc"
+	testTasks   = `{"tasks":[{"arn":"` + testTaskArn + `","family":"sleep","version":"1","desiredStatus":"RUNNING",` +
+		`"containers":[{"name":"sleep","image":"busybox","command":["sleep","60"]}]}]}`
+	waitTimeout = 5 * time.Second
+)
+
+func setup() (*Server, *httptest.Server, func()) {
+	sim := NewServer(Config{Cluster: testCluster, HeartbeatInterval: time.Hour})
+	httpServer := httptest.NewServer(sim.Handler())
+	return sim, httpServer, func() {
+		sim.Close()
+		httpServer.Close()
+	}
+}
+
+func dial(t *testing.T, httpServer *httptest.Server, path string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+path, nil)
+	require.NoError(t, err)
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn, decoder wsclient.TypeDecoder) interface{} {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(waitTimeout)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	message, _, err := wsclient.DecodeData(data, decoder)
+	require.NoError(t, err)
+	return message
+}
+
+func TestECSAPI(t *testing.T) {
+	sim, httpServer, cleanup := setup()
+	defer cleanup()
+	client := ecs.New(session.New(&aws.Config{
+		Region:      aws.String(DefaultRegion),
+		Endpoint:    aws.String(httpServer.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+
+	attribute := &ecs.Attribute{Name: aws.String("ecs.capability.foo")}
+	registerOutput, err := client.RegisterContainerInstance(&ecs.RegisterContainerInstanceInput{
+		Cluster:    aws.String(testCluster),
+		Attributes: []*ecs.Attribute{attribute},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sim.ContainerInstanceArn(), aws.StringValue(registerOutput.ContainerInstance.ContainerInstanceArn))
+	assert.Equal(t, []*ecs.Attribute{attribute}, registerOutput.ContainerInstance.Attributes)
+	require.Len(t, sim.Recorder().Registrations(), 1)
+
+	discoverOutput, err := client.DiscoverPollEndpoint(&ecs.DiscoverPollEndpointInput{
+		ContainerInstance: aws.String(sim.ContainerInstanceArn()),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, httpServer.URL+acsBasePath, aws.StringValue(discoverOutput.Endpoint))
+	assert.Equal(t, httpServer.URL+tcsBasePath, aws.StringValue(discoverOutput.TelemetryEndpoint))
+
+	_, err = client.SubmitTaskStateChange(&ecs.SubmitTaskStateChangeInput{
+		Task:   aws.String(testTaskArn),
+		Status: aws.String("RUNNING"),
+	})
+	require.NoError(t, err)
+	_, err = client.SubmitContainerStateChange(&ecs.SubmitContainerStateChangeInput{
+		Task:          aws.String(testTaskArn),
+		ContainerName: aws.String("sleep"),
+		Status:        aws.String("RUNNING"),
+	})
+	require.NoError(t, err)
+
+	taskChanges := sim.Recorder().TaskStateChanges()
+	require.Len(t, taskChanges, 1)
+	assert.Equal(t, testTaskArn, aws.StringValue(taskChanges[0].Task))
+	containerChanges := sim.Recorder().ContainerStateChanges()
+	require.Len(t, containerChanges, 1)
+	assert.Equal(t, "sleep", aws.StringValue(containerChanges[0].ContainerName))
+
+	_, err = client.DeleteCluster(&ecs.DeleteClusterInput{Cluster: aws.String(testCluster)})
+	assert.Error(t, err, "expected unsupported operations to fail")
+}
+
+func TestACSSendsQueuedTasksAndRecordsAcks(t *testing.T) {
+	sim, httpServer, cleanup := setup()
+	defer cleanup()
+	tasks, err := decodeTasks([]byte(testTasks))
+	require.NoError(t, err)
+	require.NoError(t, sim.SendTasks(tasks...))
+
+	conn := dial(t, httpServer, acsWSPath)
+	defer conn.Close()
+	decoder := acsclient.NewACSDecoder()
+	payload, ok := readMessage(t, conn, decoder).(*ecsacs.PayloadMessage)
+	require.True(t, ok, "expected queued payload message")
+	require.Len(t, payload.Tasks, 1)
+	assert.Equal(t, testTaskArn, aws.StringValue(payload.Tasks[0].Arn))
+	assert.Equal(t, int64(1), aws.Int64Value(payload.SeqNum))
+
+	ack, err := encodeMessage(&ecsacs.AckRequest{MessageId: payload.MessageId}, decoder)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, ack))
+	for deadline := time.Now().Add(waitTimeout); len(sim.Recorder().Acks()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, sim.Recorder().Acks(), 1)
+
+	require.NoError(t, sim.StopTask(testTaskArn))
+	stopPayload, ok := readMessage(t, conn, decoder).(*ecsacs.PayloadMessage)
+	require.True(t, ok, "expected stop payload message")
+	assert.Equal(t, taskDesiredStatusStopped, aws.StringValue(stopPayload.Tasks[0].DesiredStatus))
+	assert.Equal(t, int64(2), aws.Int64Value(stopPayload.SeqNum))
+
+	assert.Error(t, sim.StopTask("unknown"))
+}
+
+func TestTCSRecordsSignedMetrics(t *testing.T) {
+	sim, httpServer, cleanup := setup()
+	defer cleanup()
+	conn := dial(t, httpServer, tcsWSPath)
+	defer conn.Close()
+	decoder := tcsclient.NewTCSDecoder()
+
+	request, err := encodeMessage(&ecstcs.PublishMetricsRequest{
+		Metadata: &ecstcs.MetricsMetadata{Cluster: aws.String(testCluster)},
+	}, decoder)
+	require.NoError(t, err)
+	signed := append([]byte("Authorization: signature\r\nHost: localhost\r\n\r\n"), request...)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, signed))
+
+	_, ok := readMessage(t, conn, decoder).(*ecstcs.AckPublishMetric)
+	assert.True(t, ok, "expected metrics to be acked")
+	metrics := sim.Recorder().Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, testCluster, aws.StringValue(metrics[0].Metadata.Cluster))
+}
+
+func TestRESTAPI(t *testing.T) {
+	sim, httpServer, cleanup := setup()
+	defer cleanup()
+
+	resp, err := http.Post(httpServer.URL+TasksPath, "application/json", strings.NewReader(testTasks))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Len(t, sim.pendingACSMessages, 1)
+
+	resp, err = http.Post(httpServer.URL+TasksPath, "application/json", strings.NewReader(`{"tasks":[{}]}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(httpServer.URL+StopTaskPath, "application/json", strings.NewReader(`{"taskArn":"unknown"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	sim.Recorder().recordTaskStateChange(&ecs.SubmitTaskStateChangeInput{Task: aws.String(testTaskArn)})
+	resp, err = http.Get(httpServer.URL + RecordsPath)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	records := &recordsResponse{}
+	require.NoError(t, json.Unmarshal(body, records))
+	require.Len(t, records.TaskStateChanges, 1)
+	assert.JSONEq(t, `{"task":"`+testTaskArn+`"}`, string(records.TaskStateChanges[0]))
+
+	req, err := http.NewRequest(http.MethodDelete, httpServer.URL+RecordsPath, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, sim.Recorder().TaskStateChanges())
+}
@@ -0,0 +1,133 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+
+	// signedHeadersSeparator separates the signed http headers that the TCS
+	// client prepends to every message from the message itself.
+	signedHeadersSeparator = "\r\n\r\n"
+)
+
+// wsSession is the server side of a single websocket connection opened by the
+// agent. Messages are framed the same way as wsclient frames them, i.e.
+// {"type":"FooMessage","message":{...}}.
+type wsSession struct {
+	conn      *websocket.Conn
+	decoder   wsclient.TypeDecoder
+	closed    chan struct{}
+	closeOnce sync.Once
+	writeLock sync.Mutex
+}
+
+func newWSSession(conn *websocket.Conn, decoder wsclient.TypeDecoder) *wsSession {
+	return &wsSession{
+		conn:    conn,
+		decoder: decoder,
+		closed:  make(chan struct{}),
+	}
+}
+
+// send marshals the message into a framed request and writes it to the
+// connection. The message *must* be a pointer to a type recognized by the
+// session's decoder.
+func (session *wsSession) send(message interface{}) error {
+	data, err := encodeMessage(message, session.decoder)
+	if err != nil {
+		return err
+	}
+
+	session.writeLock.Lock()
+	defer session.writeLock.Unlock()
+	if err := session.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return session.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// serve reads messages from the connection and calls handle with each decoded
+// message until the connection is closed.
+func (session *wsSession) serve(handle func(message interface{}, typeStr string)) error {
+	defer session.close()
+	for {
+		_, data, err := session.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		message, typeStr, err := wsclient.DecodeData(stripSignedHeaders(data), session.decoder)
+		if err != nil {
+			seelog.Warnf("Unable to decode message from agent: %v", err)
+			continue
+		}
+		handle(message, typeStr)
+	}
+}
+
+func (session *wsSession) close() {
+	session.closeOnce.Do(func() {
+		close(session.closed)
+		session.conn.Close()
+	})
+}
+
+// encodeMessage builds the {"type":...,"message":...} frame for the message
+// using the same marshalling rules as wsclient.
+func encodeMessage(message interface{}, decoder wsclient.TypeDecoder) ([]byte, error) {
+	msg := &wsclient.RequestMessage{}
+	for typeStr, typeVal := range decoder.GetRecognizedTypes() {
+		if reflect.TypeOf(message) == reflect.PtrTo(typeVal) {
+			msg.Type = typeStr
+			break
+		}
+	}
+	if msg.Type == "" {
+		return nil, &wsclient.UnrecognizedWSRequestType{Type: reflect.TypeOf(message).String()}
+	}
+	data, err := jsonutil.BuildJSON(message)
+	if err != nil {
+		return nil, &wsclient.NotMarshallableWSRequest{Type: msg.Type, Err: err}
+	}
+	msg.Message = json.RawMessage(data)
+	send, err := json.Marshal(msg)
+	if err != nil {
+		return nil, &wsclient.NotMarshallableWSRequest{Type: msg.Type, Err: err}
+	}
+	return send, nil
+}
+
+// stripSignedHeaders removes the signed http headers that precede the json
+// payload in messages sent by the TCS client.
+func stripSignedHeaders(data []byte) []byte {
+	if len(data) == 0 || data[0] == '{' {
+		return data
+	}
+	if idx := bytes.Index(data, []byte(signedHeadersSeparator)); idx >= 0 {
+		return data[idx+len(signedHeadersSeparator):]
+	}
+	return data
+}
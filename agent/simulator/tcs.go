@@ -0,0 +1,66 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package simulator
+
+import (
+	"net/http"
+
+	tcsclient "github.com/aws/amazon-ecs-agent/agent/tcs/client"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cihub/seelog"
+)
+
+// tcsHandler upgrades the agent's connection to TCS to a websocket, records
+// every metrics and health request and acks them so that the agent keeps the
+// session open.
+func (server *Server) tcsHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := server.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		seelog.Warnf("Simulator unable to upgrade TCS connection: %v", err)
+		return
+	}
+	seelog.Infof("Simulator accepted TCS connection from %s", r.RemoteAddr)
+	session := newWSSession(conn, tcsclient.NewTCSDecoder())
+
+	go server.heartbeat(session, func() interface{} {
+		return &ecstcs.HeartbeatMessage{Healthy: aws.Bool(true)}
+	})
+
+	err = session.serve(func(message interface{}, typeStr string) {
+		server.handleTCSMessage(session, message, typeStr)
+	})
+	seelog.Infof("Simulator TCS connection closed: %v", err)
+}
+
+// handleTCSMessage records and acks messages sent by the agent to TCS.
+func (server *Server) handleTCSMessage(session *wsSession, message interface{}, typeStr string) {
+	var ack interface{}
+	switch msg := message.(type) {
+	case *ecstcs.PublishMetricsRequest:
+		server.recorder.recordMetrics(msg)
+		ack = &ecstcs.AckPublishMetric{}
+	case *ecstcs.PublishHealthRequest:
+		server.recorder.recordHealth(msg)
+		ack = &ecstcs.AckPublishHealth{}
+	case *ecstcs.PublishInstanceStatusRequest:
+		ack = &ecstcs.AckPublishInstanceStatus{}
+	default:
+		seelog.Debugf("Simulator received TCS message of type %s", typeStr)
+		return
+	}
+	if err := session.send(ack); err != nil {
+		seelog.Warnf("Simulator unable to ack %s: %v", typeStr, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statechange
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// Broadcaster fans out state change events to any number of local subscribers,
+// in addition to the event handler that forwards them to ECS
+type Broadcaster struct {
+	subscribers map[*Subscription]struct{}
+	lock        sync.Mutex
+}
+
+// PublishedEvent is a state change event along with the time it was published at
+type PublishedEvent struct {
+	Event       Event
+	PublishedAt time.Time
+}
+
+// Subscription receives the events published to the Broadcaster it was created by
+type Subscription struct {
+	events      chan PublishedEvent
+	broadcaster *Broadcaster
+}
+
+// NewBroadcaster creates a new Broadcaster without any subscribers
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe creates a new subscription that buffers up to bufferSize events
+func (broadcaster *Broadcaster) Subscribe(bufferSize int) *Subscription {
+	broadcaster.lock.Lock()
+	defer broadcaster.lock.Unlock()
+
+	subscription := &Subscription{
+		events:      make(chan PublishedEvent, bufferSize),
+		broadcaster: broadcaster,
+	}
+	broadcaster.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// Publish sends the event to every subscriber without blocking. A subscriber
+// whose buffer is full is unsubscribed and its events channel closed, so that
+// a slow subscriber finds out that it missed events instead of holding up the
+// event handler. The event is delivered with the time it was published at,
+// which subscribers may only get to later.
+func (broadcaster *Broadcaster) Publish(event Event) {
+	published := PublishedEvent{
+		Event:       event,
+		PublishedAt: time.Now().UTC(),
+	}
+	broadcaster.lock.Lock()
+	defer broadcaster.lock.Unlock()
+
+	for subscription := range broadcaster.subscribers {
+		select {
+		case subscription.events <- published:
+		default:
+			seelog.Warnf("State change event subscriber is not keeping up, closing its subscription")
+			broadcaster.removeSubscriber(subscription)
+		}
+	}
+}
+
+// removeSubscriber must be called with the lock held
+func (broadcaster *Broadcaster) removeSubscriber(subscription *Subscription) {
+	if _, ok := broadcaster.subscribers[subscription]; !ok {
+		return
+	}
+	delete(broadcaster.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Events returns the channel the subscribed events are delivered on. The
+// channel is closed once the subscription ends.
+func (subscription *Subscription) Events() <-chan PublishedEvent {
+	return subscription.events
+}
+
+// Unsubscribe stops delivering events to the subscription
+func (subscription *Subscription) Unsubscribe() {
+	subscription.broadcaster.lock.Lock()
+	defer subscription.broadcaster.lock.Unlock()
+
+	subscription.broadcaster.removeSubscriber(subscription)
+}
@@ -0,0 +1,78 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statechange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent EventType
+
+func (event testEvent) GetEventType() EventType {
+	return EventType(event)
+}
+
+func TestBroadcasterPublishesToEverySubscriber(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	first := broadcaster.Subscribe(1)
+	second := broadcaster.Subscribe(1)
+
+	before := time.Now()
+	broadcaster.Publish(testEvent(TaskEvent))
+
+	published := <-first.Events()
+	assert.Equal(t, testEvent(TaskEvent), published.Event)
+	assert.False(t, published.PublishedAt.Before(before), "expected the event to be timestamped when published")
+	assert.Equal(t, published, <-second.Events())
+}
+
+func TestBroadcasterClosesSlowSubscription(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	slow := broadcaster.Subscribe(1)
+	fast := broadcaster.Subscribe(2)
+
+	broadcaster.Publish(testEvent(ContainerEvent))
+	broadcaster.Publish(testEvent(TaskEvent))
+
+	assert.Equal(t, testEvent(ContainerEvent), (<-slow.Events()).Event)
+	_, ok := <-slow.Events()
+	assert.False(t, ok, "expected the slow subscription to be closed")
+	assert.Len(t, fast.Events(), 2)
+	// Unsubscribing a closed subscription is a no-op
+	slow.Unsubscribe()
+}
+
+func TestBroadcasterUnsubscribe(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	subscription := broadcaster.Subscribe(1)
+	subscription.Unsubscribe()
+
+	broadcaster.Publish(testEvent(AttachmentEvent))
+
+	_, ok := <-subscription.Events()
+	assert.False(t, ok)
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "task", TaskEvent.String())
+	assert.Equal(t, "container", ContainerEvent.String())
+	assert.Equal(t, "managedagent", ManagedAgentEvent.String())
+	assert.Equal(t, "attachment", AttachmentEvent.String())
+	assert.Equal(t, "unknown", EventType(42).String())
+}
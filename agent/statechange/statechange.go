@@ -29,6 +29,10 @@ const (
 	// ManagedAgentEvent is used to define the managed agent state transition events
 	// emitted by the engine
 	ManagedAgentEvent
+
+	// SecretsRefreshedEvent is used to define the events emitted by the engine when the value
+	// of secrets of a running container changed
+	SecretsRefreshedEvent
 )
 
 // Event defines the type of state change event
@@ -41,3 +45,21 @@ type Event interface {
 	// identify the type of event being emitted
 	GetEventType() EventType
 }
+
+// String returns the name of the event type
+func (eventType EventType) String() string {
+	switch eventType {
+	case ContainerEvent:
+		return "container"
+	case TaskEvent:
+		return "task"
+	case AttachmentEvent:
+		return "attachment"
+	case ManagedAgentEvent:
+		return "managedagent"
+	case SecretsRefreshedEvent:
+		return "secretsrefreshed"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,429 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// cgroupV2ControllersFile only exists at the root of the unified (v2) hierarchy.
+	cgroupV2ControllersFile = "cgroup.controllers"
+	// dockerCgroupParent is the cgroup that docker creates containers under, when they aren't
+	// created under a task cgroup.
+	dockerCgroupParent = "/docker"
+	// hostProcPath is where the proc filesystem of the host is mounted in the agent container.
+	hostProcPath      = "/host/proc"
+	loopbackInterface = "lo"
+	// clockTicksPerSecond is the unit of the cpu times of /proc/stat, USER_HZ, which is 100 on
+	// every architecture that the agent supports. Docker makes the same assumption.
+	clockTicksPerSecond = 100
+)
+
+// cgroupStatsReader reads the CPU, memory and block io accounting of container cgroups
+// directly from cgroupfs, in either the v1 or the unified v2 layout.
+type cgroupStatsReader struct {
+	mountPath string
+	procPath  string
+	unified   bool
+	// lastStats is the previous read, whose cpu stats and time are reported as the pre cpu
+	// stats of the next one, like in stats streams.
+	lastStats *types.StatsJSON
+}
+
+// newCgroupStatsReader creates a cgroupStatsReader for the cgroupfs mounted at mountPath. The
+// cgroup version is detected from the layout of the mount.
+func newCgroupStatsReader(mountPath string) *cgroupStatsReader {
+	_, err := os.Stat(filepath.Join(mountPath, cgroupV2ControllersFile))
+	return &cgroupStatsReader{
+		mountPath: mountPath,
+		procPath:  hostProcPath,
+		unified:   err == nil,
+	}
+}
+
+// processCgroupPath returns the path of the cgroup of the process pid, relative to the root of a
+// hierarchy, as listed in /proc/<pid>/cgroup. On v1 hierarchies, it's the path in the memory
+// hierarchy, since docker creates the cgroups of a container at the same path in all of them.
+func (reader *cgroupStatsReader) processCgroupPath(pid int) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(reader.procPath, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read cgroups of process %d", pid)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// Lines have the form "hierarchy-id:controllers:path", the controllers of the unified
+		// hierarchy are empty
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if reader.unified && parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+		if !reader.unified {
+			for _, controller := range strings.Split(parts[1], ",") {
+				if controller == "memory" {
+					return parts[2], nil
+				}
+			}
+		}
+	}
+	return "", errors.Errorf("cgroup of process %d not found", pid)
+}
+
+// cgroupParentPath returns the path of the cgroup of a container created under cgroupParent,
+// relative to the root of a hierarchy. Parents that are systemd slices are used with the systemd
+// cgroup driver of docker, which creates containers in a "docker-<id>.scope" of the slice.
+func cgroupParentPath(cgroupParent string, dockerID string) string {
+	if !strings.HasSuffix(cgroupParent, ".slice") {
+		return filepath.Join("/", cgroupParent, dockerID)
+	}
+	// systemd nests slices by name, "a-b.slice" is in "a.slice"
+	name := strings.TrimSuffix(cgroupParent, ".slice")
+	path := "/"
+	for i, part := range strings.Split(name, "-") {
+		if i == 0 {
+			path = filepath.Join(path, part+".slice")
+			continue
+		}
+		path = filepath.Join(path, strings.TrimSuffix(filepath.Base(path), ".slice")+"-"+part+".slice")
+	}
+	return filepath.Join(path, "docker-"+dockerID+".scope")
+}
+
+// containerCgroupPath returns the path of the cgroup of a container, relative to the root of a
+// hierarchy. Containers of tasks with task level cgroups are created under the task cgroup.
+func containerCgroupPath(task *apitask.Task, dockerID string) (string, error) {
+	if !task.MemoryCPULimitsEnabled {
+		return filepath.Join(dockerCgroupParent, dockerID), nil
+	}
+	cgroupParent, err := task.BuildCgroupParent()
+	if err != nil {
+		return "", err
+	}
+	return cgroupParentPath(cgroupParent, dockerID), nil
+}
+
+// read returns the stats of the cgroup at cgroupPath in the format of stats from docker, so
+// that they're processed like the stats of a docker stats stream. Network stats are read from
+// the network namespace of a process of the cgroup when withNetwork is set, since cgroups
+// don't account for network usage. The cpu stats of the previous read are reported as the pre
+// cpu stats, so that consumers of the stats can compute the cpu utilization like with docker.
+func (reader *cgroupStatsReader) read(cgroupPath string, withNetwork bool) (*types.StatsJSON, error) {
+	dockerStats := &types.StatsJSON{}
+	var err error
+	if reader.unified {
+		err = reader.readV2(cgroupPath, dockerStats)
+	} else {
+		err = reader.readV1(cgroupPath, dockerStats)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if withNetwork {
+		dockerStats.Networks, err = reader.readNetworkStats(cgroupPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dockerStats.CPUStats.SystemUsage, err = reader.readSystemCPUUsage(); err != nil {
+		return nil, err
+	}
+	dockerStats.Read = time.Now()
+	dockerStats.CPUStats.OnlineCPUs = uint32(numCores)
+	if reader.lastStats != nil {
+		dockerStats.PreRead = reader.lastStats.Read
+		dockerStats.PreCPUStats = reader.lastStats.CPUStats
+	}
+	reader.lastStats = dockerStats
+	return dockerStats, nil
+}
+
+// readSystemCPUUsage returns the cpu time of the host in nanoseconds, from the cpu line of
+// /proc/stat, computed like the system usage of docker stats.
+func (reader *cgroupStatsReader) readSystemCPUUsage() (uint64, error) {
+	path := filepath.Join(reader.procPath, "stat")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// The line has the form "cpu user nice system idle iowait irq softirq steal guest guest_nice"
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		if len(fields) < 8 {
+			return 0, errors.Errorf("invalid cpu line in %s", path)
+		}
+		var ticks uint64
+		for _, field := range fields[1:8] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, errors.Wrapf(err, "unable to parse %s", path)
+			}
+			ticks += value
+		}
+		return ticks * uint64(time.Second) / clockTicksPerSecond, nil
+	}
+	return 0, errors.Errorf("cpu line not found in %s", path)
+}
+
+func (reader *cgroupStatsReader) readV1(cgroupPath string, dockerStats *types.StatsJSON) error {
+	var err error
+	cpuacctPath := filepath.Join(reader.mountPath, "cpuacct", cgroupPath)
+	if dockerStats.CPUStats.CPUUsage.TotalUsage, err = readUint(filepath.Join(cpuacctPath, "cpuacct.usage")); err != nil {
+		return err
+	}
+	if dockerStats.CPUStats.CPUUsage.PercpuUsage, err = readUints(filepath.Join(cpuacctPath, "cpuacct.usage_percpu")); err != nil {
+		return err
+	}
+	memoryPath := filepath.Join(reader.mountPath, "memory", cgroupPath)
+	if dockerStats.MemoryStats.Usage, err = readUint(filepath.Join(memoryPath, "memory.usage_in_bytes")); err != nil {
+		return err
+	}
+	if dockerStats.MemoryStats.Stats, err = readKeyValues(filepath.Join(memoryPath, "memory.stat")); err != nil {
+		return err
+	}
+	blkioPath := filepath.Join(reader.mountPath, "blkio", cgroupPath)
+	entries, err := readBlkioServiceBytes(filepath.Join(blkioPath, "blkio.io_service_bytes_recursive"))
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		// Only the throttle policy accounts for io when the cfq scheduler isn't used
+		entries, err = readBlkioServiceBytes(filepath.Join(blkioPath, "blkio.throttle.io_service_bytes_recursive"))
+		if err != nil {
+			return err
+		}
+	}
+	dockerStats.BlkioStats.IoServiceBytesRecursive = entries
+	return nil
+}
+
+func (reader *cgroupStatsReader) readV2(cgroupPath string, dockerStats *types.StatsJSON) error {
+	path := filepath.Join(reader.mountPath, cgroupPath)
+	cpuStat, err := readKeyValues(filepath.Join(path, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	// The unified hierarchy doesn't account the usage of every cpu, so like in docker stats,
+	// PercpuUsage is left empty
+	dockerStats.CPUStats.CPUUsage.TotalUsage = cpuStat["usage_usec"] * uint64(time.Microsecond)
+	if dockerStats.MemoryStats.Usage, err = readUint(filepath.Join(path, "memory.current")); err != nil {
+		return err
+	}
+	if dockerStats.MemoryStats.Stats, err = readKeyValues(filepath.Join(path, "memory.stat")); err != nil {
+		return err
+	}
+	dockerStats.BlkioStats.IoServiceBytesRecursive, err = readIOStatBytes(filepath.Join(path, "io.stat"))
+	return err
+}
+
+// readNetworkStats reads the counters of the interfaces of the network namespace of the first
+// process of the cgroup, excluding the loopback interface.
+func (reader *cgroupStatsReader) readNetworkStats(cgroupPath string) (map[string]types.NetworkStats, error) {
+	procsPath := filepath.Join(reader.mountPath, cgroupPath, "cgroup.procs")
+	if !reader.unified {
+		procsPath = filepath.Join(reader.mountPath, "memory", cgroupPath, "cgroup.procs")
+	}
+	procs, err := ioutil.ReadFile(procsPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read processes of cgroup %s", cgroupPath)
+	}
+	pid := strings.TrimSpace(strings.SplitN(string(procs), "\n", 2)[0])
+	if pid == "" {
+		return nil, errors.Errorf("cgroup %s has no processes", cgroupPath)
+	}
+
+	netDev, err := ioutil.ReadFile(filepath.Join(reader.procPath, pid, "net", "dev"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read network stats of process %s", pid)
+	}
+	networks := make(map[string]types.NetworkStats)
+	for _, line := range strings.Split(string(netDev), "\n") {
+		// Interface lines have the form "eth0: rx bytes packets errs drop fifo frame compressed
+		// multicast tx bytes packets errs drop fifo colls carrier compressed"
+		parts := strings.SplitN(line, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == loopbackInterface {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			continue
+		}
+		values := make([]uint64, 12)
+		for i := range values {
+			if values[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				return nil, errors.Wrapf(err, "unable to parse network stats of process %s", pid)
+			}
+		}
+		networks[name] = types.NetworkStats{
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDropped: values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDropped: values[11],
+		}
+	}
+	return networks, nil
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse %s", path)
+	}
+	return value, nil
+}
+
+// readUints reads a file of space separated values, such as cpuacct.usage_percpu.
+func readUints(path string) ([]uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values []uint64
+	for _, field := range strings.Fields(string(content)) {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// readKeyValues reads a flat keyed file such as memory.stat or cpu.stat.
+func readKeyValues(path string) (map[string]uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		values[fields[0]] = value
+	}
+	return values, nil
+}
+
+// readBlkioServiceBytes reads the Read and Write entries of a v1 blkio file, such as
+// "8:0 Read 4096".
+func readBlkioServiceBytes(path string) ([]types.BlkioStatEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []types.BlkioStatEntry
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || (fields[1] != "Read" && fields[1] != "Write") {
+			continue
+		}
+		entry := types.BlkioStatEntry{Op: fields[1]}
+		if entry.Major, entry.Minor, err = parseDevice(fields[0]); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		if entry.Value, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readIOStatBytes reads the rbytes and wbytes of the devices of a v2 io.stat file, such as
+// "8:0 rbytes=4096 wbytes=0 rios=1 wios=0 dbytes=0 dios=0", as Read and Write entries.
+func readIOStatBytes(path string) ([]types.BlkioStatEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []types.BlkioStatEntry
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		major, minor, err := parseDevice(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			var op string
+			switch keyValue[0] {
+			case "rbytes":
+				op = "Read"
+			case "wbytes":
+				op = "Write"
+			default:
+				continue
+			}
+			value, err := strconv.ParseUint(keyValue[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse %s", path)
+			}
+			entries = append(entries, types.BlkioStatEntry{Major: major, Minor: minor, Op: op, Value: value})
+		}
+	}
+	return entries, nil
+}
+
+// parseDevice parses a "major:minor" device number.
+func parseDevice(device string) (uint64, uint64, error) {
+	numbers := strings.SplitN(device, ":", 2)
+	if len(numbers) != 2 {
+		return 0, 0, errors.Errorf("invalid device %q", device)
+	}
+	major, err := strconv.ParseUint(numbers[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	minor, err := strconv.ParseUint(numbers[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return major, minor, nil
+}
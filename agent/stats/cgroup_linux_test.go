@@ -0,0 +1,285 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cgroupTestPath   = "/ecs/task-id/container-id"
+	cgroupTestPID    = "42"
+	cgroupTestNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0:    2048      20    1    2    0     0          0         0     4096      40    3    4    0     0       0          0
+`
+	cgroupTestProcStat = "cpu  100 10 50 1000 5 1 2 0 0 0\ncpu0 50 5 25 500 3 1 1 0 0 0\n"
+)
+
+func writeCgroupTestFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func newCgroupTestReader(t *testing.T, mountPath string) *cgroupStatsReader {
+	procPath := t.TempDir()
+	writeCgroupTestFile(t, filepath.Join(procPath, cgroupTestPID, "net", "dev"), cgroupTestNetDev)
+	writeCgroupTestFile(t, filepath.Join(procPath, "stat"), cgroupTestProcStat)
+	reader := newCgroupStatsReader(mountPath)
+	reader.procPath = procPath
+	return reader
+}
+
+func TestCgroupStatsReaderV1(t *testing.T) {
+	mountPath := t.TempDir()
+	writeCgroupTestFile(t, filepath.Join(mountPath, "cpuacct", cgroupTestPath, "cpuacct.usage"), "2000000000\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "cpuacct", cgroupTestPath, "cpuacct.usage_percpu"), "1500000000 500000000 \n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "memory", cgroupTestPath, "memory.usage_in_bytes"), "10485760\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "memory", cgroupTestPath, "memory.stat"), "cache 2097152\nrss 8388608\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "memory", cgroupTestPath, "cgroup.procs"), cgroupTestPID+"\n43\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "blkio", cgroupTestPath, "blkio.io_service_bytes_recursive"), "Total 0\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "blkio", cgroupTestPath, "blkio.throttle.io_service_bytes_recursive"),
+		"8:0 Read 4096\n8:0 Write 8192\n8:0 Sync 0\n8:0 Total 12288\n8:16 Read 1024\nTotal 13312\n")
+	reader := newCgroupTestReader(t, mountPath)
+	assert.False(t, reader.unified)
+
+	dockerStats, err := reader.read(cgroupTestPath, true)
+	require.NoError(t, err)
+	stats, err := dockerStatsToContainerStats(dockerStats)
+	require.NoError(t, err)
+	assert.Equal(t, 2000000000/numCores, stats.cpuUsage)
+	assert.Equal(t, uint64(8388608), stats.memoryUsage)
+	assert.Equal(t, uint64(5120), stats.storageReadBytes)
+	assert.Equal(t, uint64(8192), stats.storageWriteBytes)
+	require.NotNil(t, stats.networkStats)
+	assert.Equal(t, uint64(2048), stats.networkStats.RxBytes)
+	assert.Equal(t, uint64(2), stats.networkStats.RxDropped)
+	assert.Equal(t, uint64(4096), stats.networkStats.TxBytes)
+	assert.Equal(t, uint64(40), stats.networkStats.TxPackets)
+	assert.False(t, stats.timestamp.IsZero())
+	assert.Equal(t, []uint64{1500000000, 500000000}, dockerStats.CPUStats.CPUUsage.PercpuUsage)
+	assert.Equal(t, uint64(11680000000), dockerStats.CPUStats.SystemUsage)
+	assert.Zero(t, dockerStats.PreCPUStats.CPUUsage.TotalUsage)
+
+	// The cpu stats of the previous read are reported as pre cpu stats
+	writeCgroupTestFile(t, filepath.Join(mountPath, "cpuacct", cgroupTestPath, "cpuacct.usage"), "2500000000\n")
+	nextStats, err := reader.read(cgroupTestPath, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2500000000), nextStats.CPUStats.CPUUsage.TotalUsage)
+	assert.Equal(t, dockerStats.CPUStats, nextStats.PreCPUStats)
+	assert.Equal(t, dockerStats.Read, nextStats.PreRead)
+}
+
+func TestCgroupStatsReaderV2(t *testing.T) {
+	mountPath := t.TempDir()
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupV2ControllersFile), "cpu io memory pids\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupTestPath, "cpu.stat"), "usage_usec 3000000\nuser_usec 2000000\nsystem_usec 1000000\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupTestPath, "memory.current"), "10485760\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupTestPath, "memory.stat"), "anon 8388608\nfile 2097152\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupTestPath, "io.stat"),
+		"8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n")
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupTestPath, "cgroup.procs"), cgroupTestPID+"\n")
+	reader := newCgroupTestReader(t, mountPath)
+	assert.True(t, reader.unified)
+
+	dockerStats, err := reader.read(cgroupTestPath, true)
+	require.NoError(t, err)
+	stats, err := dockerStatsToContainerStats(dockerStats)
+	require.NoError(t, err)
+	assert.Equal(t, 3000000000/numCores, stats.cpuUsage)
+	assert.Equal(t, uint64(10485760), stats.memoryUsage)
+	assert.Equal(t, uint64(5120), stats.storageReadBytes)
+	assert.Equal(t, uint64(8192), stats.storageWriteBytes)
+	require.NotNil(t, stats.networkStats)
+	assert.Equal(t, uint64(2048), stats.networkStats.RxBytes)
+
+	// Network stats aren't read for containers without their own network namespace
+	dockerStats, err = reader.read(cgroupTestPath, false)
+	require.NoError(t, err)
+	assert.Nil(t, dockerStats.Networks)
+}
+
+func TestCgroupStatsReaderMissingCgroup(t *testing.T) {
+	reader := newCgroupTestReader(t, t.TempDir())
+	_, err := reader.read(cgroupTestPath, false)
+	assert.Error(t, err)
+}
+
+func TestContainerCgroupPath(t *testing.T) {
+	task := &apitask.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id"}
+	path, err := containerCgroupPath(task, "container-id")
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/container-id", path)
+
+	task.MemoryCPULimitsEnabled = true
+	path, err = containerCgroupPath(task, "container-id")
+	require.NoError(t, err)
+	assert.Equal(t, cgroupTestPath, path)
+
+	task.PlatformFields.CgroupSlice = true
+	path, err = containerCgroupPath(task, "container-id")
+	require.NoError(t, err)
+	assert.Equal(t, "/ecstasks.slice/ecstasks-taskid.slice/docker-container-id.scope", path)
+}
+
+func TestProcessCgroupPath(t *testing.T) {
+	testCases := []struct {
+		name    string
+		unified bool
+		cgroups string
+		path    string
+	}{
+		{
+			name:    "v1 cgroupfs driver",
+			cgroups: "12:pids:/docker/container-id\n4:memory:/docker/container-id\n1:name=systemd:/docker/container-id\n",
+			path:    "/docker/container-id",
+		},
+		{
+			name:    "v1 systemd driver",
+			cgroups: "3:cpu,cpuacct:/system.slice/docker-container-id.scope\n2:memory:/system.slice/docker-container-id.scope\n",
+			path:    "/system.slice/docker-container-id.scope",
+		},
+		{
+			name:    "v2 systemd driver",
+			unified: true,
+			cgroups: "0::/system.slice/docker-container-id.scope\n",
+			path:    "/system.slice/docker-container-id.scope",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := newCgroupTestReader(t, t.TempDir())
+			reader.unified = tc.unified
+			writeCgroupTestFile(t, filepath.Join(reader.procPath, cgroupTestPID, "cgroup"), tc.cgroups)
+
+			path, err := reader.processCgroupPath(42)
+			require.NoError(t, err)
+			assert.Equal(t, tc.path, path)
+		})
+	}
+
+	reader := newCgroupTestReader(t, t.TempDir())
+	writeCgroupTestFile(t, filepath.Join(reader.procPath, cgroupTestPID, "cgroup"), "0::/system.slice/docker-container-id.scope\n")
+	_, err := reader.processCgroupPath(42)
+	assert.Error(t, err, "Expected the v2 cgroup not to be used on v1 hierarchies")
+	_, err = reader.processCgroupPath(43)
+	assert.Error(t, err)
+}
+
+func TestCgroupParentPath(t *testing.T) {
+	assert.Equal(t, cgroupTestPath, cgroupParentPath("/ecs/task-id", "container-id"))
+	assert.Equal(t, "/system.slice/docker-container-id.scope", cgroupParentPath("system.slice", "container-id"))
+	assert.Equal(t, "/ecs.slice/ecs-task.slice/docker-container-id.scope", cgroupParentPath("ecs-task.slice", "container-id"))
+}
+
+func TestStatsContainerCgroupPath(t *testing.T) {
+	task := &apitask.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id"}
+	testCases := []struct {
+		name      string
+		inspected *types.ContainerJSON
+		path      string
+	}{
+		{
+			name: "from the container process",
+			inspected: &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+				State:      &types.ContainerState{Pid: 42},
+				HostConfig: &container.HostConfig{},
+			}},
+			path: "/system.slice/docker-container-id.scope",
+		},
+		{
+			name: "from the cgroup parent",
+			inspected: &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+				State:      &types.ContainerState{},
+				HostConfig: &container.HostConfig{Resources: container.Resources{CgroupParent: "system.slice"}},
+			}},
+			path: "/system.slice/docker-container-id.scope",
+		},
+		{
+			name: "from the task",
+			inspected: &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+				State:      &types.ContainerState{Pid: 43},
+				HostConfig: &container.HostConfig{},
+			}},
+			path: "/docker/container-id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+			mockDockerClient.EXPECT().InspectContainer(gomock.Any(), "container-id", gomock.Any()).Return(tc.inspected, nil)
+			statsContainer := &StatsContainer{
+				containerMetadata: &ContainerMetadata{DockerID: "container-id"},
+				ctx:               context.TODO(),
+				client:            mockDockerClient,
+			}
+			reader := newCgroupTestReader(t, t.TempDir())
+			writeCgroupTestFile(t, filepath.Join(reader.procPath, cgroupTestPID, "cgroup"),
+				"2:memory:/system.slice/docker-container-id.scope\n")
+
+			path, err := statsContainer.cgroupPath(reader, task)
+			require.NoError(t, err)
+			assert.Equal(t, tc.path, path)
+		})
+	}
+}
+
+func TestStatsContainerMemoryEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockDockerClient.EXPECT().InspectContainer(gomock.Any(), "container-id", gomock.Any()).Return(
+		&types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+			State:      &types.ContainerState{Pid: 42},
+			HostConfig: &container.HostConfig{},
+		}}, nil)
+	statsContainer := &StatsContainer{
+		containerMetadata: &ContainerMetadata{DockerID: "container-id"},
+		ctx:               context.TODO(),
+		client:            mockDockerClient,
+	}
+
+	// The container is in a systemd scope rather than under /docker, where the task would put it
+	mountPath := t.TempDir()
+	writeCgroupTestFile(t, filepath.Join(mountPath, cgroupV2ControllersFile), "cpu io memory")
+	writeCgroupTestFile(t, filepath.Join(mountPath, "system.slice", "docker-container-id.scope", "memory.events"),
+		"oom 3\noom_kill 3\n")
+	reader := newCgroupTestReader(t, mountPath)
+	writeCgroupTestFile(t, filepath.Join(reader.procPath, cgroupTestPID, "cgroup"),
+		"0::/system.slice/docker-container-id.scope\n")
+
+	task := &apitask.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id"}
+	events, err := statsContainer.memoryEvents(reader, task)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), events.OOMKills)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/data"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
@@ -343,3 +344,6 @@ func (engine *MockTaskEngine) Disable() {
 func (engine *MockTaskEngine) Info() (types.Info, error) {
 	return types.Info{}, nil
 }
+
+func (engine *MockTaskEngine) SetImagePolicy(*image.Policy) {
+}
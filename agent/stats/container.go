@@ -27,6 +27,10 @@ import (
 	"github.com/cihub/seelog"
 )
 
+// defaultCgroupStatsInterval is the interval at which stats are read from cgroupfs, the same
+// as the rate of stats of a docker stats stream.
+const defaultCgroupStatsInterval = time.Second
+
 func newStatsContainer(dockerID string, client dockerapi.DockerClient, resolver resolver.ContainerMetadataResolver,
 	cfg *config.Config) (*StatsContainer, error) {
 	dockerContainer, err := resolver.ResolveContainer(dockerID)
@@ -59,9 +63,22 @@ func (container *StatsContainer) StartStatsCollection() {
 		queueSize = int(config.DefaultContainerMetricsPublishInterval.Seconds() * 4)
 	}
 	container.statsQueue = NewQueue(queueSize)
+	if container.config != nil && container.config.StatsCollector == config.StatsCollectorCgroupfs {
+		go container.collectCgroupStats()
+		return
+	}
 	go container.collect()
 }
 
+// cgroupStatsInterval returns the interval at which the stats of the container are read from
+// its cgroup. It matches the rate of stats that the queue is sized for.
+func (container *StatsContainer) cgroupStatsInterval() time.Duration {
+	if container.config.PollMetrics.Enabled() {
+		return container.config.PollingMetricsWaitDuration
+	}
+	return defaultCgroupStatsInterval
+}
+
 func (container *StatsContainer) StopStatsCollection() {
 	container.cancel()
 }
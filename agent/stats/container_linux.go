@@ -0,0 +1,100 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// collectCgroupStats reads the stats of the container from its cgroup at a fixed interval,
+// until the stats collection is stopped or the container is terminal.
+func (container *StatsContainer) collectCgroupStats() {
+	dockerID := container.containerMetadata.DockerID
+	task, err := container.resolver.ResolveTask(dockerID)
+	if err != nil {
+		seelog.Warnf("Container [%s]: Error resolving the task of the container, stopping stats collection: %v", dockerID, err)
+		container.StopStatsCollection()
+		return
+	}
+	reader := newCgroupStatsReader(container.config.CgroupPath)
+	cgroupPath, err := container.cgroupPath(reader, task)
+	if err != nil {
+		seelog.Warnf("Container [%s]: Error resolving the cgroup of the container, stopping stats collection: %v", dockerID, err)
+		container.StopStatsCollection()
+		return
+	}
+	networkMode := container.containerMetadata.NetworkMode
+	withNetwork := networkMode != hostNetworkMode && networkMode != noneNetworkMode
+	seelog.Debugf("Collecting stats for container %s from cgroup %s", dockerID, cgroupPath)
+
+	ticker := time.NewTicker(container.cgroupStatsInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-container.ctx.Done():
+			seelog.Infof("Container [%s]: Stopping stats collection", dockerID)
+			return
+		case <-ticker.C:
+		}
+		dockerStats, err := reader.read(cgroupPath, withNetwork)
+		if err != nil {
+			// The cgroup of the container is removed when it stops. Like for stats streams,
+			// stop collecting when the container is terminal or no longer tracked.
+			terminal, terminalErr := container.terminal()
+			if terminalErr != nil || terminal {
+				seelog.Infof("Container [%s]: container is terminal, stopping stats collection", dockerID)
+				container.StopStatsCollection()
+				return
+			}
+			seelog.Warnf("Container [%s]: Error reading stats from cgroup %s: %v", dockerID, cgroupPath, err)
+			continue
+		}
+		if err := container.statsQueue.Add(dockerStats); err != nil {
+			seelog.Warnf("Container [%s]: error converting stats for container: %v", dockerID, err)
+		}
+	}
+}
+
+// cgroupPath returns the path of the cgroup of the container, relative to the root of a
+// hierarchy. It's read from the cgroups of the init process of the container, so that it's
+// found whatever the cgroup driver of docker. When the process can't be found, it's built from
+// the cgroup parent that the container was created with, or from its task.
+func (container *StatsContainer) cgroupPath(reader *cgroupStatsReader, task *apitask.Task) (string, error) {
+	dockerID := container.containerMetadata.DockerID
+	dockerContainer, err := container.client.InspectContainer(container.ctx, dockerID, dockerclient.InspectContainerTimeout)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to inspect the container")
+	}
+	if dockerContainer.ContainerJSONBase == nil {
+		return containerCgroupPath(task, dockerID)
+	}
+	if dockerContainer.State != nil && dockerContainer.State.Pid != 0 {
+		path, err := reader.processCgroupPath(dockerContainer.State.Pid)
+		if err == nil {
+			return path, nil
+		}
+		seelog.Warnf("Container [%s]: Error reading the cgroup of the container process: %v", dockerID, err)
+	}
+	if dockerContainer.HostConfig != nil && dockerContainer.HostConfig.CgroupParent != "" {
+		return cgroupParentPath(dockerContainer.HostConfig.CgroupParent, dockerID), nil
+	}
+	return containerCgroupPath(task, dockerID)
+}
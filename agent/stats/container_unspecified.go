@@ -0,0 +1,25 @@
+//go:build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import "github.com/cihub/seelog"
+
+// collectCgroupStats falls back to stats streams, since cgroups only exist on linux.
+func (container *StatsContainer) collectCgroupStats() {
+	seelog.Warnf("Container [%s]: Collecting stats from cgroupfs is only supported on linux, using the docker stats stream",
+		container.containerMetadata.DockerID)
+	container.collect()
+}
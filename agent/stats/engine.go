@@ -34,6 +34,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	"github.com/aws/amazon-ecs-agent/agent/stats/resolver"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/aws-sdk-go/aws"
@@ -67,6 +68,10 @@ type DockerContainerMetadataResolver struct {
 type Engine interface {
 	GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error)
 	ContainerDockerStats(taskARN string, containerID string) (*types.StatsJSON, *NetworkStatsPerSec, error)
+	ContainerStatsHistory(taskARN string, containerID string, window time.Duration, resolution time.Duration) ([]StatsHistoryPoint, error)
+	ContainerAverageUsage(taskARN string, containerID string, window time.Duration) (*ContainerUsage, error)
+	ContainerMemoryEvents(taskARN string, containerID string) (*memevents.MemoryEvents, error)
+	ContainerStorageStats(taskARN string, containerID string) (*ContainerStorageStats, error)
 	GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error)
 }
 
@@ -90,6 +95,13 @@ type DockerStatsEngine struct {
 	// tasksToDefinitions maps task arns to task definition name and family metadata objects.
 	tasksToDefinitions map[string]*taskDefinition
 	taskToTaskStats    map[string]*StatsTask
+	// taskStorageStats maps task arns to the ephemeral storage used by the tasks at the last collection.
+	taskStorageStats map[string]*taskStorageStats
+	// taskEngine is used to stop tasks that exceed their ephemeral storage limit.
+	taskEngine ecsengine.TaskEngine
+	// mountDiskUsage measures the disk space used by a volume mounted at a destination in the
+	// container of a process.
+	mountDiskUsage func(pid int, destination string) (int64, error)
 }
 
 // ResolveTask resolves the api task object, given container id.
@@ -141,7 +153,9 @@ func NewDockerStatsEngine(cfg *config.Config, client dockerapi.DockerClient, con
 		tasksToHealthCheckContainers: make(map[string]map[string]*StatsContainer),
 		tasksToDefinitions:           make(map[string]*taskDefinition),
 		taskToTaskStats:              make(map[string]*StatsTask),
+		taskStorageStats:             make(map[string]*taskStorageStats),
 		containerChangeEventStream:   containerChangeEventStream,
+		mountDiskUsage:               mountDiskUsage,
 	}
 }
 
@@ -207,6 +221,7 @@ func (engine *DockerStatsEngine) MustInit(ctx context.Context, taskEngine ecseng
 	seelog.Info("Initializing stats engine")
 	engine.cluster = cluster
 	engine.containerInstanceArn = containerInstanceArn
+	engine.taskEngine = taskEngine
 
 	var err error
 	engine.resolver, err = newDockerContainerMetadataResolver(taskEngine)
@@ -0,0 +1,153 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FileSink appends container utilization metrics to a file as newline-delimited JSON, one
+// ContainerStatsSample per line. Once the file would grow past maxSizeBytes it's renamed to
+// path.1, older rotations are shifted up to path.<maxRolls> and a new file is started.
+type FileSink struct {
+	lock         sync.Mutex
+	path         string
+	maxSizeBytes int64
+	maxRolls     int
+	file         *os.File
+	size         int64
+}
+
+// NewFileSink creates a FileSink that appends to the file at path, creating it if needed. The
+// file is rotated once it reaches maxSizeBytes and at most maxRolls rotated files are kept.
+func NewFileSink(path string, maxSizeBytes int64, maxRolls int) (*FileSink, error) {
+	sink := &FileSink{
+		path:         path,
+		maxSizeBytes: maxSizeBytes,
+		maxRolls:     maxRolls,
+	}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (sink *FileSink) open() error {
+	file, err := os.OpenFile(sink.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open stats file %s", sink.path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "unable to stat stats file %s", sink.path)
+	}
+	sink.file = file
+	sink.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts the rotated files up by one, dropping the oldest, and
+// opens a new file at path. The current path is reopened when the rotated files can't be
+// shifted, so that the next publish still has a file to write to and retries the rotation.
+func (sink *FileSink) rotate() error {
+	err := sink.file.Close()
+	sink.file = nil
+	if err != nil {
+		return errors.Wrapf(err, "unable to close stats file %s", sink.path)
+	}
+	if err := sink.shiftRotatedFiles(); err != nil {
+		if openErr := sink.open(); openErr != nil {
+			return errors.Wrapf(err, "unable to reopen stats file %s after %v", sink.path, openErr)
+		}
+		return err
+	}
+	return sink.open()
+}
+
+// shiftRotatedFiles shifts the rotated files up by one, dropping the oldest, and renames the
+// current file to path.1.
+func (sink *FileSink) shiftRotatedFiles() error {
+	oldest := rotatedStatsFilePath(sink.path, sink.maxRolls)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "unable to remove stats file %s", oldest)
+	}
+	for i := sink.maxRolls - 1; i >= 1; i-- {
+		from := rotatedStatsFilePath(sink.path, i)
+		if err := os.Rename(from, rotatedStatsFilePath(sink.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "unable to rotate stats file %s", from)
+		}
+	}
+	if err := os.Rename(sink.path, rotatedStatsFilePath(sink.path, 1)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "unable to rotate stats file %s", sink.path)
+	}
+	return nil
+}
+
+func rotatedStatsFilePath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
+
+// Name implements Sink.
+func (sink *FileSink) Name() string {
+	return "file"
+}
+
+// Publish implements Sink.
+func (sink *FileSink) Publish(samples []ContainerStatsSample) error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, sample := range samples {
+		if err := encoder.Encode(sample); err != nil {
+			return errors.Wrapf(err, "unable to encode the stats of container %s of task %s",
+				sample.ContainerName, sample.TaskARN)
+		}
+	}
+	// The file is only missing when it couldn't be reopened by a previous rotation
+	if sink.file == nil {
+		if err := sink.open(); err != nil {
+			return err
+		}
+	}
+	if sink.size > 0 && sink.size+int64(buf.Len()) > sink.maxSizeBytes {
+		if err := sink.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := sink.file.Write(buf.Bytes())
+	sink.size += int64(n)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write to stats file %s", sink.path)
+	}
+	return nil
+}
+
+// Close implements Sink.
+func (sink *FileSink) Close() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.file == nil {
+		return nil
+	}
+	return sink.file.Close()
+}
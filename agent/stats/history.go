@@ -0,0 +1,239 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/pkg/errors"
+)
+
+// MaxStatsHistoryWindow is the longest window of stats history that can be requested. The stats
+// queues of containers and tasks are sized to hold the stats of 4 publishing intervals, so
+// older stats are no longer available.
+const MaxStatsHistoryWindow = 4 * config.DefaultContainerMetricsPublishInterval
+
+// StatsHistoryPoint aggregates the stats of a container collected during one resolution
+// interval.
+type StatsHistoryPoint struct {
+	// Timestamp is the start of the interval.
+	Timestamp time.Time `json:"timestamp"`
+	// Samples is the number of stats collected during the interval.
+	Samples int `json:"samples"`
+	// CPUUsagePercent is the average CPU usage during the interval, in percent of a single
+	// core. It's not set when the CPU usage couldn't be computed from the stats of the interval.
+	CPUUsagePercent *float64 `json:"cpuUsagePercent,omitempty"`
+	// MemoryUsageBytes is the average memory usage during the interval.
+	MemoryUsageBytes uint64 `json:"memoryUsageBytes"`
+	// StorageReadBytes and StorageWriteBytes are the cumulative bytes read from and written to
+	// block devices at the end of the interval.
+	StorageReadBytes  uint64 `json:"storageReadBytes"`
+	StorageWriteBytes uint64 `json:"storageWriteBytes"`
+	// Network is not set for containers without network stats, such as containers in host or
+	// none network mode.
+	Network *NetworkHistoryPoint `json:"network,omitempty"`
+}
+
+// NetworkHistoryPoint aggregates the network stats of a container during one resolution
+// interval.
+type NetworkHistoryPoint struct {
+	// RxBytes and TxBytes are the cumulative bytes received and transmitted at the end of the
+	// interval.
+	RxBytes uint64 `json:"rxBytes"`
+	TxBytes uint64 `json:"txBytes"`
+	// RxBytesPerSecond and TxBytesPerSecond are the average rates during the interval.
+	RxBytesPerSecond *float64 `json:"rxBytesPerSecond,omitempty"`
+	TxBytesPerSecond *float64 `json:"txBytesPerSecond,omitempty"`
+}
+
+// ContainerStatsHistory returns the stats of a container collected during the last window,
+// aggregated over intervals of the given resolution, oldest first. The window can't be longer
+// than MaxStatsHistoryWindow, the history that's buffered in the stats queues of the container.
+func (engine *DockerStatsEngine) ContainerStatsHistory(taskARN string, containerID string,
+	window time.Duration, resolution time.Duration) ([]StatsHistoryPoint, error) {
+	if window <= 0 || resolution <= 0 {
+		return nil, errors.New("stats engine: window and resolution must be positive")
+	}
+	if window > MaxStatsHistoryWindow {
+		return nil, errors.Errorf("stats engine: window %s is longer than the %s of stats history that's kept",
+			window, MaxStatsHistoryWindow)
+	}
+
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	container, err := engine.historyStatsContainer(taskARN, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-window)
+	points := aggregateStatsHistory(container.statsQueue.GetUsageStatsSince(since), resolution)
+
+	// Network stats follow the same rules as the stats sent to the backend, containers of
+	// awsvpc tasks report the stats of the task network namespace
+	networkMode := container.containerMetadata.NetworkMode
+	if networkMode == hostNetworkMode || networkMode == noneNetworkMode {
+		for i := range points {
+			points[i].Network = nil
+		}
+	} else if taskStats, ok := engine.taskToTaskStats[taskARN]; ok {
+		taskPoints := aggregateStatsHistory(taskStats.StatsQueue.GetUsageStatsSince(since), resolution)
+		networkByTimestamp := make(map[time.Time]*NetworkHistoryPoint, len(taskPoints))
+		for _, taskPoint := range taskPoints {
+			networkByTimestamp[taskPoint.Timestamp] = taskPoint.Network
+		}
+		for i := range points {
+			points[i].Network = networkByTimestamp[points[i].Timestamp]
+		}
+	}
+	return points, nil
+}
+
+// ContainerUsage is the average usage of a container over a window.
+type ContainerUsage struct {
+	// Samples is the number of stats collected during the window.
+	Samples int
+	// CPUUsagePercent is the average CPU usage, in percent of a single core. It's not set when
+	// the CPU usage couldn't be computed from the stats of the window.
+	CPUUsagePercent *float64
+	// MemoryUsageBytes is the average memory usage.
+	MemoryUsageBytes uint64
+}
+
+// ContainerAverageUsage returns the usage of a container averaged over all the stats collected
+// during the last window, whatever the wall clock intervals that the window spans. Samples is
+// zero when no stats were collected during the window.
+func (engine *DockerStatsEngine) ContainerAverageUsage(taskARN string, containerID string,
+	window time.Duration) (*ContainerUsage, error) {
+	if window <= 0 {
+		return nil, errors.New("stats engine: window must be positive")
+	}
+
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	container, err := engine.historyStatsContainer(taskARN, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := &statsHistoryBucket{}
+	for _, stat := range container.statsQueue.GetUsageStatsSince(time.Now().Add(-window)) {
+		bucket.add(stat)
+	}
+	if bucket.point.Samples == 0 {
+		return &ContainerUsage{}, nil
+	}
+	point := bucket.aggregate()
+	return &ContainerUsage{
+		Samples:          point.Samples,
+		CPUUsagePercent:  point.CPUUsagePercent,
+		MemoryUsageBytes: point.MemoryUsageBytes,
+	}, nil
+}
+
+// historyStatsContainer must be called with the lock of the engine held.
+func (engine *DockerStatsEngine) historyStatsContainer(taskARN string, containerID string) (*StatsContainer, error) {
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if !ok {
+		return nil, errors.Errorf("stats engine: task '%s' for container '%s' not found",
+			taskARN, containerID)
+	}
+	container, ok := containerIDToStatsContainer[containerID]
+	if !ok {
+		return nil, errors.Errorf("stats engine: container not found: %s", containerID)
+	}
+	return container, nil
+}
+
+// statsHistoryBucket accumulates the stats of one resolution interval.
+type statsHistoryBucket struct {
+	point       StatsHistoryPoint
+	cpuSum      float64
+	cpuSamples  int
+	memorySum   uint64
+	rxRateSum   float64
+	txRateSum   float64
+	rateSamples int
+}
+
+// aggregateStatsHistory groups the stats by resolution interval, oldest first.
+func aggregateStatsHistory(usageStats []UsageStats, resolution time.Duration) []StatsHistoryPoint {
+	buckets := make(map[time.Time]*statsHistoryBucket)
+	for _, stat := range usageStats {
+		start := stat.Timestamp.Truncate(resolution)
+		bucket, ok := buckets[start]
+		if !ok {
+			bucket = &statsHistoryBucket{point: StatsHistoryPoint{Timestamp: start}}
+			buckets[start] = bucket
+		}
+		bucket.add(stat)
+	}
+
+	points := make([]StatsHistoryPoint, 0, len(buckets))
+	for _, bucket := range buckets {
+		points = append(points, bucket.aggregate())
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	return points
+}
+
+// add accumulates a stat. Stats are added oldest first, so cumulative values are overwritten.
+func (bucket *statsHistoryBucket) add(stat UsageStats) {
+	bucket.point.Samples++
+	bucket.memorySum += uint64(stat.MemoryUsageInMegs) * BytesInMiB
+	bucket.point.StorageReadBytes = stat.StorageReadBytes
+	bucket.point.StorageWriteBytes = stat.StorageWriteBytes
+	// CPU usage and network rates can only be computed from the second stat on
+	if !math.IsNaN(float64(stat.CPUUsagePerc)) {
+		bucket.cpuSum += float64(stat.CPUUsagePerc)
+		bucket.cpuSamples++
+	}
+	if stat.NetworkStats == nil {
+		return
+	}
+	if bucket.point.Network == nil {
+		bucket.point.Network = &NetworkHistoryPoint{}
+	}
+	bucket.point.Network.RxBytes = stat.NetworkStats.RxBytes
+	bucket.point.Network.TxBytes = stat.NetworkStats.TxBytes
+	if !math.IsNaN(float64(stat.NetworkStats.RxBytesPerSecond)) &&
+		!math.IsNaN(float64(stat.NetworkStats.TxBytesPerSecond)) {
+		bucket.rxRateSum += float64(stat.NetworkStats.RxBytesPerSecond)
+		bucket.txRateSum += float64(stat.NetworkStats.TxBytesPerSecond)
+		bucket.rateSamples++
+	}
+}
+
+func (bucket *statsHistoryBucket) aggregate() StatsHistoryPoint {
+	point := bucket.point
+	point.MemoryUsageBytes = bucket.memorySum / uint64(point.Samples)
+	if bucket.cpuSamples > 0 {
+		cpuUsagePercent := bucket.cpuSum / float64(bucket.cpuSamples)
+		point.CPUUsagePercent = &cpuUsagePercent
+	}
+	if point.Network != nil && bucket.rateSamples > 0 {
+		rxBytesPerSecond := bucket.rxRateSum / float64(bucket.rateSamples)
+		txBytesPerSecond := bucket.txRateSum / float64(bucket.rateSamples)
+		point.Network.RxBytesPerSecond = &rxBytesPerSecond
+		point.Network.TxBytesPerSecond = &txBytesPerSecond
+	}
+	return point
+}
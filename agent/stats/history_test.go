@@ -0,0 +1,162 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHistoryTestStatsContainer creates a container with 20 stats collected every second from
+// base, using half a core, 100MiB of memory and receiving 1000 bytes per second.
+func newHistoryTestStatsContainer(networkMode string, base time.Time) *StatsContainer {
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID:    "c1-id",
+			Name:        "c1",
+			NetworkMode: networkMode,
+		},
+		statsQueue: NewQueue(100),
+	}
+	for i := 0; i < 20; i++ {
+		container.statsQueue.add(&ContainerStats{
+			cpuUsage:          uint64(i) * uint64(time.Second/2),
+			memoryUsage:       100 * BytesInMiB,
+			storageReadBytes:  uint64(i) * 10,
+			storageWriteBytes: uint64(i) * 20,
+			networkStats: &NetworkStats{
+				RxBytes:          uint64(i) * 1000,
+				TxBytes:          uint64(i) * 500,
+				RxBytesPerSecond: nan32(),
+				TxBytesPerSecond: nan32(),
+			},
+			timestamp: base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return container
+}
+
+func TestContainerStatsHistory(t *testing.T) {
+	base := time.Now().Add(-30 * time.Second).Truncate(10 * time.Second)
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestContainerStatsHistory"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1-id": newHistoryTestStatsContainer("bridge", base),
+	}
+
+	history, err := engine.ContainerStatsHistory("t1", "c1-id", time.Minute, 10*time.Second)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	first := history[0]
+	assert.True(t, base.Equal(first.Timestamp))
+	assert.Equal(t, 10, first.Samples)
+	require.NotNil(t, first.CPUUsagePercent)
+	assert.InDelta(t, 50.0, *first.CPUUsagePercent, 0.1)
+	assert.Equal(t, uint64(100*BytesInMiB), first.MemoryUsageBytes)
+	assert.Equal(t, uint64(90), first.StorageReadBytes)
+	assert.Equal(t, uint64(180), first.StorageWriteBytes)
+	require.NotNil(t, first.Network)
+	assert.Equal(t, uint64(9000), first.Network.RxBytes)
+	require.NotNil(t, first.Network.RxBytesPerSecond)
+	assert.InDelta(t, 1000.0, *first.Network.RxBytesPerSecond, 0.1)
+	assert.InDelta(t, 500.0, *first.Network.TxBytesPerSecond, 0.1)
+
+	second := history[1]
+	assert.True(t, base.Add(10*time.Second).Equal(second.Timestamp))
+	assert.Equal(t, uint64(190), second.StorageReadBytes)
+	assert.Equal(t, uint64(19000), second.Network.RxBytes)
+
+	// Stats older than the window aren't returned
+	history, err = engine.ContainerStatsHistory("t1", "c1-id", 5*time.Second, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// Reading the history doesn't consume the stats that are sent to the backend
+	_, err = engine.tasksToContainers["t1"]["c1-id"].statsQueue.GetCPUStatsSet()
+	assert.NoError(t, err)
+}
+
+func TestContainerStatsHistoryHostNetworkMode(t *testing.T) {
+	base := time.Now().Add(-30 * time.Second)
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestContainerStatsHistoryHostNetworkMode"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1-id": newHistoryTestStatsContainer(hostNetworkMode, base),
+	}
+
+	history, err := engine.ContainerStatsHistory("t1", "c1-id", time.Minute, time.Second)
+	require.NoError(t, err)
+	require.Len(t, history, 20)
+	for _, point := range history {
+		assert.Nil(t, point.Network)
+	}
+}
+
+func TestContainerStatsHistoryErrors(t *testing.T) {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestContainerStatsHistoryErrors"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1-id": newHistoryTestStatsContainer("bridge", time.Now()),
+	}
+
+	_, err := engine.ContainerStatsHistory("t2", "c1-id", time.Minute, time.Second)
+	assert.Error(t, err, "unknown task")
+	_, err = engine.ContainerStatsHistory("t1", "c2-id", time.Minute, time.Second)
+	assert.Error(t, err, "unknown container")
+	_, err = engine.ContainerStatsHistory("t1", "c1-id", time.Minute, 0)
+	assert.Error(t, err, "invalid resolution")
+	_, err = engine.ContainerStatsHistory("t1", "c1-id", MaxStatsHistoryWindow+time.Second, time.Second)
+	assert.Error(t, err, "window longer than the history that's kept")
+}
+
+func TestContainerAverageUsage(t *testing.T) {
+	// The stats span a wall clock minute boundary, 10s after the first stat, and the last one
+	// was collected at least 6s ago
+	minute := time.Now().Truncate(time.Minute)
+	if time.Since(minute) < 20*time.Second {
+		minute = minute.Add(-time.Minute)
+	}
+	base := minute.Add(-10 * time.Second)
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestContainerAverageUsage"))
+	container := newHistoryTestStatsContainer("bridge", base)
+	// Use a full core and 200MiB of memory for the last 5 seconds
+	for i := 20; i < 25; i++ {
+		container.statsQueue.add(&ContainerStats{
+			cpuUsage:    uint64(19*time.Second/2) + uint64(i-19)*uint64(time.Second),
+			memoryUsage: 200 * BytesInMiB,
+			timestamp:   base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{"c1-id": container}
+
+	usage, err := engine.ContainerAverageUsage("t1", "c1-id", 2*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 25, usage.Samples)
+	require.NotNil(t, usage.CPUUsagePercent)
+	// 19 rates at half a core and 5 at a full core, the first stat has no rate
+	assert.InDelta(t, (19*50.0+5*100.0)/24, *usage.CPUUsagePercent, 0.1)
+	assert.Equal(t, uint64((20*100+5*200)*BytesInMiB/25), usage.MemoryUsageBytes)
+
+	usage, err = engine.ContainerAverageUsage("t1", "c1-id", 5*time.Second)
+	require.NoError(t, err)
+	assert.Zero(t, usage.Samples)
+
+	_, err = engine.ContainerAverageUsage("t1", "c2-id", time.Minute)
+	assert.Error(t, err)
+	_, err = engine.ContainerAverageUsage("t1", "c1-id", 0)
+	assert.Error(t, err)
+}
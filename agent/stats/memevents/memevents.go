@@ -0,0 +1,43 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package memevents reads the OOM kill counts and the memory pressure stall information (PSI)
+// of cgroups.
+package memevents
+
+// MemoryEvents are the memory events of a cgroup. The JSON field names follow the names of the
+// cgroupfs interface files.
+type MemoryEvents struct {
+	// OOMKills is the number of processes of the cgroup killed by the OOM killer
+	OOMKills uint64 `json:"oom_kill"`
+	// Pressure is the memory pressure stall information of the cgroup. It's only available on
+	// the unified (v2) hierarchy, on kernels with PSI enabled.
+	Pressure *Pressure `json:"pressure,omitempty"`
+}
+
+// Pressure is the pressure stall information of a resource.
+type Pressure struct {
+	// Some is the share of time some of the processes of the cgroup stalled on the resource
+	Some PressureStats `json:"some"`
+	// Full is the share of time all the processes of the cgroup stalled on the resource
+	Full PressureStats `json:"full"`
+}
+
+// PressureStats are the stall percentages averaged over 10 seconds, 1 minute and 5 minutes,
+// and the total stall time in microseconds.
+type PressureStats struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
@@ -0,0 +1,143 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package memevents
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+	"github.com/pkg/errors"
+)
+
+const (
+	memoryEventsFile     = "memory.events"
+	memoryPressureFile   = "memory.pressure"
+	memoryOOMControlFile = "memory.oom_control"
+	memorySubsystem      = "memory"
+	oomKillKey           = "oom_kill"
+)
+
+// Read returns the memory events of the cgroup at cgroupPath, relative to the root of the
+// hierarchy mounted at mountPath. OOM kills are only counted by kernels from 4.13 on the v1
+// hierarchy, and only by the cgroup of the killed process rather than by all its ancestors as
+// on the unified hierarchy.
+func Read(mountPath, cgroupPath string) (*MemoryEvents, error) {
+	if !control.IsUnifiedHierarchy(mountPath) {
+		oomControl, err := readKeyValues(filepath.Join(mountPath, memorySubsystem, cgroupPath, memoryOOMControlFile))
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryEvents{OOMKills: oomControl[oomKillKey]}, nil
+	}
+
+	path := filepath.Join(mountPath, cgroupPath)
+	events, err := readKeyValues(filepath.Join(path, memoryEventsFile))
+	if err != nil {
+		return nil, err
+	}
+	memoryEvents := &MemoryEvents{OOMKills: events[oomKillKey]}
+	pressure, err := readPressure(filepath.Join(path, memoryPressureFile))
+	if err == nil {
+		memoryEvents.Pressure = pressure
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return memoryEvents, nil
+}
+
+// readKeyValues reads a flat keyed cgroupfs file, with a "<key> <value>" pair per line.
+func readKeyValues(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s of %s", fields[0], path)
+		}
+		values[fields[0]] = value
+	}
+	return values, scanner.Err()
+}
+
+// readPressure reads a pressure stall information file, with a line for "some" and a line for
+// "full" stalls, such as "some avg10=0.00 avg60=0.00 avg300=0.00 total=0".
+func readPressure(path string) (*Pressure, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pressure := &Pressure{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var stats *PressureStats
+		switch fields[0] {
+		case "some":
+			stats = &pressure.Some
+		case "full":
+			stats = &pressure.Full
+		default:
+			continue
+		}
+		if err := parsePressureStats(fields[1:], stats); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+	}
+	return pressure, scanner.Err()
+}
+
+func parsePressureStats(fields []string, stats *PressureStats) error {
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid field %q", field)
+		}
+		var err error
+		switch kv[0] {
+		case "avg10":
+			stats.Avg10, err = strconv.ParseFloat(kv[1], 64)
+		case "avg60":
+			stats.Avg60, err = strconv.ParseFloat(kv[1], 64)
+		case "avg300":
+			stats.Avg300, err = strconv.ParseFloat(kv[1], 64)
+		case "total":
+			stats.Total, err = strconv.ParseUint(kv[1], 10, 64)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package memevents
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCgroupPath = "/ecs/task-id/container-id"
+
+func writeCgroupFile(t *testing.T, path, contents string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestReadV2(t *testing.T) {
+	mountPath := t.TempDir()
+	writeCgroupFile(t, filepath.Join(mountPath, "cgroup.controllers"), "cpu io memory")
+	path := filepath.Join(mountPath, testCgroupPath)
+	writeCgroupFile(t, filepath.Join(path, memoryEventsFile), "low 0\nhigh 12\nmax 5\noom 2\noom_kill 2\noom_group_kill 0\n")
+	writeCgroupFile(t, filepath.Join(path, memoryPressureFile),
+		"some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\nfull avg10=0.50 avg60=0.25 avg300=0.00 total=4567\n")
+
+	events, err := Read(mountPath, testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, &MemoryEvents{
+		OOMKills: 2,
+		Pressure: &Pressure{
+			Some: PressureStats{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 123456},
+			Full: PressureStats{Avg10: 0.5, Avg60: 0.25, Avg300: 0, Total: 4567},
+		},
+	}, events)
+}
+
+func TestReadV2WithoutPressure(t *testing.T) {
+	mountPath := t.TempDir()
+	writeCgroupFile(t, filepath.Join(mountPath, "cgroup.controllers"), "cpu io memory")
+	writeCgroupFile(t, filepath.Join(mountPath, testCgroupPath, memoryEventsFile), "oom 0\noom_kill 0\n")
+
+	events, err := Read(mountPath, testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, &MemoryEvents{}, events)
+}
+
+func TestReadV1(t *testing.T) {
+	mountPath := t.TempDir()
+	writeCgroupFile(t, filepath.Join(mountPath, memorySubsystem, testCgroupPath, memoryOOMControlFile),
+		"oom_kill_disable 0\nunder_oom 0\noom_kill 3\n")
+
+	events, err := Read(mountPath, testCgroupPath)
+	require.NoError(t, err)
+	assert.Equal(t, &MemoryEvents{OOMKills: 3}, events)
+}
+
+func TestReadErrors(t *testing.T) {
+	mountPath := t.TempDir()
+	_, err := Read(mountPath, testCgroupPath)
+	assert.Error(t, err, "cgroup doesn't exist")
+
+	writeCgroupFile(t, filepath.Join(mountPath, "cgroup.controllers"), "cpu io memory")
+	path := filepath.Join(mountPath, testCgroupPath)
+	writeCgroupFile(t, filepath.Join(path, memoryEventsFile), "oom_kill 1\n")
+	writeCgroupFile(t, filepath.Join(path, memoryPressureFile), "some avg10=invalid avg60=0.00 avg300=0.00 total=0\n")
+	_, err = Read(mountPath, testCgroupPath)
+	assert.Error(t, err, "invalid pressure file")
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
+	"github.com/pkg/errors"
+)
+
+// ContainerMemoryEvents returns the OOM kill count and the memory pressure of the cgroup of a
+// running container. The cgroup is resolved like for collecting stats from cgroupfs, from the
+// container process, so that it's found whatever the cgroup driver of docker.
+func (engine *DockerStatsEngine) ContainerMemoryEvents(taskARN string, containerID string) (*memevents.MemoryEvents, error) {
+	engine.lock.RLock()
+	var statsContainer *StatsContainer
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if ok {
+		statsContainer, ok = containerIDToStatsContainer[containerID]
+	}
+	engine.lock.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("stats engine: container '%s' of task '%s' not found", containerID, taskARN)
+	}
+
+	task, err := engine.resolver.ResolveTaskByARN(taskARN)
+	if err != nil {
+		return nil, errors.Errorf("stats engine: task '%s' not found", taskARN)
+	}
+	return statsContainer.memoryEvents(newCgroupStatsReader(engine.config.CgroupPath), task)
+}
+
+// memoryEvents reads the memory events of the cgroup of the container, from the cgroupfs that
+// reader reads.
+func (container *StatsContainer) memoryEvents(reader *cgroupStatsReader, task *apitask.Task) (*memevents.MemoryEvents, error) {
+	dockerID := container.containerMetadata.DockerID
+	cgroupPath, err := container.cgroupPath(reader, task)
+	if err != nil {
+		return nil, errors.Wrapf(err, "stats engine: unable to get the cgroup of container '%s'", dockerID)
+	}
+	events, err := memevents.Read(reader.mountPath, cgroupPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "stats engine: unable to read memory events of container '%s'", dockerID)
+	}
+	return events, nil
+}
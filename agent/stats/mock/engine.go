@@ -20,8 +20,10 @@ package mock_stats
 
 import (
 	reflect "reflect"
+	time "time"
 
 	stats "github.com/aws/amazon-ecs-agent/agent/stats"
+	memevents "github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	ecstcs "github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	types "github.com/docker/docker/api/types"
 	gomock "github.com/golang/mock/gomock"
@@ -50,6 +52,21 @@ func (m *MockEngine) EXPECT() *MockEngineMockRecorder {
 	return m.recorder
 }
 
+// ContainerAverageUsage mocks base method
+func (m *MockEngine) ContainerAverageUsage(arg0, arg1 string, arg2 time.Duration) (*stats.ContainerUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerAverageUsage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*stats.ContainerUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerAverageUsage indicates an expected call of ContainerAverageUsage
+func (mr *MockEngineMockRecorder) ContainerAverageUsage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerAverageUsage", reflect.TypeOf((*MockEngine)(nil).ContainerAverageUsage), arg0, arg1, arg2)
+}
+
 // ContainerDockerStats mocks base method
 func (m *MockEngine) ContainerDockerStats(arg0, arg1 string) (*types.StatsJSON, *stats.NetworkStatsPerSec, error) {
 	m.ctrl.T.Helper()
@@ -66,6 +83,51 @@ func (mr *MockEngineMockRecorder) ContainerDockerStats(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerDockerStats", reflect.TypeOf((*MockEngine)(nil).ContainerDockerStats), arg0, arg1)
 }
 
+// ContainerMemoryEvents mocks base method
+func (m *MockEngine) ContainerMemoryEvents(arg0, arg1 string) (*memevents.MemoryEvents, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerMemoryEvents", arg0, arg1)
+	ret0, _ := ret[0].(*memevents.MemoryEvents)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerMemoryEvents indicates an expected call of ContainerMemoryEvents
+func (mr *MockEngineMockRecorder) ContainerMemoryEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerMemoryEvents", reflect.TypeOf((*MockEngine)(nil).ContainerMemoryEvents), arg0, arg1)
+}
+
+// ContainerStatsHistory mocks base method
+func (m *MockEngine) ContainerStatsHistory(arg0, arg1 string, arg2, arg3 time.Duration) ([]stats.StatsHistoryPoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerStatsHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]stats.StatsHistoryPoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerStatsHistory indicates an expected call of ContainerStatsHistory
+func (mr *MockEngineMockRecorder) ContainerStatsHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerStatsHistory", reflect.TypeOf((*MockEngine)(nil).ContainerStatsHistory), arg0, arg1, arg2, arg3)
+}
+
+// ContainerStorageStats mocks base method
+func (m *MockEngine) ContainerStorageStats(arg0, arg1 string) (*stats.ContainerStorageStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerStorageStats", arg0, arg1)
+	ret0, _ := ret[0].(*stats.ContainerStorageStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerStorageStats indicates an expected call of ContainerStorageStats
+func (mr *MockEngineMockRecorder) ContainerStorageStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerStorageStats", reflect.TypeOf((*MockEngine)(nil).ContainerStorageStats), arg0, arg1)
+}
+
 // GetInstanceMetrics mocks base method
 func (m *MockEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,139 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const containerStatsSubsystem = "ContainerStats"
+
+var containerStatsLabels = []string{"cluster", "task_arn", "task_family", "task_revision", "container_name"}
+
+// PrometheusCollector exposes the latest container utilization metrics of a DockerStatsEngine
+// as Prometheus series. It only reads the stats queues, so it doesn't interfere with the
+// metrics that are sent to the backend.
+type PrometheusCollector struct {
+	engine *DockerStatsEngine
+
+	cpuUsagePercent   *prometheus.Desc
+	memoryUsageBytes  *prometheus.Desc
+	storageReadBytes  *prometheus.Desc
+	storageWriteBytes *prometheus.Desc
+	networkRxBytes    *prometheus.Desc
+	networkRxPackets  *prometheus.Desc
+	networkRxDropped  *prometheus.Desc
+	networkRxErrors   *prometheus.Desc
+	networkTxBytes    *prometheus.Desc
+	networkTxPackets  *prometheus.Desc
+	networkTxDropped  *prometheus.Desc
+	networkTxErrors   *prometheus.Desc
+}
+
+// NewPrometheusCollector creates a PrometheusCollector backed by the stats engine.
+func NewPrometheusCollector(engine *DockerStatsEngine) *PrometheusCollector {
+	return &PrometheusCollector{
+		engine:            engine,
+		cpuUsagePercent:   newContainerStatsDesc("cpu_usage_percent", "Container CPU usage in percent of a single core"),
+		memoryUsageBytes:  newContainerStatsDesc("memory_usage_bytes", "Container memory usage in bytes"),
+		storageReadBytes:  newContainerStatsDesc("storage_read_bytes_total", "Bytes read by the container from block devices"),
+		storageWriteBytes: newContainerStatsDesc("storage_write_bytes_total", "Bytes written by the container to block devices"),
+		networkRxBytes:    newContainerStatsDesc("network_rx_bytes_total", "Bytes received by the container"),
+		networkRxPackets:  newContainerStatsDesc("network_rx_packets_total", "Packets received by the container"),
+		networkRxDropped:  newContainerStatsDesc("network_rx_dropped_total", "Received packets dropped for the container"),
+		networkRxErrors:   newContainerStatsDesc("network_rx_errors_total", "Receive errors of the container"),
+		networkTxBytes:    newContainerStatsDesc("network_tx_bytes_total", "Bytes transmitted by the container"),
+		networkTxPackets:  newContainerStatsDesc("network_tx_packets_total", "Packets transmitted by the container"),
+		networkTxDropped:  newContainerStatsDesc("network_tx_dropped_total", "Transmitted packets dropped for the container"),
+		networkTxErrors:   newContainerStatsDesc("network_tx_errors_total", "Transmit errors of the container"),
+	}
+}
+
+func newContainerStatsDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metrics.AgentNamespace, containerStatsSubsystem, name),
+		help, containerStatsLabels, nil)
+}
+
+// Describe implements prometheus.Collector.
+func (collector *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.cpuUsagePercent
+	ch <- collector.memoryUsageBytes
+	ch <- collector.storageReadBytes
+	ch <- collector.storageWriteBytes
+	ch <- collector.networkRxBytes
+	ch <- collector.networkRxPackets
+	ch <- collector.networkRxDropped
+	ch <- collector.networkRxErrors
+	ch <- collector.networkTxBytes
+	ch <- collector.networkTxPackets
+	ch <- collector.networkTxDropped
+	ch <- collector.networkTxErrors
+}
+
+// Collect implements prometheus.Collector. Containers that don't have any stats yet are skipped.
+func (collector *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, sample := range collector.engine.containerStatsSamples() {
+		labels := []string{sample.Cluster, sample.TaskARN, sample.TaskFamily, sample.TaskRevision,
+			sample.ContainerName}
+
+		// CPU usage can only be computed from the second sample on
+		if sample.CPUUsagePercent != nil {
+			ch <- prometheus.MustNewConstMetric(collector.cpuUsagePercent, prometheus.GaugeValue,
+				*sample.CPUUsagePercent, labels...)
+		}
+		ch <- prometheus.MustNewConstMetric(collector.memoryUsageBytes, prometheus.GaugeValue,
+			float64(sample.MemoryUsageBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(collector.storageReadBytes, prometheus.CounterValue,
+			float64(sample.StorageReadBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(collector.storageWriteBytes, prometheus.CounterValue,
+			float64(sample.StorageWriteBytes), labels...)
+
+		networkStats := sample.Network
+		if networkStats == nil {
+			continue
+		}
+		for desc, value := range map[*prometheus.Desc]uint64{
+			collector.networkRxBytes:   networkStats.RxBytes,
+			collector.networkRxPackets: networkStats.RxPackets,
+			collector.networkRxDropped: networkStats.RxDropped,
+			collector.networkRxErrors:  networkStats.RxErrors,
+			collector.networkTxBytes:   networkStats.TxBytes,
+			collector.networkTxPackets: networkStats.TxPackets,
+			collector.networkTxDropped: networkStats.TxDropped,
+			collector.networkTxErrors:  networkStats.TxErrors,
+		} {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+		}
+	}
+}
+
+// containerNetworkStatsUnsafe returns the network stats of a container, following the same rules
+// as the metrics sent to the backend: containers of awsvpc tasks report the stats of the task
+// network namespace, and containers in host or none network mode have no network stats.
+func (engine *DockerStatsEngine) containerNetworkStatsUnsafe(taskArn string, container *StatsContainer,
+	stat UsageStats) *NetworkStats {
+	networkMode := container.containerMetadata.NetworkMode
+	if networkMode == hostNetworkMode || networkMode == noneNetworkMode {
+		return nil
+	}
+	if taskStats, ok := engine.taskToTaskStats[taskArn]; ok {
+		taskStat, ok := taskStats.StatsQueue.GetLastUsageStats()
+		if !ok {
+			return nil
+		}
+		return taskStat.NetworkStats
+	}
+	return stat.NetworkStats
+}
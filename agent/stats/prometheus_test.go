@@ -0,0 +1,134 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrometheusTestStatsContainer(name, networkMode string) *StatsContainer {
+	container := &StatsContainer{
+		containerMetadata: &ContainerMetadata{
+			DockerID:    name + "-id",
+			Name:        name,
+			NetworkMode: networkMode,
+		},
+		statsQueue: NewQueue(10),
+	}
+	for _, stat := range createFakeContainerStats() {
+		container.statsQueue.add(stat)
+	}
+	return container
+}
+
+func gatherContainerStats(t *testing.T, engine *DockerStatsEngine) map[string]*dto.MetricFamily {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(NewPrometheusCollector(engine)))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	familiesByName := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		familiesByName[family.GetName()] = family
+	}
+	return familiesByName
+}
+
+func metricLabels(metric *dto.Metric) map[string]string {
+	labels := make(map[string]string)
+	for _, label := range metric.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestPrometheusCollector"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1-id": newPrometheusTestStatsContainer("c1", "bridge"),
+		"c2-id": newPrometheusTestStatsContainer("c2", hostNetworkMode),
+	}
+	engine.tasksToDefinitions["t1"] = &taskDefinition{family: "f1", version: "3"}
+	// Containers of tasks without a task definition aren't reported
+	engine.tasksToContainers["t2"] = map[string]*StatsContainer{
+		"c3-id": newPrometheusTestStatsContainer("c3", "bridge"),
+	}
+
+	families := gatherContainerStats(t, engine)
+
+	memory, ok := families["AgentMetrics_ContainerStats_memory_usage_bytes"]
+	require.True(t, ok)
+	require.Len(t, memory.GetMetric(), 2)
+	for _, metric := range memory.GetMetric() {
+		labels := metricLabels(metric)
+		assert.Equal(t, cfg.Cluster, labels["cluster"])
+		assert.Equal(t, "t1", labels["task_arn"])
+		assert.Equal(t, "f1", labels["task_family"])
+		assert.Equal(t, "3", labels["task_revision"])
+		assert.Contains(t, []string{"c1", "c2"}, labels["container_name"])
+		assert.Equal(t, float64(3*BytesInMiB), metric.GetGauge().GetValue())
+	}
+
+	cpu, ok := families["AgentMetrics_ContainerStats_cpu_usage_percent"]
+	require.True(t, ok)
+	require.Len(t, cpu.GetMetric(), 2)
+	assert.InDelta(t, 93.0, cpu.GetMetric()[0].GetGauge().GetValue(), 1)
+
+	storageRead, ok := families["AgentMetrics_ContainerStats_storage_read_bytes_total"]
+	require.True(t, ok)
+	assert.Equal(t, float64(300), storageRead.GetMetric()[0].GetCounter().GetValue())
+
+	// Containers in host network mode don't have network stats
+	rxBytes, ok := families["AgentMetrics_ContainerStats_network_rx_bytes_total"]
+	require.True(t, ok)
+	require.Len(t, rxBytes.GetMetric(), 1)
+	assert.Equal(t, "c1", metricLabels(rxBytes.GetMetric()[0])["container_name"])
+	assert.Equal(t, float64(796), rxBytes.GetMetric()[0].GetCounter().GetValue())
+
+	// Collecting doesn't consume the stats that are sent to the backend
+	_, err := engine.tasksToContainers["t1"]["c1-id"].statsQueue.GetCPUStatsSet()
+	assert.NoError(t, err)
+}
+
+func TestPrometheusCollectorAWSVPCNetworkStats(t *testing.T) {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestPrometheusCollectorAWSVPCNetworkStats"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1-id":    newPrometheusTestStatsContainer("c1", "container:pause"),
+		"pause-id": newPrometheusTestStatsContainer("pause", noneNetworkMode),
+	}
+	engine.tasksToDefinitions["t1"] = &taskDefinition{family: "f1", version: "1"}
+	taskQueue := NewQueue(10)
+	taskQueue.add(&ContainerStats{
+		networkStats: &NetworkStats{RxBytes: 1024, TxBytes: 2048},
+		timestamp:    parseNanoTime("2015-02-12T21:22:05.131117533Z"),
+	})
+	engine.taskToTaskStats["t1"] = &StatsTask{statsTaskCommon: &statsTaskCommon{StatsQueue: taskQueue}}
+
+	families := gatherContainerStats(t, engine)
+
+	rxBytes, ok := families["AgentMetrics_ContainerStats_network_rx_bytes_total"]
+	require.True(t, ok)
+	require.Len(t, rxBytes.GetMetric(), 1)
+	assert.Equal(t, "c1", metricLabels(rxBytes.GetMetric()[0])["container_name"])
+	assert.Equal(t, float64(1024), rxBytes.GetMetric()[0].GetCounter().GetValue())
+	txBytes, ok := families["AgentMetrics_ContainerStats_network_tx_bytes_total"]
+	require.True(t, ok)
+	assert.Equal(t, float64(2048), txBytes.GetMetric()[0].GetCounter().GetValue())
+}
@@ -148,6 +148,43 @@ func (queue *Queue) GetLastStat() *types.StatsJSON {
 	return queue.lastStat
 }
 
+// GetLastUsageStats returns a copy of the most recent usage stats in the queue. Unlike the stat
+// set getters, it doesn't care whether the stats were already sent to the backend.
+func (queue *Queue) GetLastUsageStats() (UsageStats, bool) {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	if len(queue.buffer) == 0 {
+		return UsageStats{}, false
+	}
+	stat := queue.buffer[len(queue.buffer)-1]
+	if stat.NetworkStats != nil {
+		networkStats := *stat.NetworkStats
+		stat.NetworkStats = &networkStats
+	}
+	return stat, true
+}
+
+// GetUsageStatsSince returns copies of the usage stats in the queue that were collected after
+// since, oldest first. Like GetLastUsageStats, it doesn't care whether the stats were sent.
+func (queue *Queue) GetUsageStatsSince(since time.Time) []UsageStats {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	var stats []UsageStats
+	for _, stat := range queue.buffer {
+		if !stat.Timestamp.After(since) {
+			continue
+		}
+		if stat.NetworkStats != nil {
+			networkStats := *stat.NetworkStats
+			stat.NetworkStats = &networkStats
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
 func (queue *Queue) GetLastNetworkStatPerSec() *NetworkStatsPerSec {
 	queue.lock.RLock()
 	defer queue.lock.RUnlock()
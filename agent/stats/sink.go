@@ -0,0 +1,172 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+)
+
+// ContainerStatsSample is the latest utilization of a container, as it's published to a Sink.
+type ContainerStatsSample struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Cluster       string    `json:"cluster"`
+	TaskARN       string    `json:"taskArn"`
+	TaskFamily    string    `json:"taskFamily"`
+	TaskRevision  string    `json:"taskRevision"`
+	ContainerName string    `json:"containerName"`
+	// CPUUsagePercent is nil until the second stat of the container is collected, since CPU
+	// usage is computed from the difference between two stats.
+	CPUUsagePercent   *float64                `json:"cpuUsagePercent,omitempty"`
+	MemoryUsageBytes  uint64                  `json:"memoryUsageBytes"`
+	StorageReadBytes  uint64                  `json:"storageReadBytes"`
+	StorageWriteBytes uint64                  `json:"storageWriteBytes"`
+	Network           *ContainerNetworkSample `json:"network,omitempty"`
+}
+
+// ContainerNetworkSample holds the cumulative network counters of a container.
+type ContainerNetworkSample struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	RxDropped uint64 `json:"rxDropped"`
+	RxErrors  uint64 `json:"rxErrors"`
+	TxBytes   uint64 `json:"txBytes"`
+	TxPackets uint64 `json:"txPackets"`
+	TxDropped uint64 `json:"txDropped"`
+	TxErrors  uint64 `json:"txErrors"`
+}
+
+// Sink receives the container utilization metrics collected by the stats engine, so that
+// they can be fed to time-series systems other than the backend.
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+	// Publish sends the latest samples of the containers tracked by the stats engine.
+	Publish(samples []ContainerStatsSample) error
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// NewSinksFromConfig creates the sinks enabled in the config. Sinks that can't be created are
+// logged and skipped, so that one misconfigured sink doesn't prevent the others from working.
+func NewSinksFromConfig(cfg *config.Config) []Sink {
+	var sinks []Sink
+	if cfg.StatsDAddress != "" {
+		sink, err := NewStatsDSink(cfg.StatsDAddress, cfg.StatsDFormat == config.StatsDFormatDogStatsD)
+		if err != nil {
+			seelog.Warnf("Unable to create the StatsD stats sink: %v", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+	if cfg.StatsFilePath != "" {
+		sink, err := NewFileSink(cfg.StatsFilePath,
+			int64(cfg.StatsFileMaxSizeMB)*BytesInMiB, cfg.StatsFileMaxRollCount)
+		if err != nil {
+			seelog.Warnf("Unable to create the file stats sink: %v", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// StartSinks publishes the latest samples of the stats engine to the sinks at every interval,
+// until the context is cancelled. It doesn't consume the stats queues, so the metrics sent to
+// the backend are unaffected.
+func (engine *DockerStatsEngine) StartSinks(ctx context.Context, sinks []Sink, interval time.Duration) {
+	if len(sinks) == 0 {
+		return
+	}
+	defer func() {
+		for _, sink := range sinks {
+			if err := sink.Close(); err != nil {
+				seelog.Warnf("Error closing the %s stats sink: %v", sink.Name(), err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			samples := engine.containerStatsSamples()
+			if len(samples) == 0 {
+				continue
+			}
+			for _, sink := range sinks {
+				if err := sink.Publish(samples); err != nil {
+					seelog.Warnf("Error publishing container stats to the %s stats sink: %v", sink.Name(), err)
+				}
+			}
+		}
+	}
+}
+
+// containerStatsSamples returns the latest samples of the containers of known tasks. Containers
+// that don't have any stats yet are skipped.
+func (engine *DockerStatsEngine) containerStatsSamples() []ContainerStatsSample {
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	var samples []ContainerStatsSample
+	for taskArn, containerMap := range engine.tasksToContainers {
+		taskDef, ok := engine.tasksToDefinitions[taskArn]
+		if !ok {
+			continue
+		}
+		for _, container := range containerMap {
+			stat, ok := container.statsQueue.GetLastUsageStats()
+			if !ok {
+				continue
+			}
+			sample := ContainerStatsSample{
+				Timestamp:         stat.Timestamp,
+				Cluster:           engine.config.Cluster,
+				TaskARN:           taskArn,
+				TaskFamily:        taskDef.family,
+				TaskRevision:      taskDef.version,
+				ContainerName:     container.containerMetadata.Name,
+				MemoryUsageBytes:  uint64(stat.MemoryUsageInMegs) * BytesInMiB,
+				StorageReadBytes:  stat.StorageReadBytes,
+				StorageWriteBytes: stat.StorageWriteBytes,
+			}
+			if !math.IsNaN(float64(stat.CPUUsagePerc)) {
+				cpuUsagePercent := float64(stat.CPUUsagePerc)
+				sample.CPUUsagePercent = &cpuUsagePercent
+			}
+			if networkStats := engine.containerNetworkStatsUnsafe(taskArn, container, stat); networkStats != nil {
+				sample.Network = &ContainerNetworkSample{
+					RxBytes:   networkStats.RxBytes,
+					RxPackets: networkStats.RxPackets,
+					RxDropped: networkStats.RxDropped,
+					RxErrors:  networkStats.RxErrors,
+					TxBytes:   networkStats.TxBytes,
+					TxPackets: networkStats.TxPackets,
+					TxDropped: networkStats.TxDropped,
+					TxErrors:  networkStats.TxErrors,
+				}
+			}
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
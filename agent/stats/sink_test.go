@@ -0,0 +1,239 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sinkTestTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/cluster/abcdef"
+
+func newSinkTestEngine(t *testing.T) *DockerStatsEngine {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream(t.Name()))
+	engine.tasksToContainers[sinkTestTaskARN] = map[string]*StatsContainer{
+		"c1-id": newPrometheusTestStatsContainer("c1", "bridge"),
+	}
+	engine.tasksToDefinitions[sinkTestTaskARN] = &taskDefinition{family: "f1", version: "3"}
+	return engine
+}
+
+func receiveStatsDLines(t *testing.T, conn net.PacketConn) []string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func TestStatsDSink(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	sink, err := NewStatsDSink(server.LocalAddr().String(), false)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	samples := newSinkTestEngine(t).containerStatsSamples()
+	require.Len(t, samples, 1)
+	require.NoError(t, sink.Publish(samples))
+
+	lines := receiveStatsDLines(t, server)
+	prefix := "ecs.container." + cfg.Cluster + ".f1.3.abcdef.c1."
+	assert.Contains(t, lines, prefix+"memory_usage_bytes:3145728|g")
+	assert.Contains(t, lines, prefix+"storage_read_bytes:300|g")
+	assert.Contains(t, lines, prefix+"network_rx_bytes:796|g")
+	assert.Len(t, lines, 12, "expected memory, storage, cpu and network gauges")
+}
+
+func TestDogStatsDSink(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	sink, err := NewStatsDSink(server.LocalAddr().String(), true)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Publish(newSinkTestEngine(t).containerStatsSamples()))
+
+	lines := receiveStatsDLines(t, server)
+	tags := "|g|#cluster:" + cfg.Cluster + ",task_arn:" + sinkTestTaskARN +
+		",task_family:f1,task_revision:3,container_name:c1"
+	assert.Contains(t, lines, "ecs.container.memory_usage_bytes:3145728"+tags)
+	assert.Contains(t, lines, "ecs.container.storage_write_bytes:400"+tags)
+}
+
+func TestStatsDSinkSplitsPackets(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	sink, err := NewStatsDSink(server.LocalAddr().String(), true)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	sample := newSinkTestEngine(t).containerStatsSamples()[0]
+	samples := []ContainerStatsSample{sample, sample, sample, sample, sample}
+	require.NoError(t, sink.Publish(samples))
+
+	received := 0
+	for received < 5*12 {
+		buf := make([]byte, 65536)
+		require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := server.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.True(t, n <= statsDMaxPacketSize, "packet of %d bytes is too large", n)
+		received += len(strings.Split(string(buf[:n]), "\n"))
+	}
+	assert.Equal(t, 5*12, received)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	sink, err := NewFileSink(path, BytesInMiB, 1)
+	require.NoError(t, err)
+
+	samples := newSinkTestEngine(t).containerStatsSamples()
+	require.NoError(t, sink.Publish(samples))
+	require.NoError(t, sink.Publish(samples))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	lines := 0
+	for scanner.Scan() {
+		var sample ContainerStatsSample
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &sample))
+		assert.Equal(t, sinkTestTaskARN, sample.TaskARN)
+		assert.Equal(t, "c1", sample.ContainerName)
+		assert.Equal(t, uint64(3*BytesInMiB), sample.MemoryUsageBytes)
+		require.NotNil(t, sample.CPUUsagePercent)
+		require.NotNil(t, sample.Network)
+		assert.Equal(t, uint64(796), sample.Network.RxBytes)
+		lines++
+	}
+	assert.Equal(t, 2, lines)
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	samples := newSinkTestEngine(t).containerStatsSamples()
+	encoded, err := json.Marshal(samples[0])
+	require.NoError(t, err)
+	lineSize := int64(len(encoded) + 1)
+
+	// Room for two publishes of one sample per file, and two rotated files.
+	sink, err := NewFileSink(path, 2*lineSize, 2)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.Publish(samples[:1]))
+	}
+	require.NoError(t, sink.Close())
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.True(t, info.Size() <= 2*lineSize, "%s is %d bytes", name, info.Size())
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only two rotated files are kept")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, lineSize, info.Size())
+}
+
+func TestFileSinkRotationError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	samples := newSinkTestEngine(t).containerStatsSamples()
+	sink, err := NewFileSink(path, 1, 1)
+	require.NoError(t, err)
+	defer sink.Close()
+	require.NoError(t, sink.Publish(samples[:1]))
+
+	// A non-empty directory in the way of the rotated file fails the rotation
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "dir"), 0755))
+	assert.Error(t, sink.Publish(samples[:1]))
+	assert.Error(t, sink.Publish(samples[:1]))
+
+	// The current file is still open, so the next publish rotates it once the directory is gone
+	require.NoError(t, os.RemoveAll(path+".1"))
+	require.NoError(t, sink.Publish(samples[:1]))
+	encoded, err := json.Marshal(samples[0])
+	require.NoError(t, err)
+	for _, name := range []string{path, path + ".1"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(encoded)+1), info.Size(), name)
+	}
+}
+
+type fakeSink struct {
+	published chan []ContainerStatsSample
+	closed    chan struct{}
+}
+
+func (sink *fakeSink) Name() string { return "fake" }
+
+func (sink *fakeSink) Publish(samples []ContainerStatsSample) error {
+	sink.published <- samples
+	return nil
+}
+
+func (sink *fakeSink) Close() error {
+	close(sink.closed)
+	return nil
+}
+
+func TestStartSinks(t *testing.T) {
+	engine := newSinkTestEngine(t)
+	sink := &fakeSink{
+		published: make(chan []ContainerStatsSample, 10),
+		closed:    make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go engine.StartSinks(ctx, []Sink{sink}, 10*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case samples := <-sink.published:
+			assert.Len(t, samples, 1)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for samples to be published")
+		}
+	}
+	cancel()
+	select {
+	case <-sink.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the sink to be closed")
+	}
+
+	// Publishing doesn't consume the stats that are sent to the backend
+	_, err := engine.tasksToContainers[sinkTestTaskARN]["c1-id"].statsQueue.GetCPUStatsSet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,175 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	statsDMetricPrefix = "ecs.container"
+	// statsDMaxPacketSize keeps datagrams within the MTU of common networks, several metrics are
+	// sent in one datagram up to this size.
+	statsDMaxPacketSize = 1432
+)
+
+var (
+	// statsDNameInvalidChars matches the characters that can't be used in a plain StatsD metric
+	// name component, the '.' among them since it separates components.
+	statsDNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	// statsDTagInvalidChars matches the characters that can't be used in a DogStatsD tag.
+	statsDTagInvalidChars = regexp.MustCompile(`[,|#\s]`)
+)
+
+// StatsDSink sends container utilization metrics to a StatsD server over UDP, as gauges.
+// DogStatsD metrics carry the task and container as tags, while plain StatsD metrics have
+// them in the metric name, e.g. ecs.container.<cluster>.<family>.<revision>.<task id>.<container>.memory_usage_bytes.
+type StatsDSink struct {
+	conn      net.Conn
+	dogStatsD bool
+}
+
+// NewStatsDSink creates a StatsDSink that sends metrics to the server at address.
+func NewStatsDSink(address string, dogStatsD bool) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to connect to StatsD server %s", address)
+	}
+	return &StatsDSink{
+		conn:      conn,
+		dogStatsD: dogStatsD,
+	}, nil
+}
+
+// Name implements Sink.
+func (sink *StatsDSink) Name() string {
+	if sink.dogStatsD {
+		return "dogstatsd"
+	}
+	return "statsd"
+}
+
+// Publish implements Sink.
+func (sink *StatsDSink) Publish(samples []ContainerStatsSample) error {
+	var packet bytes.Buffer
+	for _, sample := range samples {
+		for _, line := range sink.lines(sample) {
+			if packet.Len() > 0 && packet.Len()+len(line)+1 > statsDMaxPacketSize {
+				if err := sink.send(&packet); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		}
+	}
+	if packet.Len() == 0 {
+		return nil
+	}
+	return sink.send(&packet)
+}
+
+// Close implements Sink.
+func (sink *StatsDSink) Close() error {
+	return sink.conn.Close()
+}
+
+func (sink *StatsDSink) send(packet *bytes.Buffer) error {
+	defer packet.Reset()
+	if _, err := sink.conn.Write(packet.Bytes()); err != nil {
+		return errors.Wrap(err, "unable to send metrics to StatsD server")
+	}
+	return nil
+}
+
+// statsDGauge is a gauge of a sample, its value is already formatted.
+type statsDGauge struct {
+	name  string
+	value string
+}
+
+// lines returns the StatsD lines of the metrics of a sample.
+func (sink *StatsDSink) lines(sample ContainerStatsSample) []string {
+	gauges := []statsDGauge{
+		{"memory_usage_bytes", strconv.FormatUint(sample.MemoryUsageBytes, 10)},
+		{"storage_read_bytes", strconv.FormatUint(sample.StorageReadBytes, 10)},
+		{"storage_write_bytes", strconv.FormatUint(sample.StorageWriteBytes, 10)},
+	}
+	if sample.CPUUsagePercent != nil {
+		gauges = append(gauges, statsDGauge{"cpu_usage_percent",
+			strconv.FormatFloat(*sample.CPUUsagePercent, 'f', -1, 64)})
+	}
+	if network := sample.Network; network != nil {
+		gauges = append(gauges,
+			statsDGauge{"network_rx_bytes", strconv.FormatUint(network.RxBytes, 10)},
+			statsDGauge{"network_rx_packets", strconv.FormatUint(network.RxPackets, 10)},
+			statsDGauge{"network_rx_dropped", strconv.FormatUint(network.RxDropped, 10)},
+			statsDGauge{"network_rx_errors", strconv.FormatUint(network.RxErrors, 10)},
+			statsDGauge{"network_tx_bytes", strconv.FormatUint(network.TxBytes, 10)},
+			statsDGauge{"network_tx_packets", strconv.FormatUint(network.TxPackets, 10)},
+			statsDGauge{"network_tx_dropped", strconv.FormatUint(network.TxDropped, 10)},
+			statsDGauge{"network_tx_errors", strconv.FormatUint(network.TxErrors, 10)},
+		)
+	}
+
+	lines := make([]string, 0, len(gauges))
+	if sink.dogStatsD {
+		tags := strings.Join([]string{
+			dogStatsDTag("cluster", sample.Cluster),
+			dogStatsDTag("task_arn", sample.TaskARN),
+			dogStatsDTag("task_family", sample.TaskFamily),
+			dogStatsDTag("task_revision", sample.TaskRevision),
+			dogStatsDTag("container_name", sample.ContainerName),
+		}, ",")
+		for _, gauge := range gauges {
+			lines = append(lines, fmt.Sprintf("%s.%s:%s|g|#%s", statsDMetricPrefix, gauge.name, gauge.value, tags))
+		}
+		return lines
+	}
+
+	taskID, err := utils.GetTaskID(sample.TaskARN)
+	if err != nil {
+		taskID = sample.TaskARN
+	}
+	prefix := strings.Join([]string{
+		statsDMetricPrefix,
+		statsDNameComponent(sample.Cluster),
+		statsDNameComponent(sample.TaskFamily),
+		statsDNameComponent(sample.TaskRevision),
+		statsDNameComponent(taskID),
+		statsDNameComponent(sample.ContainerName),
+	}, ".")
+	for _, gauge := range gauges {
+		lines = append(lines, fmt.Sprintf("%s.%s:%s|g", prefix, gauge.name, gauge.value))
+	}
+	return lines
+}
+
+func statsDNameComponent(value string) string {
+	return statsDNameInvalidChars.ReplaceAllString(value, "_")
+}
+
+func dogStatsDTag(key, value string) string {
+	return key + ":" + statsDTagInvalidChars.ReplaceAllString(value, "_")
+}
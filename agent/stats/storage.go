@@ -0,0 +1,245 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
+	"github.com/cihub/seelog"
+	mounttypes "github.com/docker/docker/api/types/mount"
+	"github.com/pkg/errors"
+)
+
+// EphemeralStorageLimitExceededReason is the prefix of the stop reason of tasks stopped for using
+// more ephemeral storage than the configured limit.
+const EphemeralStorageLimitExceededReason = "EphemeralStorageLimitExceeded"
+
+// ContainerStorageStats is the ephemeral storage used by a container, along with the total used by
+// its task. Ephemeral storage is the writable layers of the containers of the task and the local
+// Docker volumes scoped to the task.
+type ContainerStorageStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	// WritableLayerBytes is the size of the files created or changed in the writable layer of the container
+	WritableLayerBytes int64 `json:"writable_layer_bytes"`
+	// Volumes maps the names of the task scoped volumes mounted by the container to their usage in bytes
+	Volumes map[string]int64 `json:"volumes,omitempty"`
+	// TaskTotalBytes is the ephemeral storage used by the whole task
+	TaskTotalBytes int64 `json:"task_total_bytes"`
+	// TaskLimitBytes is the ephemeral storage limit of the task, if one is configured
+	TaskLimitBytes int64 `json:"task_limit_bytes,omitempty"`
+}
+
+// taskStorageStats is the ephemeral storage used by a task at the last collection.
+type taskStorageStats struct {
+	timestamp time.Time
+	// writableLayers maps docker ids to the size of the writable layers of the containers
+	writableLayers map[string]int64
+	// volumes maps task volume names to their usage
+	volumes    map[string]int64
+	totalBytes int64
+}
+
+// StartStorageCollection measures the ephemeral storage used by every known task at the given
+// interval, until the context is canceled. Tasks using more than the configured limit are stopped.
+func (engine *DockerStatsEngine) StartStorageCollection(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			engine.collectStorageStats(ctx)
+		}
+	}
+}
+
+// ContainerStorageStats returns the ephemeral storage used by a container at the last collection.
+func (engine *DockerStatsEngine) ContainerStorageStats(taskARN string, containerID string) (*ContainerStorageStats, error) {
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	taskStats, ok := engine.taskStorageStats[taskARN]
+	if !ok {
+		return nil, errors.Errorf("stats engine: storage stats of task '%s' not found", taskARN)
+	}
+	writableLayerBytes, ok := taskStats.writableLayers[containerID]
+	if !ok {
+		return nil, errors.Errorf("stats engine: storage stats of container '%s' of task '%s' not found", containerID, taskARN)
+	}
+	containerStats := &ContainerStorageStats{
+		Timestamp:          taskStats.timestamp,
+		WritableLayerBytes: writableLayerBytes,
+		TaskTotalBytes:     taskStats.totalBytes,
+		TaskLimitBytes:     engine.taskStorageLimitBytes(),
+	}
+	if dockerContainer, err := engine.resolver.ResolveContainer(containerID); err == nil {
+		for _, mountPoint := range dockerContainer.Container.MountPoints {
+			usage, ok := taskStats.volumes[mountPoint.SourceVolume]
+			if !ok {
+				continue
+			}
+			if containerStats.Volumes == nil {
+				containerStats.Volumes = make(map[string]int64)
+			}
+			containerStats.Volumes[mountPoint.SourceVolume] = usage
+		}
+	}
+	return containerStats, nil
+}
+
+// collectStorageStats replaces the storage stats of all known tasks and, when an ephemeral
+// storage limit is configured, stops the tasks that exceed it.
+func (engine *DockerStatsEngine) collectStorageStats(ctx context.Context) {
+	if engine.resolver == nil {
+		// The engine hasn't been initialized yet
+		return
+	}
+	engine.lock.RLock()
+	taskARNs := make([]string, 0, len(engine.tasksToContainers))
+	for taskARN := range engine.tasksToContainers {
+		taskARNs = append(taskARNs, taskARN)
+	}
+	engine.lock.RUnlock()
+
+	tasks := make([]*apitask.Task, 0, len(taskARNs))
+	for _, taskARN := range taskARNs {
+		task, err := engine.resolver.ResolveTaskByARN(taskARN)
+		if err != nil {
+			seelog.Debugf("Unable to resolve task %s to collect its storage stats: %v", taskARN, err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	storageStats := make(map[string]*taskStorageStats, len(tasks))
+	for _, task := range tasks {
+		storageStats[task.Arn] = engine.taskStorageUsage(ctx, task)
+	}
+
+	engine.lock.Lock()
+	engine.taskStorageStats = storageStats
+	engine.lock.Unlock()
+
+	limitBytes := engine.taskStorageLimitBytes()
+	if limitBytes == 0 {
+		return
+	}
+	for _, task := range tasks {
+		if storageStats[task.Arn].totalBytes > limitBytes {
+			engine.stopTaskOverStorageLimit(task, storageStats[task.Arn].totalBytes, limitBytes)
+		}
+	}
+}
+
+// taskStorageUsage measures the writable layers of the containers of a task and its ephemeral
+// volumes. Volumes are measured through the filesystem of a running container that mounts them,
+// since Docker only sizes volumes along with every other volume of the host.
+func (engine *DockerStatsEngine) taskStorageUsage(ctx context.Context, task *apitask.Task) *taskStorageStats {
+	taskStats := &taskStorageStats{
+		timestamp:      time.Now(),
+		writableLayers: make(map[string]int64),
+		volumes:        make(map[string]int64),
+	}
+	// Maps the docker names of the ephemeral volumes of the task to their names in the task
+	volumeNames := make(map[string]string)
+	for _, volume := range ephemeralVolumes(task) {
+		volumeNames[volume.VolumeConfig.DockerVolumeName] = volume.Name
+	}
+	for _, container := range task.Containers {
+		dockerID := container.GetRuntimeID()
+		if dockerID == "" {
+			continue
+		}
+		containerJSON, err := engine.client.InspectContainerWithSize(ctx, dockerID, dockerclient.InspectContainerWithSizeTimeout)
+		if err != nil {
+			seelog.Debugf("Unable to get the size of container %s of task %s: %v", dockerID, task.Arn, err)
+			continue
+		}
+		if containerJSON.ContainerJSONBase == nil {
+			continue
+		}
+		if containerJSON.SizeRw != nil {
+			taskStats.writableLayers[dockerID] = *containerJSON.SizeRw
+			taskStats.totalBytes += *containerJSON.SizeRw
+		}
+		if containerJSON.State == nil || containerJSON.State.Pid == 0 {
+			continue
+		}
+		for _, mount := range containerJSON.Mounts {
+			volumeName, ok := volumeNames[mount.Name]
+			if mount.Type != mounttypes.TypeVolume || !ok {
+				continue
+			}
+			if _, measured := taskStats.volumes[volumeName]; measured {
+				continue
+			}
+			usage, err := engine.mountDiskUsage(containerJSON.State.Pid, mount.Destination)
+			if err != nil {
+				seelog.Debugf("Unable to get the size of volume %s of task %s: %v", volumeName, task.Arn, err)
+				continue
+			}
+			taskStats.volumes[volumeName] = usage
+			taskStats.totalBytes += usage
+		}
+	}
+	return taskStats
+}
+
+// stopTaskOverStorageLimit stops a task that uses more ephemeral storage than the limit. As for
+// stops received from ACS, the task engine gets an update carrying the desired status and the
+// terminal reason, and the managed task applies them to the task it owns.
+func (engine *DockerStatsEngine) stopTaskOverStorageLimit(task *apitask.Task, usedBytes int64, limitBytes int64) {
+	if task.GetDesiredStatus().Terminal() || engine.taskEngine == nil {
+		return
+	}
+	seelog.Warnf("Task [%s]: stopping task using %d bytes of ephemeral storage, more than its limit of %d bytes",
+		task.Arn, usedBytes, limitBytes)
+	update := &apitask.Task{
+		Arn:                 task.Arn,
+		DesiredStatusUnsafe: apitaskstatus.TaskStopped,
+	}
+	update.SetTerminalReason(fmt.Sprintf("%s: task used %d MiB of ephemeral storage, more than its limit of %d MiB",
+		EphemeralStorageLimitExceededReason, usedBytes/BytesInMiB, limitBytes/BytesInMiB))
+	engine.taskEngine.AddTask(update)
+}
+
+func (engine *DockerStatsEngine) taskStorageLimitBytes() int64 {
+	return int64(engine.config.TaskEphemeralStorageLimit) * BytesInMiB
+}
+
+// ephemeralVolumes returns the volumes that only live as long as the task and are stored on the
+// instance disk, which excludes volumes of other drivers such as EFS or NFS.
+func ephemeralVolumes(task *apitask.Task) []*taskresourcevolume.VolumeResource {
+	var volumes []*taskresourcevolume.VolumeResource
+	for _, resource := range task.GetResources() {
+		volume, ok := resource.(*taskresourcevolume.VolumeResource)
+		if !ok || volume.VolumeType != apitask.DockerVolumeType {
+			continue
+		}
+		if volume.VolumeConfig.Scope != taskresourcevolume.TaskScope ||
+			volume.VolumeConfig.Driver != taskresourcevolume.DockerLocalVolumeDriver ||
+			len(volume.VolumeConfig.DriverOpts) > 0 {
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
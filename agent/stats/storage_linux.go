@@ -0,0 +1,80 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// mountDiskUsage measures the disk space used by the volume mounted at destination in the
+// container of the process pid, through the root of the process in the proc filesystem of the host.
+func mountDiskUsage(pid int, destination string) (int64, error) {
+	return dirDiskUsage(filepath.Join(hostProcPath, strconv.Itoa(pid), "root", destination))
+}
+
+// dirDiskUsage returns the size of the files under path, like Docker sizes volumes. Files hard
+// linked more than once are counted once, and filesystems mounted under path are skipped.
+func dirDiskUsage(path string) (int64, error) {
+	rootInfo, err := os.Lstat(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to stat %s", path)
+	}
+	rootStat, ok := rootInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, errors.Errorf("unable to get the device of %s", path)
+	}
+
+	var size int64
+	linkedInodes := make(map[uint64]struct{})
+	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files can be removed by the task while they're walked
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if uint64(stat.Dev) != uint64(rootStat.Dev) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if stat.Nlink > 1 {
+			if _, seen := linkedInodes[stat.Ino]; seen {
+				return nil
+			}
+			linkedInodes[stat.Ino] = struct{}{}
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to walk %s", path)
+	}
+	return size, nil
+}
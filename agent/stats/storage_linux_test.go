@@ -0,0 +1,45 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirDiskUsage(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "agent_stats_unit_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(testDir, "nested"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testDir, "a"), make([]byte, 1000), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testDir, "nested", "b"), make([]byte, 24), 0644))
+	// Hard links are counted once, symbolic links aren't followed
+	require.NoError(t, os.Link(filepath.Join(testDir, "a"), filepath.Join(testDir, "nested", "a")))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(testDir, "etc")))
+
+	size, err := dirDiskUsage(testDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), size)
+
+	_, err = dirDiskUsage(filepath.Join(testDir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	mock_resolver "github.com/aws/amazon-ecs-agent/agent/stats/resolver/mock"
+	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
+	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
+
+	"github.com/docker/docker/api/types"
+	mounttypes "github.com/docker/docker/api/types/mount"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storageTestTask(t *testing.T) *apitask.Task {
+	container := &apicontainer.Container{
+		Name:        "app",
+		MountPoints: []apicontainer.MountPoint{{SourceVolume: "scratch", ContainerPath: "/scratch"}},
+	}
+	container.SetRuntimeID("c1")
+	task := &apitask.Task{
+		Arn:                 "t1",
+		Containers:          []*apicontainer.Container{container},
+		ResourcesMapUnsafe:  make(resourcetype.ResourcesMap),
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+	}
+	scratch, err := taskresourcevolume.NewVolumeResource(context.TODO(), "scratch", apitask.DockerVolumeType,
+		"ecs-scratch", taskresourcevolume.TaskScope, false, taskresourcevolume.DockerLocalVolumeDriver,
+		map[string]string{}, map[string]string{}, nil)
+	require.NoError(t, err)
+	task.AddResource(resourcetype.DockerVolumeKey, scratch)
+	efs, err := taskresourcevolume.NewVolumeResource(context.TODO(), "efs", apitask.EFSVolumeType,
+		"ecs-efs", taskresourcevolume.TaskScope, false, taskresourcevolume.DockerLocalVolumeDriver,
+		map[string]string{"type": "nfs"}, map[string]string{}, nil)
+	require.NoError(t, err)
+	task.AddResource(resourcetype.DockerVolumeKey, efs)
+	return task
+}
+
+func storageTestEngine(ctrl *gomock.Controller, task *apitask.Task, limitMiB int) (*DockerStatsEngine, *mock_dockerapi.MockDockerClient) {
+	resolver := mock_resolver.NewMockContainerMetadataResolver(ctrl)
+	mockDockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+	resolver.EXPECT().ResolveTaskByARN(task.Arn).Return(task, nil).AnyTimes()
+	resolver.EXPECT().ResolveContainer("c1").Return(&apicontainer.DockerContainer{
+		DockerID:  "c1",
+		Container: task.Containers[0],
+	}, nil).AnyTimes()
+
+	storageCfg := cfg
+	storageCfg.TaskEphemeralStorageLimit = limitMiB
+	engine := NewDockerStatsEngine(&storageCfg, mockDockerClient, eventStream("TestTaskStorage"))
+	engine.resolver = resolver
+	engine.tasksToContainers[task.Arn] = map[string]*StatsContainer{"c1": {}}
+	engine.mountDiskUsage = func(pid int, destination string) (int64, error) {
+		return 0, errors.Errorf("unexpected volume at %s in process %d", destination, pid)
+	}
+	return engine, mockDockerClient
+}
+
+// sizedContainer returns a running container that mounts the scratch and efs volumes of the storage
+// test task.
+func sizedContainer(sizeRw int64) *types.ContainerJSON {
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:     "c1",
+			SizeRw: &sizeRw,
+			State:  &types.ContainerState{Running: true, Pid: 42},
+		},
+		Mounts: []types.MountPoint{
+			{Type: mounttypes.TypeVolume, Name: "ecs-scratch", Destination: "/scratch"},
+			{Type: mounttypes.TypeVolume, Name: "ecs-efs", Destination: "/efs"},
+			{Type: mounttypes.TypeBind, Source: "/var/log", Destination: "/logs"},
+		},
+	}
+}
+
+// volumeDiskUsage returns a mountDiskUsage that measures the scratch volume of the storage test task.
+func volumeDiskUsage(t *testing.T, usage int64) func(int, string) (int64, error) {
+	return func(pid int, destination string) (int64, error) {
+		assert.Equal(t, 42, pid)
+		assert.Equal(t, "/scratch", destination, "Only the ephemeral volumes of the task should be measured")
+		return usage, nil
+	}
+}
+
+func TestCollectStorageStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	task := storageTestTask(t)
+	engine, mockDockerClient := storageTestEngine(ctrl, task, 100)
+	engine.mountDiskUsage = volumeDiskUsage(t, 3*BytesInMiB)
+	mockDockerClient.EXPECT().InspectContainerWithSize(gomock.Any(), "c1", gomock.Any()).Return(sizedContainer(BytesInMiB), nil)
+
+	engine.collectStorageStats(context.TODO())
+
+	storageStats, err := engine.ContainerStorageStats("t1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(BytesInMiB), storageStats.WritableLayerBytes)
+	assert.Equal(t, map[string]int64{"scratch": 3 * BytesInMiB}, storageStats.Volumes)
+	assert.Equal(t, int64(4*BytesInMiB), storageStats.TaskTotalBytes)
+	assert.Equal(t, int64(100*BytesInMiB), storageStats.TaskLimitBytes)
+	assert.Equal(t, apitaskstatus.TaskRunning, task.GetDesiredStatus())
+
+	_, err = engine.ContainerStorageStats("t1", "c2")
+	assert.Error(t, err)
+	_, err = engine.ContainerStorageStats("t2", "c1")
+	assert.Error(t, err)
+}
+
+func TestCollectStorageStatsWithoutLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The task engine mock fails the test if the task is stopped
+	task := storageTestTask(t)
+	engine, mockDockerClient := storageTestEngine(ctrl, task, 0)
+	engine.taskEngine = mock_engine.NewMockTaskEngine(ctrl)
+	engine.mountDiskUsage = volumeDiskUsage(t, 100*BytesInMiB)
+	mockDockerClient.EXPECT().InspectContainerWithSize(gomock.Any(), "c1", gomock.Any()).Return(sizedContainer(BytesInMiB), nil)
+
+	engine.collectStorageStats(context.TODO())
+
+	storageStats, err := engine.ContainerStorageStats("t1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(101*BytesInMiB), storageStats.TaskTotalBytes)
+	assert.Zero(t, storageStats.TaskLimitBytes)
+}
+
+func TestCollectStorageStatsStoppedContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Volumes of containers that aren't running can't be reached, the mountDiskUsage of the test
+	// engine fails the test if they are measured
+	task := storageTestTask(t)
+	engine, mockDockerClient := storageTestEngine(ctrl, task, 0)
+	stoppedContainer := sizedContainer(BytesInMiB)
+	stoppedContainer.State = &types.ContainerState{}
+	mockDockerClient.EXPECT().InspectContainerWithSize(gomock.Any(), "c1", gomock.Any()).Return(stoppedContainer, nil)
+
+	engine.collectStorageStats(context.TODO())
+
+	storageStats, err := engine.ContainerStorageStats("t1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(BytesInMiB), storageStats.TaskTotalBytes)
+	assert.Empty(t, storageStats.Volumes)
+}
+
+func TestCollectStorageStatsStopsTaskOverLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	task := storageTestTask(t)
+	engine, mockDockerClient := storageTestEngine(ctrl, task, 2)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	engine.taskEngine = taskEngine
+	engine.mountDiskUsage = volumeDiskUsage(t, 2*BytesInMiB)
+	mockDockerClient.EXPECT().InspectContainerWithSize(gomock.Any(), "c1", gomock.Any()).Return(sizedContainer(BytesInMiB), nil).Times(2)
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(update *apitask.Task) {
+		assert.False(t, update == task, "The update must not be the task owned by the task engine")
+		assert.Equal(t, task.Arn, update.Arn)
+		assert.Equal(t, apitaskstatus.TaskStopped, update.GetDesiredStatus())
+		assert.True(t, strings.HasPrefix(update.GetTerminalReason(), EphemeralStorageLimitExceededReason))
+		assert.Contains(t, update.GetTerminalReason(), "3 MiB")
+	})
+
+	engine.collectStorageStats(context.TODO())
+	assert.Equal(t, apitaskstatus.TaskRunning, task.GetDesiredStatus(), "Only the task engine should stop the task")
+	assert.Empty(t, task.GetTerminalReason())
+
+	// The task isn't stopped again once the task engine has applied the update
+	task.SetDesiredStatus(apitaskstatus.TaskStopped)
+	engine.collectStorageStats(context.TODO())
+
+	storageStats, err := engine.ContainerStorageStats("t1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*BytesInMiB), storageStats.TaskLimitBytes)
+}
@@ -16,6 +16,7 @@ package asmsecret
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -264,14 +265,45 @@ func (secret *ASMSecretResource) Create() error {
 	}
 	iamCredentials := executionCredentials.GetIAMRoleCredentials()
 
+	seelog.Infof("ASM secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
+	secret.secretData = make(map[string]string)
+
+	if err := secret.retrieveSecretValues(iamCredentials); err != nil {
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	return nil
+}
+
+// Refresh fetches the values of all the secrets of the task again and updates the cache
+// with them. It returns the cache keys of the secrets whose value changed.
+func (secret *ASMSecretResource) Refresh() ([]string, error) {
+	executionCredentials, ok := secret.credentialsManager.GetTaskCredentials(secret.getExecutionCredentialsID())
+	if !ok {
+		return nil, errors.New("ASM secret resource: unable to find execution role credentials")
+	}
+
+	// Secrets are retrieved into a separate resource so that the cache keeps serving the
+	// current values while the new ones are fetched
+	refreshed := &ASMSecretResource{
+		taskARN:          secret.taskARN,
+		requiredSecrets:  secret.getRequiredSecrets(),
+		asmClientCreator: secret.asmClientCreator,
+		secretData:       make(map[string]string),
+	}
+	if err := refreshed.retrieveSecretValues(executionCredentials.GetIAMRoleCredentials()); err != nil {
+		return nil, err
+	}
+	return secret.updateCachedSecretValues(refreshed.secretData), nil
+}
+
+// retrieveSecretValues retrieves the values of all the secrets, spinning up a goroutine per secret
+func (secret *ASMSecretResource) retrieveSecretValues(iamCredentials credentials.IAMRoleCredentials) error {
 	var wg sync.WaitGroup
 
 	// Get the maximum number of errors to be returned, which will be one error per goroutine
 	errorEvents := make(chan error, len(secret.requiredSecrets))
 
-	seelog.Infof("ASM secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
-	secret.secretData = make(map[string]string)
-
 	for _, asmsecret := range secret.getRequiredSecrets() {
 		wg.Add(1)
 		// Spin up goroutine per secret to speed up processing time
@@ -287,9 +319,7 @@ func (secret *ASMSecretResource) Create() error {
 			terminalReasons = append(terminalReasons, err.Error())
 		}
 
-		errorString := strings.Join(terminalReasons, ";")
-		secret.setTerminalReason(errorString)
-		return errors.New(errorString)
+		return errors.New(strings.Join(terminalReasons, ";"))
 	}
 	return nil
 }
@@ -428,6 +458,27 @@ func (secret *ASMSecretResource) GetCachedSecretValue(secretKey string) (string,
 	return s, ok
 }
 
+// updateCachedSecretValues caches the given secret values and returns the keys of the
+// previously cached values that changed
+func (secret *ASMSecretResource) updateCachedSecretValues(values map[string]string) []string {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	var changed []string
+	for secretKey, secretValue := range values {
+		if cachedValue, ok := secret.secretData[secretKey]; ok && cachedValue != secretValue {
+			changed = append(changed, secretKey)
+		}
+		secret.secretData[secretKey] = secretValue
+	}
+	sort.Strings(changed)
+	return changed
+}
+
 // SetCachedSecretValue set the secret value in the secretData field given the key and value
 func (secret *ASMSecretResource) SetCachedSecretValue(secretKey string, secretValue string) {
 	secret.lock.Lock()
@@ -185,6 +185,74 @@ func TestCreateReturnError(t *testing.T) {
 	assert.Equal(t, expectedError, asmRes.GetTerminalReason())
 }
 
+func TestRefresh(t *testing.T) {
+	requiredSecretData := map[string]apicontainer.Secret{
+		secretKeyWest1: sampleSecret(secretName1, valueFrom1, region1),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	asmClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true).Times(2)
+	asmClientCreator.EXPECT().NewASMClient(region1, iamRoleCreds).Return(mockASMClient).Times(2)
+	gomock.InOrder(
+		mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String(secretValue),
+		}, nil),
+		mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+			SecretString: aws.String("rotated-value"),
+		}, nil),
+	)
+
+	asmRes := &ASMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        requiredSecretData,
+		credentialsManager:     credentialsManager,
+		asmClientCreator:       asmClientCreator,
+	}
+	asmRes.SetCachedSecretValue(secretKeyWest1, secretValue)
+
+	changed, err := asmRes.Refresh()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	changed, err = asmRes.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{secretKeyWest1}, changed)
+	value, ok := asmRes.GetCachedSecretValue(secretKeyWest1)
+	require.True(t, ok)
+	assert.Equal(t, "rotated-value", value)
+}
+
+func TestRefreshNoExecutionCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(credentials.TaskIAMRoleCredentials{}, false)
+
+	asmRes := &ASMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets: map[string]apicontainer.Secret{
+			secretKeyWest1: sampleSecret(secretName1, valueFrom1, region1),
+		},
+		credentialsManager: credentialsManager,
+	}
+
+	_, err := asmRes.Refresh()
+	assert.Error(t, err)
+	assert.Empty(t, asmRes.GetTerminalReason())
+}
+
 func TestMarshalUnmarshalJSON(t *testing.T) {
 	requiredSecretData := map[string]apicontainer.Secret{
 		secretKeyWest1: sampleSecret(secretName1, valueFrom1, region1),
@@ -281,6 +281,11 @@ func (cgroup *CgroupResource) setupTaskCgroup() error {
 		return fmt.Errorf("cgroup resource [%s]: setup cgroup: unable to create cgroup at %s: %w", cgroup.taskARN, cgroupRoot, err)
 	}
 
+	// the unified hierarchy is always hierarchical and has no memory.use_hierarchy flag
+	if control.IsUnified(cgroup.control) {
+		return nil
+	}
+
 	// enabling cgroup memory hierarchy by doing 'echo 1 > memory.use_hierarchy'
 	memoryHierarchyPath := filepath.Join(cgroup.cgroupMountPath, memorySubsystem, cgroupRoot, memoryUseHierarchy)
 	err = cgroup.ioutil.WriteFile(memoryHierarchyPath, enableMemoryHierarchy, rootReadOnlyPermissions)
@@ -147,7 +147,7 @@ func TestMarshal(t *testing.T) {
 	cgroupRoot := "/ecs/taskid"
 	cgroupMountPath := "/sys/fs/cgroup"
 
-	cgroup := NewCgroupResource("", cgroup.New(), nil, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
+	cgroup := NewCgroupResource("", cgroup.New(cgroupMountPath, ""), nil, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
 	cgroup.SetDesiredStatus(resourcestatus.ResourceStatus(CgroupCreated))
 	cgroup.SetKnownStatus(resourcestatus.ResourceStatus(CgroupStatusNone))
 
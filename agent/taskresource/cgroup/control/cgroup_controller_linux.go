@@ -18,6 +18,7 @@ package control
 import (
 	"fmt"
 
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control/factory"
 
 	"github.com/cihub/seelog"
@@ -28,17 +29,33 @@ import (
 // control is used to implement the cgroup Control interface
 type control struct {
 	factory.CgroupFactory
+	// systemdDriver is set when docker manages cgroups with systemd, which isn't supported for
+	// the task cgroups of the v1 hierarchy
+	systemdDriver bool
 }
 
-// New is used to obtain a new cgroup control object
-func New() Control {
-	return newControl(&factory.GlobalCgroupFactory{})
+// New is used to obtain a new cgroup control object for the cgroup hierarchy mounted at
+// cgroupMountPath, and the cgroupDriver of docker. The unified (v2) hierarchy is managed through
+// cgroupfs directly, or through systemd slices with the systemd driver, any other through the
+// cgroups library.
+func New(cgroupMountPath string, cgroupDriver string) Control {
+	if IsUnifiedHierarchy(cgroupMountPath) {
+		seelog.Infof("Detected the unified cgroup hierarchy at %s", cgroupMountPath)
+		if cgroupDriver == config.CgroupDriverSystemd {
+			return newControlV2Systemd(cgroupMountPath, newSystemdConnection)
+		}
+		return newControlV2(cgroupMountPath)
+	}
+	return &control{
+		CgroupFactory: &factory.GlobalCgroupFactory{},
+		systemdDriver: cgroupDriver == config.CgroupDriverSystemd,
+	}
 }
 
 // newControl helps setup the cgroup controller
 func newControl(cgroupFact factory.CgroupFactory) Control {
 	return &control{
-		cgroupFact,
+		CgroupFactory: cgroupFact,
 	}
 }
 
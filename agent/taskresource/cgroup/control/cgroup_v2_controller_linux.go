@@ -0,0 +1,228 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// cgroupControllersFile lists the controllers available in a cgroup of the unified
+	// hierarchy. It only exists in cgroup v2.
+	cgroupControllersFile = "cgroup.controllers"
+	// cgroupSubtreeControlFile lists the controllers enabled for the children of a cgroup.
+	cgroupSubtreeControlFile = "cgroup.subtree_control"
+	cpuMaxFile               = "cpu.max"
+	cpuWeightFile            = "cpu.weight"
+	memoryMaxFile            = "memory.max"
+	memoryLowFile            = "memory.low"
+	cgroupV2FilePermissions  = os.FileMode(0644)
+	cgroupV2DirPermissions   = os.FileMode(0755)
+)
+
+// requiredControllersV2 are the controllers that task cgroups need, they're enabled in every
+// cgroup from the root of the hierarchy down to the task cgroups.
+var requiredControllersV2 = []string{"cpu", "memory"}
+
+// controlV2 implements the cgroup Control interface for the unified (v2) hierarchy by
+// managing cgroupfs directly, since the cgroups library only supports v1.
+type controlV2 struct {
+	mountPath string
+}
+
+// newControlV2 creates a control of the unified hierarchy mounted at mountPath.
+func newControlV2(mountPath string) Control {
+	return &controlV2{
+		mountPath: mountPath,
+	}
+}
+
+// IsUnifiedHierarchy returns whether the cgroupfs mounted at mountPath is the unified (v2)
+// hierarchy.
+func IsUnifiedHierarchy(mountPath string) bool {
+	_, err := os.Stat(filepath.Join(mountPath, cgroupControllersFile))
+	return err == nil
+}
+
+// IsUnified returns whether the control manages cgroups of the unified (v2) hierarchy.
+func IsUnified(control Control) bool {
+	switch control.(type) {
+	case *controlV2, *controlV2Systemd:
+		return true
+	}
+	return false
+}
+
+// Create creates a new cgroup based off the spec post validation. The cgroups library doesn't
+// support the unified hierarchy, so no cgroups.Cgroup is returned.
+func (c *controlV2) Create(cgroupSpec *Spec) (cgroups.Cgroup, error) {
+	err := validateCgroupSpec(cgroupSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: failed to validate spec: %w", err)
+	}
+
+	seelog.Infof("Creating cgroup %s", cgroupSpec.Root)
+	path, err := c.createPath(cgroupSpec.Root)
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: unable to create cgroup: %w", err)
+	}
+	if err := applyResourcesV2(path, cgroupSpec.Specs); err != nil {
+		return nil, fmt.Errorf("cgroup create: unable to apply resources: %w", err)
+	}
+	return nil, nil
+}
+
+// createPath creates the cgroups of the path that don't exist yet, and enables the required
+// controllers in each of them so that they're also available to their children.
+func (c *controlV2) createPath(cgroupPath string) (string, error) {
+	path := c.mountPath
+	if err := enableControllersV2(path); err != nil {
+		return "", err
+	}
+	for _, name := range strings.Split(strings.Trim(cgroupPath, "/"), "/") {
+		path = filepath.Join(path, name)
+		if err := os.Mkdir(path, cgroupV2DirPermissions); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+		if err := enableControllersV2(path); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// Remove is used to delete the cgroup, along with the cgroups left under it.
+func (c *controlV2) Remove(cgroupPath string) error {
+	seelog.Debugf("Removing cgroup %s", cgroupPath)
+
+	path := filepath.Join(c.mountPath, cgroupPath)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// use the %w verb to wrap the error to be unwrapped by errors.Is()
+		return fmt.Errorf("cgroup remove: unable to obtain cgroup: %w", cgroups.ErrCgroupDeleted)
+	}
+	if err := removeCgroupV2(path); err != nil {
+		return fmt.Errorf("cgroup remove: unable to delete cgroup: %w", err)
+	}
+	return nil
+}
+
+// Exists is used to verify the existence of a cgroup
+func (c *controlV2) Exists(cgroupPath string) bool {
+	seelog.Debugf("Checking existence of cgroup: %s", cgroupPath)
+
+	info, err := os.Stat(filepath.Join(c.mountPath, cgroupPath))
+	return err == nil && info.IsDir()
+}
+
+// removeCgroupV2 removes a cgroup after the cgroups under it. Cgroup directories only hold
+// interface files, which are removed along with the directory.
+func removeCgroupV2(path string) error {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := removeCgroupV2(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Remove(path)
+}
+
+// enableControllersV2 enables the required controllers for the children of the cgroup at path.
+func enableControllersV2(path string) error {
+	available, err := ioutil.ReadFile(filepath.Join(path, cgroupControllersFile))
+	if err != nil {
+		return err
+	}
+	availableControllers := strings.Fields(string(available))
+	var toEnable []string
+	for _, controller := range requiredControllersV2 {
+		if !containsString(availableControllers, controller) {
+			return errors.Errorf("controller %s is not available in cgroup %s", controller, path)
+		}
+		toEnable = append(toEnable, "+"+controller)
+	}
+	return ioutil.WriteFile(filepath.Join(path, cgroupSubtreeControlFile),
+		[]byte(strings.Join(toEnable, " ")), cgroupV2FilePermissions)
+}
+
+// applyResourcesV2 writes the cpu and memory resources of the spec to the interface files of
+// the cgroup at path.
+func applyResourcesV2(path string, resources *specs.LinuxResources) error {
+	values := make(map[string]string)
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Period != nil && *cpu.Period > 0 {
+			quota := "max"
+			if cpu.Quota != nil && *cpu.Quota > 0 {
+				quota = strconv.FormatInt(*cpu.Quota, 10)
+			}
+			values[cpuMaxFile] = fmt.Sprintf("%s %d", quota, *cpu.Period)
+		}
+		if cpu.Shares != nil && *cpu.Shares > 0 {
+			values[cpuWeightFile] = strconv.FormatUint(cpuSharesToWeight(*cpu.Shares), 10)
+		}
+	}
+	if memory := resources.Memory; memory != nil {
+		if memory.Limit != nil && *memory.Limit > 0 {
+			values[memoryMaxFile] = strconv.FormatInt(*memory.Limit, 10)
+		}
+		// The memory reservation is the memory protected from reclaim, memory.high isn't set
+		// as the spec has no throttling threshold
+		if memory.Reservation != nil && *memory.Reservation > 0 {
+			values[memoryLowFile] = strconv.FormatInt(*memory.Reservation, 10)
+		}
+	}
+	for file, value := range values {
+		if err := ioutil.WriteFile(filepath.Join(path, file), []byte(value), cgroupV2FilePermissions); err != nil {
+			return errors.Wrapf(err, "unable to set %s to %s", file, value)
+		}
+	}
+	return nil
+}
+
+// cpuSharesToWeight converts v1 cpu shares, in [2, 262144], to a v2 cpu weight, in [1, 10000],
+// the same way as the OCI runtimes do.
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUnifiedHierarchy creates a fake cgroupfs of the unified hierarchy. Unlike the actual
+// cgroupfs, new directories don't get interface files, so the given ones are created under the
+// root and cgroupRoot.
+func setupUnifiedHierarchy(t *testing.T, controllers string) string {
+	mountPath := t.TempDir()
+	path := mountPath
+	dirs := []string{path}
+	for _, name := range []string{"ecs", "foo"} {
+		path = filepath.Join(path, name)
+		dirs = append(dirs, path)
+	}
+	for _, dir := range dirs {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, cgroupControllersFile), []byte(controllers), 0644))
+	}
+	return mountPath
+}
+
+func readCgroupFile(t *testing.T, path string) string {
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(contents)
+}
+
+func TestNewDetectsUnifiedHierarchy(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpu memory")
+	assert.True(t, IsUnified(New(mountPath, "cgroupfs")))
+	assert.False(t, IsUnified(New(t.TempDir(), "cgroupfs")))
+
+	// with the systemd driver, task cgroups of the unified hierarchy are systemd slices
+	control := New(mountPath, "systemd")
+	assert.True(t, IsUnified(control))
+	assert.IsType(t, &controlV2Systemd{}, control)
+	assert.Error(t, New(t.TempDir(), "systemd").Init(), "task cgroups of the v1 hierarchy don't support the systemd driver")
+}
+
+func TestCreateV2(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpuset cpu io memory pids")
+	control := newControlV2(mountPath)
+
+	quota := int64(50000)
+	period := uint64(100000)
+	shares := uint64(1024)
+	limit := int64(512 * 1024 * 1024)
+	reservation := int64(256 * 1024 * 1024)
+	res, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Quota:  &quota,
+			Period: &period,
+			Shares: &shares,
+		},
+		Memory: &specs.LinuxMemory{
+			Limit:       &limit,
+			Reservation: &reservation,
+		},
+	}})
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	for _, dir := range []string{"", "ecs", "ecs/foo"} {
+		assert.Equal(t, "+cpu +memory", readCgroupFile(t, filepath.Join(mountPath, dir, cgroupSubtreeControlFile)))
+	}
+	path := filepath.Join(mountPath, testCgroupRoot)
+	assert.Equal(t, "50000 100000", readCgroupFile(t, filepath.Join(path, cpuMaxFile)))
+	assert.Equal(t, "39", readCgroupFile(t, filepath.Join(path, cpuWeightFile)))
+	assert.Equal(t, "536870912", readCgroupFile(t, filepath.Join(path, memoryMaxFile)))
+	assert.Equal(t, "268435456", readCgroupFile(t, filepath.Join(path, memoryLowFile)))
+}
+
+func TestCreateV2UnlimitedQuota(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpu memory")
+	control := newControlV2(mountPath)
+
+	period := uint64(100000)
+	_, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Period: &period,
+		},
+	}})
+	require.NoError(t, err)
+
+	path := filepath.Join(mountPath, testCgroupRoot)
+	assert.Equal(t, "max 100000", readCgroupFile(t, filepath.Join(path, cpuMaxFile)))
+	_, err = os.Stat(filepath.Join(path, memoryMaxFile))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCreateV2MissingController(t *testing.T) {
+	control := newControlV2(setupUnifiedHierarchy(t, "cpu io pids"))
+
+	_, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{}})
+	assert.Error(t, err)
+}
+
+func TestCreateV2WithBadSpecs(t *testing.T) {
+	control := newControlV2(setupUnifiedHierarchy(t, "cpu memory"))
+
+	_, err := control.Create(&Spec{"", &specs.LinuxResources{}})
+	assert.Error(t, err)
+	_, err = control.Create(&Spec{testCgroupRoot, nil})
+	assert.Error(t, err)
+}
+
+func TestRemoveV2(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpu memory")
+	control := newControlV2(mountPath)
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, testCgroupRoot, "container"), 0755))
+
+	// the fake cgroupfs has regular files, which rmdir doesn't remove on a real cgroupfs
+	require.NoError(t, os.Remove(filepath.Join(mountPath, testCgroupRoot, cgroupControllersFile)))
+	assert.True(t, control.Exists(testCgroupRoot))
+	assert.NoError(t, control.Remove(testCgroupRoot))
+	assert.False(t, control.Exists(testCgroupRoot))
+
+	err := control.Remove(testCgroupRoot)
+	assert.True(t, errors.Is(err, cgroups.ErrCgroupDeleted))
+}
+
+func TestInitV2(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpu memory")
+	control := newControlV2(mountPath)
+
+	assert.NoError(t, control.Init())
+	assert.Equal(t, "+cpu +memory", readCgroupFile(t, filepath.Join(mountPath, "ecs", cgroupSubtreeControlFile)))
+}
+
+func TestInitV2MissingController(t *testing.T) {
+	control := newControlV2(setupUnifiedHierarchy(t, "cpu"))
+
+	assert.Error(t, control.Init())
+}
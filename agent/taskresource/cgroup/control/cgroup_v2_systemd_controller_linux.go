@@ -0,0 +1,202 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/cihub/seelog"
+	"github.com/containerd/cgroups"
+	systemddbus "github.com/coreos/go-systemd/dbus"
+	"github.com/godbus/dbus"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	systemdSliceSuffix = ".slice"
+	// systemdJobMode replaces the queued jobs of the slice that conflict with the new one.
+	systemdJobMode = "replace"
+	// systemdJobDone is the result of the jobs that succeeded.
+	systemdJobDone = "done"
+	// systemdJobTimeout bounds the wait for systemd to start or stop a slice.
+	systemdJobTimeout = 30 * time.Second
+)
+
+// systemdConnection is the part of the systemd D-Bus API used to manage the slices of tasks.
+type systemdConnection interface {
+	StartTransientUnit(name string, mode string, properties []systemddbus.Property, ch chan<- string) (int, error)
+	StopUnit(name string, mode string, ch chan<- string) (int, error)
+	Close()
+}
+
+// controlV2Systemd implements the cgroup Control interface for the unified (v2) hierarchy when
+// docker manages cgroups with systemd. Task cgroups are then transient systemd slices, which
+// docker creates the scopes of the containers in. Their resources are set through systemd, which
+// would otherwise reset the interface files of the cgroups it manages.
+type controlV2Systemd struct {
+	*controlV2
+	connect func() (systemdConnection, error)
+}
+
+// newControlV2Systemd creates a control of the slices of the unified hierarchy mounted at
+// mountPath, which connects to systemd with connect.
+func newControlV2Systemd(mountPath string, connect func() (systemdConnection, error)) Control {
+	return &controlV2Systemd{
+		controlV2: &controlV2{
+			mountPath: mountPath,
+		},
+		connect: connect,
+	}
+}
+
+// newSystemdConnection connects to systemd through the system bus, or directly through its
+// private socket when the system bus isn't available.
+func newSystemdConnection() (systemdConnection, error) {
+	conn, err := systemddbus.New()
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Init makes sure that systemd can be reached and that the controllers needed by task slices
+// are available on the host. Systemd creates the parent slice of the task slices along with them.
+func (c *controlV2Systemd) Init() error {
+	seelog.Infof("Using systemd slices in %s%s for task cgroups", config.DefaultTaskCgroupSlicePrefix, systemdSliceSuffix)
+
+	available, err := ioutil.ReadFile(filepath.Join(c.mountPath, cgroupControllersFile))
+	if err != nil {
+		return err
+	}
+	for _, controller := range requiredControllersV2 {
+		if !containsString(strings.Fields(string(available)), controller) {
+			return errors.Errorf("controller %s is not available in cgroup %s", controller, c.mountPath)
+		}
+	}
+	conn, err := c.connect()
+	if err != nil {
+		return errors.Wrap(err, "unable to connect to systemd")
+	}
+	conn.Close()
+	return nil
+}
+
+// Create creates the slice of the cgroup spec, the last element of its root, with the resources
+// of the spec. The cgroups library doesn't support the unified hierarchy, so no cgroups.Cgroup is
+// returned.
+func (c *controlV2Systemd) Create(cgroupSpec *Spec) (cgroups.Cgroup, error) {
+	err := validateCgroupSpec(cgroupSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: failed to validate spec: %w", err)
+	}
+	slice := filepath.Base(cgroupSpec.Root)
+	if !strings.HasSuffix(slice, systemdSliceSuffix) {
+		return nil, fmt.Errorf("cgroup create: cgroup %s is not a systemd slice", cgroupSpec.Root)
+	}
+
+	seelog.Infof("Creating systemd slice %s", slice)
+	properties := append([]systemddbus.Property{
+		systemddbus.PropDescription("ECS task slice " + slice),
+	}, systemdResourceProperties(cgroupSpec.Specs)...)
+	err = c.runJob(func(conn systemdConnection, ch chan<- string) (int, error) {
+		return conn.StartTransientUnit(slice, systemdJobMode, properties, ch)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cgroup create: unable to create slice %s: %w", slice, err)
+	}
+	return nil, nil
+}
+
+// Remove is used to stop the slice of the cgroup, systemd then deletes the cgroup.
+func (c *controlV2Systemd) Remove(cgroupPath string) error {
+	seelog.Debugf("Removing cgroup %s", cgroupPath)
+
+	if _, err := os.Stat(filepath.Join(c.mountPath, cgroupPath)); os.IsNotExist(err) {
+		// use the %w verb to wrap the error to be unwrapped by errors.Is()
+		return fmt.Errorf("cgroup remove: unable to obtain cgroup: %w", cgroups.ErrCgroupDeleted)
+	}
+	slice := filepath.Base(cgroupPath)
+	err := c.runJob(func(conn systemdConnection, ch chan<- string) (int, error) {
+		return conn.StopUnit(slice, systemdJobMode, ch)
+	})
+	if err != nil {
+		return fmt.Errorf("cgroup remove: unable to stop slice %s: %w", slice, err)
+	}
+	return nil
+}
+
+// runJob connects to systemd, queues a job with queue and waits for the job to be done.
+func (c *controlV2Systemd) runJob(queue func(conn systemdConnection, ch chan<- string) (int, error)) error {
+	conn, err := c.connect()
+	if err != nil {
+		return errors.Wrap(err, "unable to connect to systemd")
+	}
+	defer conn.Close()
+
+	ch := make(chan string, 1)
+	if _, err := queue(conn, ch); err != nil {
+		return err
+	}
+	select {
+	case result := <-ch:
+		if result != systemdJobDone {
+			return errors.Errorf("systemd job %s", result)
+		}
+		return nil
+	case <-time.After(systemdJobTimeout):
+		return errors.Errorf("timed out waiting for systemd after %s", systemdJobTimeout)
+	}
+}
+
+// systemdResourceProperties converts the cpu and memory resources of the spec to the properties of
+// a slice, with the same mapping as applyResourcesV2.
+func systemdResourceProperties(resources *specs.LinuxResources) []systemddbus.Property {
+	var properties []systemddbus.Property
+	if cpu := resources.CPU; cpu != nil {
+		if cpu.Period != nil && *cpu.Period > 0 && cpu.Quota != nil && *cpu.Quota > 0 {
+			properties = append(properties,
+				newSystemdProperty("CPUQuotaPeriodUSec", *cpu.Period),
+				newSystemdProperty("CPUQuotaPerSecUSec", uint64(*cpu.Quota)*uint64(time.Second/time.Microsecond)/(*cpu.Period)))
+		}
+		if cpu.Shares != nil && *cpu.Shares > 0 {
+			properties = append(properties, newSystemdProperty("CPUWeight", cpuSharesToWeight(*cpu.Shares)))
+		}
+	}
+	if memory := resources.Memory; memory != nil {
+		if memory.Limit != nil && *memory.Limit > 0 {
+			properties = append(properties, newSystemdProperty("MemoryMax", uint64(*memory.Limit)))
+		}
+		if memory.Reservation != nil && *memory.Reservation > 0 {
+			properties = append(properties, newSystemdProperty("MemoryLow", uint64(*memory.Reservation)))
+		}
+	}
+	return properties
+}
+
+func newSystemdProperty(name string, value interface{}) systemddbus.Property {
+	return systemddbus.Property{
+		Name:  name,
+		Value: dbus.MakeVariant(value),
+	}
+}
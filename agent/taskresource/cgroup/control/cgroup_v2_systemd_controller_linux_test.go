@@ -0,0 +1,153 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/cgroups"
+	systemddbus "github.com/coreos/go-systemd/dbus"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSliceRoot = "/ecstasks.slice/ecstasks-foo.slice"
+
+// fakeSystemdConnection records the units started and stopped through it, and completes their
+// jobs with result.
+type fakeSystemdConnection struct {
+	result     string
+	started    map[string][]systemddbus.Property
+	stopped    []string
+	closeCount int
+}
+
+func newFakeSystemdConnection(result string) *fakeSystemdConnection {
+	return &fakeSystemdConnection{
+		result:  result,
+		started: make(map[string][]systemddbus.Property),
+	}
+}
+
+func (conn *fakeSystemdConnection) StartTransientUnit(name string, mode string, properties []systemddbus.Property, ch chan<- string) (int, error) {
+	conn.started[name] = properties
+	ch <- conn.result
+	return 1, nil
+}
+
+func (conn *fakeSystemdConnection) StopUnit(name string, mode string, ch chan<- string) (int, error) {
+	conn.stopped = append(conn.stopped, name)
+	ch <- conn.result
+	return 1, nil
+}
+
+func (conn *fakeSystemdConnection) Close() {
+	conn.closeCount++
+}
+
+func (conn *fakeSystemdConnection) connect() (systemdConnection, error) {
+	return conn, nil
+}
+
+func propertyValues(properties []systemddbus.Property) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, property := range properties {
+		values[property.Name] = property.Value.Value()
+	}
+	return values
+}
+
+func TestCreateV2Systemd(t *testing.T) {
+	conn := newFakeSystemdConnection(systemdJobDone)
+	control := newControlV2Systemd(t.TempDir(), conn.connect)
+
+	quota := int64(50000)
+	period := uint64(100000)
+	shares := uint64(1024)
+	limit := int64(512 * 1024 * 1024)
+	reservation := int64(256 * 1024 * 1024)
+	res, err := control.Create(&Spec{testSliceRoot, &specs.LinuxResources{
+		CPU: &specs.LinuxCPU{
+			Quota:  &quota,
+			Period: &period,
+			Shares: &shares,
+		},
+		Memory: &specs.LinuxMemory{
+			Limit:       &limit,
+			Reservation: &reservation,
+		},
+	}})
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	require.Contains(t, conn.started, "ecstasks-foo.slice")
+	values := propertyValues(conn.started["ecstasks-foo.slice"])
+	assert.Equal(t, uint64(100000), values["CPUQuotaPeriodUSec"])
+	assert.Equal(t, uint64(500000), values["CPUQuotaPerSecUSec"])
+	assert.Equal(t, uint64(39), values["CPUWeight"])
+	assert.Equal(t, uint64(limit), values["MemoryMax"])
+	assert.Equal(t, uint64(reservation), values["MemoryLow"])
+	assert.Equal(t, 1, conn.closeCount)
+}
+
+func TestCreateV2SystemdErrors(t *testing.T) {
+	control := newControlV2Systemd(t.TempDir(), newFakeSystemdConnection(systemdJobDone).connect)
+	_, err := control.Create(&Spec{testCgroupRoot, &specs.LinuxResources{}})
+	assert.Error(t, err, "the cgroup must be a slice")
+	_, err = control.Create(&Spec{testSliceRoot, nil})
+	assert.Error(t, err)
+
+	control = newControlV2Systemd(t.TempDir(), newFakeSystemdConnection("failed").connect)
+	_, err = control.Create(&Spec{testSliceRoot, &specs.LinuxResources{}})
+	assert.Error(t, err)
+
+	control = newControlV2Systemd(t.TempDir(), func() (systemdConnection, error) {
+		return nil, errors.New("no bus")
+	})
+	_, err = control.Create(&Spec{testSliceRoot, &specs.LinuxResources{}})
+	assert.Error(t, err)
+}
+
+func TestRemoveV2Systemd(t *testing.T) {
+	mountPath := t.TempDir()
+	conn := newFakeSystemdConnection(systemdJobDone)
+	control := newControlV2Systemd(mountPath, conn.connect)
+
+	err := control.Remove(testSliceRoot)
+	assert.True(t, errors.Is(err, cgroups.ErrCgroupDeleted))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(mountPath, testSliceRoot), 0755))
+	assert.True(t, control.Exists(testSliceRoot))
+	assert.NoError(t, control.Remove(testSliceRoot))
+	assert.Equal(t, []string{"ecstasks-foo.slice"}, conn.stopped)
+}
+
+func TestInitV2Systemd(t *testing.T) {
+	mountPath := setupUnifiedHierarchy(t, "cpu memory")
+	conn := newFakeSystemdConnection(systemdJobDone)
+	assert.NoError(t, newControlV2Systemd(mountPath, conn.connect).Init())
+	assert.Equal(t, 1, conn.closeCount)
+
+	// the controllers must be available, and systemd reachable
+	assert.Error(t, newControlV2Systemd(setupUnifiedHierarchy(t, "cpu"), conn.connect).Init())
+	assert.Error(t, newControlV2Systemd(mountPath, func() (systemdConnection, error) {
+		return nil, errors.New("no bus")
+	}).Init())
+}
@@ -20,10 +20,14 @@ import (
 
 	"github.com/cihub/seelog"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
 )
 
 // Init is used to setup the cgroup root for ecs
 func (c *control) Init() error {
+	if c.systemdDriver {
+		return errors.New("task cgroups of the v1 hierarchy aren't supported with the systemd cgroup driver of docker")
+	}
 	seelog.Infof("Creating root ecs cgroup: %s", config.DefaultTaskCgroupPrefix)
 
 	// Build cgroup spec
@@ -34,3 +38,16 @@ func (c *control) Init() error {
 	_, err := c.Create(cgroupSpec)
 	return err
 }
+
+// Init is used to setup the cgroup root for ecs in the unified hierarchy, it fails when the
+// controllers needed by task cgroups aren't available on the host
+func (c *controlV2) Init() error {
+	seelog.Infof("Creating root ecs cgroup: %s", config.DefaultTaskCgroupPrefix)
+
+	cgroupSpec := &Spec{
+		Root:  config.DefaultTaskCgroupPrefix,
+		Specs: &specs.LinuxResources{},
+	}
+	_, err := c.Create(cgroupSpec)
+	return err
+}
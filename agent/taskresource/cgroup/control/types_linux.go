@@ -29,6 +29,8 @@ type Spec struct {
 	Specs *specs.LinuxResources
 }
 
+// Control is used to manage the cgroups of tasks, for either the v1 or the unified (v2)
+// hierarchy. The cgroup returned by Create is nil for the unified hierarchy.
 type Control interface {
 	Create(cgroupSpec *Spec) (cgroups.Cgroup, error)
 	Remove(cgroupPath string) error
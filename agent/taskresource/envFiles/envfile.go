@@ -16,6 +16,7 @@ package envFiles
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,8 +27,9 @@ import (
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
-	"github.com/aws/amazon-ecs-agent/agent/s3"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/amazon-ecs-agent/agent/utils/bufiowrapper"
@@ -53,11 +55,12 @@ const (
 	renameBackoffMultiple = 1.5
 	renameRetryAttempts   = 5
 
-	s3DownloadTimeout = 30 * time.Second
+	s3DownloadTimeout    = 30 * time.Second
+	httpsDownloadTimeout = 30 * time.Second
 )
 
 // EnvironmentFileResource represents envfile as a task resource
-// these environment files are retrieved from s3
+// these environment files are retrieved from s3, ssm, the host or https URLs
 type EnvironmentFileResource struct {
 	cluster       string
 	taskARN       string
@@ -71,6 +74,9 @@ type EnvironmentFileResource struct {
 	executionCredentialsID string
 	credentialsManager     credentials.Manager
 	s3ClientCreator        factory.S3ClientCreator
+	ssmClientCreator       ssmfactory.SSMClientCreator
+	httpClient             *http.Client
+	hostDir                string // directory that host env files are allowed from
 	ioutil                 ioutilwrapper.IOUtil
 	bufio                  bufiowrapper.Bufio
 
@@ -86,17 +92,20 @@ type EnvironmentFileResource struct {
 }
 
 // NewEnvironmentFileResource creates a new EnvironmentFileResource object
-func NewEnvironmentFileResource(cluster, taskARN, region, dataDir, containerName string, envfiles []apicontainer.EnvironmentFile,
+func NewEnvironmentFileResource(cluster, taskARN, region, dataDir, hostDir, containerName string, envfiles []apicontainer.EnvironmentFile,
 	credentialsManager credentials.Manager, executionCredentialsID string) (*EnvironmentFileResource, error) {
 	envfileResource := &EnvironmentFileResource{
 		cluster:                cluster,
 		taskARN:                taskARN,
 		region:                 region,
+		hostDir:                hostDir,
 		containerName:          containerName,
 		environmentFilesSource: envfiles,
 		ioutil:                 ioutilwrapper.NewIOUtil(),
 		bufio:                  bufiowrapper.NewBufio(),
 		s3ClientCreator:        factory.NewS3ClientCreator(),
+		ssmClientCreator:       ssmfactory.NewSSMClientCreator(),
+		httpClient:             httpclient.New(httpsDownloadTimeout, false),
 		executionCredentialsID: executionCredentialsID,
 		credentialsManager:     credentialsManager,
 	}
@@ -119,6 +128,8 @@ func (envfile *EnvironmentFileResource) Initialize(resourceFields *taskresource.
 	envfile.initStatusToTransition()
 	envfile.credentialsManager = resourceFields.CredentialsManager
 	envfile.s3ClientCreator = factory.NewS3ClientCreator()
+	envfile.ssmClientCreator = ssmfactory.NewSSMClientCreator()
+	envfile.httpClient = httpclient.New(httpsDownloadTimeout, false)
 	envfile.ioutil = ioutilwrapper.NewIOUtil()
 	envfile.bufio = bufiowrapper.NewBufio()
 	envfile.lock.Unlock()
@@ -289,26 +300,40 @@ func (envfile *EnvironmentFileResource) setTerminalReason(reason string) {
 }
 
 // Create performs resource creation. This retrieves env file contents concurrently
-// from s3 and writes them to disk
+// from their sources and writes them to disk
 func (envfile *EnvironmentFileResource) Create() error {
 	seelog.Debugf("Creating envfile resource.")
-	// make sure it has the task execution role
-	executionCredentials, ok := envfile.credentialsManager.GetTaskCredentials(envfile.executionCredentialsID)
-	if !ok {
-		err := errors.New("environment file resource: unable to find execution role credentials")
-		envfile.setTerminalReason(err.Error())
-		return err
+	sources := make([]envfileSource, len(envfile.environmentFilesSource))
+	requiresExecutionCredentials := false
+	for i, envfileObj := range envfile.environmentFilesSource {
+		source, err := envfile.getSource(envfileObj.Type)
+		if err != nil {
+			envfile.setTerminalReason(err.Error())
+			return err
+		}
+		sources[i] = source
+		requiresExecutionCredentials = requiresExecutionCredentials || source.requiresExecutionCredentials()
+	}
+
+	var iamCredentials credentials.IAMRoleCredentials
+	if requiresExecutionCredentials {
+		// make sure it has the task execution role
+		executionCredentials, ok := envfile.credentialsManager.GetTaskCredentials(envfile.executionCredentialsID)
+		if !ok {
+			err := errors.New("environment file resource: unable to find execution role credentials")
+			envfile.setTerminalReason(err.Error())
+			return err
+		}
+		iamCredentials = executionCredentials.GetIAMRoleCredentials()
 	}
 
 	var wg sync.WaitGroup
 	errorEvents := make(chan error, len(envfile.environmentFilesSource))
 
-	iamCredentials := executionCredentials.GetIAMRoleCredentials()
-	for _, envfileSource := range envfile.environmentFilesSource {
+	for i, envfileObj := range envfile.environmentFilesSource {
 		wg.Add(1)
-		// if we support types besides S3 ARN, we will need to add filtering before the below method is called
 		// call an additional go routine per env file
-		go envfile.downloadEnvfileFromS3(envfileSource.Value, iamCredentials, &wg, errorEvents)
+		go envfile.retrieveEnvfile(sources[i], envfileObj, iamCredentials, &wg, errorEvents)
 	}
 
 	wg.Wait()
@@ -332,53 +357,48 @@ var mkdirAll = os.MkdirAll
 
 // createEnvfileDirectory creates the directory that we will be writing the
 // envfile to - needs to be called for each different envfile
-func (envfile *EnvironmentFileResource) createEnvfileDirectory(bucket, key string) error {
-	// create directories to include bucket and key but not the actual resulting file
-	keyDir := filepath.Dir(key)
-	envfileDir := filepath.Join(envfile.resourceDir, bucket, keyDir)
+func (envfile *EnvironmentFileResource) createEnvfileDirectory(downloadPath string) error {
+	// create directories to include the path but not the actual resulting file
+	envfileDir := filepath.Dir(downloadPath)
 	err := mkdirAll(envfileDir, os.ModePerm)
 	if err != nil {
-		return errors.Wrapf(err, "unable to create envfiles directory with bucket %s", bucket)
+		return errors.Wrapf(err, "unable to create envfiles directory %s", envfileDir)
 	}
 
 	return nil
 }
 
-func (envfile *EnvironmentFileResource) downloadEnvfileFromS3(envFilePath string, iamCredentials credentials.IAMRoleCredentials,
-	wg *sync.WaitGroup, errorEvents chan error) {
+func (envfile *EnvironmentFileResource) retrieveEnvfile(source envfileSource, envfileObj apicontainer.EnvironmentFile,
+	iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
 	defer wg.Done()
 
-	bucket, key, err := s3.ParseS3ARN(envFilePath)
+	writeFunc, err := source.newWriter(envfileObj.Value, iamCredentials)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s, error: %v", envFilePath, err)
+		errorEvents <- err
 		return
 	}
 
-	s3Client, err := envfile.s3ClientCreator.NewS3ClientForBucket(bucket, envfile.region, iamCredentials)
+	localPath, err := source.localPath(envfileObj.Value)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to initialize s3 client for bucket %s, error: %v", bucket, err)
+		errorEvents <- err
 		return
 	}
+	// we save envfiles to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/${localPath}
+	downloadPath := filepath.Join(envfile.resourceDir, localPath)
 
-	err = envfile.createEnvfileDirectory(bucket, key)
+	err = envfile.createEnvfileDirectory(downloadPath)
 	if err != nil {
 		errorEvents <- fmt.Errorf("unable to initialize envfile resource directory, error: %v", err)
 		return
 	}
 
-	seelog.Debugf("Downloading envfile with bucket name %v and key name %v", bucket, key)
-	// we save envfiles to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/${s3bucketname}/${s3filename.env}
-	downloadPath := filepath.Join(envfile.resourceDir, bucket, key)
-	err = envfile.writeEnvFile(func(file oswrapper.File) error {
-		return s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client)
-	}, downloadPath)
-
+	err = envfile.writeEnvFile(writeFunc, downloadPath)
 	if err != nil {
-		errorEvents <- fmt.Errorf("unable to download env file with key %s from bucket %s, error: %v", key, bucket, err)
+		errorEvents <- fmt.Errorf("unable to retrieve %s env file, error: %v", envfileObj.Type, err)
 		return
 	}
 
-	seelog.Debugf("Downloaded envfile from s3 and saved to %s", downloadPath)
+	seelog.Debugf("Retrieved %s envfile and saved to %s", envfileObj.Type, downloadPath)
 }
 
 var rename = os.Rename
@@ -442,6 +462,7 @@ type environmentFileResourceJSON struct {
 	KnownStatus            *EnvironmentFileStatus         `json:"knownStatus"`
 	EnvironmentFilesSource []apicontainer.EnvironmentFile `json:"environmentFilesSource"`
 	ExecutionCredentialsID string                         `json:"executionCredentialsID"`
+	HostDir                string                         `json:"hostDir,omitempty"`
 }
 
 // MarshalJSON serializes the EnvironmentFileResource struct to JSON
@@ -466,6 +487,7 @@ func (envfile *EnvironmentFileResource) MarshalJSON() ([]byte, error) {
 		}(),
 		EnvironmentFilesSource: envfile.environmentFilesSource,
 		ExecutionCredentialsID: envfile.executionCredentialsID,
+		HostDir:                envfile.hostDir,
 	})
 
 }
@@ -497,6 +519,7 @@ func (envfile *EnvironmentFileResource) UnmarshalJSON(b []byte) error {
 	envfile.taskARN = envfileJson.TaskARN
 	envfile.containerName = envfileJson.ContainerName
 	envfile.executionCredentialsID = envfileJson.ExecutionCredentialsID
+	envfile.hostDir = envfileJson.HostDir
 
 	return nil
 }
@@ -512,13 +535,16 @@ func (envfile *EnvironmentFileResource) convertEnvfileToPath() ([]string, error)
 	var envfileLocations []string
 
 	for _, envfileObj := range envfile.environmentFilesSource {
-		bucket, key, err := s3.ParseS3ARN(envfileObj.Value)
+		source, err := envfile.getSource(envfileObj.Type)
+		if err != nil {
+			return nil, err
+		}
+		localPath, err := source.localPath(envfileObj.Value)
 		if err != nil {
-			seelog.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s", envfileObj.Value)
 			return nil, err
 		}
 
-		downloadPath := filepath.Join(envfile.resourceDir, bucket, key)
+		downloadPath := filepath.Join(envfile.resourceDir, localPath)
 		envfileLocations = append(envfileLocations, downloadPath)
 	}
 
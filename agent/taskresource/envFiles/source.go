@@ -0,0 +1,81 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvironmentFileTypeS3 is the type of environment files stored in S3, referenced by object ARN
+	EnvironmentFileTypeS3 = "s3"
+	// EnvironmentFileTypeSSM is the type of environment files stored in SSM parameters, referenced
+	// by parameter name or ARN
+	EnvironmentFileTypeSSM = "ssm"
+	// EnvironmentFileTypeHost is the type of environment files present on the host, referenced by
+	// absolute path under the directory allowed by ECS_ENV_FILES_HOST_DIR
+	EnvironmentFileTypeHost = "host"
+	// EnvironmentFileTypeHTTPS is the type of environment files served over HTTPS, referenced by
+	// URL with the SHA-256 checksum of the file as fragment: https://host/app.env#sha256=<hex>
+	EnvironmentFileTypeHTTPS = "https"
+
+	// sourceDirPrefix prefixes the directories of the sources other than S3. S3 bucket names can't
+	// contain underscores, so these never collide with the directory of a bucket.
+	sourceDirPrefix = "_"
+)
+
+// envfileSource retrieves the environment files of one type
+type envfileSource interface {
+	// localPath returns the path, relative to the resource directory, that the environment file
+	// referenced by value is saved at
+	localPath(value string) (string, error)
+	// newWriter validates value and returns a function that writes the content of the environment
+	// file to a file. It's called before the file is created, so that invalid values fail early.
+	newWriter(value string, iamCredentials credentials.IAMRoleCredentials) (func(file oswrapper.File) error, error)
+	// requiresExecutionCredentials returns true if the task execution role is needed to retrieve
+	// the environment files
+	requiresExecutionCredentials() bool
+}
+
+// getSource returns the source of the environment files of the given type
+func (envfile *EnvironmentFileResource) getSource(envfileType string) (envfileSource, error) {
+	envfile.lock.RLock()
+	defer envfile.lock.RUnlock()
+
+	switch envfileType {
+	case EnvironmentFileTypeS3:
+		return &s3Source{
+			region:          envfile.region,
+			s3ClientCreator: envfile.s3ClientCreator,
+		}, nil
+	case EnvironmentFileTypeSSM:
+		return &ssmSource{
+			region:           envfile.region,
+			ssmClientCreator: envfile.ssmClientCreator,
+		}, nil
+	case EnvironmentFileTypeHost:
+		return &hostSource{
+			allowedDir: envfile.hostDir,
+		}, nil
+	case EnvironmentFileTypeHTTPS:
+		return &httpsSource{
+			client: envfile.httpClient,
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported environment file type %q", envfileType)
+	}
+}
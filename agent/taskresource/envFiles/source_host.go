@@ -0,0 +1,98 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+
+	"github.com/pkg/errors"
+)
+
+const hostSourceDir = sourceDirPrefix + "host"
+
+// hostSource copies environment files already present on the host. Only files under allowedDir,
+// once symbolic links are resolved, can be used, so that tasks can't read arbitrary host files.
+type hostSource struct {
+	allowedDir string
+}
+
+// localPath returns _host/${hostpath}
+func (source *hostSource) localPath(value string) (string, error) {
+	if !filepath.IsAbs(value) {
+		return "", errors.Errorf("host environment file %s must be an absolute path", value)
+	}
+	cleaned := filepath.Clean(value)
+	return filepath.Join(hostSourceDir, strings.TrimPrefix(cleaned, filepath.VolumeName(cleaned))), nil
+}
+
+// resolve returns the path of the host file referenced by value, making sure it's in allowedDir
+func (source *hostSource) resolve(value string) (string, error) {
+	if source.allowedDir == "" {
+		return "", errors.New("host environment files are not allowed on this instance, ECS_ENV_FILES_HOST_DIR is not set")
+	}
+	if !filepath.IsAbs(value) {
+		return "", errors.Errorf("host environment file %s must be an absolute path", value)
+	}
+
+	allowedDir, err := filepath.EvalSymlinks(source.allowedDir)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to resolve the directory of host environment files")
+	}
+	path, err := filepath.EvalSymlinks(value)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(allowedDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("host environment file %s is not in %s", value, source.allowedDir)
+	}
+	return path, nil
+}
+
+func (source *hostSource) newWriter(value string, iamCredentials credentials.IAMRoleCredentials) (func(file oswrapper.File) error, error) {
+	path, err := source.resolve(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to use host environment file %s", value)
+	}
+
+	return func(file oswrapper.File) error {
+		hostFile, err := os.Open(path)
+		if err != nil {
+			return errors.Wrapf(err, "unable to open host env file %s", value)
+		}
+		defer hostFile.Close()
+
+		info, err := hostFile.Stat()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return errors.Errorf("host environment file %s is not a regular file", value)
+		}
+		if _, err = io.Copy(file, hostFile); err != nil {
+			return errors.Wrapf(err, "unable to copy host env file %s", value)
+		}
+		return nil
+	}, nil
+}
+
+func (source *hostSource) requiresExecutionCredentials() bool {
+	return false
+}
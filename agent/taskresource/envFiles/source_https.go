@@ -0,0 +1,132 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	httpsSourceDir = sourceDirPrefix + "https"
+
+	checksumFragmentPrefix = "sha256="
+
+	// maxHTTPSEnvfileSize bounds the size of the environment files downloaded over HTTPS
+	maxHTTPSEnvfileSize = 10 * 1024 * 1024
+)
+
+// httpsSource downloads environment files over HTTPS. The URL has to carry the SHA-256 checksum of
+// the file as fragment, which isn't sent to the server, and the download fails if it doesn't match.
+type httpsSource struct {
+	client *http.Client
+}
+
+// parseURL returns the URL to download and the expected checksum of the file referenced by value
+func parseURL(value string) (string, []byte, error) {
+	parsedURL, err := url.Parse(value)
+	if err != nil {
+		return "", nil, err
+	}
+	if parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return "", nil, errors.Errorf("%s is not an https URL", redactURL(parsedURL))
+	}
+	if !strings.HasPrefix(parsedURL.Fragment, checksumFragmentPrefix) {
+		return "", nil, errors.Errorf("%s has no #%s<checksum> fragment", redactURL(parsedURL), checksumFragmentPrefix)
+	}
+	checksum, err := hex.DecodeString(strings.TrimPrefix(parsedURL.Fragment, checksumFragmentPrefix))
+	if err != nil || len(checksum) != sha256.Size {
+		return "", nil, errors.Errorf("%s has an invalid sha256 checksum", redactURL(parsedURL))
+	}
+
+	parsedURL.Fragment = ""
+	return parsedURL.String(), checksum, nil
+}
+
+// redactURL returns the URL without its credentials, query and fragment, for use in errors. The query
+// may hold credentials too, such as the signature of a presigned URL.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = ""
+	redacted.Fragment = ""
+	return redacted.Redacted()
+}
+
+// localPath returns _https/${sha256 of the url}.env
+func (source *httpsSource) localPath(value string) (string, error) {
+	if _, _, err := parseURL(value); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(value))
+	return filepath.Join(httpsSourceDir, hex.EncodeToString(sum[:])+envFileExtension), nil
+}
+
+func (source *httpsSource) newWriter(value string, iamCredentials credentials.IAMRoleCredentials) (func(file oswrapper.File) error, error) {
+	envfileURL, checksum, err := parseURL(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse https URL specified in environmentFile")
+	}
+
+	return func(file oswrapper.File) error {
+		if err := source.download(envfileURL, checksum, file); err != nil {
+			parsedURL, _ := url.Parse(envfileURL)
+			return errors.Wrapf(err, "unable to download env file from %s", redactURL(parsedURL))
+		}
+		return nil
+	}, nil
+}
+
+// download writes the file at envfileURL to file, verifying its checksum
+func (source *httpsSource) download(envfileURL string, checksum []byte, file oswrapper.File) error {
+	resp, err := source.client.Get(envfileURL)
+	if err != nil {
+		// url.Error includes the URL, which is added to the error redacted instead
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(resp.Body, maxHTTPSEnvfileSize+1))
+	if err != nil {
+		return err
+	}
+	if n > maxHTTPSEnvfileSize {
+		return errors.Errorf("environment file is larger than %d bytes", maxHTTPSEnvfileSize)
+	}
+	if actual := hash.Sum(nil); !bytes.Equal(actual, checksum) {
+		return errors.Errorf("checksum mismatch: expected sha256 %x, got %x", checksum, actual)
+	}
+	return nil
+}
+
+func (source *httpsSource) requiresExecutionCredentials() bool {
+	return false
+}
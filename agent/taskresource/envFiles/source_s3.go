@@ -0,0 +1,66 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/s3"
+	"github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+
+	"github.com/cihub/seelog"
+)
+
+// s3Source retrieves environment files from S3 with the task execution role
+type s3Source struct {
+	region          string
+	s3ClientCreator factory.S3ClientCreator
+}
+
+// localPath returns ${s3bucketname}/${s3filename.env}
+func (source *s3Source) localPath(value string) (string, error) {
+	bucket, key, err := s3.ParseS3ARN(value)
+	if err != nil {
+		seelog.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s", value)
+		return "", err
+	}
+	return filepath.Join(bucket, key), nil
+}
+
+func (source *s3Source) newWriter(value string, iamCredentials credentials.IAMRoleCredentials) (func(file oswrapper.File) error, error) {
+	bucket, key, err := s3.ParseS3ARN(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s, error: %v", value, err)
+	}
+
+	s3Client, err := source.s3ClientCreator.NewS3ClientForBucket(bucket, source.region, iamCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize s3 client for bucket %s, error: %v", bucket, err)
+	}
+
+	return func(file oswrapper.File) error {
+		seelog.Debugf("Downloading envfile with bucket name %v and key name %v", bucket, key)
+		if err := s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client); err != nil {
+			return fmt.Errorf("unable to download env file with key %s from bucket %s, error: %v", key, bucket, err)
+		}
+		return nil
+	}, nil
+}
+
+func (source *s3Source) requiresExecutionCredentials() bool {
+	return true
+}
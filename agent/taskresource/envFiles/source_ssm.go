@@ -0,0 +1,98 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/ssm"
+	"github.com/aws/amazon-ecs-agent/agent/ssm/factory"
+	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+	"github.com/aws/aws-sdk-go/aws/arn"
+
+	"github.com/pkg/errors"
+)
+
+const ssmSourceDir = sourceDirPrefix + "ssm"
+
+// ssmSource retrieves environment files stored as SSM parameters with the task execution role.
+// Parameters are referenced by name, in the region of the instance, or by ARN.
+type ssmSource struct {
+	region           string
+	ssmClientCreator factory.SSMClientCreator
+}
+
+// parseSSMParameter returns the region and the name of the parameter referenced by value
+func (source *ssmSource) parseSSMParameter(value string) (string, string, error) {
+	if !arn.IsARN(value) {
+		if value == "" {
+			return "", "", errors.New("empty ssm parameter name")
+		}
+		return source.region, value, nil
+	}
+
+	parsedARN, err := arn.Parse(value)
+	if err != nil {
+		return "", "", err
+	}
+	if parsedARN.Service != "ssm" || !strings.HasPrefix(parsedARN.Resource, "parameter/") {
+		return "", "", errors.Errorf("%s is not an ssm parameter ARN", value)
+	}
+	return parsedARN.Region, value, nil
+}
+
+// localPath returns _ssm/${region}/${parametername}
+func (source *ssmSource) localPath(value string) (string, error) {
+	region, name, err := source.parseSSMParameter(value)
+	if err != nil {
+		return "", err
+	}
+	if arn.IsARN(name) {
+		parsedARN, _ := arn.Parse(name)
+		name = strings.TrimPrefix(parsedARN.Resource, "parameter")
+	}
+	// Parameter names can't contain "..", cleaning the path keeps it in the resource directory
+	return filepath.Join(ssmSourceDir, region, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(name))), nil
+}
+
+func (source *ssmSource) newWriter(value string, iamCredentials credentials.IAMRoleCredentials) (func(file oswrapper.File) error, error) {
+	region, name, err := source.parseSSMParameter(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ssm parameter specified in environmentFile %s, error: %v", value, err)
+	}
+
+	ssmClient := source.ssmClientCreator.NewSSMClient(region, iamCredentials)
+	return func(file oswrapper.File) error {
+		values, err := ssm.GetSecretsFromSSM([]string{name}, ssmClient)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve env file from ssm parameter %s in %s, error: %v", name, region, err)
+		}
+		if len(values) != 1 {
+			return errors.Errorf("unexpected number of parameters returned by ssm: %d", len(values))
+		}
+		for _, content := range values {
+			if _, err := file.Write([]byte(content)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func (source *ssmSource) requiresExecutionCredentials() bool {
+	return true
+}
@@ -0,0 +1,336 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package envFiles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	mock_ssm_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
+	mock_ssm "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envfileContent = "KEY=value\n"
+
+// newSourceTestEnvfileResource returns a resource writing env files to a temporary directory
+func newSourceTestEnvfileResource(t *testing.T, envfiles []container.EnvironmentFile,
+	mockCredentialsManager *mock_credentials.MockManager) *EnvironmentFileResource {
+	return &EnvironmentFileResource{
+		cluster:                cluster,
+		taskARN:                taskARN,
+		region:                 region,
+		resourceDir:            t.TempDir(),
+		environmentFilesSource: envfiles,
+		executionCredentialsID: executionCredentialsID,
+		credentialsManager:     mockCredentialsManager,
+		ioutil:                 ioutilwrapper.NewIOUtil(),
+	}
+}
+
+func readCreatedEnvfile(t *testing.T, envfileResource *EnvironmentFileResource) string {
+	paths, err := envfileResource.convertEnvfileToPath()
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	content, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	return string(content)
+}
+
+func checksumFragment(content string) string {
+	checksum := sha256.Sum256([]byte(content))
+	return "#sha256=" + hex.EncodeToString(checksum[:])
+}
+
+func TestCreateWithSSMEnvfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+	mockSSMClientCreator := mock_ssm_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	parameterARN := "arn:aws:ssm:us-east-1:123456789012:parameter/app/envfile"
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(parameterARN, EnvironmentFileTypeSSM),
+	}, mockCredentialsManager)
+	envfileResource.ssmClientCreator = mockSSMClientCreator
+	creds := credentials.TaskIAMRoleCredentials{
+		ARN: iamRoleARN,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			AccessKeyID:     accessKeyId,
+			SecretAccessKey: secretAccessKey,
+		},
+	}
+
+	gomock.InOrder(
+		mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true),
+		mockSSMClientCreator.EXPECT().NewSSMClient("us-east-1", creds.IAMRoleCredentials).Return(mockSSMClient),
+		mockSSMClient.EXPECT().GetParameters(gomock.Any()).Do(func(input *ssm.GetParametersInput) {
+			assert.Equal(t, []*string{aws.String(parameterARN)}, input.Names)
+		}).Return(&ssm.GetParametersOutput{
+			Parameters: []*ssm.Parameter{
+				{Name: aws.String(parameterARN), Value: aws.String(envfileContent)},
+			},
+		}, nil),
+	)
+
+	require.NoError(t, envfileResource.Create())
+	assert.Equal(t, envfileContent, readCreatedEnvfile(t, envfileResource))
+}
+
+func TestCreateWithInvalidSSMARN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile("arn:aws:s3:::bucket/key.env", EnvironmentFileTypeSSM),
+	}, mockCredentialsManager)
+	mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(credentials.TaskIAMRoleCredentials{}, true)
+
+	assert.Error(t, envfileResource.Create())
+	assert.NotEmpty(t, envfileResource.GetTerminalReason())
+}
+
+func TestCreateWithHostEnvfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// no expectations, host env files don't need the task execution role
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	hostDir := t.TempDir()
+	hostFile := filepath.Join(hostDir, "app.env")
+	require.NoError(t, os.WriteFile(hostFile, []byte(envfileContent), 0600))
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(hostFile, EnvironmentFileTypeHost),
+	}, mockCredentialsManager)
+	envfileResource.hostDir = hostDir
+
+	require.NoError(t, envfileResource.Create())
+	assert.Equal(t, envfileContent, readCreatedEnvfile(t, envfileResource))
+}
+
+func TestCreateWithHostEnvfileNotAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	hostDir := t.TempDir()
+	outsideFile := filepath.Join(t.TempDir(), "app.env")
+	require.NoError(t, os.WriteFile(outsideFile, []byte(envfileContent), 0600))
+
+	testCases := []struct {
+		name    string
+		hostDir string
+		value   string
+	}{
+		{
+			name:    "host dir not configured",
+			hostDir: "",
+			value:   outsideFile,
+		},
+		{
+			name:    "file outside of host dir",
+			hostDir: hostDir,
+			value:   outsideFile,
+		},
+		{
+			name:    "relative path",
+			hostDir: hostDir,
+			value:   "app.env",
+		},
+		{
+			name:    "path escaping host dir",
+			hostDir: hostDir,
+			value:   filepath.Join(hostDir, "..", filepath.Base(filepath.Dir(outsideFile)), "app.env"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+				sampleEnvironmentFile(tc.value, EnvironmentFileTypeHost),
+			}, mockCredentialsManager)
+			envfileResource.hostDir = tc.hostDir
+
+			assert.Error(t, envfileResource.Create())
+			assert.NotEmpty(t, envfileResource.GetTerminalReason())
+		})
+	}
+}
+
+func TestCreateWithHostEnvfileSymlinkOutsideHostDir(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	hostDir := t.TempDir()
+	outsideFile := filepath.Join(t.TempDir(), "app.env")
+	require.NoError(t, os.WriteFile(outsideFile, []byte(envfileContent), 0600))
+	symlink := filepath.Join(hostDir, "app.env")
+	if err := os.Symlink(outsideFile, symlink); err != nil {
+		t.Skipf("unable to create symlink: %v", err)
+	}
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(symlink, EnvironmentFileTypeHost),
+	}, mockCredentialsManager)
+	envfileResource.hostDir = hostDir
+
+	assert.Error(t, envfileResource.Create())
+}
+
+func TestCreateWithHTTPSEnvfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app.env", r.URL.Path)
+		fmt.Fprint(w, envfileContent)
+	}))
+	defer server.Close()
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(server.URL+"/app.env"+checksumFragment(envfileContent), EnvironmentFileTypeHTTPS),
+	}, mockCredentialsManager)
+	envfileResource.httpClient = server.Client()
+
+	require.NoError(t, envfileResource.Create())
+	assert.Equal(t, envfileContent, readCreatedEnvfile(t, envfileResource))
+}
+
+func TestCreateWithHTTPSEnvfileErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app.env" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, envfileContent)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name  string
+		value string
+	}{
+		{
+			name:  "checksum mismatch",
+			value: server.URL + "/app.env" + checksumFragment("OTHER=value\n"),
+		},
+		{
+			name:  "missing checksum",
+			value: server.URL + "/app.env",
+		},
+		{
+			name:  "invalid checksum",
+			value: server.URL + "/app.env#sha256=abc",
+		},
+		{
+			name:  "not https",
+			value: "http://example.com/app.env" + checksumFragment(envfileContent),
+		},
+		{
+			name:  "not found",
+			value: server.URL + "/missing.env" + checksumFragment(envfileContent),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+				sampleEnvironmentFile(tc.value, EnvironmentFileTypeHTTPS),
+			}, mockCredentialsManager)
+			envfileResource.httpClient = server.Client()
+
+			assert.Error(t, envfileResource.Create())
+			assert.NotEmpty(t, envfileResource.GetTerminalReason())
+		})
+	}
+}
+
+func TestHTTPSEnvfileErrorRedactsQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(server.URL+"/app.env?X-Amz-Signature=secret"+checksumFragment(envfileContent),
+			EnvironmentFileTypeHTTPS),
+	}, mockCredentialsManager)
+	envfileResource.httpClient = server.Client()
+
+	err := envfileResource.Create()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestCreateWithUnsupportedEnvfileType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	envfileResource := newSourceTestEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile("ftp://example.com/app.env", "ftp"),
+	}, mockCredentialsManager)
+
+	assert.Error(t, envfileResource.Create())
+	assert.NotEmpty(t, envfileResource.GetTerminalReason())
+}
+
+func TestEnvfileLocalPaths(t *testing.T) {
+	envfileResource := &EnvironmentFileResource{
+		region:      region,
+		resourceDir: resourceDir,
+		environmentFilesSource: []container.EnvironmentFile{
+			sampleEnvironmentFile(fmt.Sprintf("arn:aws:s3:::%s/%s", s3Bucket, s3Key), EnvironmentFileTypeS3),
+			sampleEnvironmentFile("/app/envfile", EnvironmentFileTypeSSM),
+			sampleEnvironmentFile("arn:aws:ssm:us-east-1:123456789012:parameter/app/envfile", EnvironmentFileTypeSSM),
+			sampleEnvironmentFile("https://example.com/app.env"+checksumFragment(envfileContent), EnvironmentFileTypeHTTPS),
+		},
+	}
+
+	paths, err := envfileResource.convertEnvfileToPath()
+	require.NoError(t, err)
+	require.Len(t, paths, 4)
+	assert.Equal(t, filepath.Join(resourceDir, s3Bucket, s3Key), paths[0])
+	assert.Equal(t, filepath.Join(resourceDir, "_ssm", region, "app", "envfile"), paths[1])
+	assert.Equal(t, filepath.Join(resourceDir, "_ssm", "us-east-1", "app", "envfile"), paths[2])
+	assert.Equal(t, resourceDir, filepath.Dir(filepath.Dir(paths[3])))
+	assert.Equal(t, "_https", filepath.Base(filepath.Dir(paths[3])))
+}
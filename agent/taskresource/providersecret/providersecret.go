@@ -0,0 +1,452 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providersecret
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretprovider"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+)
+
+const (
+	// ResourceName is the name of the providersecret resource
+	ResourceName = "providersecret"
+)
+
+// ProviderSecretResource represents secrets as a task resource.
+// The secrets are stored in secret providers configured on the host, such as a local
+// encrypted secrets directory or a Vault compatible server.
+type ProviderSecretResource struct {
+	taskARN             string
+	createdAt           time.Time
+	desiredStatusUnsafe resourcestatus.ResourceStatus
+	knownStatusUnsafe   resourcestatus.ResourceStatus
+	// appliedStatus is the status that has been "applied" (e.g., we've called some
+	// operation such as 'Create' on the resource) but we don't yet know that the
+	// application was successful, which may then change the known status. This is
+	// used while progressing resource states in progressTask() of task manager
+	appliedStatus                      resourcestatus.ResourceStatus
+	resourceStatusToTransitionFunction map[resourcestatus.ResourceStatus]func() error
+
+	// required for store provider secrets value, key is the name of the provider
+	requiredSecrets map[string][]apicontainer.Secret
+	// map to store secret values, key is a combination of provider and valueFrom
+	secretData map[string]string
+
+	// providers are the secret providers configured on the host
+	providers secretprovider.Providers
+
+	// terminalReason should be set for resource creation failures. This ensures
+	// the resource object carries some context for why provisioning failed.
+	terminalReason     string
+	terminalReasonOnce sync.Once
+
+	// lock is used for fields that are accessed and updated concurrently
+	lock sync.RWMutex
+}
+
+// NewProviderSecretResource creates a new ProviderSecretResource object
+func NewProviderSecretResource(taskARN string,
+	providerSecrets map[string][]apicontainer.Secret,
+	providers secretprovider.Providers) *ProviderSecretResource {
+
+	s := &ProviderSecretResource{
+		taskARN:         taskARN,
+		requiredSecrets: providerSecrets,
+		providers:       providers,
+	}
+
+	s.initStatusToTransition()
+	return s
+}
+
+func (secret *ProviderSecretResource) initStatusToTransition() {
+	resourceStatusToTransitionFunction := map[resourcestatus.ResourceStatus]func() error{
+		resourcestatus.ResourceStatus(ProviderSecretCreated): secret.Create,
+	}
+	secret.resourceStatusToTransitionFunction = resourceStatusToTransitionFunction
+}
+
+func (secret *ProviderSecretResource) setTerminalReason(reason string) {
+	secret.terminalReasonOnce.Do(func() {
+		seelog.Infof("provider secret resource: setting terminal reason for provider secret resource in task: [%s]", secret.taskARN)
+		secret.terminalReason = reason
+	})
+}
+
+// GetTerminalReason returns an error string to propagate up through to task
+// state change messages
+func (secret *ProviderSecretResource) GetTerminalReason() string {
+	return secret.terminalReason
+}
+
+// SetDesiredStatus safely sets the desired status of the resource
+func (secret *ProviderSecretResource) SetDesiredStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.desiredStatusUnsafe = status
+}
+
+// GetDesiredStatus safely returns the desired status of the task
+func (secret *ProviderSecretResource) GetDesiredStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe
+}
+
+// GetName safely returns the name of the resource
+func (secret *ProviderSecretResource) GetName() string {
+	return ResourceName
+}
+
+// DesiredTerminal returns true if the secret's desired status is REMOVED
+func (secret *ProviderSecretResource) DesiredTerminal() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe == resourcestatus.ResourceStatus(ProviderSecretRemoved)
+}
+
+// KnownCreated returns true if the secret's known status is CREATED
+func (secret *ProviderSecretResource) KnownCreated() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe == resourcestatus.ResourceStatus(ProviderSecretCreated)
+}
+
+// TerminalStatus returns the last transition state of the resource
+func (secret *ProviderSecretResource) TerminalStatus() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(ProviderSecretRemoved)
+}
+
+// NextKnownState returns the state that the resource should
+// progress to based on its `KnownState`.
+func (secret *ProviderSecretResource) NextKnownState() resourcestatus.ResourceStatus {
+	return secret.GetKnownStatus() + 1
+}
+
+// ApplyTransition calls the function required to move to the specified status
+func (secret *ProviderSecretResource) ApplyTransition(nextState resourcestatus.ResourceStatus) error {
+	transitionFunc, ok := secret.resourceStatusToTransitionFunction[nextState]
+	if !ok {
+		return errors.Errorf("resource [%s]: transition to %s impossible", secret.GetName(),
+			secret.StatusString(nextState))
+	}
+	return transitionFunc()
+}
+
+// SteadyState returns the transition state of the resource defined as "ready"
+func (secret *ProviderSecretResource) SteadyState() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(ProviderSecretCreated)
+}
+
+// SetKnownStatus safely sets the currently known status of the resource
+func (secret *ProviderSecretResource) SetKnownStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.knownStatusUnsafe = status
+	secret.updateAppliedStatusUnsafe(status)
+}
+
+// updateAppliedStatusUnsafe updates the resource transitioning status
+func (secret *ProviderSecretResource) updateAppliedStatusUnsafe(knownStatus resourcestatus.ResourceStatus) {
+	if secret.appliedStatus == resourcestatus.ResourceStatus(ProviderSecretStatusNone) {
+		return
+	}
+
+	// Check if the resource transition has already finished
+	if secret.appliedStatus <= knownStatus {
+		secret.appliedStatus = resourcestatus.ResourceStatus(ProviderSecretStatusNone)
+	}
+}
+
+// SetAppliedStatus sets the applied status of resource and returns whether
+// the resource is already in a transition
+func (secret *ProviderSecretResource) SetAppliedStatus(status resourcestatus.ResourceStatus) bool {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.appliedStatus != resourcestatus.ResourceStatus(ProviderSecretStatusNone) {
+		// return false to indicate the set operation failed
+		return false
+	}
+
+	secret.appliedStatus = status
+	return true
+}
+
+// GetKnownStatus safely returns the currently known status of the task
+func (secret *ProviderSecretResource) GetKnownStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe
+}
+
+// StatusString returns the string of the provider secret resource status
+func (secret *ProviderSecretResource) StatusString(status resourcestatus.ResourceStatus) string {
+	return ProviderSecretStatus(status).String()
+}
+
+// SetCreatedAt sets the timestamp for resource's creation time
+func (secret *ProviderSecretResource) SetCreatedAt(createdAt time.Time) {
+	if createdAt.IsZero() {
+		return
+	}
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.createdAt = createdAt
+}
+
+// GetCreatedAt sets the timestamp for resource's creation time
+func (secret *ProviderSecretResource) GetCreatedAt() time.Time {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.createdAt
+}
+
+// Create retrieves the secret values from the providers configured on the host
+func (secret *ProviderSecretResource) Create() error {
+	seelog.Infof("provider secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
+	values, err := secret.retrieveSecretValues(true)
+	if err != nil {
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	secret.updateCachedSecretValues(values)
+	return nil
+}
+
+// Refresh fetches the values of all the secrets of the task again, bypassing the cache, and
+// updates the cache with them. It returns the cache keys of the secrets whose value changed.
+func (secret *ProviderSecretResource) Refresh() ([]string, error) {
+	values, err := secret.retrieveSecretValues(false)
+	if err != nil {
+		return nil, err
+	}
+	return secret.updateCachedSecretValues(values), nil
+}
+
+// retrieveSecretValues retrieves the values of the secrets of the task, skipping the ones
+// already cached if useCache is set
+func (secret *ProviderSecretResource) retrieveSecretValues(useCache bool) (map[string]string, error) {
+	values := make(map[string]string)
+	for providerName, secrets := range secret.getRequiredSecrets() {
+		provider, err := secret.getProviders().Get(providerName)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range secrets {
+			secretKey := s.GetSecretResourceCacheKey()
+			if _, ok := values[secretKey]; ok {
+				continue
+			}
+			if _, ok := secret.GetCachedSecretValue(secretKey); ok && useCache {
+				continue
+			}
+			value, err := provider.GetSecretValue(context.Background(), s.ValueFrom)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fetching secret data from %s secret provider", providerName)
+			}
+			values[secretKey] = value
+		}
+	}
+	return values, nil
+}
+
+// getRequiredSecrets returns the requiredSecrets field of providersecret task resource
+func (secret *ProviderSecretResource) getRequiredSecrets() map[string][]apicontainer.Secret {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.requiredSecrets
+}
+
+// getProviders returns the secret providers of the host
+func (secret *ProviderSecretResource) getProviders() secretprovider.Providers {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.providers
+}
+
+// Cleanup removes the secret value created for the task
+func (secret *ProviderSecretResource) Cleanup() error {
+	secret.clearProviderSecretValue()
+	return nil
+}
+
+// clearProviderSecretValue cycles through the collection of secret value data and
+// removes them from the task
+func (secret *ProviderSecretResource) clearProviderSecretValue() {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	for key := range secret.secretData {
+		delete(secret.secretData, key)
+	}
+}
+
+// GetCachedSecretValue retrieves the secret value from secretData field
+func (secret *ProviderSecretResource) GetCachedSecretValue(secretKey string) (string, bool) {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	s, ok := secret.secretData[secretKey]
+	return s, ok
+}
+
+// updateCachedSecretValues caches the given secret values and returns the keys of the
+// previously cached values that changed
+func (secret *ProviderSecretResource) updateCachedSecretValues(values map[string]string) []string {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	var changed []string
+	for secretKey, secretValue := range values {
+		if cachedValue, ok := secret.secretData[secretKey]; ok && cachedValue != secretValue {
+			changed = append(changed, secretKey)
+		}
+		secret.secretData[secretKey] = secretValue
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// SetCachedSecretValue set the secret value in the secretData field given the key and value
+func (secret *ProviderSecretResource) SetCachedSecretValue(secretKey string, secretValue string) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	secret.secretData[secretKey] = secretValue
+}
+
+func (secret *ProviderSecretResource) Initialize(resourceFields *taskresource.ResourceFields,
+	taskKnownStatus status.TaskStatus,
+	taskDesiredStatus status.TaskStatus) {
+	secret.initStatusToTransition()
+	secret.lock.Lock()
+	secret.providers = resourceFields.SecretProviders
+	secret.lock.Unlock()
+
+	// if task hasn't turn to 'created' status, and it's desire status is 'running'
+	// the resource status needs to be reset to 'NONE' status so the secret value
+	// will be retrieved again
+	if taskKnownStatus < status.TaskCreated &&
+		taskDesiredStatus <= status.TaskRunning {
+		secret.SetKnownStatus(resourcestatus.ResourceStatusNone)
+	}
+}
+
+type ProviderSecretResourceJSON struct {
+	TaskARN         string                           `json:"taskARN"`
+	CreatedAt       *time.Time                       `json:"createdAt,omitempty"`
+	DesiredStatus   *ProviderSecretStatus            `json:"desiredStatus"`
+	KnownStatus     *ProviderSecretStatus            `json:"knownStatus"`
+	RequiredSecrets map[string][]apicontainer.Secret `json:"secretResources"`
+}
+
+// MarshalJSON serialises the ProviderSecretResource struct to JSON
+func (secret *ProviderSecretResource) MarshalJSON() ([]byte, error) {
+	if secret == nil {
+		return nil, errors.New("providersecret resource is nil")
+	}
+	createdAt := secret.GetCreatedAt()
+	return json.Marshal(ProviderSecretResourceJSON{
+		TaskARN:   secret.taskARN,
+		CreatedAt: &createdAt,
+		DesiredStatus: func() *ProviderSecretStatus {
+			desiredState := secret.GetDesiredStatus()
+			s := ProviderSecretStatus(desiredState)
+			return &s
+		}(),
+		KnownStatus: func() *ProviderSecretStatus {
+			knownState := secret.GetKnownStatus()
+			s := ProviderSecretStatus(knownState)
+			return &s
+		}(),
+		RequiredSecrets: secret.getRequiredSecrets(),
+	})
+}
+
+// UnmarshalJSON deserialises the raw JSON to a ProviderSecretResource struct
+func (secret *ProviderSecretResource) UnmarshalJSON(b []byte) error {
+	temp := ProviderSecretResourceJSON{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return err
+	}
+
+	if temp.DesiredStatus != nil {
+		secret.SetDesiredStatus(resourcestatus.ResourceStatus(*temp.DesiredStatus))
+	}
+	if temp.KnownStatus != nil {
+		secret.SetKnownStatus(resourcestatus.ResourceStatus(*temp.KnownStatus))
+	}
+	if temp.CreatedAt != nil && !temp.CreatedAt.IsZero() {
+		secret.SetCreatedAt(*temp.CreatedAt)
+	}
+	if temp.RequiredSecrets != nil {
+		secret.requiredSecrets = temp.RequiredSecrets
+	}
+	secret.taskARN = temp.TaskARN
+
+	return nil
+}
+
+// GetAppliedStatus safely returns the currently applied status of the resource
+func (secret *ProviderSecretResource) GetAppliedStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.appliedStatus
+}
+
+func (secret *ProviderSecretResource) DependOnTaskNetwork() bool {
+	return false
+}
+
+func (secret *ProviderSecretResource) BuildContainerDependency(containerName string, satisfied apicontainerstatus.ContainerStatus,
+	dependent resourcestatus.ResourceStatus) {
+}
+
+func (secret *ProviderSecretResource) GetContainerDependencies(dependent resourcestatus.ResourceStatus) []apicontainer.ContainerDependency {
+	return nil
+}
@@ -0,0 +1,188 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package providersecret
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretprovider"
+	mock_secretprovider "github.com/aws/amazon-ecs-agent/agent/taskresource/secretprovider/mocks"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	taskARN        = "task1"
+	localValueFrom = "db/password"
+	vaultValueFrom = "secret/app#api_key"
+	localSecretKey = "local:db/password"
+	vaultSecretKey = "vault:secret/app#api_key"
+)
+
+func requiredProviderSecrets() map[string][]apicontainer.Secret {
+	return map[string][]apicontainer.Secret{
+		apicontainer.SecretProviderLocal: {
+			{
+				Name:      "DB_PASSWORD",
+				ValueFrom: localValueFrom,
+				Provider:  apicontainer.SecretProviderLocal,
+			},
+		},
+		apicontainer.SecretProviderVault: {
+			{
+				Name:      "API_KEY",
+				ValueFrom: vaultValueFrom,
+				Provider:  apicontainer.SecretProviderVault,
+			},
+		},
+	}
+}
+
+func TestCreateAndGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	localProvider := mock_secretprovider.NewMockProvider(ctrl)
+	vaultProvider := mock_secretprovider.NewMockProvider(ctrl)
+	localProvider.EXPECT().GetSecretValue(gomock.Any(), localValueFrom).Return("hunter2", nil)
+	vaultProvider.EXPECT().GetSecretValue(gomock.Any(), vaultValueFrom).Return("key", nil)
+
+	secret := NewProviderSecretResource(taskARN, requiredProviderSecrets(), secretprovider.Providers{
+		apicontainer.SecretProviderLocal: localProvider,
+		apicontainer.SecretProviderVault: vaultProvider,
+	})
+	require.NoError(t, secret.Create())
+
+	value, ok := secret.GetCachedSecretValue(localSecretKey)
+	assert.True(t, ok)
+	assert.Equal(t, "hunter2", value)
+	value, ok = secret.GetCachedSecretValue(vaultSecretKey)
+	assert.True(t, ok)
+	assert.Equal(t, "key", value)
+}
+
+func TestCreateProviderNotConfigured(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	localProvider := mock_secretprovider.NewMockProvider(ctrl)
+	localProvider.EXPECT().GetSecretValue(gomock.Any(), localValueFrom).Return("hunter2", nil).AnyTimes()
+
+	secret := NewProviderSecretResource(taskARN, requiredProviderSecrets(), secretprovider.Providers{
+		apicontainer.SecretProviderLocal: localProvider,
+	})
+	assert.Error(t, secret.Create())
+	assert.NotEmpty(t, secret.GetTerminalReason())
+}
+
+func TestCreateProviderError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	localProvider := mock_secretprovider.NewMockProvider(ctrl)
+	localProvider.EXPECT().GetSecretValue(gomock.Any(), localValueFrom).Return("", errors.New("error"))
+
+	secret := NewProviderSecretResource(taskARN, map[string][]apicontainer.Secret{
+		apicontainer.SecretProviderLocal: requiredProviderSecrets()[apicontainer.SecretProviderLocal],
+	}, secretprovider.Providers{
+		apicontainer.SecretProviderLocal: localProvider,
+	})
+	assert.Error(t, secret.Create())
+	assert.NotEmpty(t, secret.GetTerminalReason())
+}
+
+func TestCreateUsesCachedValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	localProvider := mock_secretprovider.NewMockProvider(ctrl)
+	secret := NewProviderSecretResource(taskARN, map[string][]apicontainer.Secret{
+		apicontainer.SecretProviderLocal: requiredProviderSecrets()[apicontainer.SecretProviderLocal],
+	}, secretprovider.Providers{
+		apicontainer.SecretProviderLocal: localProvider,
+	})
+	secret.SetCachedSecretValue(localSecretKey, "hunter2")
+	require.NoError(t, secret.Create())
+}
+
+func TestRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	localProvider := mock_secretprovider.NewMockProvider(ctrl)
+	vaultProvider := mock_secretprovider.NewMockProvider(ctrl)
+	localProvider.EXPECT().GetSecretValue(gomock.Any(), localValueFrom).Return("new", nil)
+	vaultProvider.EXPECT().GetSecretValue(gomock.Any(), vaultValueFrom).Return("key", nil)
+
+	secret := NewProviderSecretResource(taskARN, requiredProviderSecrets(), secretprovider.Providers{
+		apicontainer.SecretProviderLocal: localProvider,
+		apicontainer.SecretProviderVault: vaultProvider,
+	})
+	secret.SetCachedSecretValue(localSecretKey, "old")
+	secret.SetCachedSecretValue(vaultSecretKey, "key")
+
+	changed, err := secret.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{localSecretKey}, changed)
+	value, _ := secret.GetCachedSecretValue(localSecretKey)
+	assert.Equal(t, "new", value)
+}
+
+func TestMarshalUnmarshalJSON(t *testing.T) {
+	secretIn := NewProviderSecretResource(taskARN, requiredProviderSecrets(), nil)
+	secretIn.SetDesiredStatus(resourcestatus.ResourceStatus(ProviderSecretCreated))
+	secretIn.SetKnownStatus(resourcestatus.ResourceStatus(ProviderSecretStatusNone))
+	secretIn.SetCachedSecretValue(localSecretKey, "hunter2")
+
+	bytes, err := json.Marshal(secretIn)
+	require.NoError(t, err)
+	assert.NotContains(t, string(bytes), "hunter2")
+
+	secretOut := &ProviderSecretResource{}
+	require.NoError(t, json.Unmarshal(bytes, secretOut))
+	assert.Equal(t, secretIn.taskARN, secretOut.taskARN)
+	assert.Equal(t, secretIn.GetDesiredStatus(), secretOut.GetDesiredStatus())
+	assert.Equal(t, secretIn.GetKnownStatus(), secretOut.GetKnownStatus())
+	assert.Equal(t, secretIn.getRequiredSecrets(), secretOut.getRequiredSecrets())
+}
+
+func TestInitialize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers := secretprovider.Providers{
+		apicontainer.SecretProviderLocal: mock_secretprovider.NewMockProvider(ctrl),
+	}
+	secret := &ProviderSecretResource{
+		knownStatusUnsafe:   resourcestatus.ResourceStatus(ProviderSecretCreated),
+		desiredStatusUnsafe: resourcestatus.ResourceStatus(ProviderSecretCreated),
+	}
+	secret.Initialize(&taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			SecretProviders: providers,
+		},
+	}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+	assert.Equal(t, resourcestatus.ResourceStatusNone, secret.GetKnownStatus())
+	assert.Equal(t, providers, secret.getProviders())
+	assert.NotNil(t, secret.resourceStatusToTransitionFunction)
+}
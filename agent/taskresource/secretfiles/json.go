@@ -0,0 +1,87 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfiles
+
+import (
+	"encoding/json"
+	"time"
+
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/pkg/errors"
+)
+
+type secretFilesResourceJSON struct {
+	TaskARN        string
+	ResourceDir    string
+	TerminalReason string
+
+	CreatedAt     time.Time
+	DesiredStatus *SecretFilesStatus
+	KnownStatus   *SecretFilesStatus
+	AppliedStatus *SecretFilesStatus
+}
+
+// MarshalJSON marshals a SecretFilesResource object into bytes of json. The secret values are
+// never persisted, they only live in the tmpfs directory.
+func (secretFiles *SecretFilesResource) MarshalJSON() ([]byte, error) {
+	if secretFiles == nil {
+		return nil, errors.New("secret files resource is nil")
+	}
+
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	desiredStatus := SecretFilesStatus(secretFiles.desiredStatusUnsafe)
+	knownStatus := SecretFilesStatus(secretFiles.knownStatusUnsafe)
+	appliedStatus := SecretFilesStatus(secretFiles.appliedStatusUnsafe)
+	return json.Marshal(secretFilesResourceJSON{
+		TaskARN:        secretFiles.taskARN,
+		ResourceDir:    secretFiles.resourceDir,
+		TerminalReason: secretFiles.terminalReasonUnsafe,
+		CreatedAt:      secretFiles.createdAtUnsafe,
+		DesiredStatus:  &desiredStatus,
+		KnownStatus:    &knownStatus,
+		AppliedStatus:  &appliedStatus,
+	})
+}
+
+// UnmarshalJSON unmarshals bytes of json into a SecretFilesResource object.
+func (secretFiles *SecretFilesResource) UnmarshalJSON(b []byte) error {
+	if secretFiles == nil {
+		return errors.New("secret files resource is nil")
+	}
+
+	temp := secretFilesResourceJSON{}
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return err
+	}
+
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	secretFiles.taskARN = temp.TaskARN
+	secretFiles.resourceDir = temp.ResourceDir
+	secretFiles.terminalReasonUnsafe = temp.TerminalReason
+	secretFiles.createdAtUnsafe = temp.CreatedAt
+	if temp.DesiredStatus != nil {
+		secretFiles.desiredStatusUnsafe = resourcestatus.ResourceStatus(*temp.DesiredStatus)
+	}
+	if temp.KnownStatus != nil {
+		secretFiles.knownStatusUnsafe = resourcestatus.ResourceStatus(*temp.KnownStatus)
+	}
+	if temp.AppliedStatus != nil {
+		secretFiles.appliedStatusUnsafe = resourcestatus.ResourceStatus(*temp.AppliedStatus)
+	}
+	return nil
+}
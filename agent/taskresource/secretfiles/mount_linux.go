@@ -0,0 +1,126 @@
+//go:build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfiles
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	tmpfsFlags   = unix.MS_NOEXEC | unix.MS_NOSUID | unix.MS_NODEV
+	tmpfsOptions = "mode=0711"
+
+	mountInfoPath = "/proc/self/mountinfo"
+)
+
+// mountTmpfs mounts a fresh tmpfs at dir so that secret values never reach the disk
+func mountTmpfs(dir string) error {
+	return unix.Mount("tmpfs", dir, "tmpfs", tmpfsFlags, tmpfsOptions)
+}
+
+// unmountTmpfs lazily unmounts the tmpfs at dir. A directory that is not a mount point
+// is not an error, so that cleanup is idempotent.
+func unmountTmpfs(dir string) error {
+	err := unix.Unmount(dir, unix.MNT_DETACH)
+	if err == unix.EINVAL || err == unix.ENOENT {
+		return nil
+	}
+	return err
+}
+
+// mountIsShared returns whether the mount that dir is on has shared propagation, along with its
+// mount point. Docker only sees a tmpfs mounted by the agent from within its container when the
+// data directory is bind mounted into the agent container with shared propagation.
+func mountIsShared(dir string) (bool, string, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, "", err
+	}
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return false, "", err
+	}
+	defer file.Close()
+	return parseMountPropagation(file, dir)
+}
+
+// parseMountPropagation finds the mount that dir is on in the mountinfo content read from r, i.e.
+// the last mount whose mount point is the longest prefix of dir, and returns whether it's shared.
+// See proc(5) for the format of mountinfo.
+func parseMountPropagation(r io.Reader, dir string) (bool, string, error) {
+	found, shared := false, false
+	mountPoint := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		point := unescapeMountInfo(fields[4])
+		if !isPathPrefix(point, dir) || (found && len(point) < len(mountPoint)) {
+			continue
+		}
+		found, shared, mountPoint = true, false, point
+		// The optional fields are terminated by a single hyphen
+		for _, field := range fields[6:] {
+			if field == "-" {
+				break
+			}
+			if strings.HasPrefix(field, "shared:") {
+				shared = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, "", err
+	}
+	if !found {
+		return false, "", errors.Errorf("no mount found for %s", dir)
+	}
+	return shared, mountPoint, nil
+}
+
+func isPathPrefix(prefix, path string) bool {
+	return prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// unescapeMountInfo decodes the octal escapes of spaces, tabs, newlines and backslashes in paths
+// of mountinfo
+func unescapeMountInfo(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var builder strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				builder.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		builder.WriteByte(path[i])
+	}
+	return builder.String()
+}
@@ -0,0 +1,422 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfiles
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ResourceName is the name of the secret files resource
+	ResourceName = "secretfiles"
+	// secretFilesDirPath is the directory under the agent data directory that holds the
+	// tmpfs mount of each task
+	secretFilesDirPath   = "secretfiles"
+	secretTempFilePrefix = ".tmp_secret"
+
+	// containerDirMode lets every user in the container list the secrets, the agent keeps
+	// owning the directory so that it can always replace the files
+	containerDirMode = 0755
+	// restrictedFileMode is used when the container user is known, in which case the files
+	// are owned by that user
+	restrictedFileMode = 0400
+	// sharedFileMode is used when the container user can only be resolved inside the container
+	// image, so the files have to be readable by everyone in the container
+	sharedFileMode = 0444
+)
+
+var (
+	mkdirAll  = os.MkdirAll
+	removeAll = os.RemoveAll
+	rename    = os.Rename
+	mount     = mountTmpfs
+	unmount   = unmountTmpfs
+	isShared  = mountIsShared
+)
+
+// SecretFilesResource manages a tmpfs directory per task that holds the secrets delivered to
+// containers as files. Each container gets its own sub directory, which is bind mounted into
+// the container. The secret values are never persisted in the agent state.
+type SecretFilesResource struct {
+	taskARN     string
+	resourceDir string // path of the tmpfs mount of the task
+
+	// Fields for the common functionality of task resource. Access to these fields are protected by lock.
+	createdAtUnsafe      time.Time
+	desiredStatusUnsafe  resourcestatus.ResourceStatus
+	knownStatusUnsafe    resourcestatus.ResourceStatus
+	appliedStatusUnsafe  resourcestatus.ResourceStatus
+	statusToTransitions  map[resourcestatus.ResourceStatus]func() error
+	terminalReasonUnsafe string
+	terminalReasonOnce   sync.Once
+	lock                 sync.RWMutex
+}
+
+// NewSecretFilesResource creates a new SecretFilesResource object
+func NewSecretFilesResource(taskARN, dataDir string) *SecretFilesResource {
+	secretFiles := &SecretFilesResource{
+		taskARN: taskARN,
+		// we mount the tmpfs for a task at path: /var/lib/ecs/data/secretfiles/task_id/
+		resourceDir: TaskDir(dataDir, taskARN),
+	}
+
+	secretFiles.initStatusToTransition()
+	return secretFiles
+}
+
+// TaskDir returns the directory holding the secret files of a task under the given data directory
+func TaskDir(dataDir, taskARN string) string {
+	taskARNFields := strings.Split(taskARN, "/")
+	taskID := taskARNFields[len(taskARNFields)-1]
+	return filepath.Join(dataDir, secretFilesDirPath, taskID)
+}
+
+// ContainerDir returns the directory holding the secret files of a container of a task under
+// the given data directory
+func ContainerDir(dataDir, taskARN, containerName string) string {
+	return filepath.Join(TaskDir(dataDir, taskARN), containerName)
+}
+
+// Initialize initializes the resource fields of the secret files resource
+func (secretFiles *SecretFilesResource) Initialize(resourceFields *taskresource.ResourceFields,
+	taskKnownStatus status.TaskStatus,
+	taskDesiredStatus status.TaskStatus) {
+	secretFiles.lock.Lock()
+	secretFiles.initStatusToTransition()
+	secretFiles.lock.Unlock()
+
+	// if task isn't in 'created' status and desired status is 'running',
+	// reset the resource status to 'NONE' so that the tmpfs is mounted again,
+	// this is in case agent crashes
+	if taskKnownStatus < status.TaskCreated && taskDesiredStatus <= status.TaskRunning {
+		secretFiles.SetKnownStatus(resourcestatus.ResourceStatusNone)
+	}
+}
+
+func (secretFiles *SecretFilesResource) initStatusToTransition() {
+	resourceStatusToTransitionFunc := map[resourcestatus.ResourceStatus]func() error{
+		resourcestatus.ResourceStatus(SecretFilesCreated): secretFiles.Create,
+	}
+	secretFiles.statusToTransitions = resourceStatusToTransitionFunc
+}
+
+// SetDesiredStatus safely sets the desired status of the resource
+func (secretFiles *SecretFilesResource) SetDesiredStatus(status resourcestatus.ResourceStatus) {
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	secretFiles.desiredStatusUnsafe = status
+}
+
+// GetDesiredStatus safely returns the desired status of the resource
+func (secretFiles *SecretFilesResource) GetDesiredStatus() resourcestatus.ResourceStatus {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.desiredStatusUnsafe
+}
+
+func (secretFiles *SecretFilesResource) updateAppliedStatusUnsafe(knownStatus resourcestatus.ResourceStatus) {
+	if secretFiles.appliedStatusUnsafe == resourcestatus.ResourceStatus(SecretFilesStatusNone) {
+		return
+	}
+
+	// only apply if resource transition has already finished
+	if secretFiles.appliedStatusUnsafe <= knownStatus {
+		secretFiles.appliedStatusUnsafe = resourcestatus.ResourceStatus(SecretFilesStatusNone)
+	}
+}
+
+// SetKnownStatus safely sets the currently known status of the resource
+func (secretFiles *SecretFilesResource) SetKnownStatus(status resourcestatus.ResourceStatus) {
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	secretFiles.knownStatusUnsafe = status
+	secretFiles.updateAppliedStatusUnsafe(status)
+}
+
+// GetKnownStatus safely returns the currently known status of the resource
+func (secretFiles *SecretFilesResource) GetKnownStatus() resourcestatus.ResourceStatus {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.knownStatusUnsafe
+}
+
+// SetCreatedAt sets the timestamp for resource's creation time
+func (secretFiles *SecretFilesResource) SetCreatedAt(createdAt time.Time) {
+	if createdAt.IsZero() {
+		return
+	}
+
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	secretFiles.createdAtUnsafe = createdAt
+}
+
+// GetCreatedAt returns the timestamp for resource's creation time
+func (secretFiles *SecretFilesResource) GetCreatedAt() time.Time {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.createdAtUnsafe
+}
+
+// GetName returns the name of the resource
+func (secretFiles *SecretFilesResource) GetName() string {
+	return ResourceName
+}
+
+// DesiredTerminal returns true if the resource's desired status is REMOVED
+func (secretFiles *SecretFilesResource) DesiredTerminal() bool {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.desiredStatusUnsafe == resourcestatus.ResourceStatus(SecretFilesRemoved)
+}
+
+// KnownCreated returns true if the resource's known status is CREATED
+func (secretFiles *SecretFilesResource) KnownCreated() bool {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.knownStatusUnsafe == resourcestatus.ResourceStatus(SecretFilesCreated)
+}
+
+// TerminalStatus returns the last transition state of the resource
+func (secretFiles *SecretFilesResource) TerminalStatus() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(SecretFilesRemoved)
+}
+
+// NextKnownState returns the state that the resource should progress to based on its `KnownState`
+func (secretFiles *SecretFilesResource) NextKnownState() resourcestatus.ResourceStatus {
+	return secretFiles.GetKnownStatus() + 1
+}
+
+// ApplyTransition calls the function required to move to the specified status
+func (secretFiles *SecretFilesResource) ApplyTransition(nextState resourcestatus.ResourceStatus) error {
+	transitionFunc, ok := secretFiles.statusToTransitions[nextState]
+	if !ok {
+		return errors.Errorf("resource [%s]: transition to %s impossible", secretFiles.GetName(),
+			secretFiles.StatusString(nextState))
+	}
+	return transitionFunc()
+}
+
+// SteadyState returns the transition state of the resource defined as "ready"
+func (secretFiles *SecretFilesResource) SteadyState() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(SecretFilesCreated)
+}
+
+// SetAppliedStatus sets the applied status of resource and returns whether
+// the resource is already in a transition
+func (secretFiles *SecretFilesResource) SetAppliedStatus(status resourcestatus.ResourceStatus) bool {
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	if secretFiles.appliedStatusUnsafe != resourcestatus.ResourceStatus(SecretFilesStatusNone) {
+		// set operation failed, return false
+		return false
+	}
+
+	secretFiles.appliedStatusUnsafe = status
+	return true
+}
+
+// GetAppliedStatus safely returns the currently applied status of the resource
+func (secretFiles *SecretFilesResource) GetAppliedStatus() resourcestatus.ResourceStatus {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.appliedStatusUnsafe
+}
+
+// StatusString returns the string representation of the resource status
+func (secretFiles *SecretFilesResource) StatusString(status resourcestatus.ResourceStatus) string {
+	return SecretFilesStatus(status).String()
+}
+
+// GetTerminalReason returns an error string to propagate up through to task
+// state change messages
+func (secretFiles *SecretFilesResource) GetTerminalReason() string {
+	secretFiles.lock.RLock()
+	defer secretFiles.lock.RUnlock()
+
+	return secretFiles.terminalReasonUnsafe
+}
+
+func (secretFiles *SecretFilesResource) setTerminalReason(reason string) {
+	secretFiles.lock.Lock()
+	defer secretFiles.lock.Unlock()
+
+	secretFiles.terminalReasonOnce.Do(func() {
+		seelog.Infof("secret files resource: setting terminal reason for task: [%s]", secretFiles.taskARN)
+		secretFiles.terminalReasonUnsafe = reason
+	})
+}
+
+// DependOnTaskNetwork shows whether the resource creation needs task network setup beforehand
+func (secretFiles *SecretFilesResource) DependOnTaskNetwork() bool {
+	return false
+}
+
+// BuildContainerDependency adds a new dependency container and its satisfied status
+func (secretFiles *SecretFilesResource) BuildContainerDependency(containerName string, satisfied apicontainerstatus.ContainerStatus,
+	dependent resourcestatus.ResourceStatus) {
+}
+
+// GetContainerDependencies returns dependent containers for a status
+func (secretFiles *SecretFilesResource) GetContainerDependencies(dependent resourcestatus.ResourceStatus) []apicontainer.ContainerDependency {
+	return nil
+}
+
+// GetResourceDir returns the path of the tmpfs mount of the task
+func (secretFiles *SecretFilesResource) GetResourceDir() string {
+	return secretFiles.resourceDir
+}
+
+// Create mounts a tmpfs for the secret files of the task. Any tmpfs left over from a previous
+// attempt is unmounted first so that mounts don't stack up. The task fails when the tmpfs wouldn't
+// be visible to Docker, as containers would otherwise silently get an empty directory.
+func (secretFiles *SecretFilesResource) Create() error {
+	seelog.Debugf("Creating secret files resource for task %s", secretFiles.taskARN)
+	if err := mkdirAll(secretFiles.resourceDir, 0700); err != nil {
+		err = errors.Wrapf(err, "unable to create secret files directory %s", secretFiles.resourceDir)
+		secretFiles.setTerminalReason(err.Error())
+		return err
+	}
+	if err := unmount(secretFiles.resourceDir); err != nil {
+		seelog.Warnf("Unable to unmount stale secret files directory %s: %v", secretFiles.resourceDir, err)
+	}
+	shared, mountPoint, err := isShared(secretFiles.resourceDir)
+	if err != nil {
+		err = errors.Wrapf(err, "unable to check the mount propagation of secret files directory %s",
+			secretFiles.resourceDir)
+		secretFiles.setTerminalReason(err.Error())
+		return err
+	}
+	if !shared {
+		err = errors.Errorf("secret files directory %s is on mount %s that doesn't have shared propagation, "+
+			"so containers can't see the secret files: mount the agent data directory with rshared propagation",
+			secretFiles.resourceDir, mountPoint)
+		secretFiles.setTerminalReason(err.Error())
+		return err
+	}
+	if err := mount(secretFiles.resourceDir); err != nil {
+		err = errors.Wrapf(err, "unable to mount tmpfs at secret files directory %s", secretFiles.resourceDir)
+		secretFiles.setTerminalReason(err.Error())
+		return err
+	}
+	return nil
+}
+
+// WriteContainerSecrets writes the secrets of a container into its directory on the tmpfs, one file
+// per secret named after the secret. Each file is replaced atomically, so a reader never observes a
+// partially written value. When uid is negative the container user isn't known and the files are
+// made readable by every user in the container, otherwise they are only readable by uid:gid.
+func (secretFiles *SecretFilesResource) WriteContainerSecrets(containerName string, secrets map[string]string,
+	uid, gid int) error {
+	fileMode := os.FileMode(restrictedFileMode)
+	if uid < 0 {
+		fileMode = sharedFileMode
+	}
+
+	containerDir := filepath.Join(secretFiles.resourceDir, containerName)
+	if err := mkdirAll(containerDir, containerDirMode); err != nil {
+		return errors.Wrapf(err, "unable to create secret files directory for container %s", containerName)
+	}
+
+	for name, value := range secrets {
+		if err := writeSecretFile(containerDir, name, value, fileMode, uid, gid); err != nil {
+			return errors.Wrapf(err, "unable to write secret %s for container %s", name, containerName)
+		}
+	}
+	return nil
+}
+
+// UpdateContainerSecrets replaces the values of secrets already written for a container,
+// keeping the owner and permissions of their files. Secrets without a file are ignored.
+func (secretFiles *SecretFilesResource) UpdateContainerSecrets(containerName string, secrets map[string]string) error {
+	containerDir := filepath.Join(secretFiles.resourceDir, containerName)
+	for name, value := range secrets {
+		info, err := os.Stat(filepath.Join(containerDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to update secret %s for container %s", name, containerName)
+		}
+		uid, gid := fileOwner(info)
+		if err := writeSecretFile(containerDir, name, value, info.Mode().Perm(), uid, gid); err != nil {
+			return errors.Wrapf(err, "unable to update secret %s for container %s", name, containerName)
+		}
+	}
+	return nil
+}
+
+func writeSecretFile(dir, name, value string, mode os.FileMode, uid, gid int) error {
+	tmpFile, err := ioutil.TempFile(dir, secretTempFilePrefix)
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = tmpFile.WriteString(value)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	if uid >= 0 {
+		if err = os.Chown(tmpPath, uid, gid); err != nil {
+			return err
+		}
+	}
+	return rename(tmpPath, filepath.Join(dir, name))
+}
+
+// Cleanup unmounts the tmpfs of the task and removes its directory
+func (secretFiles *SecretFilesResource) Cleanup() error {
+	if err := unmount(secretFiles.resourceDir); err != nil {
+		return fmt.Errorf("unable to unmount secret files directory %s: %v", secretFiles.resourceDir, err)
+	}
+	if err := removeAll(secretFiles.resourceDir); err != nil {
+		return fmt.Errorf("unable to remove secret files directory %s: %v", secretFiles.resourceDir, err)
+	}
+
+	seelog.Infof("Removed secret files directory at %s", secretFiles.resourceDir)
+	return nil
+}
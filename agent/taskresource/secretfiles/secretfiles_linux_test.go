@@ -0,0 +1,237 @@
+//go:build linux && unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfiles
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+)
+
+const (
+	testTaskARN       = "arn:aws:ecs:us-west-2:01234567891011:task/mycluster/3de392df-6bfa-470b-97ed-aa6f482cd7a"
+	testTaskID        = "3de392df-6bfa-470b-97ed-aa6f482cd7a"
+	testContainerName = "app"
+)
+
+func setupMounts(t *testing.T) (*[]string, *[]string) {
+	var mounted, unmounted []string
+	mount = func(dir string) error {
+		mounted = append(mounted, dir)
+		return nil
+	}
+	unmount = func(dir string) error {
+		unmounted = append(unmounted, dir)
+		return nil
+	}
+	isShared = func(dir string) (bool, string, error) {
+		return true, "/", nil
+	}
+	t.Cleanup(func() {
+		mount = mountTmpfs
+		unmount = unmountTmpfs
+		isShared = mountIsShared
+	})
+	return &mounted, &unmounted
+}
+
+func TestCreateMountsTmpfs(t *testing.T) {
+	mounted, unmounted := setupMounts(t)
+	dataDir := t.TempDir()
+
+	secretFiles := NewSecretFilesResource(testTaskARN, dataDir)
+	require.NoError(t, secretFiles.Create())
+
+	taskDir := filepath.Join(dataDir, "secretfiles", testTaskID)
+	assert.Equal(t, taskDir, secretFiles.GetResourceDir())
+	assert.DirExists(t, taskDir)
+	assert.Equal(t, []string{taskDir}, *mounted)
+	assert.Equal(t, []string{taskDir}, *unmounted, "stale mounts should be removed first")
+}
+
+func TestCreateMountError(t *testing.T) {
+	setupMounts(t)
+	mount = func(dir string) error {
+		return errors.New("mount error")
+	}
+
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	assert.Error(t, secretFiles.Create())
+	assert.Contains(t, secretFiles.GetTerminalReason(), "mount error")
+}
+
+func TestCreateMountNotShared(t *testing.T) {
+	mounted, _ := setupMounts(t)
+	isShared = func(dir string) (bool, string, error) {
+		return false, "/var/lib/ecs/data", nil
+	}
+
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	assert.Error(t, secretFiles.Create())
+	assert.Empty(t, *mounted)
+	assert.Contains(t, secretFiles.GetTerminalReason(), "/var/lib/ecs/data")
+	assert.Contains(t, secretFiles.GetTerminalReason(), "shared propagation")
+}
+
+const testMountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 8:1 /var/lib/ecs /var/lib/ecs rw,relatime - ext4 /dev/sda1 rw
+31 22 8:1 /var/lib/ecs/data /var/lib/ecs/data rw,relatime shared:5 master:1 - ext4 /dev/sda1 rw
+32 22 8:1 /srv/my\040data /srv/my\040data rw,relatime - ext4 /dev/sda1 rw
+33 30 8:1 /var/lib/ecs/cache /var/lib/ecs/cache rw,relatime shared:7 - ext4 /dev/sda1 rw
+34 33 0:50 / /var/lib/ecs/cache rw,relatime - tmpfs tmpfs rw
+`
+
+func TestParseMountPropagation(t *testing.T) {
+	testCases := []struct {
+		dir        string
+		shared     bool
+		mountPoint string
+	}{
+		{"/var/lib/ecs/data/secretfiles/task", true, "/var/lib/ecs/data"},
+		{"/var/lib/ecs/data", true, "/var/lib/ecs/data"},
+		{"/var/lib/ecs/database", false, "/var/lib/ecs"},
+		{"/var/lib/ecs/cache/secretfiles", false, "/var/lib/ecs/cache"},
+		{"/srv/my data/secretfiles", false, "/srv/my data"},
+		{"/opt/ecs", true, "/"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.dir, func(t *testing.T) {
+			shared, mountPoint, err := parseMountPropagation(strings.NewReader(testMountInfo), tc.dir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.shared, shared)
+			assert.Equal(t, tc.mountPoint, mountPoint)
+		})
+	}
+
+	_, _, err := parseMountPropagation(strings.NewReader(""), "/var/lib/ecs")
+	assert.Error(t, err)
+}
+
+func TestWriteContainerSecrets(t *testing.T) {
+	setupMounts(t)
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	require.NoError(t, secretFiles.Create())
+
+	uid, gid := os.Getuid(), os.Getgid()
+	require.NoError(t, secretFiles.WriteContainerSecrets(testContainerName,
+		map[string]string{"db-password": "secret"}, uid, gid))
+
+	containerDir := filepath.Join(secretFiles.GetResourceDir(), testContainerName)
+	info, err := os.Stat(containerDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(containerDirMode), info.Mode().Perm())
+
+	secretPath := filepath.Join(containerDir, "db-password")
+	info, err = os.Stat(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(restrictedFileMode), info.Mode().Perm())
+	value, err := ioutil.ReadFile(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(value))
+
+	// writing again replaces the value and leaves no temporary files behind
+	require.NoError(t, secretFiles.WriteContainerSecrets(testContainerName,
+		map[string]string{"db-password": "rotated"}, uid, gid))
+	value, err = ioutil.ReadFile(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", string(value))
+	entries, err := ioutil.ReadDir(containerDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteContainerSecretsUnknownUser(t *testing.T) {
+	setupMounts(t)
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	require.NoError(t, secretFiles.Create())
+
+	require.NoError(t, secretFiles.WriteContainerSecrets(testContainerName,
+		map[string]string{"token": "secret"}, -1, -1))
+
+	info, err := os.Stat(filepath.Join(secretFiles.GetResourceDir(), testContainerName, "token"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(sharedFileMode), info.Mode().Perm())
+}
+
+func TestUpdateContainerSecrets(t *testing.T) {
+	setupMounts(t)
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	require.NoError(t, secretFiles.Create())
+	require.NoError(t, secretFiles.WriteContainerSecrets(testContainerName,
+		map[string]string{"token": "secret"}, -1, -1))
+
+	require.NoError(t, secretFiles.UpdateContainerSecrets(testContainerName,
+		map[string]string{"token": "rotated", "unknown": "value"}))
+
+	containerDir := filepath.Join(secretFiles.GetResourceDir(), testContainerName)
+	secretPath := filepath.Join(containerDir, "token")
+	value, err := ioutil.ReadFile(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", string(value))
+	info, err := os.Stat(secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(sharedFileMode), info.Mode().Perm(), "permissions should be kept")
+	_, err = os.Stat(filepath.Join(containerDir, "unknown"))
+	assert.True(t, os.IsNotExist(err), "secrets that were not written should not be created")
+}
+
+func TestCleanup(t *testing.T) {
+	_, unmounted := setupMounts(t)
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	require.NoError(t, secretFiles.Create())
+	require.NoError(t, secretFiles.WriteContainerSecrets(testContainerName,
+		map[string]string{"token": "secret"}, os.Getuid(), os.Getgid()))
+
+	require.NoError(t, secretFiles.Cleanup())
+	assert.Len(t, *unmounted, 2)
+	_, err := os.Stat(secretFiles.GetResourceDir())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCleanupUnmountError(t *testing.T) {
+	setupMounts(t)
+	unmount = func(dir string) error {
+		return errors.New("unmount error")
+	}
+
+	secretFiles := NewSecretFilesResource(testTaskARN, t.TempDir())
+	assert.Error(t, secretFiles.Cleanup())
+}
+
+func TestMarshalUnmarshalJSON(t *testing.T) {
+	secretFiles := NewSecretFilesResource(testTaskARN, "/data")
+	secretFiles.SetDesiredStatus(resourcestatus.ResourceStatus(SecretFilesCreated))
+	secretFiles.SetKnownStatus(resourcestatus.ResourceStatus(SecretFilesStatusNone))
+
+	bytes, err := json.Marshal(secretFiles)
+	require.NoError(t, err)
+
+	unmarshalled := &SecretFilesResource{}
+	require.NoError(t, json.Unmarshal(bytes, unmarshalled))
+	assert.Equal(t, secretFiles.taskARN, unmarshalled.taskARN)
+	assert.Equal(t, secretFiles.GetResourceDir(), unmarshalled.GetResourceDir())
+	assert.Equal(t, secretFiles.GetDesiredStatus(), unmarshalled.GetDesiredStatus())
+	assert.Equal(t, secretFiles.GetKnownStatus(), unmarshalled.GetKnownStatus())
+}
@@ -0,0 +1,78 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfiles
+
+import (
+	"errors"
+	"strings"
+
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+)
+
+type SecretFilesStatus resourcestatus.ResourceStatus
+
+const (
+	// SecretFilesStatusNone is the zero state of a task resource
+	SecretFilesStatusNone SecretFilesStatus = iota
+	// SecretFilesCreated means the tmpfs directory of the task is mounted
+	SecretFilesCreated
+	// SecretFilesRemoved means the tmpfs directory of the task is unmounted and removed
+	SecretFilesRemoved
+)
+
+var secretFilesStatusMap = map[string]SecretFilesStatus{
+	"NONE":    SecretFilesStatusNone,
+	"CREATED": SecretFilesCreated,
+	"REMOVED": SecretFilesRemoved,
+}
+
+// String returns a human readable string representation of this object
+func (status SecretFilesStatus) String() string {
+	for k, v := range secretFilesStatusMap {
+		if v == status {
+			return k
+		}
+	}
+	return "NONE"
+}
+
+// MarshalJSON overrides the logic for JSON-encoding the ResourceStatus type
+func (status *SecretFilesStatus) MarshalJSON() ([]byte, error) {
+	if status == nil {
+		return nil, errors.New("secret files resource status is nil")
+	}
+	return []byte(`"` + status.String() + `"`), nil
+}
+
+// UnmarshalJSON overrides the logic for parsing the JSON-encoded ResourceStatus data
+func (status *SecretFilesStatus) UnmarshalJSON(b []byte) error {
+	if strings.ToLower(string(b)) == "null" {
+		*status = SecretFilesStatusNone
+		return nil
+	}
+
+	if b[0] != '"' || b[len(b)-1] != '"' {
+		*status = SecretFilesStatusNone
+		return errors.New("resource status unmarshal: status must be a string or null; Got " + string(b))
+	}
+
+	strStatus := b[1 : len(b)-1]
+	stat, ok := secretFilesStatusMap[string(strStatus)]
+	if !ok {
+		*status = SecretFilesStatusNone
+		return errors.New("resource status unmarshal: unrecognized status")
+	}
+	*status = stat
+	return nil
+}
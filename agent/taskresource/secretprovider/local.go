@@ -0,0 +1,118 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretprovider
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LocalSecretKeySize is the size of the AES-256 key that encrypts local secrets
+const LocalSecretKeySize = 32
+
+// LocalProvider reads secrets from a directory on the host. Each secret is a file, at the path
+// given by the valueFrom of the secret relative to the directory, that holds the value encrypted
+// with AES-256-GCM: the nonce followed by the sealed value. The valueFrom is authenticated along
+// with the value, so an encrypted file can't be moved to stand in for another secret.
+type LocalProvider struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewLocalProvider creates a provider reading the secrets in dir, decrypting them with the
+// base64 encoded key stored in keyFile
+func NewLocalProvider(dir, keyFile string) (*LocalProvider, error) {
+	encodedKey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read local secrets key")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedKey)))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode local secrets key")
+	}
+	aead, err := newLocalSecretAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalProvider{
+		dir:  dir,
+		aead: aead,
+	}, nil
+}
+
+func newLocalSecretAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != LocalSecretKeySize {
+		return nil, errors.Errorf("local secrets key must be %d bytes, got %d", LocalSecretKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GetSecretValue decrypts the secret stored at valueFrom in the secrets directory
+func (provider *LocalProvider) GetSecretValue(ctx context.Context, valueFrom string) (string, error) {
+	path, err := provider.secretPath(valueFrom)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read local secret %s", valueFrom)
+	}
+
+	nonceSize := provider.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.Errorf("local secret %s is not encrypted", valueFrom)
+	}
+	value, err := provider.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(valueFrom))
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to decrypt local secret %s", valueFrom)
+	}
+	return string(value), nil
+}
+
+// secretPath returns the path of the file of a secret, making sure it's in the secrets directory
+func (provider *LocalProvider) secretPath(valueFrom string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(valueFrom))
+	if valueFrom == "" || filepath.IsAbs(cleaned) || cleaned == ".." ||
+		strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("local secret %q must be a path relative to the secrets directory", valueFrom)
+	}
+	return filepath.Join(provider.dir, cleaned), nil
+}
+
+// SealLocalSecret encrypts the value of the secret stored at valueFrom in the format read by
+// LocalProvider
+func SealLocalSecret(key []byte, valueFrom, value string) ([]byte, error) {
+	aead, err := newLocalSecretAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, []byte(value), []byte(valueFrom)), nil
+}
@@ -0,0 +1,82 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretprovider
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalProvider(t *testing.T) (*LocalProvider, []byte, string) {
+	dir := t.TempDir()
+	key := make([]byte, LocalSecretKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+
+	provider, err := NewLocalProvider(dir, keyFile)
+	require.NoError(t, err)
+	return provider, key, dir
+}
+
+func TestLocalProviderGetSecretValue(t *testing.T) {
+	provider, key, dir := newTestLocalProvider(t)
+	sealed, err := SealLocalSecret(key, "db/password", "hunter2")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "db"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "db", "password"), sealed, 0600))
+
+	value, err := provider.GetSecretValue(context.TODO(), "db/password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+}
+
+func TestLocalProviderRejectsMovedSecret(t *testing.T) {
+	provider, key, dir := newTestLocalProvider(t)
+	sealed, err := SealLocalSecret(key, "db/password", "hunter2")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other"), sealed, 0600))
+
+	_, err = provider.GetSecretValue(context.TODO(), "other")
+	assert.Error(t, err)
+}
+
+func TestLocalProviderRejectsPathsOutsideDir(t *testing.T) {
+	provider, _, _ := newTestLocalProvider(t)
+	for _, valueFrom := range []string{"", "../key", "/etc/passwd", "a/../../key"} {
+		_, err := provider.GetSecretValue(context.TODO(), valueFrom)
+		assert.Error(t, err, valueFrom)
+	}
+}
+
+func TestNewLocalProviderInvalidKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600))
+	_, err := NewLocalProvider(t.TempDir(), keyFile)
+	assert.Error(t, err)
+
+	_, err = NewLocalProvider(t.TempDir(), filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
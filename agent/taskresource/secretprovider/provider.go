@@ -0,0 +1,41 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package secretprovider retrieves secrets from stores configured on the host, for hosts, such as
+// ECS Anywhere instances, that can't reach SSM Parameter Store or Secrets Manager.
+package secretprovider
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Provider retrieves secret values from a secret store
+type Provider interface {
+	// GetSecretValue returns the value of the secret that valueFrom refers to
+	GetSecretValue(ctx context.Context, valueFrom string) (string, error)
+}
+
+// Providers holds the providers configured on the host, keyed by the name used
+// in the provider field of secrets
+type Providers map[string]Provider
+
+// Get returns the provider with the given name
+func (providers Providers) Get(name string) (Provider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, errors.Errorf("secret provider %s is not configured on this instance", name)
+	}
+	return provider, nil
+}
@@ -0,0 +1,207 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxVaultResponseSize bounds the size of the responses read from Vault
+	maxVaultResponseSize = 1024 * 1024
+	vaultTokenHeader     = "X-Vault-Token"
+)
+
+// VaultProvider reads secrets from the KV secrets engine of a Vault compatible server. The
+// valueFrom of a secret has the form "<mount>/<path>#<key>"; without a key, the whole secret is
+// returned as a JSON object. Only the mounts allowed by the operator can be read, since the host
+// token is sent along with every request and task definitions name the path.
+type VaultProvider struct {
+	address   string
+	tokenFile string
+	kvVersion int
+	mounts    map[string]struct{}
+	client    *http.Client
+}
+
+// NewVaultProvider creates a provider reading secrets from the KV engines mounted at mounts on the
+// server at address, authenticating with the token stored in tokenFile. The token is read on every
+// request so that it can be renewed on the host without restarting the agent.
+func NewVaultProvider(address, tokenFile string, kvVersion int, mounts []string, client *http.Client) (*VaultProvider, error) {
+	if kvVersion != 1 && kvVersion != 2 {
+		return nil, errors.Errorf("unsupported vault KV secrets engine version %d", kvVersion)
+	}
+	if _, err := url.ParseRequestURI(address); err != nil {
+		return nil, errors.Wrap(err, "invalid vault address")
+	}
+	allowedMounts := make(map[string]struct{})
+	for _, mount := range mounts {
+		mount = strings.Trim(mount, "/")
+		if err := validatePath(mount); err != nil {
+			return nil, errors.Wrapf(err, "invalid vault KV mount %q", mount)
+		}
+		allowedMounts[mount] = struct{}{}
+	}
+	if len(allowedMounts) == 0 {
+		return nil, errors.New("no vault KV mount is allowed")
+	}
+	return &VaultProvider{
+		address:   strings.TrimSuffix(address, "/"),
+		tokenFile: tokenFile,
+		kvVersion: kvVersion,
+		mounts:    allowedMounts,
+		client:    client,
+	}, nil
+}
+
+// GetSecretValue reads the secret that valueFrom refers to from Vault
+func (provider *VaultProvider) GetSecretValue(ctx context.Context, valueFrom string) (string, error) {
+	secretPath, key := valueFrom, ""
+	if i := strings.LastIndex(valueFrom, "#"); i >= 0 {
+		secretPath, key = valueFrom[:i], valueFrom[i+1:]
+	}
+	if err := validatePath(secretPath); err != nil {
+		return "", errors.Wrapf(err, "vault secret %q must have the form <mount>/<path>[#<key>]", valueFrom)
+	}
+	mount, path, found := provider.splitMount(secretPath)
+	if !found {
+		return "", errors.Errorf("vault secret %s is not in an allowed KV mount", secretPath)
+	}
+
+	data, err := provider.readSecret(ctx, mount, path)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read vault secret %s", secretPath)
+	}
+
+	if key == "" {
+		value, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", errors.Errorf("vault secret %s has no key %s", secretPath, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// readSecret returns the key/value pairs of the secret at path in the KV engine mounted at mount
+func (provider *VaultProvider) readSecret(ctx context.Context, mount, path string) (map[string]interface{}, error) {
+	token, err := ioutil.ReadFile(provider.tokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read vault token")
+	}
+
+	secretURL := fmt.Sprintf("%s/v1/%s/%s", provider.address, escapePath(mount), escapePath(path))
+	if provider.kvVersion == 2 {
+		secretURL = fmt.Sprintf("%s/v1/%s/data/%s", provider.address, escapePath(mount), escapePath(path))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(vaultTokenHeader, strings.TrimSpace(string(token)))
+
+	resp, err := provider.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxVaultResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d from vault", resp.StatusCode)
+	}
+
+	if provider.kvVersion == 2 {
+		var secret struct {
+			Data struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &secret); err != nil {
+			return nil, errors.Wrap(err, "unable to parse vault response")
+		}
+		if secret.Data.Data == nil {
+			return nil, errors.New("vault response has no data")
+		}
+		return secret.Data.Data, nil
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, errors.Wrap(err, "unable to parse vault response")
+	}
+	if secret.Data == nil {
+		return nil, errors.New("vault response has no data")
+	}
+	return secret.Data, nil
+}
+
+// splitMount splits secretPath into the allowed mount it's in and the path of the secret in the
+// mount. Mounts may have several segments, the longest allowed one wins.
+func (provider *VaultProvider) splitMount(secretPath string) (string, string, bool) {
+	segments := strings.Split(secretPath, "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		mount := strings.Join(segments[:i], "/")
+		if _, ok := provider.mounts[mount]; ok {
+			return mount, strings.Join(segments[i:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// validatePath makes sure that a slash separated path has no empty, "." or ".." segment, so that
+// requests can't leave the mount they are made to
+func validatePath(path string) error {
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "":
+			return errors.New("empty path segment")
+		case ".", "..":
+			return errors.Errorf("invalid path segment %q", segment)
+		}
+	}
+	return nil
+}
+
+// escapePath escapes each segment of a slash separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,157 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretprovider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testVaultToken = "s.token"
+
+func newTestVaultServer(t *testing.T, kvVersion int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(vaultTokenHeader) != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		path := "/v1/secret/app/db"
+		body := `{"data":{"password":"hunter2","port":5432}}`
+		if kvVersion == 2 {
+			path = "/v1/secret/data/app/db"
+			body = `{"data":{"data":{"password":"hunter2","port":5432},"metadata":{"version":3}}}`
+		}
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestVaultProvider(t *testing.T, address, token string, kvVersion int) *VaultProvider {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte(token+"\n"), 0600))
+	provider, err := NewVaultProvider(address, tokenFile, kvVersion, []string{"secret"}, http.DefaultClient)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestVaultProviderGetSecretValue(t *testing.T) {
+	for _, kvVersion := range []int{1, 2} {
+		server := newTestVaultServer(t, kvVersion)
+		provider := newTestVaultProvider(t, server.URL, testVaultToken, kvVersion)
+
+		value, err := provider.GetSecretValue(context.TODO(), "secret/app/db#password")
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", value)
+
+		value, err = provider.GetSecretValue(context.TODO(), "secret/app/db#port")
+		require.NoError(t, err)
+		assert.Equal(t, "5432", value)
+
+		value, err = provider.GetSecretValue(context.TODO(), "secret/app/db")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"password":"hunter2","port":5432}`, value)
+
+		_, err = provider.GetSecretValue(context.TODO(), "secret/app/db#missing")
+		assert.Error(t, err)
+
+		_, err = provider.GetSecretValue(context.TODO(), "secret/app/other#password")
+		assert.Error(t, err)
+	}
+}
+
+func TestVaultProviderInvalidToken(t *testing.T) {
+	server := newTestVaultServer(t, 2)
+	provider := newTestVaultProvider(t, server.URL, "wrong", 2)
+
+	_, err := provider.GetSecretValue(context.TODO(), "secret/app/db#password")
+	assert.Error(t, err)
+}
+
+func TestVaultProviderInvalidValueFrom(t *testing.T) {
+	server := newTestVaultServer(t, 2)
+	provider := newTestVaultProvider(t, server.URL, testVaultToken, 2)
+
+	for _, valueFrom := range []string{
+		"",
+		"secret",
+		"/secret/#password",
+		"secret//app/db#password",
+		"secret/./app/db#password",
+		"secret/../auth/token/lookup-self#id",
+		"secret/app/../../sys/config#password",
+	} {
+		_, err := provider.GetSecretValue(context.TODO(), valueFrom)
+		assert.Error(t, err, valueFrom)
+	}
+}
+
+func TestVaultProviderMountNotAllowed(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data":{"id":"s.token"}}`))
+	}))
+	defer server.Close()
+	provider := newTestVaultProvider(t, server.URL, testVaultToken, 1)
+
+	for _, valueFrom := range []string{"auth/token/lookup-self#id", "sys/config#id", "secrets/app/db#id"} {
+		_, err := provider.GetSecretValue(context.TODO(), valueFrom)
+		assert.Error(t, err, valueFrom)
+	}
+	assert.Zero(t, requests, "The vault token should not be sent outside of the allowed mounts")
+}
+
+func TestVaultProviderNestedMount(t *testing.T) {
+	var requestPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		w.Write([]byte(`{"data":{"data":{"password":"hunter2"}}}`))
+	}))
+	defer server.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte(testVaultToken), 0600))
+	provider, err := NewVaultProvider(server.URL, tokenFile, 2, []string{"team/kv", "secret"}, http.DefaultClient)
+	require.NoError(t, err)
+
+	value, err := provider.GetSecretValue(context.TODO(), "team/kv/app/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+	assert.Equal(t, "/v1/team/kv/data/app/db", requestPath)
+}
+
+func TestNewVaultProviderInvalidKVVersion(t *testing.T) {
+	_, err := NewVaultProvider("https://vault.local:8200", "token", 3, []string{"secret"}, http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestNewVaultProviderInvalidMounts(t *testing.T) {
+	for _, mounts := range [][]string{nil, {""}, {"secret", ".."}, {"a//b"}} {
+		_, err := NewVaultProvider("https://vault.local:8200", "token", 2, mounts, http.DefaultClient)
+		assert.Error(t, err, mounts)
+	}
+}
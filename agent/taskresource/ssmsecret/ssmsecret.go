@@ -16,6 +16,7 @@ package ssmsecret
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -261,15 +262,47 @@ func (secret *SSMSecretResource) Create() error {
 	}
 	iamCredentials := executionCredentials.GetIAMRoleCredentials()
 
+	seelog.Infof("ssm secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
+	secret.secretData = make(map[string]string)
+
+	if err := secret.retrieveSecretValues(iamCredentials); err != nil {
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	return nil
+}
+
+// Refresh fetches the values of all the secrets of the task again, bypassing the cache, and
+// updates the cache with them. It returns the cache keys of the secrets whose value changed.
+func (secret *SSMSecretResource) Refresh() ([]string, error) {
+	executionCredentials, ok := secret.credentialsManager.GetTaskCredentials(secret.getExecutionCredentialsID())
+	if !ok {
+		return nil, errors.New("ssm secret resource: unable to find execution role credentials")
+	}
+
+	// Secrets are retrieved into a separate resource so that the cache keeps serving the
+	// current values while the new ones are fetched
+	refreshed := &SSMSecretResource{
+		taskARN:          secret.taskARN,
+		requiredSecrets:  secret.getRequiredSecrets(),
+		ssmClientCreator: secret.ssmClientCreator,
+		secretData:       make(map[string]string),
+	}
+	if err := refreshed.retrieveSecretValues(executionCredentials.GetIAMRoleCredentials()); err != nil {
+		return nil, err
+	}
+	return secret.updateCachedSecretValues(refreshed.secretData), nil
+}
+
+// retrieveSecretValues retrieves the values of all the secrets that aren't cached yet,
+// spinning up a goroutine per region
+func (secret *SSMSecretResource) retrieveSecretValues(iamCredentials credentials.IAMRoleCredentials) error {
 	var wg sync.WaitGroup
 
 	// Get the maximum number of errors can be returned, which will be one error per goroutine
 	chanLen := secret.getGoRoutineMaxNum()
 	errorEvents := make(chan error, chanLen)
 
-	seelog.Infof("ssm secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
-	secret.secretData = make(map[string]string)
-
 	for region, secrets := range secret.getRequiredSecrets() {
 		wg.Add(1)
 		// Spin up goroutine each region to speed up processing time
@@ -278,10 +311,9 @@ func (secret *SSMSecretResource) Create() error {
 
 	wg.Wait()
 
-	// Get the first error returned and set as terminal reason
+	// Get the first error returned
 	select {
 	case err := <-errorEvents:
-		secret.setTerminalReason(err.Error())
 		return err
 	default:
 		return nil
@@ -394,6 +426,27 @@ func (secret *SSMSecretResource) GetCachedSecretValue(secretKey string) (string,
 	return s, ok
 }
 
+// updateCachedSecretValues caches the given secret values and returns the keys of the
+// previously cached values that changed
+func (secret *SSMSecretResource) updateCachedSecretValues(values map[string]string) []string {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	var changed []string
+	for secretKey, secretValue := range values {
+		if cachedValue, ok := secret.secretData[secretKey]; ok && cachedValue != secretValue {
+			changed = append(changed, secretKey)
+		}
+		secret.secretData[secretKey] = secretValue
+	}
+	sort.Strings(changed)
+	return changed
+}
+
 // SetCachedSecretValue set the secret value in the secretData field given the key and value
 func (secret *SSMSecretResource) SetCachedSecretValue(secretKey string, secretValue string) {
 	secret.lock.Lock()
@@ -17,6 +17,7 @@ package ssmsecret
 
 import (
 	"encoding/json"
+	"errors"
 	"strconv"
 	"testing"
 	"time"
@@ -393,6 +394,117 @@ func TestCreateReturnError(t *testing.T) {
 	assert.Equal(t, expectedError, ssmRes.GetTerminalReason())
 }
 
+func TestRefresh(t *testing.T) {
+	requiredSecretData := map[string][]apicontainer.Secret{
+		region1: {
+			{
+				Name:      secretName1,
+				ValueFrom: valueFrom1,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+			{
+				Name:      secretName2,
+				ValueFrom: valueFrom2,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+
+	ssmOutput := &ssm.GetParametersOutput{
+		InvalidParameters: []*string{},
+		Parameters: []*ssm.Parameter{
+			{
+				Name:  aws.String(valueFrom1),
+				Value: aws.String("rotated-value"),
+			},
+			{
+				Name:  aws.String(valueFrom2),
+				Value: aws.String(secretValue),
+			},
+		},
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	ssmClientCreator.EXPECT().NewSSMClient(region1, iamRoleCreds).Return(mockSSMClient)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(ssmOutput, nil)
+
+	ssmRes := &SSMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        requiredSecretData,
+		credentialsManager:     credentialsManager,
+		ssmClientCreator:       ssmClientCreator,
+	}
+	ssmRes.SetCachedSecretValue(secretKeyWest1, secretValue)
+	ssmRes.SetCachedSecretValue(secretKeyWest2, secretValue)
+
+	changed, err := ssmRes.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{secretKeyWest1}, changed)
+
+	value, ok := ssmRes.GetCachedSecretValue(secretKeyWest1)
+	require.True(t, ok)
+	assert.Equal(t, "rotated-value", value)
+}
+
+func TestRefreshError(t *testing.T) {
+	requiredSecretData := map[string][]apicontainer.Secret{
+		region1: {
+			{
+				Name:      secretName1,
+				ValueFrom: valueFrom1,
+				Region:    region1,
+				Provider:  "ssm",
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	ssmClientCreator.EXPECT().NewSSMClient(region1, iamRoleCreds).Return(mockSSMClient)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Return(nil, errors.New("throttled"))
+
+	ssmRes := &SSMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        requiredSecretData,
+		credentialsManager:     credentialsManager,
+		ssmClientCreator:       ssmClientCreator,
+	}
+	ssmRes.SetCachedSecretValue(secretKeyWest1, secretValue)
+
+	_, err := ssmRes.Refresh()
+	assert.Error(t, err)
+	assert.Empty(t, ssmRes.GetTerminalReason(), "a failed refresh should not fail the task")
+
+	value, ok := ssmRes.GetCachedSecretValue(secretKeyWest1)
+	require.True(t, ok)
+	assert.Equal(t, secretValue, value, "cached values should be kept when the refresh fails")
+}
+
 func TestGetGoRoutineMaxNumTwoRegions(t *testing.T) {
 	requiredSecretData := make(map[string][]apicontainer.Secret)
 	secretsInRegion1 := []apicontainer.Secret{
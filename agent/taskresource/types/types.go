@@ -25,6 +25,8 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/fsxwindowsfileserver"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/providersecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfiles"
 	ssmsecretres "github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 )
@@ -48,6 +50,10 @@ const (
 	EnvironmentFilesKey = envFiles.ResourceName
 	// FSxWindowsFileServerKey is the string used in resources map to represent fsxwindowsfileserver resource
 	FSxWindowsFileServerKey = fsxwindowsfileserver.ResourceName
+	// SecretFilesKey is the string used in resources map to represent secretfiles resource
+	SecretFilesKey = secretfiles.ResourceName
+	// ProviderSecretKey is the string used in resources map to represent providersecret resource
+	ProviderSecretKey = providersecret.ResourceName
 )
 
 // ResourcesMap represents the map of resource type to the corresponding resource
@@ -91,6 +97,10 @@ func unmarshalResource(key string, value json.RawMessage, result map[string][]ta
 		return unmarshalEnvironmentFilesKey(key, value, result)
 	case FSxWindowsFileServerKey:
 		return unmarshalFSxWindowsFileServerKey(key, value, result)
+	case SecretFilesKey:
+		return unmarshalSecretFilesKey(key, value, result)
+	case ProviderSecretKey:
+		return unmarshalProviderSecretKey(key, value, result)
 	default:
 		return errors.New("Unsupported resource type")
 	}
@@ -256,3 +266,39 @@ func unmarshalFSxWindowsFileServerKey(key string, value json.RawMessage, result
 	}
 	return nil
 }
+
+func unmarshalSecretFilesKey(key string, value json.RawMessage, result map[string][]taskresource.TaskResource) error {
+	var secretFilesResources []json.RawMessage
+	err := json.Unmarshal(value, &secretFilesResources)
+	if err != nil {
+		return err
+	}
+
+	for _, secretFiles := range secretFilesResources {
+		res := &secretfiles.SecretFilesResource{}
+		err := res.UnmarshalJSON(secretFiles)
+		if err != nil {
+			return err
+		}
+		result[key] = append(result[key], res)
+	}
+	return nil
+}
+
+func unmarshalProviderSecretKey(key string, value json.RawMessage, result map[string][]taskresource.TaskResource) error {
+	var providerSecrets []json.RawMessage
+	err := json.Unmarshal(value, &providerSecrets)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range providerSecrets {
+		res := &providersecret.ProviderSecretResource{}
+		err := res.UnmarshalJSON(secret)
+		if err != nil {
+			return err
+		}
+		result[key] = append(result[key], res)
+	}
+	return nil
+}
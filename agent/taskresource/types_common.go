@@ -18,6 +18,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	fsxfactory "github.com/aws/amazon-ecs-agent/agent/fsx/factory"
 	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretprovider"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
 )
 
@@ -28,4 +29,5 @@ type ResourceFieldsCommon struct {
 	FSxClientCreator   fsxfactory.FSxClientCreator
 	CredentialsManager credentials.Manager
 	EC2InstanceID      string
+	SecretProviders    secretprovider.Providers
 }
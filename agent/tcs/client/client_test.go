@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/doctor"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
@@ -112,6 +113,22 @@ func (*mockStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*mockStatsEngine) ContainerStatsHistory(taskARN string, id string, window time.Duration, resolution time.Duration) ([]stats.StatsHistoryPoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerAverageUsage(taskARN string, id string, window time.Duration) (*stats.ContainerUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerMemoryEvents(taskARN string, id string) (*memevents.MemoryEvents, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerStorageStats(taskARN string, id string) (*stats.ContainerStorageStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*mockStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -126,6 +143,22 @@ func (*emptyStatsEngine) ContainerDockerStats(taskARN string, id string) (*types
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*emptyStatsEngine) ContainerStatsHistory(taskARN string, id string, window time.Duration, resolution time.Duration) ([]stats.StatsHistoryPoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*emptyStatsEngine) ContainerAverageUsage(taskARN string, id string, window time.Duration) (*stats.ContainerUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*emptyStatsEngine) ContainerMemoryEvents(taskARN string, id string) (*memevents.MemoryEvents, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*emptyStatsEngine) ContainerStorageStats(taskARN string, id string) (*stats.ContainerStorageStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*emptyStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -146,6 +179,22 @@ func (*idleStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*idleStatsEngine) ContainerStatsHistory(taskARN string, id string, window time.Duration, resolution time.Duration) ([]stats.StatsHistoryPoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*idleStatsEngine) ContainerAverageUsage(taskARN string, id string, window time.Duration) (*stats.ContainerUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*idleStatsEngine) ContainerMemoryEvents(taskARN string, id string) (*memevents.MemoryEvents, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*idleStatsEngine) ContainerStorageStats(taskARN string, id string) (*stats.ContainerStorageStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*idleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -174,6 +223,22 @@ func (*nonIdleStatsEngine) ContainerDockerStats(taskARN string, id string) (*typ
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*nonIdleStatsEngine) ContainerStatsHistory(taskARN string, id string, window time.Duration, resolution time.Duration) ([]stats.StatsHistoryPoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*nonIdleStatsEngine) ContainerAverageUsage(taskARN string, id string, window time.Duration) (*stats.ContainerUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*nonIdleStatsEngine) ContainerMemoryEvents(taskARN string, id string) (*memevents.MemoryEvents, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*nonIdleStatsEngine) ContainerStorageStats(taskARN string, id string) (*stats.ContainerStorageStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*nonIdleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
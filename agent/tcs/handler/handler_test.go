@@ -34,6 +34,7 @@ import (
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/stats/memevents"
 	tcsclient "github.com/aws/amazon-ecs-agent/agent/tcs/client"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/amazon-ecs-agent/agent/version"
@@ -75,6 +76,22 @@ func (*mockStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
+func (*mockStatsEngine) ContainerStatsHistory(taskARN string, id string, window time.Duration, resolution time.Duration) ([]stats.StatsHistoryPoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerAverageUsage(taskARN string, id string, window time.Duration) (*stats.ContainerUsage, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerMemoryEvents(taskARN string, id string) (*memevents.MemoryEvents, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*mockStatsEngine) ContainerStorageStats(taskARN string, id string) (*stats.ContainerStorageStats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (*mockStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
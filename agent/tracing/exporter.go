@@ -0,0 +1,234 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/version"
+	"github.com/cihub/seelog"
+)
+
+const (
+	serviceName = "amazon-ecs-agent"
+	scopeName   = "github.com/aws/amazon-ecs-agent/agent"
+
+	// exportQueueSize is the number of ended spans buffered for export. Spans ended while the
+	// queue is full are dropped.
+	exportQueueSize = 2048
+	// exportBatchSize is the maximum number of spans sent in one request
+	exportBatchSize = 512
+	exportTimeout   = 10 * time.Second
+
+	statusCodeError = 2
+)
+
+// exportInterval is the interval at which queued spans are sent to the collector
+var exportInterval = 5 * time.Second
+
+// exporter sends spans to an OpenTelemetry collector, with the JSON encoding of OTLP/HTTP.
+type exporter struct {
+	endpoint   string
+	resource   otlpResource
+	httpClient *http.Client
+	spans      chan *Span
+}
+
+func newExporter(endpoint, cluster string) *exporter {
+	resource := otlpResource{
+		Attributes: []otlpAttribute{
+			newOTLPAttribute("service.name", serviceName),
+			newOTLPAttribute("service.version", version.Version),
+		},
+	}
+	if cluster != "" {
+		resource.Attributes = append(resource.Attributes, newOTLPAttribute("aws.ecs.cluster.name", cluster))
+	}
+	return &exporter{
+		endpoint:   endpoint,
+		resource:   resource,
+		httpClient: &http.Client{Timeout: exportTimeout},
+		spans:      make(chan *Span, exportQueueSize),
+	}
+}
+
+func (e *exporter) export(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		seelog.Debugf("Trace export queue is full, dropping span %s", span.name)
+	}
+}
+
+// run sends the queued spans in batches, until the context is cancelled. The spans queued by
+// then are sent one last time.
+func (e *exporter) run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+				default:
+					e.send(batch)
+					return
+				}
+			}
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		}
+	}
+}
+
+func (e *exporter) send(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		seelog.Warnf("Unable to marshal %d spans: %v", len(spans), err)
+		return
+	}
+	resp, err := e.httpClient.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		seelog.Warnf("Unable to export %d spans to %s: %v", len(spans), e.endpoint, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		seelog.Warnf("Unable to export %d spans to %s: %s", len(spans), e.endpoint, resp.Status)
+	}
+}
+
+func (e *exporter) request(spans []*Span) *otlpTracesRequest {
+	scopeSpans := otlpScopeSpans{
+		Scope: otlpScope{Name: scopeName, Version: version.Version},
+	}
+	for _, span := range spans {
+		scopeSpans.Spans = append(scopeSpans.Spans, newOTLPSpan(span))
+	}
+	return &otlpTracesRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   e.resource,
+			ScopeSpans: []otlpScopeSpans{scopeSpans},
+		}},
+	}
+}
+
+// The following types are the JSON encoding of the OTLP ExportTraceServiceRequest. Trace and
+// span ids are hex encoded, and 64 bits integers are encoded as strings.
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Links             []otlpLink      `json:"links,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOTLPAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
+
+func newOTLPSpan(span *Span) otlpSpan {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	s := otlpSpan{
+		TraceID:           hex.EncodeToString(span.traceID[:]),
+		SpanID:            hex.EncodeToString(span.spanID[:]),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+	}
+	if span.parentSpanID != [8]byte{} {
+		s.ParentSpanID = hex.EncodeToString(span.parentSpanID[:])
+	}
+	for _, attribute := range span.attributes {
+		s.Attributes = append(s.Attributes, newOTLPAttribute(attribute.Key, attribute.Value))
+	}
+	for _, link := range span.links {
+		s.Links = append(s.Links, otlpLink{
+			TraceID: hex.EncodeToString(link.traceID[:]),
+			SpanID:  hex.EncodeToString(link.spanID[:]),
+		})
+	}
+	if span.err != nil {
+		s.Status = &otlpStatus{Code: statusCodeError, Message: span.err.Error()}
+	}
+	return s
+}
@@ -0,0 +1,249 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing records spans of the work done by the agent, such as task and container
+// transitions and docker calls, and exports them to an OpenTelemetry collector over OTLP/HTTP.
+// Tracing is disabled unless an endpoint is configured, in which case every function of the
+// package is a no-op.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// AttributeTaskARN is the attribute of the task ARN
+	AttributeTaskARN = "aws.ecs.task.arn"
+	// AttributeTaskFamily is the attribute of the task definition family
+	AttributeTaskFamily = "aws.ecs.task.family"
+	// AttributeTaskRevision is the attribute of the task definition revision
+	AttributeTaskRevision = "aws.ecs.task.revision"
+	// AttributeContainerName is the attribute of the container name
+	AttributeContainerName = "container.name"
+	// AttributeDockerID is the attribute of the docker id of the container
+	AttributeDockerID = "container.id"
+	// AttributeImage is the attribute of the image name
+	AttributeImage = "container.image.name"
+	// AttributeResourceName is the attribute of the task resource name
+	AttributeResourceName = "aws.ecs.task.resource.name"
+	// AttributeStatus is the attribute of the status a container or resource transitions to
+	AttributeStatus = "aws.ecs.transition.status"
+)
+
+// SpanKind is the kind of a span, as defined by OpenTelemetry
+type SpanKind int
+
+const (
+	// SpanKindInternal is the kind of spans of work done by the agent itself
+	SpanKindInternal SpanKind = 1
+	// SpanKindClient is the kind of spans of calls made by the agent to another service
+	SpanKindClient SpanKind = 3
+)
+
+// Attribute is a key value pair describing a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String creates an attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a unit of work of a trace. A nil span is valid, and does nothing, so that callers
+// don't need to check whether tracing is enabled.
+type Span struct {
+	tracer       *tracer
+	traceID      [16]byte
+	spanID       [8]byte
+	parentSpanID [8]byte
+	name         string
+	kind         SpanKind
+	start        time.Time
+	// links are the spans of other traces that the span is related to
+	links []spanLink
+
+	lock       sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        error
+	ended      bool
+}
+
+// SetAttributes adds attributes to the span
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// RecordError marks the span as failed with the error, if it's not nil
+func (span *Span) RecordError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.err = err
+}
+
+// End completes the span and queues it for export. Only the first call has an effect.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	if span.ended {
+		span.lock.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.lock.Unlock()
+	span.tracer.exporter.export(span)
+}
+
+// TraceID returns the hex encoded id of the trace of the span
+func (span *Span) TraceID() string {
+	if span == nil {
+		return ""
+	}
+	return hex.EncodeToString(span.traceID[:])
+}
+
+// spanLink identifies a span of another trace
+type spanLink struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type tracer struct {
+	exporter *exporter
+}
+
+type spanContextKey struct{}
+
+var (
+	tracerGlobal     *tracer
+	tracerGlobalLock sync.RWMutex
+)
+
+// Init enables tracing when an OTLP traces endpoint is configured, and disables it otherwise.
+// Spans are exported until the context is cancelled.
+func Init(ctx context.Context, cfg *config.Config) {
+	tracerGlobalLock.Lock()
+	defer tracerGlobalLock.Unlock()
+	if cfg.OTLPTracesEndpoint == "" {
+		tracerGlobal = nil
+		return
+	}
+	exporter := newExporter(cfg.OTLPTracesEndpoint, cfg.Cluster)
+	go exporter.run(ctx)
+	tracerGlobal = &tracer{exporter: exporter}
+	seelog.Infof("Exporting traces to %s", cfg.OTLPTracesEndpoint)
+}
+
+func getTracer() *tracer {
+	tracerGlobalLock.RLock()
+	defer tracerGlobalLock.RUnlock()
+	return tracerGlobal
+}
+
+// StartSpan starts a span. The span is a child of the span of the context if there's one,
+// otherwise it's the root span of a new trace. The returned context carries the new span.
+func StartSpan(ctx context.Context, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.start(ctx, SpanFromContext(ctx), name, kind, attributes)
+}
+
+// StartChildSpan starts a span only when the context carries a parent span. It's meant for
+// work that's done on behalf of many callers, such as docker calls, so that only the work
+// that's part of a trace is recorded.
+func StartChildSpan(ctx context.Context, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	t := getTracer()
+	if t == nil || parent == nil {
+		return ctx, nil
+	}
+	return t.start(ctx, parent, name, kind, attributes)
+}
+
+// StartLinkedSpan starts the root span of a new trace, linked to the given spans. It's meant for
+// work that's related to earlier traces but happens long after they ended, such as stopping and
+// cleaning up a task that was launched hours ago. Nil spans aren't linked.
+func StartLinkedSpan(ctx context.Context, links []*Span, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, nil
+	}
+	ctx, span := t.start(ctx, nil, name, kind, attributes)
+	for _, link := range links {
+		if link != nil {
+			span.links = append(span.links, spanLink{traceID: link.traceID, spanID: link.spanID})
+		}
+	}
+	return ctx, span
+}
+
+func (t *tracer) start(ctx context.Context, parent *Span, name string, kind SpanKind,
+	attributes []Attribute) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentSpanID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// ContextWithSpan returns a copy of the context that carries the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span carried by the context, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
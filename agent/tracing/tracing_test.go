@@ -0,0 +1,138 @@
+//go:build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetTracer() {
+	tracerGlobalLock.Lock()
+	defer tracerGlobalLock.Unlock()
+	tracerGlobal = nil
+}
+
+func TestTracingDisabled(t *testing.T) {
+	defer resetTracer()
+	Init(context.Background(), &config.Config{})
+
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "task", SpanKindInternal, String(AttributeTaskARN, "arn"))
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+	// A nil span can be used like any other
+	span.SetAttributes(String(AttributeDockerID, "id"))
+	span.RecordError(errors.New("error"))
+	span.End()
+	assert.Empty(t, span.TraceID())
+
+	spanCtx, span = StartLinkedSpan(ctx, []*Span{nil}, "task.cleanup", SpanKindInternal)
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+}
+
+func TestStartChildSpanWithoutParent(t *testing.T) {
+	defer resetTracer()
+	tracerGlobal = &tracer{exporter: newExporter("http://localhost:4318/v1/traces", "")}
+
+	ctx := context.Background()
+	spanCtx, span := StartChildSpan(ctx, "docker.CreateContainer", SpanKindClient)
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+}
+
+func TestExportSpans(t *testing.T) {
+	defer resetTracer()
+	requests := make(chan *otlpTracesRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		request := &otlpTracesRequest{}
+		require.NoError(t, json.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	Init(ctx, &config.Config{OTLPTracesEndpoint: server.URL, Cluster: "cluster"})
+
+	taskCtx, taskSpan := StartSpan(context.Background(), "task", SpanKindInternal, String(AttributeTaskARN, "arn"))
+	require.NotNil(t, taskSpan)
+	transitionCtx, transitionSpan := StartSpan(taskCtx, "container.create", SpanKindInternal)
+	_, dockerSpan := StartChildSpan(transitionCtx, "docker.CreateContainer", SpanKindClient)
+	require.NotNil(t, dockerSpan)
+	dockerSpan.RecordError(errors.New("no such image"))
+	dockerSpan.End()
+	transitionSpan.SetAttributes(String(AttributeDockerID, "id"))
+	transitionSpan.End()
+	taskSpan.End()
+	// Ending a span again doesn't export it again
+	taskSpan.End()
+	_, cleanupSpan := StartLinkedSpan(transitionCtx, []*Span{taskSpan, nil}, "task.cleanup", SpanKindInternal)
+	cleanupSpan.End()
+	// Spans queued by the time the context is cancelled are exported
+	cancel()
+
+	var request *otlpTracesRequest
+	select {
+	case request = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for spans to be exported")
+	}
+	require.Len(t, request.ResourceSpans, 1)
+	assert.Contains(t, request.ResourceSpans[0].Resource.Attributes, newOTLPAttribute("service.name", serviceName))
+	assert.Contains(t, request.ResourceSpans[0].Resource.Attributes, newOTLPAttribute("aws.ecs.cluster.name", "cluster"))
+	require.Len(t, request.ResourceSpans[0].ScopeSpans, 1)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 4)
+
+	docker, transition, task, cleanup := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, taskSpan.TraceID(), task.TraceID)
+	assert.Len(t, task.TraceID, 32)
+	assert.Len(t, task.SpanID, 16)
+	assert.Empty(t, task.ParentSpanID)
+	assert.Equal(t, []otlpAttribute{newOTLPAttribute(AttributeTaskARN, "arn")}, task.Attributes)
+	assert.Nil(t, task.Status)
+
+	assert.Equal(t, task.TraceID, transition.TraceID)
+	assert.Equal(t, task.SpanID, transition.ParentSpanID)
+	assert.Equal(t, []otlpAttribute{newOTLPAttribute(AttributeDockerID, "id")}, transition.Attributes)
+
+	assert.Equal(t, task.TraceID, docker.TraceID)
+	assert.Equal(t, transition.SpanID, docker.ParentSpanID)
+	assert.Equal(t, SpanKindClient, docker.Kind)
+	require.NotNil(t, docker.Status)
+	assert.Equal(t, statusCodeError, docker.Status.Code)
+	assert.Equal(t, "no such image", docker.Status.Message)
+
+	// Linked spans start a new trace, whatever the span of their context
+	assert.NotEqual(t, task.TraceID, cleanup.TraceID)
+	assert.Empty(t, cleanup.ParentSpanID)
+	assert.Equal(t, []otlpLink{{TraceID: task.TraceID, SpanID: task.SpanID}}, cleanup.Links)
+	assert.Empty(t, task.Links)
+}